@@ -18,13 +18,21 @@ import (
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
 	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
+	productclient "github.com/ADRPUR/event-driven-marketplace/internal/product/client"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
 	"github.com/ADRPUR/event-driven-marketplace/pkg/config"
 	"github.com/ADRPUR/event-driven-marketplace/pkg/database"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/mail"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/tlsconfig"
 	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/upload"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -44,43 +52,173 @@ func main() {
 		log.Fatalf("Paseto maker: %v", err)
 	}
 
+	// TLS / mTLS for both listeners (nil → plaintext)
+	tlsCfg, err := tlsconfig.ServerConfig(cfg.TLS)
+	if err != nil {
+		log.Fatalf("TLS config: %v", err)
+	}
+	servicePeers := middleware.WithServicePeers(cfg.TLS.AllowedSANs, cfg.TLS.ServiceScopes)
+
+	// The data export includes the user's products, read from the
+	// product-service as a service principal with products:read.
+	clientTLS, err := tlsconfig.ClientConfig(cfg.TLS)
+	if err != nil {
+		log.Fatalf("TLS client config: %v", err)
+	}
+	creds := insecure.NewCredentials()
+	if clientTLS != nil {
+		creds = credentials.NewTLS(clientTLS)
+	}
+	productConn, err := grpc.NewClient(cfg.ProductService, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Fatalf("product-service client: %v", err)
+	}
+	defer productConn.Close()
+
+	// Uploaded media (local directory or S3-compatible bucket)
+	urlKey, err := cfg.Blob.URLKey([]byte(cfg.SymmetricKey))
+	if err != nil {
+		log.Fatalf("blob signing key: %v", err)
+	}
+	store, err := blob.New(cfg.Blob, urlKey)
+	if err != nil {
+		log.Fatalf("blob store: %v", err)
+	}
+	// Uploads are size-limited and type-checked; with clamd configured they
+	// are also scanned, and rejected files are quarantined in the store.
+	var scanner upload.Scanner
+	if cfg.ClamAVAddr != "" {
+		scanner = upload.NewClamAV(cfg.ClamAVAddr, cfg.ClamAVTimeout)
+	}
+	photoGuard := upload.NewGuard(cfg.PhotoUpload, scanner, store)
+	photos := service.NewPhotos(store, cfg.Blob.URLTTL, cfg.Images, photoGuard)
+
 	// 4) Repository & Service
 	repo := repository.NewGormRepository(db)
-	svc := service.New(repo, repo, maker, 15*time.Minute, 24*time.Hour)
+	const accessTTL, refreshTTL = 15 * time.Minute, 24 * time.Hour
+	audit := service.NewAudit(repo)
+	svc := service.New(repo, repo, maker, accessTTL, refreshTTL,
+		service.WithAudit(audit), service.WithPhotos(photos))
+	oauthSvc := service.NewOAuth(repo, maker, time.Hour)
+	apiKeySvc := service.NewAPIKeys(repo)
+	impersonationSvc := service.NewImpersonation(repo, repo, maker, 15*time.Minute)
+	impersonationAudit := service.NewImpersonationAuditor(repo, "auth-service")
+	impersonationRecorder := httpHandler.ImpersonationRecorder(impersonationAudit)
+	privacySvc := service.NewPrivacy(repo, repo, repo, repo, productclient.New(productConn), photos, audit, cfg.AccountDeletionGrace)
+	accountsSvc := service.NewAccounts(repo, photos, audit, cfg.AccountRetention)
+	uploadsSvc := service.NewPhotoUploads(upload.NewGormSessions(db), store, svc, cfg.PhotoUpload.MaxBytes, cfg.UploadSessionTTL)
+	addressSvc := service.NewAddresses(repo, audit)
+	emailSvc := service.NewEmailChanges(repo, repo, mail.New(cfg.Mail), cfg.EmailConfirmURL, cfg.EmailChangeTTL, audit)
+
+	// Domain events are queued in the outbox and delivered by the relay.
+	var publisher events.Publisher = events.Log{}
+	if len(cfg.EventWebhookURLs) > 0 {
+		publisher = events.NewWebhook(cfg.EventWebhookURLs, cfg.EventWebhookSecret, 10*time.Second)
+	}
+	relay := events.NewRelay(db, service.EventSource, publisher)
+
+	// Background jobs, stopped on shutdown
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	go runEvery(jobCtx, time.Hour, "account purge", func(ctx context.Context) error {
+		n, err := privacySvc.PurgeDue(ctx)
+		if n > 0 {
+			log.Printf("account purge: erased %d account(s)", n)
+		}
+		return err
+	})
+	go runEvery(jobCtx, time.Hour, "deleted account purge", func(ctx context.Context) error {
+		n, err := accountsSvc.PurgeExpired(ctx)
+		if n > 0 {
+			log.Printf("deleted account purge: removed %d account(s)", n)
+		}
+		return err
+	})
+	go runEvery(jobCtx, 5*time.Second, "event relay", func(ctx context.Context) error {
+		_, err := relay.Flush(ctx)
+		return err
+	})
+	go runEvery(jobCtx, time.Hour, "upload purge", func(ctx context.Context) error {
+		n, err := uploadsSvc.PurgeExpired(ctx)
+		if n > 0 {
+			log.Printf("upload purge: removed %d unfinished upload(s)", n)
+		}
+		return err
+	})
 
 	// 5) Gin HTTP server
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 	r.Use(gin.Recovery())
+	r.Use(middleware.RequestID())
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", middleware.APIKeyHeader, middleware.CSRFHeader},
+		ExposeHeaders:    []string{"Content-Length", middleware.RequestIDHeader},
 		AllowCredentials: true,
 	}))
-	// Expose the photo folder
+	// Photos uploaded before the blob store
 	r.Static("/static", "./static")
+	// The local backend serves its objects itself; signed URLs point here.
+	if local, ok := store.(*blob.Local); ok {
+		prefix := strings.TrimSuffix(cfg.Blob.URLPrefix, "/")
+		r.GET(prefix+"/*key", gin.WrapH(http.StripPrefix(prefix, local)))
+	}
 
-	// Public routes: register, login, refresh
-	httpHandler.RegisterPublicRoutes(r, httpHandler.New(svc))
-	// Protected routes: /me, /logout, /me/photo etc.
-	authMW := middleware.AuthMiddleware(maker)
-	protected := r.Group("/", authMW)
-	httpHandler.RegisterProtectedRoutes(protected, httpHandler.New(svc))
+	// Optional browser session mode: tokens travel in HttpOnly cookies and
+	// state-changing requests must carry the double-submit CSRF token.
+	authOpts := []middleware.Option{middleware.WithAPIKeys(apiKeySvc), servicePeers}
+	var handlerOpts []httpHandler.Option
+	if cfg.CookieSession {
+		r.Use(middleware.CSRF())
+		authOpts = append(authOpts, middleware.WithCookie())
+		handlerOpts = append(handlerOpts, httpHandler.WithCookieSession(middleware.CookieConfig{
+			Domain:     cfg.CookieDomain,
+			Secure:     cfg.CookieSecure,
+			SameSite:   middleware.ParseSameSite(cfg.CookieSameSite),
+			SessionTTL: refreshTTL,
+		}))
+	}
+	handlerOpts = append(handlerOpts, httpHandler.WithMaxUploadBytes(cfg.PhotoUpload.MaxBytes))
+	authHandler := httpHandler.New(svc, handlerOpts...)
+
+	// Public routes: register, login, refresh, OAuth2 token endpoint, email confirmation
+	httpHandler.RegisterPublicRoutes(r, authHandler)
+	httpHandler.RegisterOAuthPublicRoutes(r, httpHandler.NewOAuth(oauthSvc))
+	httpHandler.RegisterEmailChangePublicRoutes(r, httpHandler.NewEmailChanges(emailSvc))
+	// Protected routes: /me, /logout, /me/photo, /oauth/* etc.
+	// Tokens issued to third-party OAuth2 clients are not accepted here.
+	// Requests made with impersonation tokens are written to the audit trail.
+	authMW := middleware.AuthMiddleware(maker, authOpts...)
+	// Third-party clients and API keys may read the profile with profile:read.
+	profile := r.Group("/", authMW, middleware.ImpersonationAudit(impersonationRecorder), middleware.RequireScope(token.ScopeProfileRead))
+	httpHandler.RegisterProfileReadRoutes(profile, authHandler)
+	protected := r.Group("/", authMW, middleware.ImpersonationAudit(impersonationRecorder), middleware.RequireFirstParty())
+	httpHandler.RegisterProtectedRoutes(protected, authHandler)
+	httpHandler.RegisterOAuthProtectedRoutes(protected, httpHandler.NewOAuth(oauthSvc))
+	httpHandler.RegisterAPIKeyRoutes(protected, httpHandler.NewAPIKeys(apiKeySvc))
+	httpHandler.RegisterPrivacyRoutes(protected, httpHandler.NewPrivacy(privacySvc))
+	httpHandler.RegisterAddressRoutes(protected, httpHandler.NewAddresses(addressSvc))
+	httpHandler.RegisterEmailChangeRoutes(protected, httpHandler.NewEmailChanges(emailSvc))
+	// Admin routes
+	adminHandler := httpHandler.NewAdmin(impersonationSvc, svc, audit, accountsSvc)
+	httpHandler.RegisterImpersonationRoutes(protected.Group("/", middleware.RequireRole(token.RoleAdmin)), adminHandler)
+	admin := protected.Group("/admin", middleware.RequireRole(token.RoleAdmin))
+	httpHandler.RegisterAdminRoutes(admin, adminHandler)
 
 	httpAddr := cfg.AuthHTTPAddr
 	if !strings.Contains(httpAddr, ":") {
 		httpAddr = ":" + httpAddr
 	}
 	httpSrv := &http.Server{
-		Addr:    httpAddr,
-		Handler: r,
+		Addr:      httpAddr,
+		Handler:   r,
+		TLSConfig: tlsCfg,
 	}
 
 	go func() {
-		log.Printf("HTTP server listening on %s", httpAddr)
-		if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("HTTP server listening on %s (tls=%t)", httpAddr, tlsCfg != nil)
+		if err := serveHTTP(httpSrv); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("HTTP server error: %v", err)
 		}
 	}()
@@ -90,10 +228,34 @@ func main() {
 	if !strings.Contains(grpcAddr, ":") {
 		grpcAddr = ":" + grpcAddr
 	}
-	grpcSrv := grpc.NewServer(
-		grpc.UnaryInterceptor(middleware.AuthUnaryInterceptor(maker)),
-	)
-	auth1.RegisterAuthServiceServer(grpcSrv, grpcHandler.NewGRPCServer(svc))
+	// The default 4 MiB message limit applies to every RPC, UploadPhoto
+	// included; larger photos are sent with UploadPhotoStream.
+	grpcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			middleware.RequestIDUnaryInterceptor(),
+			middleware.AuthUnaryInterceptor(maker, middleware.WithAPIKeys(apiKeySvc), servicePeers),
+			middleware.ImpersonationAuditInterceptor(impersonationRecorder),
+			// OAuth2 tokens and API keys only reach the RPCs mapped to a scope
+			middleware.ScopeUnaryInterceptor(grpcHandler.MethodScopes),
+		),
+		grpc.ChainStreamInterceptor(
+			middleware.RequestIDStreamInterceptor(),
+			middleware.AuthStreamInterceptor(maker, middleware.WithAPIKeys(apiKeySvc), servicePeers),
+			middleware.ImpersonationAuditStreamInterceptor(impersonationRecorder),
+			middleware.ScopeStreamInterceptor(grpcHandler.MethodScopes),
+		),
+	}
+	if tlsCfg != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	grpcSrv := grpc.NewServer(grpcOpts...)
+	auth1.RegisterAuthServiceServer(grpcSrv, grpcHandler.NewGRPCServer(svc,
+		grpcHandler.WithPhotoUploads(uploadsSvc),
+		grpcHandler.WithAddresses(addressSvc),
+		grpcHandler.WithEmailChanges(emailSvc),
+		// API-key checks and the impersonation trail of product-service
+		grpcHandler.WithInternal(apiKeySvc, impersonationAudit),
+	))
 	reflection.Register(grpcSrv)
 
 	go func() {
@@ -101,7 +263,7 @@ func main() {
 		if err != nil {
 			log.Fatalf("failed to listen on %s: %v", grpcAddr, err)
 		}
-		log.Printf("gRPC server listening on %s", grpcAddr)
+		log.Printf("gRPC server listening on %s (tls=%t)", grpcAddr, tlsCfg != nil)
 		if err := grpcSrv.Serve(lis); err != nil {
 			log.Fatalf("gRPC server error: %v", err)
 		}
@@ -112,6 +274,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down gracefully...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -122,3 +285,28 @@ func main() {
 	grpcSrv.GracefulStop()
 	log.Println("Auth-Service stopped gracefully")
 }
+
+// serveHTTP starts srv with TLS when srv.TLSConfig is set (certificates are
+// already loaded into it), otherwise in plaintext.
+func serveHTTP(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// runEvery calls job immediately and then every interval until ctx is done.
+func runEvery(ctx context.Context, interval time.Duration, name string, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := job(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("%s: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
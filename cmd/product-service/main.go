@@ -19,15 +19,23 @@ import (
 	"time"
 
 	productv1 "github.com/ADRPUR/event-driven-marketplace/api/proto/product/v1"
+	authclient "github.com/ADRPUR/event-driven-marketplace/internal/auth/client"
 
 	httphandler "github.com/ADRPUR/event-driven-marketplace/internal/product/handler/http"
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
 	"github.com/ADRPUR/event-driven-marketplace/pkg/config"
 	"github.com/ADRPUR/event-driven-marketplace/pkg/database"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/fx"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/tlsconfig"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/upload"
 
 	"github.com/gin-gonic/gin"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -45,8 +53,71 @@ func main() {
 	// ------------------------------------------------------------------
 	// 2. Wiring layers
 	// ------------------------------------------------------------------
-	repo := repository.NewGormRepository(db)
-	svc := service.New(repo) // business‑logic layer
+	if err := repository.ValidateSearchConfig(context.Background(), db, cfg.SearchConfig); err != nil {
+		log.Fatalf("SEARCH_CONFIG: %v", err)
+	}
+	repo := repository.NewGormRepository(db, repository.WithSearchConfig(cfg.SearchConfig))
+	categoryRepo := repository.NewGormCategoryRepository(db)
+	categories := service.NewCategories(categoryRepo)
+	svcOpts := []service.Option{service.WithCategories(categoryRepo)}
+	if rates := fx.NewProvider(cfg.FX); rates != nil {
+		svcOpts = append(svcOpts, service.WithFX(fx.NewConverter(rates)))
+	}
+	svc := service.New(repo, svcOpts...) // business‑logic layer
+	// Product images go to the blob store shared with the auth-service.
+	// Uploads are size-limited and type-checked; with clamd configured they
+	// are also scanned, and rejected files are quarantined in the store.
+	urlKey, err := cfg.Blob.URLKey([]byte(cfg.SymmetricKey))
+	if err != nil {
+		log.Fatalf("blob signing key: %v", err)
+	}
+	store, err := blob.New(cfg.Blob, urlKey)
+	if err != nil {
+		log.Fatalf("blob store: %v", err)
+	}
+	var scanner upload.Scanner
+	if cfg.ClamAVAddr != "" {
+		scanner = upload.NewClamAV(cfg.ClamAVAddr, cfg.ClamAVTimeout)
+	}
+	images := service.NewImages(repo, repository.NewGormImageRepository(db), store, cfg.Blob.URLTTL, cfg.Images,
+		upload.NewGuard(cfg.ImageUpload, scanner, store), cfg.ImageUpload.MaxBytes,
+		upload.NewGormSessions(db), cfg.UploadSessionTTL)
+	inventory := service.NewInventory(repository.NewGormInventoryRepository(db), repo, cfg.LowStock, cfg.ReservationTTL)
+
+	// Inventory events are queued in the outbox and delivered by the relay.
+	var publisher events.Publisher = events.Log{}
+	if len(cfg.EventWebhookURLs) > 0 {
+		publisher = events.NewWebhook(cfg.EventWebhookURLs, cfg.EventWebhookSecret, 10*time.Second)
+	}
+	relay := events.NewRelay(db, service.EventSource, publisher)
+
+	// Background jobs, stopped on shutdown
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	go runEvery(jobCtx, 5*time.Second, "event relay", func(ctx context.Context) error {
+		_, err := relay.Flush(ctx)
+		return err
+	})
+	go runEvery(jobCtx, 30*time.Second, "reservation expiry", func(ctx context.Context) error {
+		n, err := inventory.ExpireDue(ctx)
+		if n > 0 {
+			log.Printf("reservation expiry: released %d reservation(s)", n)
+		}
+		return err
+	})
+	go runEvery(jobCtx, time.Hour, "upload purge", func(ctx context.Context) error {
+		n, err := images.PurgeExpired(ctx)
+		if n > 0 {
+			log.Printf("upload purge: removed %d unfinished upload(s)", n)
+		}
+		return err
+	})
+	go runEvery(jobCtx, cfg.StockSnapshots, "stock snapshots", func(ctx context.Context) error {
+		n, err := inventory.Snapshot(ctx)
+		if n > 0 {
+			log.Printf("stock snapshots: took %d snapshot(s)", n)
+		}
+		return err
+	})
 
 	// Create a Paseto token maker
 	maker, err := token.NewPasetoMaker(cfg.SymmetricKey)
@@ -54,27 +125,75 @@ func main() {
 		log.Fatalf("paseto maker: %v", err)
 	}
 
+	// TLS / mTLS for both listeners (nil → plaintext). Internal callers with
+	// an allowed client certificate are authenticated as service principals.
+	tlsCfg, err := tlsconfig.ServerConfig(cfg.TLS)
+	if err != nil {
+		log.Fatalf("tls config: %v", err)
+	}
+
+	// Personal API keys are verified, and the impersonation trail written,
+	// by the auth-service. It accepts these calls only from service
+	// principals, so they need a client certificate it allows.
+	clientTLS, err := tlsconfig.ClientConfig(cfg.TLS)
+	if err != nil {
+		log.Fatalf("tls client config: %v", err)
+	}
+	creds := insecure.NewCredentials()
+	if clientTLS != nil {
+		creds = credentials.NewTLS(clientTLS)
+	}
+	authConn, err := grpc.NewClient(cfg.AuthService, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Fatalf("auth-service client: %v", err)
+	}
+	defer authConn.Close()
+	authClient := authclient.New(authConn)
+	apiKeys := middleware.WithAPIKeys(authClient)
+	impersonationAudit := authClient
+	servicePeers := middleware.WithServicePeers(cfg.TLS.AllowedSANs, cfg.TLS.ServiceScopes)
+
 	// ------------------------------------------------------------------
 	// 3. HTTP server (Gin)
 	// ------------------------------------------------------------------
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
-	r.Use(gin.Recovery(), middleware.AuthMiddleware(maker))
+	r.Use(gin.Recovery())
+	r.Use(middleware.RequestID())
+	// The local backend serves its objects itself; signed URLs point here.
+	// It is mounted before authentication: the signature is the credential,
+	// so image URLs work in <img src> without a token.
+	if local, ok := store.(*blob.Local); ok {
+		prefix := strings.TrimSuffix(cfg.Blob.URLPrefix, "/")
+		r.GET(prefix+"/*key", gin.WrapH(http.StripPrefix(prefix, local)))
+	}
+	authOpts := []middleware.Option{apiKeys, servicePeers}
+	if cfg.CookieSession {
+		// Browser clients authenticate with the auth-service cookies.
+		r.Use(middleware.CSRF())
+		authOpts = append(authOpts, middleware.WithCookie())
+	}
+	r.Use(middleware.AuthMiddleware(maker, authOpts...))
+	r.Use(middleware.ImpersonationAudit(impersonationAudit))
 
-	httphandler.RegisterHTTPRoutes(r, svc)
+	httphandler.RegisterHTTPRoutes(r, svc,
+		httphandler.WithImages(images), httphandler.WithMaxUploadBytes(cfg.ImageUpload.MaxBytes))
+	httphandler.NewCategories(categories).RegisterRoutes(r)
+	httphandler.NewInventory(inventory).RegisterRoutes(r)
 
 	if !strings.Contains(cfg.ProdHTTPAddr, ":") {
 		cfg.ProdHTTPAddr = ":" + cfg.ProdHTTPAddr
 	}
 
 	httpSrv := &http.Server{
-		Addr:    cfg.ProdHTTPAddr, // default ":8080"
-		Handler: r,
+		Addr:      cfg.ProdHTTPAddr, // default ":8080"
+		Handler:   r,
+		TLSConfig: tlsCfg,
 	}
 
 	go func() {
-		log.Printf("HTTP server listening on %s", cfg.ProdHTTPAddr)
-		if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("HTTP server listening on %s (tls=%t)", cfg.ProdHTTPAddr, tlsCfg != nil)
+		if err := serveHTTP(httpSrv); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("HTTP server error: %v", err)
 		}
 	}()
@@ -82,9 +201,27 @@ func main() {
 	// ------------------------------------------------------------------
 	// 4. gRPC server
 	// ------------------------------------------------------------------
-	interceptor := grpc.UnaryInterceptor(middleware.AuthUnaryInterceptor(maker))
-	grpcSrv := grpc.NewServer(interceptor)
-	productv1.RegisterProductServiceServer(grpcSrv, grpcHandler.NewGRPCServer(svc))
+	interceptor := grpc.ChainUnaryInterceptor(
+		middleware.RequestIDUnaryInterceptor(),
+		middleware.AuthUnaryInterceptor(maker, apiKeys, servicePeers),
+		middleware.ImpersonationAuditInterceptor(impersonationAudit),
+		middleware.ScopeUnaryInterceptor(grpcHandler.MethodScopes),
+	)
+	streamInterceptor := grpc.ChainStreamInterceptor(
+		middleware.RequestIDStreamInterceptor(),
+		middleware.AuthStreamInterceptor(maker, apiKeys, servicePeers),
+		middleware.ImpersonationAuditStreamInterceptor(impersonationAudit),
+		middleware.ScopeStreamInterceptor(grpcHandler.MethodScopes),
+	)
+	// The default 4 MiB message limit applies; images are streamed in
+	// chunks with UploadProductImage.
+	grpcOpts := []grpc.ServerOption{interceptor, streamInterceptor}
+	if tlsCfg != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	grpcSrv := grpc.NewServer(grpcOpts...)
+	productv1.RegisterProductServiceServer(grpcSrv, grpcHandler.NewGRPCServer(svc,
+		grpcHandler.WithCategories(categories), grpcHandler.WithInventory(inventory), grpcHandler.WithImages(images)))
 	reflection.Register(grpcSrv)
 
 	if !strings.Contains(cfg.ProdGRPCAddr, ":") {
@@ -96,7 +233,7 @@ func main() {
 		if err != nil {
 			log.Fatalf("failed to listen on %s: %v", cfg.ProdGRPCAddr, err)
 		}
-		log.Printf("gRPC server listening on %s", cfg.ProdGRPCAddr)
+		log.Printf("gRPC server listening on %s (tls=%t)", cfg.ProdGRPCAddr, tlsCfg != nil)
 		if err := grpcSrv.Serve(lis); err != nil {
 			log.Fatalf("gRPC server error: %v", err)
 		}
@@ -112,6 +249,7 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	stopJobs()
 	if err := httpSrv.Shutdown(ctx); err != nil {
 		log.Printf("HTTP server shutdown error: %v", err)
 	}
@@ -119,3 +257,28 @@ func main() {
 
 	log.Println("Product‑Service stopped gracefully")
 }
+
+// serveHTTP starts srv with TLS when srv.TLSConfig is set (certificates are
+// already loaded into it), otherwise in plaintext.
+func serveHTTP(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// runEvery calls job immediately and then every interval until ctx is done.
+func runEvery(ctx context.Context, interval time.Duration, name string, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := job(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("%s: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
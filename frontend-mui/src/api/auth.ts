@@ -3,7 +3,19 @@ import type { AuthResponse } from "../types/user";
 
 export const API_URL = import.meta.env.VITE_API_URL || "http://localhost:8090";
 
-const api = axios.create({ baseURL: API_URL });
+// Photo URLs are signed and change with every upload; object-store URLs are absolute.
+export function mediaUrl(path: string): string {
+    return /^https?:\/\//.test(path) ? path : `${API_URL}${path}`;
+}
+
+// Cookies + CSRF header are used when the auth service runs in cookie session mode.
+const api = axios.create({
+    baseURL: API_URL,
+    withCredentials: true,
+    withXSRFToken: true,
+    xsrfCookieName: "csrf_token",
+    xsrfHeaderName: "X-CSRF-Token",
+});
 
 export async function login(email: string, password: string): Promise<AuthResponse> {
     const res = await api.post("/login", { email, password });
@@ -1,18 +1,26 @@
 import axios from "axios";
-import type {User, UserDetails, UsersListResponse} from "../types/user";
+import type {Address, UpdateProfileRequest, User, UserDetails, UsersListResponse} from "../types/user";
 
 export const API_BASE_URL = "http://localhost:8090";
 
-const api = axios.create({ baseURL: API_BASE_URL });
+// Cookies + CSRF header are used when the auth service runs in cookie session mode.
+const api = axios.create({
+    baseURL: API_BASE_URL,
+    withCredentials: true,
+    withXSRFToken: true,
+    xsrfCookieName: "csrf_token",
+    xsrfHeaderName: "X-CSRF-Token",
+});
 
 export async function getProfile(token: string): Promise<UserDetails> {
     const res = await api.get<UserDetails>("/me", { headers: { Authorization: `Bearer ${token}` } });
     return res.data;
 }
 
-export async function updateProfile(token: string, data: Partial<User>): Promise<{ user: User }> {
-    const res = await api.put<{ user: User }>("/me", data, {
-        headers: { Authorization: `Bearer ${token}` },
+// Sends a JSON Merge Patch: only the given fields change, null clears one.
+export async function updateProfile(token: string, data: UpdateProfileRequest): Promise<UserDetails["details"]> {
+    const res = await api.patch<UserDetails["details"]>("/me", data, {
+        headers: { Authorization: `Bearer ${token}`, "Content-Type": "application/merge-patch+json" },
     });
     return res.data;
 }
@@ -29,6 +37,33 @@ export async function uploadPhoto(token: string, file: File): Promise<{ photoPat
     return res.data;
 }
 
+// Mails a confirmation link to the new address; the email changes once it is confirmed.
+export async function requestEmailChange(token: string, email: string, password: string): Promise<void> {
+    await api.post("/me/email", { email, password }, { headers: { Authorization: `Bearer ${token}` } });
+}
+
+export async function confirmEmailChange(confirmationToken: string): Promise<void> {
+    await api.post("/email/confirm", { token: confirmationToken });
+}
+
+export async function listAddresses(token: string): Promise<Address[]> {
+    const res = await api.get<Address[]>("/me/addresses", { headers: { Authorization: `Bearer ${token}` } });
+    return res.data;
+}
+
+// Saves a new address, or replaces address.id when set.
+export async function saveAddress(token: string, address: Address): Promise<Address> {
+    const headers = { Authorization: `Bearer ${token}` };
+    const res = address.id
+        ? await api.put<Address>(`/me/addresses/${address.id}`, address, { headers })
+        : await api.post<Address>("/me/addresses", address, { headers });
+    return res.data;
+}
+
+export async function deleteAddress(token: string, id: string): Promise<void> {
+    await api.delete(`/me/addresses/${id}`, { headers: { Authorization: `Bearer ${token}` } });
+}
+
 // Obține toți userii (admin)
 export async function getAllUsers(token: string): Promise<UsersListResponse> {
     const res = await api.get<UsersListResponse>("/users", {
@@ -19,7 +19,7 @@ import { useState } from "react";
 import { Outlet, Link, useLocation, useNavigate } from "react-router-dom";
 import { Logout, Person, List as ListIcon, Group } from "@mui/icons-material";
 import { useAuthStore } from "../store/authStore";
-import {API_URL} from "../api/auth.ts";
+import {mediaUrl} from "../api/auth.ts";
 
 // Helper for user initials
 function getInitials(name?: string, fallback = "?") {
@@ -114,7 +114,7 @@ export default function DashboardLayout() {
                 <Avatar
                     src={
                       user?.photo
-                          ? `${API_URL}${user.photo}?t=${Date.now()}`
+                          ? mediaUrl(user.photo)
                           : undefined
                     }
                     sx={{ bgColor: "#6366f1" }}
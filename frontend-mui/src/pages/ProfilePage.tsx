@@ -16,9 +16,10 @@ import type {Area} from "react-easy-crop";
 import dayjs, {Dayjs} from "dayjs";
 
 import {useAuthStore} from "../store/authStore";
-import {getProfile, updateProfile, uploadPhoto} from "../api/user";
-import {API_URL} from "../api/auth";
-import type {UserDetails, Address} from "../types/user";
+import {getProfile, saveAddress, updateProfile, uploadPhoto} from "../api/user";
+import {mediaUrl} from "../api/auth";
+import type {UserDetails, Address, AddressError} from "../types/user";
+import axios from "axios";
 
 import CropDialog from "../components/CropDialog";
 import {createImage} from "../utils/helper";   // helper ce încarcă imaginea în <img/>
@@ -27,6 +28,12 @@ import {createImage} from "../utils/helper";   // helper ce încarcă imaginea 
 const toISO = (d?: string | Dayjs) =>
     typeof d === "string" ? d.slice(0, 10) : d ? d.format("YYYY-MM-DD") : "";
 
+/* adresa implicită de livrare */
+const defaultShipping = (p: UserDetails): Address | undefined =>
+    p.details.Addresses?.find((a) => a.kind === "shipping" && a.isDefault);
+
+const emptyAddress = {line: "", city: "", region: "", postalCode: "", country: ""};
+
 /* decupează în canvas zona selectată */
 async function getCroppedBlob(src: string, crop: Area): Promise<Blob> {
     const img = await createImage(src);
@@ -65,13 +72,9 @@ export default function ProfilePage() {
         lastName: "",
         phone: "",
         dateOfBirth: "",
-        address: {
-            line: "",
-            city: "",
-            postal_code: "",
-            country: "",
-        },
+        address: emptyAddress,
     });
+    const [addressErrors, setAddressErrors] = useState<Record<string, string>>({});
 
     /* --- fetch profil --- */
     useEffect(() => {
@@ -79,17 +82,19 @@ export default function ProfilePage() {
         (async () => {
             const p = await getProfile(token);
             setData(p);
+            const a = defaultShipping(p);
             setForm({
                 firstName: p.details.FirstName ?? "",
                 lastName: p.details.LastName ?? "",
                 phone: p.details.Phone ?? "",
                 dateOfBirth: toISO(p.details.DateOfBirth),
-                address: {
-                    line: (p.details.Address as Address)?.line ?? "",
-                    city: (p.details.Address as Address)?.city ?? "",
-                    postal_code: (p.details.Address as Address)?.postal_code ?? "",
-                    country: (p.details.Address as Address)?.country ?? "",
-                }
+                address: a ? {
+                    line: a.lines[0] ?? "",
+                    city: a.city,
+                    region: a.region ?? "",
+                    postalCode: a.postalCode ?? "",
+                    country: a.country,
+                } : emptyAddress,
             });
         })();
     }, [token]);
@@ -129,19 +134,35 @@ export default function ProfilePage() {
     const handleSave = async () => {
         if (!token) return;
         setSaving(true);
+        setAddressErrors({});
         try {
             await updateProfile(token, {
                 firstName: form.firstName,
                 lastName: form.lastName,
                 phone: form.phone,
                 dateOfBirth: form.dateOfBirth,
-                address: form.address ? {
-                    line: form.address.line,
-                    city: form.address.city,
-                    postal_code: form.address.postal_code,
-                    country: form.address.country,
-                } : null,
             });
+            const current = data ? defaultShipping(data) : undefined;
+            if (form.address.line || form.address.city || current) {
+                try {
+                    await saveAddress(token, {
+                        id: current?.id,
+                        kind: "shipping",
+                        isDefault: true,
+                        lines: [form.address.line],
+                        city: form.address.city,
+                        region: form.address.region,
+                        postalCode: form.address.postalCode,
+                        country: form.address.country,
+                    });
+                } catch (err) {
+                    if (axios.isAxiosError<AddressError>(err) && err.response?.data.fields) {
+                        setAddressErrors(err.response.data.fields);
+                        return;
+                    }
+                    throw err;
+                }
+            }
             const upd = await getProfile(token);
             setData(upd);
             login(upd, token);
@@ -176,7 +197,7 @@ export default function ProfilePage() {
                             component="img"
                             src={
                                 data.details.PhotoPath
-                                    ? `${API_URL}${data.details.PhotoPath}?t=${Date.now()}`
+                                    ? mediaUrl(data.details.PhotoPath)
                                     : undefined
                             }
                             alt={data.details.FirstName}
@@ -261,10 +282,11 @@ export default function ProfilePage() {
                                 <Typography variant="subtitle1" fontWeight={600} mb={2}>
                                     Address
                                 </Typography>
-                                <InfoRow label="Street Line" value={(data.details.Address as Address)?.line}/>
-                                <InfoRow label="City" value={(data.details.Address as Address)?.city}/>
-                                <InfoRow label="Postal Code" value={(data.details.Address as Address)?.postal_code}/>
-                                <InfoRow label="Country" value={(data.details.Address as Address)?.country}/>
+                                <InfoRow label="Street Line" value={defaultShipping(data)?.lines.join(", ")}/>
+                                <InfoRow label="City" value={defaultShipping(data)?.city}/>
+                                <InfoRow label="Region" value={defaultShipping(data)?.region}/>
+                                <InfoRow label="Postal Code" value={defaultShipping(data)?.postalCode}/>
+                                <InfoRow label="Country" value={defaultShipping(data)?.country}/>
                             </CardContent>
                         </Card>
                     </Box>
@@ -342,6 +364,8 @@ export default function ProfilePage() {
                                         size="small"
                                         label="Street / Line"
                                         value={form.address.line}
+                                        error={!!addressErrors.lines}
+                                        helperText={addressErrors.lines}
                                         onChange={(e) =>
                                             setForm(f => ({
                                                 ...f,
@@ -356,6 +380,8 @@ export default function ProfilePage() {
                                         size="small"
                                         label="City"
                                         value={form.address.city}
+                                        error={!!addressErrors.city}
+                                        helperText={addressErrors.city}
                                         onChange={(e) =>
                                             setForm(f => ({
                                                 ...f,
@@ -366,24 +392,44 @@ export default function ProfilePage() {
                                             }))
                                         }
                                     />
+                                    <TextField
+                                        size="small"
+                                        label="State / Region"
+                                        value={form.address.region}
+                                        error={!!addressErrors.region}
+                                        helperText={addressErrors.region}
+                                        onChange={(e) =>
+                                            setForm(f => ({
+                                                ...f,
+                                                address: {
+                                                    ...f.address,
+                                                    region: e.target.value,
+                                                },
+                                            }))
+                                        }
+                                    />
                                     <TextField
                                         size="small"
                                         label="Postal Code"
-                                        value={form.address.postal_code}
+                                        value={form.address.postalCode}
+                                        error={!!addressErrors.postalCode}
+                                        helperText={addressErrors.postalCode}
                                         onChange={(e) =>
                                             setForm(f => ({
                                                 ...f,
                                                 address: {
                                                     ...f.address,
-                                                    postal_code: e.target.value,
+                                                    postalCode: e.target.value,
                                                 },
                                             }))
                                         }
                                     />
                                     <TextField
                                         size="small"
-                                        label="Country"
+                                        label="Country (ISO code)"
                                         value={form.address.country}
+                                        error={!!addressErrors.country}
+                                        helperText={addressErrors.country}
                                         onChange={(e) =>
                                             setForm(f => ({
                                                 ...f,
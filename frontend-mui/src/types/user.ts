@@ -8,16 +8,28 @@ export interface User {
   lastName?: string;
   dateOfBirth?: string | dayjs.Dayjs;
   phone?: string;
-  address?: Address | null;
+  addresses?: Address[];
   photo?: string;
   thumbnail?: string;
 }
 
+export type AddressKind = "shipping" | "billing";
+
 export interface Address {
-  line?: string;
-  city?: string;
-  postal_code?: string;
-  country?: string;
+  id?: string;
+  kind: AddressKind;
+  isDefault: boolean;
+  lines: string[];
+  city: string;
+  region?: string;
+  postalCode?: string;
+  country: string; // ISO 3166-1 alpha-2
+}
+
+// Returned with a 400 when an address fails validation.
+export interface AddressError {
+  error: string;
+  fields?: Record<string, string>;
 }
 
 export interface UserDetails {
@@ -29,7 +41,7 @@ export interface UserDetails {
     LastName?: string;
     DateOfBirth?: string | dayjs.Dayjs;
     Phone?: string;
-    Address?: Address | null;
+    Addresses?: Address[];
     PhotoPath?: string;
     Thumbnail?: string;
     CreatedAt?: string | dayjs.Dayjs;
@@ -49,14 +61,12 @@ export interface UsersListResponse {
   users: User[];
 }
 
+// PATCH /me body; null clears a field.
 export interface UpdateProfileRequest {
-  firstName?: string;
-  lastName?: string;
-  dateOfBirth?: string;
-  phone?: string;
-  address?: string;
-  photoPath?: string;
-  thumbnailPath?: string;
+  firstName?: string | null;
+  lastName?: string | null;
+  dateOfBirth?: string | null;
+  phone?: string | null;
 }
 
 
@@ -0,0 +1,135 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	auth1 "github.com/ADRPUR/event-driven-marketplace/api/proto/auth/v1"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/address"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ListAddresses ------------------
+func (s *grpcServer) ListAddresses(ctx context.Context, _ *auth1.ListAddressesRequest) (*auth1.ListAddressesResponse, error) {
+	userID, err := s.addressUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.addresses.ListAddresses(ctx, userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	return &auth1.ListAddressesResponse{Addresses: toProtoAddresses(list)}, nil
+}
+
+// AddAddress ------------------
+func (s *grpcServer) AddAddress(ctx context.Context, req *auth1.Address) (*auth1.Address, error) {
+	userID, err := s.addressUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a := fromProtoAddress(req)
+	if err := s.addresses.AddAddress(ctx, userID, &a); err != nil {
+		return nil, status.Errorf(addressErrorCode(err), "%v", err)
+	}
+	return toProtoAddress(&a), nil
+}
+
+// UpdateAddress ------------------
+func (s *grpcServer) UpdateAddress(ctx context.Context, req *auth1.Address) (*auth1.Address, error) {
+	userID, err := s.addressUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid id")
+	}
+	a := fromProtoAddress(req)
+	a.ID = id
+	if err := s.addresses.UpdateAddress(ctx, userID, &a); err != nil {
+		return nil, status.Errorf(addressErrorCode(err), "%v", err)
+	}
+	return toProtoAddress(&a), nil
+}
+
+// DeleteAddress ------------------
+func (s *grpcServer) DeleteAddress(ctx context.Context, req *auth1.DeleteAddressRequest) (*emptypb.Empty, error) {
+	userID, err := s.addressUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid id")
+	}
+	if err := s.addresses.DeleteAddress(ctx, userID, id); err != nil {
+		return nil, status.Errorf(addressErrorCode(err), "%v", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// addressUser returns the caller's ID once address RPCs are enabled.
+func (s *grpcServer) addressUser(ctx context.Context) (uuid.UUID, error) {
+	if s.addresses == nil {
+		return uuid.Nil, status.Error(codes.Unimplemented, "addresses are disabled")
+	}
+	payload, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || payload == nil {
+		return uuid.Nil, status.Errorf(codes.Unauthenticated, "not authenticated")
+	}
+	return payload.UserID, nil
+}
+
+func addressErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, service.ErrAddressNotFound):
+		return codes.NotFound
+	case errors.Is(err, service.ErrInvalidAddress):
+		return codes.InvalidArgument
+	case errors.Is(err, service.ErrTooManyAddresses):
+		return codes.ResourceExhausted
+	}
+	return codes.Internal
+}
+
+func fromProtoAddress(a *auth1.Address) model.Address {
+	return model.Address{
+		Kind:      a.Kind,
+		IsDefault: a.IsDefault,
+		Address: address.Address{
+			Lines:      a.Lines,
+			City:       a.City,
+			Region:     a.Region,
+			PostalCode: a.PostalCode,
+			Country:    a.Country,
+		},
+	}
+}
+
+func toProtoAddress(a *model.Address) *auth1.Address {
+	return &auth1.Address{
+		Id:         a.ID.String(),
+		Kind:       a.Kind,
+		IsDefault:  a.IsDefault,
+		Lines:      a.Lines,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+	}
+}
+
+func toProtoAddresses(list []model.Address) []*auth1.Address {
+	out := make([]*auth1.Address, len(list))
+	for i := range list {
+		out[i] = toProtoAddress(&list[i])
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	auth1 "github.com/ADRPUR/event-driven-marketplace/api/proto/auth/v1"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// RequestEmailChange ------------------
+func (s *grpcServer) RequestEmailChange(ctx context.Context, req *auth1.RequestEmailChangeRequest) (*emptypb.Empty, error) {
+	if s.emails == nil {
+		return nil, status.Error(codes.Unimplemented, "email changes are disabled")
+	}
+	payload, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || payload == nil {
+		return nil, status.Errorf(codes.Unauthenticated, "not authenticated")
+	}
+	if payload.IsImpersonated() {
+		return nil, status.Errorf(codes.PermissionDenied, "%v", service.ErrImpersonationToken)
+	}
+	if err := s.emails.RequestEmailChange(ctx, payload.UserID, req.NewEmail, req.Password); err != nil {
+		return nil, status.Errorf(emailChangeErrorCode(err), "%v", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ConfirmEmailChange ------------------
+func (s *grpcServer) ConfirmEmailChange(ctx context.Context, req *auth1.ConfirmEmailChangeRequest) (*emptypb.Empty, error) {
+	if s.emails == nil {
+		return nil, status.Error(codes.Unimplemented, "email changes are disabled")
+	}
+	if err := s.emails.ConfirmEmailChange(ctx, req.Token); err != nil {
+		return nil, status.Errorf(emailChangeErrorCode(err), "%v", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func emailChangeErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		return codes.PermissionDenied
+	case errors.Is(err, service.ErrUserNotFound):
+		return codes.NotFound
+	case errors.Is(err, service.ErrInvalidEmail), errors.Is(err, service.ErrSameEmail),
+		errors.Is(err, service.ErrInvalidEmailToken):
+		return codes.InvalidArgument
+	case errors.Is(err, service.ErrEmailTaken):
+		return codes.AlreadyExists
+	}
+	return codes.Internal
+}
@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	auth1 "github.com/ADRPUR/event-driven-marketplace/api/proto/auth/v1"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// WithInternal enables the RPCs through which the other marketplace
+// services verify API keys and report impersonated requests, so that they
+// need no access to the auth tables.
+func WithInternal(keys service.APIKeyService, auditor *service.ImpersonationAuditor) Option {
+	return func(s *grpcServer) { s.keys, s.auditor = keys, auditor }
+}
+
+func (s *grpcServer) VerifyAPIKey(ctx context.Context, req *auth1.VerifyAPIKeyRequest) (*auth1.Principal, error) {
+	if _, err := s.internalCaller(ctx); err != nil {
+		return nil, err
+	}
+	pl, err := s.keys.VerifyAPIKey(ctx, req.Key)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAPIKey) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	p := &auth1.Principal{UserId: pl.UserID.String(), Scopes: pl.Scopes, ApiKeyId: pl.APIKeyID}
+	if !pl.ExpiredAt.IsZero() {
+		p.ExpiresAt = timestamppb.New(pl.ExpiredAt)
+	}
+	return p, nil
+}
+
+func (s *grpcServer) RecordImpersonatedRequest(ctx context.Context, req *auth1.ImpersonatedRequest) (*emptypb.Empty, error) {
+	caller, err := s.internalCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	adminID, aerr := uuid.Parse(req.AdminId)
+	userID, uerr := uuid.Parse(req.UserId)
+	tokenID, terr := uuid.Parse(req.TokenId)
+	if aerr != nil || uerr != nil || terr != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid admin_id, user_id or token_id")
+	}
+	// The entry is tagged with the authenticated caller, not a claimed name.
+	if err := s.auditor.ForService(caller.Service).Record(ctx, service.ImpersonatedRequest{
+		AdminID:   adminID,
+		UserID:    userID,
+		TokenID:   tokenID,
+		Method:    req.Method,
+		Path:      req.Path,
+		Status:    int(req.Status),
+		IP:        req.Ip,
+		UserAgent: req.UserAgent,
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// internalCaller returns the service principal calling an internal RPC.
+func (s *grpcServer) internalCaller(ctx context.Context) (*token.Payload, error) {
+	if s.keys == nil || s.auditor == nil {
+		return nil, status.Error(codes.Unimplemented, "internal RPCs are disabled")
+	}
+	pl, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || pl == nil || !pl.IsService() {
+		return nil, status.Error(codes.PermissionDenied, "only services may call this")
+	}
+	return pl, nil
+}
@@ -1,41 +1,76 @@
 package grpc
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
-	"gorm.io/datatypes"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
 	"time"
 
 	auth1 "github.com/ADRPUR/event-driven-marketplace/api/proto/auth/v1"
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
 	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
+// MethodScopes maps the RPCs open to OAuth2 clients and API keys to the
+// scope they need. Pass it to middleware.ScopeUnaryInterceptor; every other
+// RPC stays first-party only.
+var MethodScopes = map[string]string{
+	"/auth.v1.AuthService/Me": token.ScopeProfileRead,
+
+	"/auth.v1.AuthService/VerifyAPIKey":              token.ScopeAuthInternal,
+	"/auth.v1.AuthService/RecordImpersonatedRequest": token.ScopeAuthInternal,
+}
+
 // grpcServer implements auth1.AuthServiceServer
 type grpcServer struct {
 	auth1.UnimplementedAuthServiceServer
-	svc service.AuthService
+	svc       service.AuthService
+	uploads   service.PhotoUploadService // nil → streaming uploads are unimplemented
+	addresses service.AddressService     // nil → address RPCs are unimplemented
+	emails    service.EmailChangeService // nil → email change RPCs are unimplemented
+	keys      service.APIKeyService      // nil → internal RPCs are unimplemented
+	auditor   *service.ImpersonationAuditor
+}
+
+// Option customises the gRPC server.
+type Option func(*grpcServer)
+
+// WithPhotoUploads enables UploadPhotoStream and GetPhotoUploadStatus.
+func WithPhotoUploads(u service.PhotoUploadService) Option {
+	return func(s *grpcServer) { s.uploads = u }
 }
 
-func NewGRPCServer(svc service.AuthService) auth1.AuthServiceServer {
-	return &grpcServer{svc: svc}
+// WithAddresses enables the address RPCs.
+func WithAddresses(a service.AddressService) Option {
+	return func(s *grpcServer) { s.addresses = a }
+}
+
+// WithEmailChanges enables RequestEmailChange and ConfirmEmailChange.
+func WithEmailChanges(e service.EmailChangeService) Option {
+	return func(s *grpcServer) { s.emails = e }
+}
+
+func NewGRPCServer(svc service.AuthService, opts ...Option) auth1.AuthServiceServer {
+	s := &grpcServer{svc: svc}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Register ------------------
 func (s *grpcServer) Register(ctx context.Context, req *auth1.RegisterRequest) (*auth1.RegisterResponse, error) {
 	dob, _ := parseDate(req.Details.DateOfBirth)
-	var address datatypes.JSON
-	if req.Details.Address != nil {
-		addrBytes, err := json.Marshal(req.Details.Address)
-		if err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "invalid address")
-		}
-		address = addrBytes
-	}
 	user := &model.User{
 		Email: req.Email,
 		Role:  req.Role,
@@ -45,10 +80,12 @@ func (s *grpcServer) Register(ctx context.Context, req *auth1.RegisterRequest) (
 		LastName:      req.Details.LastName,
 		DateOfBirth:   dob,
 		Phone:         req.Details.Phone,
-		Address:       address,
 		PhotoPath:     req.Details.PhotoPath,
 		ThumbnailPath: req.Details.ThumbnailPath,
 	}
+	for _, a := range req.Details.Addresses {
+		details.Addresses = append(details.Addresses, fromProtoAddress(a))
+	}
 	if err := s.svc.Register(ctx, user, details, req.Password); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
@@ -112,38 +149,104 @@ func (s *grpcServer) UpdateUserDetails(ctx context.Context, req *auth1.UpdateUse
 	if !ok || payload == nil {
 		return nil, status.Errorf(codes.Unauthenticated, "not authenticated")
 	}
-	dob, _ := parseDate(req.Details.DateOfBirth)
-
-	var address datatypes.JSON
-	if req.Details.Address != nil {
-		addrBytes, err := json.Marshal(req.Details.Address)
-		if err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "invalid address")
+	d := req.GetDetails()
+	values := map[string]string{
+		"first_name":    d.GetFirstName(),
+		"last_name":     d.GetLastName(),
+		"date_of_birth": d.GetDateOfBirth(),
+		"phone":         d.GetPhone(),
+	}
+	var paths []string
+	if len(req.GetUpdateMask().GetPaths()) == 0 {
+		for _, path := range profilePaths {
+			if values[path] != "" {
+				paths = append(paths, path)
+			}
+		}
+	} else {
+		var err error
+		if paths, err = profileMask(req.GetUpdateMask()); err != nil {
+			return nil, err
 		}
-		address = addrBytes
 	}
-	details := &model.UserDetails{
-		UserID:        payload.UserID,
-		FirstName:     req.Details.FirstName,
-		LastName:      req.Details.LastName,
-		DateOfBirth:   dob,
-		Phone:         req.Details.Phone,
-		Address:       address,
-		PhotoPath:     req.Details.PhotoPath,
-		ThumbnailPath: req.Details.ThumbnailPath,
+	var patch service.ProfilePatch
+	for _, path := range paths {
+		v := values[path]
+		switch path {
+		case "first_name":
+			patch.FirstName = &v
+		case "last_name":
+			patch.LastName = &v
+		case "date_of_birth":
+			patch.DateOfBirth = &v
+		case "phone":
+			patch.Phone = &v
+		}
 	}
-	if err := s.svc.UpdateUserDetails(ctx, payload.UserID, details); err != nil {
+	_, err := s.svc.UpdateProfile(ctx, payload.UserID, patch)
+	var fields service.FieldErrors
+	switch {
+	case errors.As(err, &fields):
+		named := make(map[string]string, len(fields))
+		for field, msg := range fields {
+			named["details."+profilePathOf[field]] = msg
+		}
+		return nil, invalidFields(service.ErrInvalidProfile.Error(), named)
+	case errors.Is(err, service.ErrUserNotFound):
+		return nil, status.Errorf(codes.NotFound, "%v", err)
+	case err != nil:
 		return nil, status.Errorf(codes.Internal, "%v", err)
 	}
 	return &emptypb.Empty{}, nil
 }
 
+// profilePaths are the UserDetails fields UpdateUserDetails can write.
+var profilePaths = []string{"first_name", "last_name", "date_of_birth", "phone"}
+
+// profileMask checks an update_mask of UpdateUserDetails as AIP-161
+// describes it: paths are field names of the UserDetails resource and "*"
+// alone stands for every writable field. A path that names no field, or a
+// read-only one, is rejected.
+func profileMask(mask *fieldmaskpb.FieldMask) ([]string, error) {
+	paths := mask.GetPaths()
+	if len(paths) == 1 && paths[0] == "*" {
+		return profilePaths, nil
+	}
+	fields := (&auth1.UserDetails{}).ProtoReflect().Descriptor().Fields()
+	for _, path := range paths {
+		var problem string
+		switch {
+		case slices.Contains(profilePaths, path):
+			continue
+		case path == "*":
+			problem = `"*" must be the only path`
+		case fields.ByName(protoreflect.Name(path)) != nil:
+			problem = fmt.Sprintf("field %q is read-only", path)
+		default:
+			problem = fmt.Sprintf("UserDetails has no field %q", path)
+		}
+		return nil, invalidFields("invalid update_mask", map[string]string{"update_mask": problem})
+	}
+	return paths, nil
+}
+
+// profilePathOf maps the service's profile field names to proto fields.
+var profilePathOf = map[string]string{
+	service.ProfileFirstName:   "first_name",
+	service.ProfileLastName:    "last_name",
+	service.ProfileDateOfBirth: "date_of_birth",
+	service.ProfilePhone:       "phone",
+}
+
 // ChangePassword ------------------
 func (s *grpcServer) ChangePassword(ctx context.Context, req *auth1.ChangePasswordRequest) (*emptypb.Empty, error) {
 	payload, ok := ctx.Value(token.CtxKey).(*token.Payload)
 	if !ok || payload == nil {
 		return nil, status.Errorf(codes.Unauthenticated, "not authenticated")
 	}
+	if payload.IsImpersonated() {
+		return nil, status.Errorf(codes.PermissionDenied, "%v", service.ErrImpersonationToken)
+	}
 	if err := s.svc.ChangePassword(ctx, payload.UserID, req.OldPassword, req.NewPassword); err != nil {
 		return nil, status.Errorf(codes.PermissionDenied, "%v", err)
 	}
@@ -156,9 +259,9 @@ func (s *grpcServer) UploadPhoto(ctx context.Context, req *auth1.UploadPhotoRequ
 	if !ok || payload == nil {
 		return nil, status.Errorf(codes.Unauthenticated, "not authenticated")
 	}
-	photoPath, thumbPath, err := s.svc.UploadPhoto(ctx, payload.UserID, req.PhotoData, req.FileExt)
+	photoPath, thumbPath, err := s.svc.UploadPhoto(ctx, payload.UserID, bytes.NewReader(req.PhotoData))
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "%v", err)
+		return nil, status.Errorf(uploadErrorCode(err), "%v", err)
 	}
 	return &auth1.UploadPhotoResponse{
 		PhotoPath:     photoPath,
@@ -179,13 +282,7 @@ func toProtoUser(u *model.User, d *model.UserDetails) *auth1.User {
 		details.Phone = d.Phone
 		details.PhotoPath = d.PhotoPath
 		details.ThumbnailPath = d.ThumbnailPath
-
-		// Conversion datatypes.JSON → map[string]string
-		var addr map[string]string
-		if d.Address != nil && len(d.Address) > 0 {
-			_ = json.Unmarshal(d.Address, &addr)
-		}
-		details.Address = addr
+		details.Addresses = toProtoAddresses(d.Addresses)
 	}
 	return &auth1.User{
 		Id:      u.ID.String(),
@@ -195,6 +292,25 @@ func toProtoUser(u *model.User, d *model.UserDetails) *auth1.User {
 	}
 }
 
+// invalidFields returns an InvalidArgument status with a BadRequest detail
+// listing one violation per field.
+func invalidFields(msg string, fields map[string]string) error {
+	names := make([]string, 0, len(fields))
+	for f := range fields {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	br := &errdetails.BadRequest{}
+	for _, f := range names {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f, Description: fields[f]})
+	}
+	st, err := status.New(codes.InvalidArgument, msg).WithDetails(br)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, msg)
+	}
+	return st.Err()
+}
+
 func parseDate(s string) (t time.Time, err error) {
 	if s == "" {
 		return
@@ -202,3 +318,24 @@ func parseDate(s string) (t time.Time, err error) {
 	t, err = time.Parse("2006-01-02", s)
 	return
 }
+
+func uploadErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, service.ErrInvalidImage), errors.Is(err, service.ErrUploadRejected),
+		errors.Is(err, service.ErrInvalidUpload), errors.Is(err, service.ErrUploadMismatch):
+		return codes.InvalidArgument
+	case errors.Is(err, service.ErrChecksumMismatch):
+		return codes.DataLoss
+	case errors.Is(err, service.ErrUploadOffset):
+		return codes.FailedPrecondition
+	case errors.Is(err, service.ErrUploadNotFound):
+		return codes.NotFound
+	case errors.Is(err, service.ErrUploadIDTaken):
+		return codes.AlreadyExists
+	case errors.Is(err, service.ErrPhotoTooLarge):
+		return codes.ResourceExhausted
+	case errors.Is(err, service.ErrScanUnavailable):
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
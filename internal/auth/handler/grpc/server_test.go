@@ -2,20 +2,27 @@ package grpc_test
 
 import (
 	"context"
-	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"fmt"
+	"io"
 	"net"
 	"testing"
 	"time"
 
 	authv1 "github.com/ADRPUR/event-driven-marketplace/api/proto/auth/v1"
+	authclient "github.com/ADRPUR/event-driven-marketplace/internal/auth/client"
 	grpcHandler "github.com/ADRPUR/event-driven-marketplace/internal/auth/handler/grpc"
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
 	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 // --- Mock service ---
@@ -41,15 +48,16 @@ func (m *mockService) GetUserWithDetails(ctx context.Context, id uuid.UUID) (*mo
 	args := m.Called(id)
 	return args.Get(0).(*model.User), args.Get(1).(*model.UserDetails), args.Error(2)
 }
-func (m *mockService) UpdateUserDetails(ctx context.Context, userID uuid.UUID, details *model.UserDetails) error {
-	args := m.Called(userID)
-	return args.Error(0)
+func (m *mockService) UpdateProfile(ctx context.Context, userID uuid.UUID, p service.ProfilePatch) (*model.UserDetails, error) {
+	args := m.Called(userID, p)
+	d, _ := args.Get(0).(*model.UserDetails)
+	return d, args.Error(1)
 }
 func (m *mockService) ChangePassword(ctx context.Context, userID uuid.UUID, old, new string) error {
 	args := m.Called(userID, old, new)
 	return args.Error(0)
 }
-func (m *mockService) UploadPhoto(ctx context.Context, userID uuid.UUID, data []byte, ext string) (string, string, error) {
+func (m *mockService) UploadPhoto(ctx context.Context, userID uuid.UUID, r io.Reader) (string, string, error) {
 	args := m.Called(userID)
 	return args.String(0), args.String(1), args.Error(2)
 }
@@ -113,3 +121,110 @@ func TestGRPC_Login_OK(t *testing.T) {
 	assert.NotNil(t, resp)
 	assert.Equal(t, email, resp.User.Email)
 }
+
+func TestGRPC_UpdateUserDetails_FieldMask(t *testing.T) {
+	svc := new(mockService)
+	lis := bufconn.Listen(bufSize)
+	userID := uuid.New()
+	withUser := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(context.WithValue(ctx, token.CtxKey, &token.Payload{UserID: userID}), req)
+	}
+	s := grpc.NewServer(grpc.UnaryInterceptor(withUser))
+	authv1.RegisterAuthServiceServer(s, grpcHandler.NewGRPCServer(svc))
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(bufDialer(lis)), grpc.WithInsecure())
+	assert.NoError(t, err)
+	client := authv1.NewAuthServiceClient(conn)
+
+	str := func(s string) *string { return &s }
+	details := &authv1.UserDetails{FirstName: "Ana", LastName: "ignored", PhotoPath: "/evil.jpg"}
+
+	// the mask limits the write; an empty masked field is cleared
+	svc.On("UpdateProfile", userID, service.ProfilePatch{FirstName: str("Ana"), Phone: str("")}).Return(&model.UserDetails{}, nil).Once()
+	_, err = client.UpdateUserDetails(context.Background(), &authv1.UpdateUserDetailsRequest{
+		Details:    details,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"first_name", "phone"}},
+	})
+	assert.NoError(t, err)
+
+	// without a mask, the non-empty fields are written and photo_path never is
+	svc.On("UpdateProfile", userID, service.ProfilePatch{FirstName: str("Ana"), LastName: str("ignored")}).Return(&model.UserDetails{}, nil).Once()
+	_, err = client.UpdateUserDetails(context.Background(), &authv1.UpdateUserDetailsRequest{Details: details})
+	assert.NoError(t, err)
+
+	// "*" writes every writable field
+	svc.On("UpdateProfile", userID, service.ProfilePatch{FirstName: str("Ana"), LastName: str("ignored"), DateOfBirth: str(""), Phone: str("")}).Return(&model.UserDetails{}, nil).Once()
+	_, err = client.UpdateUserDetails(context.Background(), &authv1.UpdateUserDetailsRequest{
+		Details:    details,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"*"}},
+	})
+	assert.NoError(t, err)
+
+	// paths must be UserDetails field names, and writable ones
+	for _, paths := range [][]string{{"photo_path"}, {"addresses"}, {"firstName"}, {"details.first_name"}, {"first_name", "nickname"}, {"first_name", "*"}} {
+		_, err = client.UpdateUserDetails(context.Background(), &authv1.UpdateUserDetailsRequest{
+			Details:    details,
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: paths},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "%v", paths)
+	}
+
+	svc.On("UpdateProfile", userID, service.ProfilePatch{DateOfBirth: str("yesterday")}).
+		Return(nil, fmt.Errorf("%w: %w", service.ErrInvalidProfile, service.FieldErrors{"dateOfBirth": "must be a date in YYYY-MM-DD format"})).Once()
+	_, err = client.UpdateUserDetails(context.Background(), &authv1.UpdateUserDetailsRequest{
+		Details:    &authv1.UserDetails{DateOfBirth: "yesterday"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"date_of_birth"}},
+	})
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		br := st.Details()[0].(*errdetails.BadRequest)
+		assert.Equal(t, "details.date_of_birth", br.FieldViolations[0].Field)
+	}
+	svc.AssertExpectations(t)
+}
+
+type stubAPIKeys struct{ service.APIKeyService }
+
+func (stubAPIKeys) VerifyAPIKey(ctx context.Context, key string) (*token.Payload, error) {
+	if key != "mk_valid" {
+		return nil, service.ErrInvalidAPIKey
+	}
+	return &token.Payload{UserID: uuid.New(), Scopes: []string{token.ScopeProductsRead}, APIKeyID: "k1"}, nil
+}
+
+func startInternalServer(t *testing.T, caller *token.Payload) (*grpc.ClientConn, func()) {
+	lis := bufconn.Listen(bufSize)
+	s := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		return h(context.WithValue(ctx, token.CtxKey, caller), req)
+	}))
+	authv1.RegisterAuthServiceServer(s, grpcHandler.NewGRPCServer(new(mockService),
+		grpcHandler.WithInternal(stubAPIKeys{}, service.NewImpersonationAuditor(nil, "auth-service"))))
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(bufDialer(lis)), grpc.WithInsecure())
+	assert.NoError(t, err)
+	return conn, func() { s.Stop(); lis.Close() }
+}
+
+func TestGRPC_VerifyAPIKey_ServicesOnly(t *testing.T) {
+	conn, cleanup := startInternalServer(t, &token.Payload{Service: "product-service", Scopes: []string{token.ScopeAuthInternal}})
+	defer cleanup()
+	client := authclient.New(conn)
+
+	pl, err := client.VerifyAPIKey(context.Background(), "mk_valid")
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", pl.APIKeyID)
+	assert.True(t, pl.ExpiredAt.IsZero(), "a key without expiry stays without expiry")
+	assert.False(t, pl.IsExpired())
+
+	_, err = client.VerifyAPIKey(context.Background(), "mk_other")
+	assert.ErrorIs(t, err, authclient.ErrInvalidAPIKey)
+
+	userConn, userCleanup := startInternalServer(t, &token.Payload{UserID: uuid.New(), ExpiredAt: time.Now().Add(time.Hour)})
+	defer userCleanup()
+	_, err = authv1.NewAuthServiceClient(userConn).VerifyAPIKey(context.Background(), &authv1.VerifyAPIKeyRequest{Key: "mk_valid"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
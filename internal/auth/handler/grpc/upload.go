@@ -0,0 +1,109 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	auth1 "github.com/ADRPUR/event-driven-marketplace/api/proto/auth/v1"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UploadPhotoStream ------------------
+//
+// Whatever was received is kept when the stream breaks, so the client can
+// reopen it with the same metadata and continue from the stored offset.
+func (s *grpcServer) UploadPhotoStream(stream auth1.AuthService_UploadPhotoStreamServer) error {
+	if s.uploads == nil {
+		return status.Error(codes.Unimplemented, "streaming uploads are disabled")
+	}
+	ctx := stream.Context()
+	payload, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || payload == nil {
+		return status.Errorf(codes.Unauthenticated, "not authenticated")
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	meta := first.GetMetadata()
+	if meta == nil {
+		return status.Error(codes.InvalidArgument, "the first message must carry the metadata")
+	}
+	uploadID, err := uuid.Parse(meta.UploadId)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "invalid upload_id")
+	}
+	up, err := s.uploads.OpenPhotoUpload(ctx, payload.UserID, uploadID, meta.Size, meta.Sha256)
+	if err != nil {
+		return status.Errorf(uploadErrorCode(err), "%v", err)
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err == nil && msg.GetChunk() == nil {
+			err = status.Error(codes.InvalidArgument, "expected a chunk")
+		}
+		if err == nil {
+			if werr := up.Write(ctx, msg.GetChunk().Offset, msg.GetChunk().Data); werr != nil {
+				err = status.Errorf(uploadErrorCode(werr), "%v", werr)
+			}
+		}
+		if err != nil {
+			if ferr := up.Flush(ctx); ferr != nil {
+				return status.Errorf(codes.Internal, "%v", ferr)
+			}
+			return err
+		}
+	}
+
+	resp := &auth1.UploadPhotoStreamResponse{UploadId: uploadID.String()}
+	if up.Offset() < up.Session().Size {
+		if err := up.Flush(ctx); err != nil {
+			return status.Errorf(uploadErrorCode(err), "%v", err)
+		}
+		resp.Received = up.Offset()
+		return stream.SendAndClose(resp)
+	}
+	photoPath, thumbPath, err := up.Complete(ctx)
+	if err != nil {
+		return status.Errorf(uploadErrorCode(err), "%v", err)
+	}
+	resp.Received = up.Offset()
+	resp.Complete = true
+	resp.PhotoPath = photoPath
+	resp.ThumbnailPath = thumbPath
+	return stream.SendAndClose(resp)
+}
+
+// GetPhotoUploadStatus ------------------
+func (s *grpcServer) GetPhotoUploadStatus(ctx context.Context, req *auth1.PhotoUploadStatusRequest) (*auth1.PhotoUploadStatusResponse, error) {
+	if s.uploads == nil {
+		return nil, status.Error(codes.Unimplemented, "streaming uploads are disabled")
+	}
+	payload, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || payload == nil {
+		return nil, status.Errorf(codes.Unauthenticated, "not authenticated")
+	}
+	uploadID, err := uuid.Parse(req.UploadId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid upload_id")
+	}
+	sess, err := s.uploads.PhotoUploadStatus(ctx, payload.UserID, uploadID)
+	if err != nil {
+		return nil, status.Errorf(uploadErrorCode(err), "%v", err)
+	}
+	return &auth1.PhotoUploadStatusResponse{
+		UploadId:  sess.ID.String(),
+		Size:      sess.Size,
+		Received:  sess.Received,
+		ExpiresAt: timestamppb.New(sess.ExpiresAt),
+	}, nil
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/utils"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/address"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AddressHandler exposes the signed-in user's saved addresses.
+type AddressHandler struct {
+	svc service.AddressService
+}
+
+func NewAddresses(svc service.AddressService) *AddressHandler { return &AddressHandler{svc: svc} }
+
+// RegisterAddressRoutes mounts /me/addresses (requires authentication).
+func RegisterAddressRoutes(r *gin.RouterGroup, h *AddressHandler) {
+	r.GET("/me/addresses", h.list)
+	r.POST("/me/addresses", h.create)
+	r.PUT("/me/addresses/:id", h.update)
+	r.DELETE("/me/addresses/:id", h.delete)
+}
+
+// addressRequest is the JSON body of an address.
+type addressRequest struct {
+	Kind      string `json:"kind"` // "shipping" (default) or "billing"
+	IsDefault bool   `json:"isDefault"`
+	address.Address
+}
+
+func (r addressRequest) model() model.Address {
+	return model.Address{Kind: r.Kind, IsDefault: r.IsDefault, Address: r.Address}
+}
+
+// -------------------- Handlers --------------------
+
+// GET /me/addresses — list addresses, defaults first
+func (h *AddressHandler) list(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	list, err := h.svc.ListAddresses(c, payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if list == nil {
+		list = []model.Address{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// POST /me/addresses — save a new address
+func (h *AddressHandler) create(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	var req addressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	a := req.model()
+	if err := h.svc.AddAddress(c, payload.UserID, &a); err != nil {
+		c.JSON(addressErrorStatus(err), errorJSON(err))
+		return
+	}
+	c.JSON(http.StatusCreated, a)
+}
+
+// PUT /me/addresses/:id — replace an address
+func (h *AddressHandler) update(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+		return
+	}
+	var req addressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	a := req.model()
+	a.ID = id
+	if err := h.svc.UpdateAddress(c, payload.UserID, &a); err != nil {
+		c.JSON(addressErrorStatus(err), errorJSON(err))
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// DELETE /me/addresses/:id — delete an address
+func (h *AddressHandler) delete(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+		return
+	}
+	if err := h.svc.DeleteAddress(c, payload.UserID, id); err != nil {
+		c.JSON(addressErrorStatus(err), errorJSON(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// -------------------- Helpers --------------------
+
+func addressErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrAddressNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidAddress), errors.Is(err, service.ErrTooManyAddresses):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
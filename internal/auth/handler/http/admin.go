@@ -0,0 +1,312 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminHandler exposes administrative endpoints. Mount it behind
+// middleware.RequireRole(token.RoleAdmin).
+type AdminHandler struct {
+	impersonation service.ImpersonationService
+	users         service.UserAdminService
+	audit         service.AuditService
+	deleted       service.DeletedAccountService
+}
+
+func NewAdmin(impersonation service.ImpersonationService, users service.UserAdminService, audit service.AuditService,
+	deleted service.DeletedAccountService) *AdminHandler {
+	return &AdminHandler{impersonation: impersonation, users: users, audit: audit, deleted: deleted}
+}
+
+// RegisterImpersonationRoutes mounts POST /users/:id/impersonate. Mount it
+// on an admin-only group at the root, not under /admin.
+func RegisterImpersonationRoutes(r *gin.RouterGroup, h *AdminHandler) {
+	r.POST("/users/:id/impersonate", h.impersonate)
+}
+
+// RegisterAdminRoutes mounts the admin-only endpoints
+func RegisterAdminRoutes(r *gin.RouterGroup, h *AdminHandler) {
+	r.PUT("/users/:id/role", h.setRole)
+	r.DELETE("/users/:id", h.deleteUser)
+	r.GET("/users/deleted", h.listDeletedUsers)
+	r.POST("/users/:id/restore", h.restoreUser)
+	r.GET("/audit-events", h.listAuditEvents)
+	r.GET("/audit-events/export", h.exportAuditEvents)
+}
+
+// Page sizes of the admin listings
+const (
+	auditPageLimit   = 50
+	auditMaxLimit    = 500
+	auditExportLimit = 10000
+)
+
+// -------------------- Handlers --------------------
+
+// POST /users/:id/impersonate — issue a short-lived token acting as the user
+func (h *AdminHandler) impersonate(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+		return
+	}
+	var req struct {
+		Reason string `json:"reason" binding:"required,max=1024"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	at, pl, err := h.impersonation.Impersonate(c, payload.UserID, userID, req.Reason, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrForbidden), errors.Is(err, service.ErrCannotImpersonate):
+			status = http.StatusForbidden
+		case errors.Is(err, service.ErrUserNotFound):
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"accessToken":    at,
+		"expiresAt":      pl.ExpiredAt.Unix(),
+		"userId":         pl.UserID,
+		"impersonatorId": pl.ImpersonatorID,
+	})
+}
+
+// PUT /users/:id/role — change a user's role
+func (h *AdminHandler) setRole(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+		return
+	}
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.users.SetRole(c, userID, req.Role); err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "role updated"})
+}
+
+// DELETE /users/:id — delete an account
+func (h *AdminHandler) deleteUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+		return
+	}
+	if err := h.users.DeleteUser(c, userID); err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /users/deleted — list soft-deleted accounts (?offset=&limit=)
+func (h *AdminHandler) listDeletedUsers(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(auditPageLimit)))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > auditMaxLimit {
+		limit = auditPageLimit
+	}
+	list, err := h.deleted.ListDeletedUsers(c, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out := make([]gin.H, 0, len(list))
+	for _, u := range list {
+		out = append(out, gin.H{
+			"id":        u.ID,
+			"email":     u.Email,
+			"role":      u.Role,
+			"createdAt": u.CreatedAt,
+			"deletedAt": u.DeletedAt.Time,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// POST /users/:id/restore — undelete a soft-deleted account
+func (h *AdminHandler) restoreUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+		return
+	}
+	if err := h.deleted.RestoreUser(c, userID); err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "account restored"})
+}
+
+// GET /audit-events — query the audit log
+// ?type=&outcome=&actorId=&targetId=&from=&to= (RFC 3339)&offset=&limit=
+func (h *AdminHandler) listAuditEvents(c *gin.Context) {
+	f, err := auditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	f.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(auditPageLimit)))
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	if f.Limit <= 0 || f.Limit > auditMaxLimit {
+		f.Limit = auditPageLimit
+	}
+	list, err := h.audit.ListAuditEvents(c, f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out := make([]gin.H, 0, len(list))
+	for _, e := range list {
+		out = append(out, auditEventJSON(e))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// GET /audit-events/export — same filters as /audit-events, as CSV
+func (h *AdminHandler) exportAuditEvents(c *gin.Context) {
+	f, err := auditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.Limit = auditExportLimit
+	list, err := h.audit.ListAuditEvents(c, f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", `attachment; filename="audit-events.csv"`)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"id", "created_at", "type", "outcome", "actor_id", "target_id", "ip", "user_agent", "request_id", "detail"})
+	for _, e := range list {
+		_ = w.Write(csvSafeRow(
+			e.ID.String(),
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			e.Type,
+			e.Outcome,
+			uuidString(e.ActorID),
+			uuidString(e.TargetID),
+			e.IP,
+			e.UserAgent,
+			e.RequestID,
+			e.Detail,
+		))
+	}
+	w.Flush()
+}
+
+// -------------------- Helpers --------------------
+
+func adminErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidRole):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrEmailTaken), errors.Is(err, service.ErrUserErased):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func auditFilter(c *gin.Context) (repository.AuditFilter, error) {
+	f := repository.AuditFilter{Type: c.Query("type"), Outcome: c.Query("outcome")}
+	for param, dst := range map[string]**uuid.UUID{"actorId": &f.ActorID, "targetId": &f.TargetID} {
+		if v := c.Query(param); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return f, errors.New("invalid " + param)
+			}
+			*dst = &id
+		}
+	}
+	for param, dst := range map[string]*time.Time{"from": &f.From, "to": &f.To} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, errors.New("invalid " + param + ", expected RFC 3339")
+			}
+			*dst = t
+		}
+	}
+	return f, nil
+}
+
+func auditEventJSON(e model.AuditEvent) gin.H {
+	return gin.H{
+		"id":        e.ID,
+		"type":      e.Type,
+		"outcome":   e.Outcome,
+		"actorId":   e.ActorID,
+		"targetId":  e.TargetID,
+		"ip":        e.IP,
+		"userAgent": e.UserAgent,
+		"requestId": e.RequestID,
+		"detail":    e.Detail,
+		"createdAt": e.CreatedAt,
+	}
+}
+
+func uuidString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// csvSafeRow applies csvSafe to every cell, so that no column has to be
+// trusted to never start with a formula character.
+func csvSafeRow(cells ...string) []string {
+	for i, s := range cells {
+		cells[i] = csvSafe(s)
+	}
+	return cells
+}
+
+// csvSafe neutralises values that spreadsheet applications would evaluate as
+// formulas; user agents, request IDs and details are attacker-controlled.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/utils"
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// APIKeyHandler exposes personal API key management for the signed-in user.
+type APIKeyHandler struct {
+	svc service.APIKeyService
+}
+
+func NewAPIKeys(svc service.APIKeyService) *APIKeyHandler { return &APIKeyHandler{svc: svc} }
+
+// RegisterAPIKeyRoutes mounts /me/api-keys (requires authentication).
+// Creating and revoking credentials is blocked for impersonation tokens.
+func RegisterAPIKeyRoutes(r *gin.RouterGroup, h *APIKeyHandler) {
+	r.POST("/me/api-keys", middleware.DenyImpersonation(), h.create)
+	r.GET("/me/api-keys", h.list)
+	r.DELETE("/me/api-keys/:id", middleware.DenyImpersonation(), h.revoke)
+}
+
+// -------------------- Handlers --------------------
+
+// POST /me/api-keys — create a key; the plaintext key is returned only once
+func (h *APIKeyHandler) create(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	var req struct {
+		Name      string     `json:"name" binding:"required,max=255"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	k, key, err := h.svc.CreateAPIKey(c, payload.UserID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp := apiKeyJSON(k)
+	resp["key"] = key
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GET /me/api-keys — list keys (never includes the secret)
+func (h *APIKeyHandler) list(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	keys, err := h.svc.ListAPIKeys(c, payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, len(keys))
+	for i := range keys {
+		resp[i] = apiKeyJSON(&keys[i])
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DELETE /me/api-keys/:id — revoke a key
+func (h *APIKeyHandler) revoke(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+		return
+	}
+	if err := h.svc.RevokeAPIKey(c, payload.UserID, id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// -------------------- Helpers --------------------
+
+func apiKeyJSON(k *model.APIKey) gin.H {
+	return gin.H{
+		"id":         k.ID,
+		"name":       k.Name,
+		"prefix":     k.Prefix,
+		"scopes":     strings.Fields(k.Scopes),
+		"expiresAt":  k.ExpiresAt,
+		"lastUsedAt": k.LastUsedAt,
+		"revokedAt":  k.RevokedAt,
+		"active":     k.Active(),
+		"createdAt":  k.CreatedAt,
+	}
+}
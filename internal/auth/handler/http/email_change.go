@@ -0,0 +1,87 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/utils"
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// EmailChangeHandler exposes the login email change flow.
+type EmailChangeHandler struct {
+	svc service.EmailChangeService
+}
+
+func NewEmailChanges(svc service.EmailChangeService) *EmailChangeHandler {
+	return &EmailChangeHandler{svc: svc}
+}
+
+// RegisterEmailChangePublicRoutes mounts the confirmation endpoint; the
+// mailed token authenticates it.
+func RegisterEmailChangePublicRoutes(r *gin.Engine, h *EmailChangeHandler) {
+	r.POST("/email/confirm", h.confirm)
+}
+
+// RegisterEmailChangeRoutes mounts POST /me/email (requires authentication,
+// blocked for impersonation tokens).
+func RegisterEmailChangeRoutes(r *gin.RouterGroup, h *EmailChangeHandler) {
+	r.POST("/me/email", middleware.DenyImpersonation(), h.request)
+}
+
+// -------------------- Handlers --------------------
+
+// POST /me/email — mail a confirmation link to the new address
+func (h *EmailChangeHandler) request(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	var req struct {
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.RequestEmailChange(c, payload.UserID, req.Email, req.Password); err != nil {
+		c.JSON(emailChangeStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "confirmation sent to " + req.Email})
+}
+
+// POST /email/confirm — apply the change identified by the mailed token
+func (h *EmailChangeHandler) confirm(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.ConfirmEmailChange(c, req.Token); err != nil {
+		c.JSON(emailChangeStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func emailChangeStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidEmail), errors.Is(err, service.ErrSameEmail),
+		errors.Is(err, service.ErrInvalidEmailToken):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrEmailTaken):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -1,24 +1,57 @@
 package http
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
 	"mime/multipart"
 	"net/http"
-	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/utils"
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	svc service.AuthService
+	svc       service.AuthService
+	cookies   *middleware.CookieConfig // nil → tokens are returned in the JSON body
+	maxUpload int64                    // body limit of POST /me/photo; 0 → unlimited
 }
 
-func New(svc service.AuthService) *Handler { return &Handler{svc: svc} }
+// Option customises a Handler.
+type Option func(*Handler)
+
+// WithCookieSession makes /login and /refresh deliver tokens as HttpOnly
+// cookies (plus a readable CSRF cookie) instead of the JSON body, and lets
+// /refresh and /logout read the session token from its cookie.
+func WithCookieSession(cfg middleware.CookieConfig) Option {
+	return func(h *Handler) { h.cookies = &cfg }
+}
+
+// WithMaxUploadBytes caps the request body of POST /me/photo at the photo
+// size limit plus room for the multipart framing, so that oversized bodies
+// are cut off by the server rather than read until the service gives up.
+func WithMaxUploadBytes(n int64) Option {
+	return func(h *Handler) { h.maxUpload = n }
+}
+
+// multipartOverhead is allowed on top of the photo for boundaries, part
+// headers and other form fields.
+const multipartOverhead = 64 << 10
+
+func New(svc service.AuthService, opts ...Option) *Handler {
+	h := &Handler{svc: svc}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
 
 // RegisterPublicRoutes mounts /register, /login, /refresh
 func RegisterPublicRoutes(r *gin.Engine, h *Handler) {
@@ -30,24 +63,30 @@ func RegisterPublicRoutes(r *gin.Engine, h *Handler) {
 // RegisterProtectedRoutes mounts endpoints that require authentication (middleware)
 func RegisterProtectedRoutes(r *gin.RouterGroup, h *Handler) {
 	r.POST("/logout", h.logout)
-	r.GET("/me", h.me)
 	r.PUT("/me", h.updateMe)
+	r.PATCH("/me", h.patchMe)
 	r.POST("/me/photo", h.uploadPhoto)
-	r.POST("/me/password", h.changePassword)
+	r.POST("/me/password", middleware.DenyImpersonation(), h.changePassword)
+}
+
+// RegisterProfileReadRoutes mounts the profile reads that OAuth2 clients and
+// API keys may reach with the profile:read scope.
+func RegisterProfileReadRoutes(r *gin.RouterGroup, h *Handler) {
+	r.GET("/me", h.me)
 }
 
 // -------------------- Handlers --------------------
 
 func (h *Handler) register(c *gin.Context) {
 	var req struct {
-		Email       string         `json:"email" binding:"required,email"`
-		Password    string         `json:"password" binding:"required,min=6"`
-		Role        string         `json:"role"`
-		FirstName   string         `json:"firstName"`
-		LastName    string         `json:"lastName"`
-		DateOfBirth string         `json:"dateOfBirth"`
-		Phone       string         `json:"phone"`
-		Address     map[string]any `json:"address"` // you can unmarshal into a struct if you want
+		Email       string           `json:"email" binding:"required,email"`
+		Password    string           `json:"password" binding:"required,min=6"`
+		Role        string           `json:"role"`
+		FirstName   string           `json:"firstName"`
+		LastName    string           `json:"lastName"`
+		DateOfBirth string           `json:"dateOfBirth"`
+		Phone       string           `json:"phone"`
+		Addresses   []addressRequest `json:"addresses"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -63,17 +102,12 @@ func (h *Handler) register(c *gin.Context) {
 		DateOfBirth: dob,
 		Phone:       req.Phone,
 	}
-	if req.Address != nil {
-		bytes, err := json.Marshal(req.Address)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
-			return
-		}
-		d.Address = bytes
+	for _, a := range req.Addresses {
+		d.Addresses = append(d.Addresses, a.model())
 	}
 	err := h.svc.Register(c, u, d, req.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, errorJSON(err))
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"id": u.ID})
@@ -94,39 +128,50 @@ func (h *Handler) login(c *gin.Context) {
 		return
 	}
 	user, details, _ := h.svc.GetUserWithDetails(c, pl.UserID)
+	userJSON := gin.H{
+		"id":          user.ID,
+		"email":       user.Email,
+		"role":        user.Role,
+		"firstName":   details.FirstName,
+		"lastName":    details.LastName,
+		"dateOfBirth": details.DateOfBirth,
+		"phone":       details.Phone,
+		"addresses":   details.Addresses,
+		"photo":       details.PhotoPath,
+		"thumbnail":   details.ThumbnailPath,
+	}
+	if h.cookies != nil {
+		if err := h.setSessionCookies(c, at, st, pl.ExpiredAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"expiresAt": pl.ExpiredAt.Unix(), "user": userJSON})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"accessToken":  at,
 		"refreshToken": rt,
 		"sessionToken": st,
 		"expiresAt":    pl.ExpiredAt.Unix(),
-		"user": gin.H{
-			"id":          user.ID,
-			"email":       user.Email,
-			"role":        user.Role,
-			"firstName":   details.FirstName,
-			"lastName":    details.LastName,
-			"dateOfBirth": details.DateOfBirth,
-			"phone":       details.Phone,
-			"address":     details.Address,
-			"photo":       details.PhotoPath,
-			"thumbnail":   details.ThumbnailPath,
-		},
+		"user":         userJSON,
 	})
 }
 
 func (h *Handler) refresh(c *gin.Context) {
-	var req struct {
-		SessionToken string `json:"sessionToken" binding:"required"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	sessionToken, ok := h.sessionToken(c)
+	if !ok {
 		return
 	}
-	at, pl, err := h.svc.Refresh(c, req.SessionToken)
+	at, pl, err := h.svc.Refresh(c, sessionToken)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
+	if h.cookies != nil {
+		h.cookies.SetCookie(c.Writer, middleware.AccessTokenCookie, at, time.Until(pl.ExpiredAt), true)
+		c.JSON(http.StatusOK, gin.H{"expiresAt": pl.ExpiredAt.Unix()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"accessToken": at,
 		"expiresAt":   pl.ExpiredAt.Unix(),
@@ -134,17 +179,19 @@ func (h *Handler) refresh(c *gin.Context) {
 }
 
 func (h *Handler) logout(c *gin.Context) {
-	var req struct {
-		SessionToken string `json:"sessionToken" binding:"required"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	sessionToken, ok := h.sessionToken(c)
+	if !ok {
 		return
 	}
-	if err := h.svc.Logout(c, req.SessionToken); err != nil {
+	if err := h.svc.Logout(c, sessionToken); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if h.cookies != nil {
+		for _, name := range []string{middleware.AccessTokenCookie, middleware.SessionCookie, middleware.CSRFCookie} {
+			h.cookies.SetCookie(c.Writer, name, "", 0, true)
+		}
+	}
 	c.Status(http.StatusNoContent)
 }
 
@@ -159,14 +206,6 @@ func (h *Handler) me(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 		return
 	}
-
-	// Address conversion to map[string]any
-	var address map[string]any
-	if details != nil && len(details.Address) > 0 {
-		if err := json.Unmarshal(details.Address, &address); err != nil {
-			address = nil // or you can log/ignore
-		}
-	}
 	c.JSON(http.StatusOK, gin.H{
 		"id":      user.ID,
 		"email":   user.Email,
@@ -182,87 +221,117 @@ func (h *Handler) me(c *gin.Context) {
 	//	"lastName":    safeStr(details, func(d *model.UserDetails) string { return d.LastName }),
 	//	"dateOfBirth": safeDate(details, func(d *model.UserDetails) time.Time { return d.DateOfBirth }),
 	//	"phone":       safeStr(details, func(d *model.UserDetails) string { return d.Phone }),
-	//	"address":     address,
 	//	"photo":       safeStr(details, func(d *model.UserDetails) string { return d.PhotoPath }),
 	//	"thumbnail":   safeStr(details, func(d *model.UserDetails) string { return d.ThumbnailPath }),
 	//})
 }
 
-// [PUT] /me — update user details
+// [PUT] /me — replace the profile; omitted fields are cleared
 func (h *Handler) updateMe(c *gin.Context) {
+	h.writeProfile(c, true)
+}
+
+// [PATCH] /me — JSON Merge Patch (RFC 7396) of the profile: only the fields
+// present are changed, null clears a field
+func (h *Handler) patchMe(c *gin.Context) {
+	h.writeProfile(c, false)
+}
+
+// profileFields are the fields /me accepts, in patch order.
+var profileFields = []string{service.ProfileFirstName, service.ProfileLastName, service.ProfileDateOfBirth, service.ProfilePhone}
+
+func (h *Handler) writeProfile(c *gin.Context, replace bool) {
 	payload := utils.ExtractPayload(c)
 	if payload == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
-	var req struct {
-		FirstName   string         `json:"firstName"`
-		LastName    string         `json:"lastName"`
-		DateOfBirth string         `json:"dateOfBirth"`
-		Phone       string         `json:"phone"`
-		Address     map[string]any `json:"address"`
+	if ct := c.ContentType(); ct != "application/json" && ct != "application/merge-patch+json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "use application/merge-patch+json or application/json"})
+		return
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil || body == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body must be a JSON object"})
 		return
 	}
-	dob, _ := time.Parse("2006-01-02", req.DateOfBirth)
-	_, details, err := h.svc.GetUserWithDetails(c, payload.UserID)
-	if err != nil || details == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user details not found"})
+	values := map[string]*string{}
+	errs := service.FieldErrors{}
+	for field, raw := range body {
+		if !slices.Contains(profileFields, field) {
+			errs[field] = "unknown or read-only field"
+			continue
+		}
+		var v *string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			errs[field] = "must be a string or null"
+			continue
+		}
+		if v == nil {
+			v = new(string) // null clears the field
+		}
+		values[field] = v
+	}
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, errorJSON(fmt.Errorf("%w: %w", service.ErrInvalidProfile, errs)))
 		return
 	}
-	details.FirstName = req.FirstName
-	details.LastName = req.LastName
-	details.DateOfBirth = dob
-	details.Phone = req.Phone
-	if req.Address != nil {
-		bytes, err := json.Marshal(req.Address)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
-			return
+	if replace {
+		for _, field := range profileFields {
+			if values[field] == nil {
+				values[field] = new(string)
+			}
 		}
-		details.Address = bytes
 	}
-	if err := h.svc.UpdateUserDetails(c, payload.UserID, details); err != nil {
+	details, err := h.svc.UpdateProfile(c, payload.UserID, service.ProfilePatch{
+		FirstName:   values[service.ProfileFirstName],
+		LastName:    values[service.ProfileLastName],
+		DateOfBirth: values[service.ProfileDateOfBirth],
+		Phone:       values[service.ProfilePhone],
+	})
+	switch {
+	case errors.Is(err, service.ErrInvalidProfile):
+		c.JSON(http.StatusBadRequest, errorJSON(err))
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user details not found"})
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	default:
+		c.JSON(http.StatusOK, details)
 	}
-	c.Status(http.StatusOK)
 }
 
 // POST /me/photo — upload profile photo
+//
+// The "photo" part is streamed to the service, which stops reading once the
+// size limit is crossed; nothing is buffered to memory or temp files first.
 func (h *Handler) uploadPhoto(c *gin.Context) {
 	payload := utils.ExtractPayload(c)
 	if payload == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
-	file, err := c.FormFile("photo")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "file required"})
-		return
+	if h.maxUpload > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUpload+multipartOverhead)
 	}
-	src, err := file.Open()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	part, err := formPart(c, "photo")
+	if bodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrPhotoTooLarge.Error()})
 		return
 	}
-	defer func(src multipart.File) {
-		err := src.Close()
-		if err != nil {
-
-		}
-	}(src)
-	data, err := io.ReadAll(src)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file required"})
 		return
 	}
-	ext := filepath.Ext(file.Filename)
-	photoPath, thumbPath, err := h.svc.UploadPhoto(c, payload.UserID, data, ext)
+	defer part.Close()
+	// The format is detected from the content, not from the file name.
+	photoPath, thumbPath, err := h.svc.UploadPhoto(c, payload.UserID, part)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if bodyTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrPhotoTooLarge.Error()})
+			return
+		}
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"photo": photoPath, "thumbnail": thumbPath})
@@ -290,6 +359,40 @@ func (h *Handler) changePassword(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// sessionToken reads the session token from the JSON body or, in cookie
+// mode, from the session cookie. It writes a 400 response when missing.
+func (h *Handler) sessionToken(c *gin.Context) (string, bool) {
+	var req struct {
+		SessionToken string `json:"sessionToken"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return "", false
+		}
+	}
+	if req.SessionToken == "" && h.cookies != nil {
+		req.SessionToken, _ = c.Cookie(middleware.SessionCookie)
+	}
+	if req.SessionToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sessionToken is required"})
+		return "", false
+	}
+	return req.SessionToken, true
+}
+
+// setSessionCookies issues the access, session and CSRF cookies after login.
+func (h *Handler) setSessionCookies(c *gin.Context, accessToken, sessionToken string, accessExpiry time.Time) error {
+	csrf := make([]byte, 32)
+	if _, err := rand.Read(csrf); err != nil {
+		return err
+	}
+	h.cookies.SetCookie(c.Writer, middleware.AccessTokenCookie, accessToken, time.Until(accessExpiry), true)
+	h.cookies.SetCookie(c.Writer, middleware.SessionCookie, sessionToken, h.cookies.SessionTTL, true)
+	h.cookies.SetCookie(c.Writer, middleware.CSRFCookie, base64.RawURLEncoding.EncodeToString(csrf), h.cookies.SessionTTL, false)
+	return nil
+}
+
 func safeStr(d *model.UserDetails, f func(*model.UserDetails) string) string {
 	if d != nil {
 		return f(d)
@@ -303,3 +406,53 @@ func safeDate(d *model.UserDetails, f func(*model.UserDetails) time.Time) time.T
 	}
 	return time.Time{}
 }
+
+// formPart returns the multipart file part named name without reading the
+// parts before it into memory.
+func formPart(c *gin.Context, name string) (*multipart.Part, error) {
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == name && part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// bodyTooLarge reports whether err comes from an http.MaxBytesReader.
+func bodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidImage):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, service.ErrPhotoTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrUploadRejected):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrScanUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// errorJSON is the error body; validation failures also list the invalid
+// fields, e.g. {"error": "...", "fields": {"postalCode": "..."}}.
+func errorJSON(err error) gin.H {
+	body := gin.H{"error": err.Error()}
+	var fe interface{ Fields() map[string]string }
+	if errors.As(err, &fe) {
+		body["fields"] = fe.Fields()
+	}
+	return body
+}
@@ -4,19 +4,25 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	httpHandler "github.com/ADRPUR/event-driven-marketplace/internal/auth/handler/http"
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/address"
 	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"gorm.io/datatypes"
 )
 
 // --- Mock service ---
@@ -42,16 +48,18 @@ func (m *mockService) GetUserWithDetails(ctx context.Context, id uuid.UUID) (*mo
 	args := m.Called(id)
 	return args.Get(0).(*model.User), args.Get(1).(*model.UserDetails), args.Error(2)
 }
-func (m *mockService) UpdateUserDetails(ctx context.Context, userID uuid.UUID, details *model.UserDetails) error {
-	args := m.Called(userID)
-	return args.Error(0)
+func (m *mockService) UpdateProfile(ctx context.Context, userID uuid.UUID, p service.ProfilePatch) (*model.UserDetails, error) {
+	args := m.Called(userID, p)
+	d, _ := args.Get(0).(*model.UserDetails)
+	return d, args.Error(1)
 }
 func (m *mockService) ChangePassword(ctx context.Context, userID uuid.UUID, old, new string) error {
 	args := m.Called(userID, old, new)
 	return args.Error(0)
 }
-func (m *mockService) UploadPhoto(ctx context.Context, userID uuid.UUID, data []byte, ext string) (string, string, error) {
-	args := m.Called(userID)
+func (m *mockService) UploadPhoto(ctx context.Context, userID uuid.UUID, r io.Reader) (string, string, error) {
+	data, _ := io.ReadAll(r)
+	args := m.Called(userID, string(data))
 	return args.String(0), args.String(1), args.Error(2)
 }
 
@@ -89,7 +97,10 @@ func TestLogin_OK(t *testing.T) {
 	password := "Abc123!"
 	payload := &token.Payload{UserID: uuid.New(), ExpiredAt: time.Now().Add(time.Hour)}
 	user := &model.User{ID: payload.UserID, Email: email, Role: "user"}
-	details := &model.UserDetails{FirstName: "John", Address: datatypes.JSON([]byte(`{"city":"Chisinau"}`))}
+	details := &model.UserDetails{FirstName: "John", Addresses: []model.Address{{
+		Kind: model.AddressShipping, IsDefault: true,
+		Address: address.Address{Lines: []string{"Str. Ismail 1"}, City: "Chisinau", PostalCode: "MD-2001", Country: "MD"},
+	}}}
 	svc.On("Login", email, password).Return("at", "rt", "st", payload, nil)
 	svc.On("GetUserWithDetails", payload.UserID).Return(user, details, nil)
 
@@ -104,4 +115,185 @@ func TestLogin_OK(t *testing.T) {
 	var resp map[string]any
 	_ = json.Unmarshal(w.Body.Bytes(), &resp)
 	assert.Equal(t, email, resp["user"].(map[string]any)["email"])
+	addrs := resp["user"].(map[string]any)["addresses"].([]any)
+	assert.Equal(t, "Chisinau", addrs[0].(map[string]any)["city"])
+}
+
+func TestRegister_InvalidAddress(t *testing.T) {
+	svc := new(mockService)
+	r := setupRouter(svc)
+	verr := address.ValidationError{{Field: "postalCode", Message: "invalid format for US, e.g. 94105"}}
+	svc.On("Register", "test@abc.com").Return(fmt.Errorf("%w: %w", service.ErrInvalidAddress, verr))
+
+	body, _ := json.Marshal(map[string]any{
+		"email":     "test@abc.com",
+		"password":  "Abc123!",
+		"addresses": []map[string]any{{"lines": []string{"1 Main St"}, "city": "Springfield", "region": "IL", "postalCode": "6270", "country": "US"}},
+	})
+	req, _ := http.NewRequest("POST", "/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp struct {
+		Fields map[string]string `json:"fields"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, verr.Fields(), resp.Fields)
+}
+
+func TestLogin_CookieSession(t *testing.T) {
+	svc := new(mockService)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.CSRF())
+	httpHandler.RegisterPublicRoutes(r, httpHandler.New(svc, httpHandler.WithCookieSession(middleware.CookieConfig{
+		Secure:     true,
+		SameSite:   http.SameSiteStrictMode,
+		SessionTTL: time.Hour,
+	})))
+
+	email, password := "cookie@abc.com", "Abc123!"
+	payload := &token.Payload{UserID: uuid.New(), ExpiredAt: time.Now().Add(time.Minute)}
+	svc.On("Login", email, password).Return("at", "rt", "st", payload, nil)
+	svc.On("GetUserWithDetails", payload.UserID).Return(&model.User{ID: payload.UserID, Email: email}, &model.UserDetails{}, nil)
+
+	body, _ := json.Marshal(map[string]any{"email": email, "password": password})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NotContains(t, resp, "accessToken")
+	cookies := map[string]*http.Cookie{}
+	for _, c := range w.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	assert.Equal(t, "at", cookies[middleware.AccessTokenCookie].Value)
+	assert.True(t, cookies[middleware.AccessTokenCookie].HttpOnly)
+	assert.True(t, cookies[middleware.SessionCookie].Secure)
+	assert.False(t, cookies[middleware.CSRFCookie].HttpOnly)
+
+	// /refresh with the session cookie but without the CSRF header is rejected
+	req, _ = http.NewRequest("POST", "/refresh", nil)
+	req.AddCookie(cookies[middleware.SessionCookie])
+	req.AddCookie(cookies[middleware.CSRFCookie])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	svc.On("Refresh", "st").Return("at2", payload, nil)
+	req, _ = http.NewRequest("POST", "/refresh", nil)
+	req.AddCookie(cookies[middleware.SessionCookie])
+	req.AddCookie(cookies[middleware.CSRFCookie])
+	req.Header.Set(middleware.CSRFHeader, cookies[middleware.CSRFCookie].Value)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestUploadPhoto_StreamsPart(t *testing.T) {
+	svc := new(mockService)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	pl := &token.Payload{UserID: uuid.New(), ExpiredAt: time.Now().Add(time.Minute)}
+	protected := r.Group("/", func(c *gin.Context) { c.Set(token.CtxKey, pl) })
+	httpHandler.RegisterProtectedRoutes(protected, httpHandler.New(svc))
+
+	svc.On("UploadPhoto", pl.UserID, "small").Return("/media/p.jpg", "/media/p_256.jpg", nil)
+	svc.On("UploadPhoto", pl.UserID, "huge").Return("", "", service.ErrPhotoTooLarge)
+
+	upload := func(content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("note", "ignored")
+		fw, _ := mw.CreateFormFile("photo", "me.jpg")
+		_, _ = fw.Write([]byte(content))
+		_ = mw.Close()
+		req, _ := http.NewRequest("POST", "/me/photo", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, upload("small").Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload("huge").Code)
+}
+
+func TestUploadPhoto_BodyLimit(t *testing.T) {
+	svc := new(mockService)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	pl := &token.Payload{UserID: uuid.New(), ExpiredAt: time.Now().Add(time.Minute)}
+	protected := r.Group("/", func(c *gin.Context) { c.Set(token.CtxKey, pl) })
+	httpHandler.RegisterProtectedRoutes(protected, httpHandler.New(svc, httpHandler.WithMaxUploadBytes(1024)))
+
+	// The oversized field comes before the photo, so the body limit trips
+	// before the service reads anything.
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("note", strings.Repeat("x", 128<<10))
+	fw, _ := mw.CreateFormFile("photo", "me.jpg")
+	_, _ = fw.Write([]byte("small"))
+	_ = mw.Close()
+	req, _ := http.NewRequest("POST", "/me/photo", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	svc.AssertNotCalled(t, "UploadPhoto", mock.Anything, mock.Anything)
+}
+
+func TestPatchMe_MergePatch(t *testing.T) {
+	svc := new(mockService)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	pl := &token.Payload{UserID: uuid.New(), ExpiredAt: time.Now().Add(time.Minute)}
+	protected := r.Group("/", func(c *gin.Context) { c.Set(token.CtxKey, pl) })
+	httpHandler.RegisterProtectedRoutes(protected, httpHandler.New(svc))
+
+	send := func(method, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/me", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	str := func(s string) *string { return &s }
+
+	// only the sent fields are patched; null clears
+	svc.On("UpdateProfile", pl.UserID, service.ProfilePatch{FirstName: str("Ana"), Phone: str("")}).
+		Return(&model.UserDetails{FirstName: "Ana"}, nil).Once()
+	assert.Equal(t, http.StatusOK, send("PATCH", `{"firstName":"Ana","phone":null}`).Code)
+
+	// PUT replaces the whole profile
+	svc.On("UpdateProfile", pl.UserID, service.ProfilePatch{FirstName: str("Ana"), LastName: str(""), DateOfBirth: str(""), Phone: str("")}).
+		Return(&model.UserDetails{FirstName: "Ana"}, nil).Once()
+	assert.Equal(t, http.StatusOK, send("PUT", `{"firstName":"Ana"}`).Code)
+
+	// unknown, read-only and mistyped fields are rejected before the service is called
+	w := send("PATCH", `{"photo":"/x.jpg","lastName":5}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp struct {
+		Fields map[string]string `json:"fields"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Contains(t, resp.Fields, "photo")
+	assert.Contains(t, resp.Fields, "lastName")
+
+	assert.Equal(t, http.StatusBadRequest, send("PATCH", `["firstName"]`).Code)
+
+	svc.On("UpdateProfile", pl.UserID, service.ProfilePatch{DateOfBirth: str("31-12-1990")}).
+		Return(nil, fmt.Errorf("%w: %w", service.ErrInvalidProfile, service.FieldErrors{"dateOfBirth": "must be a date in YYYY-MM-DD format"})).Once()
+	w = send("PATCH", `{"dateOfBirth":"31-12-1990"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Contains(t, resp.Fields, "dateOfBirth")
+	svc.AssertExpectations(t)
 }
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"context"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
+	"github.com/google/uuid"
+)
+
+// ImpersonationRecorder adapts a service.ImpersonationAuditor to
+// middleware.ImpersonationRecorder, for both the HTTP middleware and the gRPC
+// interceptors of the auth-service.
+func ImpersonationRecorder(a *service.ImpersonationAuditor) middleware.ImpersonationRecorder {
+	return impersonationRecorder{a}
+}
+
+type impersonationRecorder struct{ auditor *service.ImpersonationAuditor }
+
+func (r impersonationRecorder) RecordImpersonatedRequest(ctx context.Context, rec middleware.RequestRecord) error {
+	adminID, err := uuid.Parse(rec.Payload.ImpersonatorID)
+	if err != nil {
+		return err
+	}
+	return r.auditor.Record(ctx, service.ImpersonatedRequest{
+		AdminID:   adminID,
+		UserID:    rec.Payload.UserID,
+		TokenID:   rec.Payload.ID,
+		Method:    rec.Method,
+		Path:      rec.Path,
+		Status:    rec.Status,
+		IP:        rec.IP,
+		UserAgent: rec.UserAgent,
+	})
+}
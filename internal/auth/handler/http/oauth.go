@@ -0,0 +1,250 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/utils"
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthHandler exposes the OAuth2 authorization server endpoints.
+type OAuthHandler struct {
+	svc service.OAuthService
+}
+
+func NewOAuth(svc service.OAuthService) *OAuthHandler { return &OAuthHandler{svc: svc} }
+
+// RegisterOAuthPublicRoutes mounts the token endpoint, which authenticates clients itself.
+func RegisterOAuthPublicRoutes(r *gin.Engine, h *OAuthHandler) {
+	r.POST("/oauth/token", h.token)
+}
+
+// RegisterOAuthProtectedRoutes mounts client management, authorization and
+// consent endpoints for the signed-in user. Granting or revoking access is
+// blocked for impersonation tokens.
+func RegisterOAuthProtectedRoutes(r *gin.RouterGroup, h *OAuthHandler) {
+	deny := middleware.DenyImpersonation()
+	r.POST("/oauth/clients", deny, h.createClient)
+	r.GET("/oauth/clients", h.listClients)
+	r.GET("/oauth/authorize", deny, h.authorize)
+	r.POST("/oauth/authorize", deny, h.authorize)
+	r.GET("/oauth/consents", h.listConsents)
+	r.DELETE("/oauth/consents/:clientId", deny, h.revokeConsent)
+}
+
+// -------------------- Handlers --------------------
+
+// POST /oauth/clients — register a third-party application
+func (h *OAuthHandler) createClient(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	var req struct {
+		Name         string   `json:"name" binding:"required,max=255"`
+		RedirectURIs []string `json:"redirectUris"`
+		Scopes       []string `json:"scopes" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	client, secret, err := h.svc.RegisterClient(c, payload.UserID, req.Name, req.RedirectURIs, req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp := clientJSON(client)
+	resp["clientSecret"] = secret // returned only once
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GET /oauth/clients — list applications registered by the user
+func (h *OAuthHandler) listClients(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	clients, err := h.svc.ListClients(c, payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, len(clients))
+	for i := range clients {
+		resp[i] = clientJSON(&clients[i])
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GET|POST /oauth/authorize — authorization endpoint.
+// The SPA renders the consent screen when the response is 200 with
+// "consentRequired", then repeats the call via POST with approve=true.
+// On success the response carries the redirect URI including the code.
+func (h *OAuthHandler) authorize(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	var req struct {
+		ResponseType        string `form:"response_type" json:"response_type"`
+		ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+		RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+		Scope               string `form:"scope" json:"scope"`
+		State               string `form:"state" json:"state"`
+		CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+		CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+		Approve             bool   `form:"approve" json:"approve"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidRequest.Error(), "error_description": err.Error()})
+		return
+	}
+	if req.ResponseType != "code" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_response_type"})
+		return
+	}
+	scopes := strings.Fields(req.Scope)
+	redirect, err := h.svc.Authorize(c, payload.UserID, service.AuthorizeRequest{
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scopes:              scopes,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Approve:             c.Request.Method == http.MethodPost && req.Approve,
+	})
+	if errors.Is(err, service.ErrConsentRequired) {
+		client, err := h.svc.GetClient(c, req.ClientID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"consentRequired": true,
+			"client":          gin.H{"id": client.ID, "name": client.Name},
+			"scopes":          scopes,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(oauthStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"redirectUri": redirect})
+}
+
+// POST /oauth/token — token endpoint (RFC 6749 §3.2)
+func (h *OAuthHandler) token(c *gin.Context) {
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID = c.PostForm("client_id")
+		clientSecret = c.PostForm("client_secret")
+	}
+	if clientID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": service.ErrInvalidClient.Error()})
+		return
+	}
+
+	var (
+		at  string
+		pl  *token.Payload
+		err error
+	)
+	switch c.PostForm("grant_type") {
+	case "authorization_code":
+		at, pl, err = h.svc.ExchangeCode(c, clientID, clientSecret,
+			c.PostForm("code"), c.PostForm("redirect_uri"), c.PostForm("code_verifier"))
+	case "client_credentials":
+		at, pl, err = h.svc.ClientCredentials(c, clientID, clientSecret, strings.Fields(c.PostForm("scope")))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+	if err != nil {
+		c.JSON(oauthStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": at,
+		"token_type":   "Bearer",
+		"expires_in":   int64(time.Until(pl.ExpiredAt).Seconds()),
+		"scope":        strings.Join(pl.Scopes, " "),
+	})
+}
+
+// GET /oauth/consents — list applications the user has granted access to
+func (h *OAuthHandler) listConsents(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	consents, err := h.svc.ListConsents(c, payload.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, len(consents))
+	for i, cs := range consents {
+		resp[i] = gin.H{
+			"clientId":  cs.ClientID,
+			"scopes":    strings.Fields(cs.Scopes),
+			"createdAt": cs.CreatedAt,
+			"updatedAt": cs.UpdatedAt,
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DELETE /oauth/consents/:clientId — revoke access granted to an application
+func (h *OAuthHandler) revokeConsent(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	if err := h.svc.RevokeConsent(c, payload.UserID, c.Param("clientId")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// -------------------- Helpers --------------------
+
+func clientJSON(cl *model.OAuthClient) gin.H {
+	return gin.H{
+		"clientId":     cl.ID,
+		"name":         cl.Name,
+		"redirectUris": strings.Fields(cl.RedirectURIs),
+		"scopes":       strings.Fields(cl.Scopes),
+		"createdAt":    cl.CreatedAt,
+	}
+}
+
+// oauthStatus maps OAuth2 errors to HTTP status codes (RFC 6749 §5.2).
+func oauthStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidClient):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrInvalidGrant),
+		errors.Is(err, service.ErrInvalidScope),
+		errors.Is(err, service.ErrInvalidRedirectURI),
+		errors.Is(err, service.ErrInvalidRequest):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/utils"
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// PrivacyHandler exposes the GDPR data export and account deletion request.
+type PrivacyHandler struct {
+	svc service.PrivacyService
+}
+
+func NewPrivacy(svc service.PrivacyService) *PrivacyHandler { return &PrivacyHandler{svc: svc} }
+
+// RegisterPrivacyRoutes mounts /me/export and /me/deletion-request (requires
+// authentication). Both are blocked for impersonation tokens.
+func RegisterPrivacyRoutes(r *gin.RouterGroup, h *PrivacyHandler) {
+	r.GET("/me/export", middleware.DenyImpersonation(), h.export)
+	r.POST("/me/deletion-request", middleware.DenyImpersonation(), h.requestDeletion)
+}
+
+// -------------------- Handlers --------------------
+
+// GET /me/export — zip archive of all data stored about the user
+func (h *PrivacyHandler) export(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	// Buffer the archive so a failure still yields a JSON error.
+	var buf bytes.Buffer
+	if err := h.svc.Export(c, payload.UserID, &buf); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="export-`+payload.UserID.String()+`.zip"`)
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// POST /me/deletion-request — deactivate the account and schedule erasure
+func (h *PrivacyHandler) requestDeletion(c *gin.Context) {
+	payload := utils.ExtractPayload(c)
+	if payload == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dr, err := h.svc.RequestDeletion(c, payload.UserID, req.Password)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrInvalidCredentials):
+			status = http.StatusUnauthorized
+		case errors.Is(err, service.ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, service.ErrDeletionRequested):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"requestedAt": dr.RequestedAt, "purgeAfter": dr.PurgeAfter})
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/address"
+)
+
+// Address kinds
+const (
+	AddressShipping = "shipping"
+	AddressBilling  = "billing"
+)
+
+// Address is one of the user's saved addresses. At most one address per
+// kind is the default.
+type Address struct {
+	ID              uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID          uuid.UUID `json:"-" gorm:"type:uuid;not null;index"`
+	Kind            string    `json:"kind" gorm:"not null"`
+	IsDefault       bool      `json:"isDefault" gorm:"not null;default:false"`
+	address.Address `gorm:"embedded"`
+	CreatedAt       time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (Address) TableName() string { return "user_addresses" }
+
+// ValidAddressKind reports whether kind is a known address kind.
+func ValidAddressKind(kind string) bool {
+	return kind == AddressShipping || kind == AddressBilling
+}
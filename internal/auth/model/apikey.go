@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey is a personal, user-scoped credential for machine clients.
+// Only the SHA-256 hash of the secret is stored; Prefix is kept in clear to
+// look the key up and to let users recognise it in listings.
+type APIKey struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	Name       string    `gorm:"not null"`
+	Prefix     string    `gorm:"unique;not null"`
+	KeyHash    string    `gorm:"not null"`
+	Scopes     string    // space-separated; empty means the key acts with the user's full rights
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// Active reports whether the key is neither revoked nor expired.
+func (k *APIKey) Active() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt))
+}
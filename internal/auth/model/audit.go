@@ -0,0 +1,31 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent is an append-only record of an authentication or account event.
+type AuditEvent struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	Type      string     `gorm:"not null;index"`  // see service.Audit* constants
+	Outcome   string     `gorm:"not null"`        // "success" or "failure"
+	ActorID   *uuid.UUID `gorm:"type:uuid;index"` // who acted; nil for anonymous failures
+	TargetID  *uuid.UUID `gorm:"type:uuid;index"` // account acted upon
+	IP        string
+	UserAgent string
+	RequestID string
+	Detail    string
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+// AuditEmail identifies an email address in AuditEvent.Detail without storing
+// it: the log outlives accounts and must not collect addresses of strangers.
+func AuditEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
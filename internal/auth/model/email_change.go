@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChange is a pending change of a user's login email. It is applied
+// when the token mailed to NewEmail is confirmed; a new request replaces
+// the pending one.
+type EmailChange struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	NewEmail  string    `gorm:"not null"`
+	TokenHash string    `gorm:"not null;uniqueIndex"` // SHA-256 of the confirmation token
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImpersonationLog is an append-only record of an impersonation session
+// being started or of a request made with an impersonation token.
+type ImpersonationLog struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	AdminID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenID   uuid.UUID `gorm:"type:uuid;not null;index"` // token.Payload.ID of the impersonation token
+	Service   string    `gorm:"not null"`                 // "auth-service", "product-service"
+	Method    string    `gorm:"not null"`                 // HTTP method or "GRPC"
+	Path      string    `gorm:"not null"`                 // route or full gRPC method
+	Status    int
+	Reason    string // ticket or free text, set when the session starts
+	IP        string
+	UserAgent string
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -5,14 +5,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 // User contains authentication-related data.
 type User struct {
 	ID           uuid.UUID      `gorm:"type:uuid;primaryKey"`
-	Email        string         `gorm:"unique;not null"`
+	Email        string         `gorm:"not null;uniqueIndex:idx_users_email_active,where:deleted_at IS NULL"` // unique among active accounts
 	PasswordHash string         `gorm:"not null"`
 	Role         string         `gorm:"default:user;not null"`
 	CreatedAt    time.Time      `gorm:"autoCreateTime"`
@@ -27,11 +26,12 @@ type UserDetails struct {
 	LastName      string
 	DateOfBirth   time.Time
 	Phone         string
-	Address       datatypes.JSON
 	PhotoPath     string
 	ThumbnailPath string
-	CreatedAt     time.Time `gorm:"autoCreateTime"`
-	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+	Addresses     []Address      `gorm:"foreignKey:UserID;references:UserID"` // default first
+	CreatedAt     time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime"`
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
 }
 
 // Session stores session data for authenticated users.
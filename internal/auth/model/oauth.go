@@ -0,0 +1,77 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthClient is a third-party application registered by a user.
+// RedirectURIs and Scopes are stored space-separated, as in the OAuth2 wire format.
+type OAuthClient struct {
+	ID           string    `gorm:"primaryKey"` // public client_id
+	SecretHash   string    `gorm:"not null"`
+	OwnerID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	Name         string    `gorm:"not null"`
+	RedirectURIs string    `gorm:"not null"`
+	Scopes       string    `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+// AllowsRedirect reports whether uri exactly matches a registered redirect URI.
+func (c *OAuthClient) AllowsRedirect(uri string) bool {
+	for _, u := range strings.Fields(c.RedirectURIs) {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsScopes reports whether every requested scope was registered for the client.
+func (c *OAuthClient) AllowsScopes(scopes []string) bool {
+	return ScopesSubset(scopes, strings.Fields(c.Scopes))
+}
+
+// OAuthAuthorizationCode is a single-use code issued by the authorization endpoint.
+type OAuthAuthorizationCode struct {
+	CodeHash            string    `gorm:"primaryKey"`
+	ClientID            string    `gorm:"not null;index"`
+	UserID              uuid.UUID `gorm:"type:uuid;not null"`
+	RedirectURI         string    `gorm:"not null"`
+	Scopes              string    `gorm:"not null"`
+	CodeChallenge       string
+	CodeChallengeMethod string
+	ExpiresAt           time.Time `gorm:"not null"`
+	CreatedAt           time.Time `gorm:"autoCreateTime"`
+}
+
+// OAuthConsent records the scopes a user granted to a client.
+type OAuthConsent struct {
+	UserID    uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	ClientID  string     `gorm:"primaryKey"`
+	Scopes    string     `gorm:"not null"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+	RevokedAt *time.Time // nil while the consent is active
+}
+
+// Covers reports whether an active consent already grants every requested scope.
+func (c *OAuthConsent) Covers(scopes []string) bool {
+	return c.RevokedAt == nil && ScopesSubset(scopes, strings.Fields(c.Scopes))
+}
+
+// ScopesSubset reports whether every element of want is present in have.
+func ScopesSubset(want, have []string) bool {
+	set := make(map[string]struct{}, len(have))
+	for _, s := range have {
+		set[s] = struct{}{}
+	}
+	for _, s := range want {
+		if _, ok := set[s]; !ok {
+			return false
+		}
+	}
+	return true
+}
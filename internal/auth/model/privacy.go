@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeletionRequest tracks a user's request to erase their account. The
+// account is deactivated at once and its personal data erased once
+// PurgeAfter has passed.
+type DeletionRequest struct {
+	UserID      uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	RequestedAt time.Time  `gorm:"not null"`
+	PurgeAfter  time.Time  `gorm:"not null;index"`
+	ErasedAt    *time.Time // nil until the purge job has run
+}
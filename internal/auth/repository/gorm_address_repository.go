@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+)
+
+var ErrAddressNotFound = errors.New("address not found")
+
+// --------------------- AddressRepository ----------------------
+
+func (r *GormRepository) ListAddresses(ctx context.Context, userID uuid.UUID) ([]model.Address, error) {
+	var list []model.Address
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Scopes(orderAddresses).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *GormRepository) GetAddress(ctx context.Context, userID, id uuid.UUID) (*model.Address, error) {
+	var a model.Address
+	if err := r.db.WithContext(ctx).First(&a, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAddressNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *GormRepository) CreateAddress(ctx context.Context, a *model.Address) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultAddress(tx, a); err != nil {
+			return err
+		}
+		return tx.Create(a).Error
+	})
+}
+
+func (r *GormRepository) UpdateAddress(ctx context.Context, a *model.Address) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultAddress(tx, a); err != nil {
+			return err
+		}
+		res := tx.Model(a).Where("user_id = ?", a.UserID).
+			Select("kind", "is_default", "lines", "city", "region", "postal_code", "country").
+			Updates(a)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrAddressNotFound
+		}
+		return nil
+	})
+}
+
+func (r *GormRepository) DeleteAddress(ctx context.Context, userID, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var a model.Address
+		if err := tx.First(&a, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAddressNotFound
+			}
+			return err
+		}
+		if err := tx.Delete(&a).Error; err != nil {
+			return err
+		}
+		if !a.IsDefault {
+			return nil
+		}
+		var next model.Address
+		err := tx.Where("user_id = ? AND kind = ?", userID, a.Kind).Order("created_at").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_default", true).Error
+	})
+}
+
+// clearDefaultAddress unsets the current default of a's kind when a is to
+// become the default.
+func clearDefaultAddress(tx *gorm.DB, a *model.Address) error {
+	if !a.IsDefault {
+		return nil
+	}
+	return tx.Model(&model.Address{}).
+		Where("user_id = ? AND kind = ? AND is_default AND id <> ?", a.UserID, a.Kind, a.ID).
+		Update("is_default", false).Error
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// lastUsedResolution bounds how often TouchAPIKey writes to the database.
+const lastUsedResolution = time.Minute
+
+// --------------------- APIKeyRepository ----------------------
+
+func (r *GormRepository) CreateAPIKey(ctx context.Context, k *model.APIKey) error {
+	return r.db.WithContext(ctx).Create(k).Error
+}
+
+// GetAPIKeyByPrefix finds a key whose owner still exists; keys of deleted
+// accounts are not found.
+func (r *GormRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	var k model.APIKey
+	if err := r.db.WithContext(ctx).
+		Select("api_keys.*").
+		Joins("JOIN users ON users.id = api_keys.user_id AND users.deleted_at IS NULL").
+		First(&k, "api_keys.prefix = ?", prefix).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *GormRepository) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]model.APIKey, error) {
+	var list []model.APIKey
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *GormRepository) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	tx := r.db.WithContext(ctx).Model(&model.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey records the key as used now. Writes are skipped when the last
+// recorded use is more recent than lastUsedResolution.
+func (r *GormRepository) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&model.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-lastUsedResolution)).
+		Update("last_used_at", now).Error
+}
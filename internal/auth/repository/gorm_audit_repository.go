@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+)
+
+// --------------------- AuditRepository ----------------------
+
+func (r *GormRepository) CreateAuditEvent(ctx context.Context, e *model.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// ListAuditEvents returns matching events, newest first.
+func (r *GormRepository) ListAuditEvents(ctx context.Context, f AuditFilter) ([]model.AuditEvent, error) {
+	q := r.db.WithContext(ctx).Model(&model.AuditEvent{})
+	if f.Type != "" {
+		q = q.Where("type = ?", f.Type)
+	}
+	if f.Outcome != "" {
+		q = q.Where("outcome = ?", f.Outcome)
+	}
+	if f.ActorID != nil {
+		q = q.Where("actor_id = ?", *f.ActorID)
+	}
+	if f.TargetID != nil {
+		q = q.Where("target_id = ?", *f.TargetID)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("created_at < ?", f.To)
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	var list []model.AuditEvent
+	if err := q.Order("created_at DESC").Offset(f.Offset).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
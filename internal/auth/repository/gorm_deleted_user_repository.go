@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+)
+
+var (
+	ErrEmailTaken = errors.New("email is used by another account")
+	ErrUserErased = errors.New("account has been erased")
+)
+
+// --------------------- DeletedUserRepository ----------------------
+
+// ListDeleted returns soft-deleted accounts, most recently deleted first.
+func (r *GormRepository) ListDeleted(ctx context.Context, offset, limit int) ([]model.User, error) {
+	var list []model.User
+	if err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *GormRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		var n int64
+		if err := tx.Model(&model.DeletionRequest{}).Where("user_id = ? AND erased_at IS NOT NULL", id).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrUserErased
+		}
+		if err := tx.Model(&model.User{}).Where("email = ?", user.Email).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrEmailTaken
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&model.DeletionRequest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.UserDetails{}).Where("user_id = ?", id).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&model.User{}).Where("id = ?", id).Update("deleted_at", nil).Error
+	})
+}
+
+func (r *GormRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, []model.UserDetails, error) {
+	var ids []uuid.UUID
+	var details []model.UserDetails
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&model.User{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Order("deleted_at").Limit(limit).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Unscoped().Where("user_id IN ?", ids).Find(&details).Error; err != nil {
+			return err
+		}
+		// sessions, API keys, OAuth data and deletion requests cascade
+		if err := tx.Unscoped().Where("user_id IN ?", ids).Delete(&model.UserDetails{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&model.User{}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ids, details, nil
+}
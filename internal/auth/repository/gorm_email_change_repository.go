@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
+)
+
+var ErrEmailChangeNotFound = errors.New("email change not found")
+
+// --------------------- EmailChangeRepository ----------------------
+
+// SaveEmailChange replaces the user's pending change, if any.
+func (r *GormRepository) SaveEmailChange(ctx context.Context, c *model.EmailChange) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"new_email", "token_hash", "created_at", "expires_at"}),
+	}).Create(c).Error
+}
+
+func (r *GormRepository) GetEmailChangeByToken(ctx context.Context, tokenHash string) (*model.EmailChange, error) {
+	var c model.EmailChange
+	if err := r.db.WithContext(ctx).First(&c, "token_hash = ?", tokenHash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmailChangeNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *GormRepository) DeleteEmailChange(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&model.EmailChange{}, "user_id = ?", userID).Error
+}
+
+// ApplyEmailChange consumes the pending change c (it must still carry the
+// same token), sets the new email, ends the user's sessions and queues e,
+// all in one transaction. The unique index on active emails settles races
+// with a concurrent registration.
+func (r *GormRepository) ApplyEmailChange(ctx context.Context, c *model.EmailChange, e events.Event) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ? AND token_hash = ?", c.UserID, c.TokenHash).Delete(&model.EmailChange{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrEmailChangeNotFound
+		}
+		var n int64
+		if err := tx.Model(&model.User{}).Where("email = ? AND id <> ?", c.NewEmail, c.UserID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrEmailTaken
+		}
+		res = tx.Model(&model.User{}).Where("id = ?", c.UserID).Update("email", c.NewEmail)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		// signed-in devices must log in again with the new email
+		if err := tx.Where("user_id = ?", c.UserID).Delete(&model.Session{}).Error; err != nil {
+			return err
+		}
+		return events.Add(tx, e)
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrEmailTaken
+	}
+	return err
+}
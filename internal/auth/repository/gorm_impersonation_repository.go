@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+)
+
+// --------------------- ImpersonationRepository ----------------------
+
+func (r *GormRepository) CreateImpersonationLog(ctx context.Context, l *model.ImpersonationLog) error {
+	return r.db.WithContext(ctx).Create(l).Error
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+)
+
+var (
+	ErrClientNotFound  = errors.New("oauth client not found")
+	ErrCodeNotFound    = errors.New("authorization code not found")
+	ErrConsentNotFound = errors.New("consent not found")
+)
+
+// --------------------- OAuthRepository ----------------------
+
+func (r *GormRepository) CreateClient(ctx context.Context, c *model.OAuthClient) error {
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+func (r *GormRepository) GetClient(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	var c model.OAuthClient
+	if err := r.db.WithContext(ctx).First(&c, "id = ?", clientID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClientNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *GormRepository) ListClientsByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.OAuthClient, error) {
+	var list []model.OAuthClient
+	if err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("created_at DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *GormRepository) CreateAuthorizationCode(ctx context.Context, code *model.OAuthAuthorizationCode) error {
+	return r.db.WithContext(ctx).Create(code).Error
+}
+
+func (r *GormRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.OAuthAuthorizationCode, error) {
+	var code model.OAuthAuthorizationCode
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&code, "code_hash = ?", codeHash).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.OAuthAuthorizationCode{}, "code_hash = ?", codeHash).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCodeNotFound
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *GormRepository) GetConsent(ctx context.Context, userID uuid.UUID, clientID string) (*model.OAuthConsent, error) {
+	var c model.OAuthConsent
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND client_id = ?", userID, clientID).
+		First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConsentNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// SaveConsent upserts the consent for (user, client), reactivating a revoked one.
+func (r *GormRepository) SaveConsent(ctx context.Context, consent *model.OAuthConsent) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scopes", "updated_at", "revoked_at"}),
+	}).Create(consent).Error
+}
+
+func (r *GormRepository) ListConsents(ctx context.Context, userID uuid.UUID) ([]model.OAuthConsent, error) {
+	var list []model.OAuthConsent
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *GormRepository) RevokeConsent(ctx context.Context, userID uuid.UUID, clientID string) error {
+	tx := r.db.WithContext(ctx).Model(&model.OAuthConsent{}).
+		Where("user_id = ? AND client_id = ? AND revoked_at IS NULL", userID, clientID).
+		Update("revoked_at", time.Now())
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return ErrConsentNotFound
+	}
+	return nil
+}
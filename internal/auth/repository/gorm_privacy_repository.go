@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+)
+
+var ErrDeletionRequested = errors.New("deletion already requested")
+
+// --------------------- PrivacyRepository ----------------------
+
+func (r *GormRepository) ListSessionsByUser(ctx context.Context, userID uuid.UUID) ([]model.Session, error) {
+	var list []model.Session
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *GormRepository) RequestDeletion(ctx context.Context, req *model.DeletionRequest) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&model.DeletionRequest{}).Where("user_id = ?", req.UserID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrDeletionRequested
+		}
+		if err := tx.Create(req).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", req.UserID).Delete(&model.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", req.UserID).Delete(&model.EmailChange{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.APIKey{}).
+			Where("user_id = ? AND revoked_at IS NULL", req.UserID).
+			Update("revoked_at", req.RequestedAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.UserDetails{}, "user_id = ?", req.UserID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.User{}, "id = ?", req.UserID).Error
+	})
+}
+
+// ListDueDeletions returns pending requests whose grace period is over.
+func (r *GormRepository) ListDueDeletions(ctx context.Context, now time.Time, limit int) ([]model.DeletionRequest, error) {
+	var list []model.DeletionRequest
+	if err := r.db.WithContext(ctx).
+		Where("erased_at IS NULL AND purge_after <= ?", now).
+		Order("purge_after").
+		Limit(limit).
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *GormRepository) EraseUser(ctx context.Context, userID uuid.UUID) (*model.UserDetails, error) {
+	var erased *model.UserDetails
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var details model.UserDetails
+		err := tx.Unscoped().Where("user_id = ?", userID).First(&details).Error
+		switch {
+		case err == nil:
+			erased = &details
+			if err := tx.Unscoped().Delete(&model.UserDetails{}, "user_id = ?", userID).Error; err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		for _, m := range []any{&model.Address{}, &model.EmailChange{}, &model.Session{}, &model.APIKey{}, &model.OAuthConsent{}, &model.OAuthAuthorizationCode{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("owner_id = ?", userID).Delete(&model.OAuthClient{}).Error; err != nil {
+			return err
+		}
+		if err := anonymiseTrails(tx, userID); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.Unscoped().Model(&model.User{}).Where("id = ?", userID).Updates(map[string]any{
+			"email":         "erased-" + userID.String() + "@invalid",
+			"password_hash": "!",
+			"deleted_at":    gorm.Expr("COALESCE(deleted_at, ?)", now),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.DeletionRequest{}).Where("user_id = ?", userID).Update("erased_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return erased, nil
+}
+
+// anonymiseTrails strips the personal data of userID from the audit and
+// impersonation trails, which keep the events themselves: client IPs and
+// user agents of events about or by the user, and the user's email address,
+// raw or hashed, wherever it appears in audit details. It must run before
+// the email is overwritten.
+func anonymiseTrails(tx *gorm.DB, userID uuid.UUID) error {
+	var user model.User
+	err := tx.Unscoped().Select("email").Where("id = ?", userID).Take(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err := tx.Model(&model.AuditEvent{}).
+		Where("target_id = ? OR actor_id = ?", userID, userID).
+		Updates(map[string]any{
+			"ip":         "",
+			"user_agent": "",
+			"detail":     gorm.Expr(`regexp_replace(detail, 'sha256:[0-9a-f]{64}', '[erased]', 'g')`),
+		}).Error; err != nil {
+		return err
+	}
+	if user.Email != "" {
+		for _, s := range []string{user.Email, model.AuditEmail(user.Email)} {
+			if err := tx.Model(&model.AuditEvent{}).
+				Where("strpos(detail, ?) > 0", s).
+				Update("detail", gorm.Expr("replace(detail, ?, '[erased]')", s)).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Model(&model.ImpersonationLog{}).
+		Where("user_id = ? OR admin_id = ?", userID, userID).
+		Updates(map[string]any{"ip": "", "user_agent": ""}).Error
+}
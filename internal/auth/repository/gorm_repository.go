@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
@@ -58,19 +59,22 @@ func (r *GormRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.User
 		return nil, nil, err
 	}
 	var details model.UserDetails
-	if err := r.db.WithContext(ctx).Where("user_id = ?", user.ID).First(&details).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Addresses", orderAddresses).Where("user_id = ?", user.ID).First(&details).Error; err != nil {
 		return &user, nil, nil
 	}
 	return &user, &details, nil
 }
 
+func orderAddresses(db *gorm.DB) *gorm.DB { return db.Order("is_default DESC, created_at") }
+
 func (r *GormRepository) Update(ctx context.Context, user *model.User, details *model.UserDetails) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Model(user).Where("id = ?", user.ID).Updates(user).Error; err != nil {
 			return err
 		}
 		if details != nil {
-			if err := tx.Model(details).Where("user_id = ?", user.ID).Updates(details).Error; err != nil {
+			// addresses are changed through AddressRepository
+			if err := tx.Model(details).Where("user_id = ?", user.ID).Omit(clause.Associations).Updates(details).Error; err != nil {
 				return err
 			}
 		}
@@ -78,8 +82,26 @@ func (r *GormRepository) Update(ctx context.Context, user *model.User, details *
 	})
 }
 
+func (r *GormRepository) UpdateDetails(ctx context.Context, userID uuid.UUID, fields map[string]any) error {
+	res := r.db.WithContext(ctx).Model(&model.UserDetails{}).Where("user_id = ?", userID).Updates(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Delete soft-deletes the user and revokes their API keys, which would
+// otherwise keep working until the account is purged.
 func (r *GormRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.APIKey{}).
+			Where("user_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
 		if err := tx.Delete(&model.UserDetails{}, "user_id = ?", id).Error; err != nil {
 			return err
 		}
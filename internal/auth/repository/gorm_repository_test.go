@@ -2,12 +2,13 @@ package repository_test
 
 import (
 	"context"
-	"encoding/json"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -39,20 +40,19 @@ func TestGormRepository_CreateUser(t *testing.T) {
 	mock.ExpectExec("INSERT INTO \"users\"").
 		WithArgs(user.ID, user.Email, user.PasswordHash, user.Role, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	// user_details: user_id, first_name, last_name, date_of_birth, phone, address, photo_path, thumbnail_path, created_at, updated_at, deleted_at
+	// user_details: user_id, first_name, last_name, date_of_birth, phone, photo_path, thumbnail_path, created_at, updated_at, deleted_at
 	mock.ExpectExec("INSERT INTO \"user_details\"").
 		WithArgs(
-			details.UserID,    // 1
-			details.FirstName, // 2
-			details.LastName,  // 3
-			sqlmock.AnyArg(),  // 4 date_of_birth
-			details.Phone,     // 5
-			// nothing for $6 (address is NULL)
-			details.PhotoPath,     // 7
-			details.ThumbnailPath, // 8 ($8)
-			sqlmock.AnyArg(),      // 9 created_at ($9)
-			sqlmock.AnyArg(),      // 10 updated_at ($10)
-			sqlmock.AnyArg(),      // 11 deleted_at ($11)
+			details.UserID,        // 1
+			details.FirstName,     // 2
+			details.LastName,      // 3
+			sqlmock.AnyArg(),      // 4 date_of_birth
+			details.Phone,         // 5
+			details.PhotoPath,     // 6
+			details.ThumbnailPath, // 7
+			sqlmock.AnyArg(),      // 8 created_at
+			sqlmock.AnyArg(),      // 9 updated_at
+			sqlmock.AnyArg(),      // 10 deleted_at
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
@@ -95,19 +95,43 @@ func TestGormRepository_GetByEmail_Success(t *testing.T) {
 	mock.ExpectQuery("SELECT \\* FROM \"user_details\" WHERE user_id = \\$1 AND \"user_details\"\\.\"deleted_at\" IS NULL ORDER BY \"user_details\"\\.\"user_id\" LIMIT \\$2").
 		WithArgs(id, 1).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"user_id", "first_name", "last_name", "date_of_birth", "phone", "address", "photo_path", "thumbnail_path", "created_at", "updated_at", "deleted_at",
+			"user_id", "first_name", "last_name", "date_of_birth", "phone", "photo_path", "thumbnail_path", "created_at", "updated_at", "deleted_at",
 		}).
-			AddRow(id, "N", "L", time.Now(), "123", []byte(`{"city":"Bucharest"}`), "/static/photo.jpg", "", time.Now(), time.Now(), nil))
+			AddRow(id, "N", "L", time.Now(), "123", "/static/photo.jpg", "", time.Now(), time.Now(), nil))
 
 	u, d, err := repo.GetByEmail(context.Background(), email)
 	assert.NoError(t, err)
 	assert.Equal(t, email, u.Email)
 	assert.Equal(t, "N", d.FirstName)
 	assert.Equal(t, "123", d.Phone)
-	assert.NotNil(t, d.Address)
-	var addr map[string]any
-	_ = json.Unmarshal(d.Address, &addr)
-	assert.Equal(t, "Bucharest", addr["city"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepository_GetByID_LoadsAddresses(t *testing.T) {
+	db, mock, cleanup := setupDB(t)
+	defer cleanup()
+	repo := repository.NewGormRepository(db)
+
+	id := uuid.New()
+	mock.ExpectQuery("SELECT \\* FROM \"users\" WHERE id = \\$1 AND \"users\"\\.\"deleted_at\" IS NULL ORDER BY \"users\"\\.\"id\" LIMIT \\$2").
+		WithArgs(id, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash", "role"}).AddRow(id, "a@abc.com", "hash", "user"))
+	mock.ExpectQuery("SELECT \\* FROM \"user_details\" WHERE user_id = \\$1").
+		WithArgs(id, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "first_name"}).AddRow(id, "N"))
+	mock.ExpectQuery("SELECT \\* FROM \"user_addresses\" WHERE \"user_addresses\"\\.\"user_id\" = \\$1 ORDER BY is_default DESC, created_at").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "kind", "is_default", "lines", "city", "region", "postal_code", "country"}).
+			AddRow(uuid.New(), id, "shipping", true, []byte(`["Str. Unirii 1"]`), "Cluj-Napoca", "CJ", "400001", "RO"))
+
+	_, d, err := repo.GetByID(context.Background(), id)
+	assert.NoError(t, err)
+	if assert.Len(t, d.Addresses, 1) {
+		a := d.Addresses[0]
+		assert.Equal(t, []string{"Str. Unirii 1"}, a.Lines)
+		assert.Equal(t, "RO", a.Country)
+		assert.True(t, a.IsDefault)
+	}
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -118,6 +142,9 @@ func TestGormRepository_DeleteUser(t *testing.T) {
 
 	id := uuid.New()
 	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "api_keys" SET "revoked_at"=\$1 WHERE user_id = \$2 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 2))
 	mock.ExpectExec("UPDATE \"user_details\" SET \"deleted_at\"=\\$1 WHERE user_id = \\$2 AND \"user_details\"\\.\"deleted_at\" IS NULL").
 		WithArgs(sqlmock.AnyArg(), id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -130,3 +157,39 @@ func TestGormRepository_DeleteUser(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestGormRepository_GetAPIKeyByPrefix_IgnoresDeletedOwners(t *testing.T) {
+	db, mock, cleanup := setupDB(t)
+	defer cleanup()
+	repo := repository.NewGormRepository(db)
+
+	// the key row exists, but the join drops it because its owner is deleted
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT api_keys.* FROM "api_keys" JOIN users ON users.id = api_keys.user_id AND users.deleted_at IS NULL WHERE api_keys.prefix = $1`)).
+		WithArgs("a1b2c3d4", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "prefix"}))
+
+	_, err := repo.GetAPIKeyByPrefix(context.Background(), "a1b2c3d4")
+	assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGormRepository_ApplyEmailChange_EndsSessions(t *testing.T) {
+	db, mock, cleanup := setupDB(t)
+	defer cleanup()
+	repo := repository.NewGormRepository(db)
+
+	c := &model.EmailChange{UserID: uuid.New(), NewEmail: "new@abc.com", TokenHash: "hash"}
+	e, err := events.New("auth-service", "user.email_changed", c.UserID.String(), map[string]string{})
+	assert.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "email_changes"`).WithArgs(c.UserID, c.TokenHash).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`UPDATE "users" SET "email"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "sessions" WHERE user_id = \$1`).WithArgs(c.UserID).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO "outbox_events"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.ApplyEmailChange(context.Background(), c, e))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"time"
+
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
 	"github.com/google/uuid"
 )
 
@@ -12,6 +15,9 @@ type UserRepository interface {
 	GetByEmail(ctx context.Context, email string) (*model.User, *model.UserDetails, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*model.User, *model.UserDetails, error)
 	Update(ctx context.Context, user *model.User, details *model.UserDetails) error
+	// UpdateDetails sets the given user_details columns, zero values
+	// included. It fails with ErrUserNotFound when the user has no details.
+	UpdateDetails(ctx context.Context, userID uuid.UUID, fields map[string]any) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
@@ -23,3 +29,101 @@ type SessionRepository interface {
 	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
 	CleanupExpired(ctx context.Context) error
 }
+
+// OAuthRepository manages OAuth2 clients, authorization codes and consents.
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, c *model.OAuthClient) error
+	GetClient(ctx context.Context, clientID string) (*model.OAuthClient, error)
+	ListClientsByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.OAuthClient, error)
+	CreateAuthorizationCode(ctx context.Context, code *model.OAuthAuthorizationCode) error
+	// ConsumeAuthorizationCode deletes and returns the code so it can be used only once.
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.OAuthAuthorizationCode, error)
+	GetConsent(ctx context.Context, userID uuid.UUID, clientID string) (*model.OAuthConsent, error)
+	SaveConsent(ctx context.Context, consent *model.OAuthConsent) error
+	ListConsents(ctx context.Context, userID uuid.UUID) ([]model.OAuthConsent, error)
+	RevokeConsent(ctx context.Context, userID uuid.UUID, clientID string) error
+}
+
+// APIKeyRepository manages personal API keys.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, k *model.APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
+}
+
+// ImpersonationRepository stores the impersonation audit trail.
+type ImpersonationRepository interface {
+	CreateImpersonationLog(ctx context.Context, l *model.ImpersonationLog) error
+}
+
+// AuditFilter narrows ListAuditEvents. Zero fields are ignored.
+type AuditFilter struct {
+	Type     string
+	Outcome  string
+	ActorID  *uuid.UUID
+	TargetID *uuid.UUID
+	From     time.Time
+	To       time.Time
+	Offset   int
+	Limit    int
+}
+
+// AuditRepository stores the append-only security audit log.
+type AuditRepository interface {
+	CreateAuditEvent(ctx context.Context, e *model.AuditEvent) error
+	ListAuditEvents(ctx context.Context, f AuditFilter) ([]model.AuditEvent, error)
+}
+
+// PrivacyRepository supports data export and account erasure.
+type PrivacyRepository interface {
+	ListSessionsByUser(ctx context.Context, userID uuid.UUID) ([]model.Session, error)
+	// RequestDeletion stores req and deactivates the account: sessions are
+	// deleted, API keys revoked and the user soft-deleted.
+	RequestDeletion(ctx context.Context, req *model.DeletionRequest) error
+	ListDueDeletions(ctx context.Context, now time.Time, limit int) ([]model.DeletionRequest, error)
+	// EraseUser removes the user's personal data and credentials and
+	// anonymizes the users row, which audit records still reference. It
+	// returns the erased details so stored files can be removed.
+	EraseUser(ctx context.Context, userID uuid.UUID) (*model.UserDetails, error)
+}
+
+// DeletedUserRepository manages soft-deleted accounts.
+type DeletedUserRepository interface {
+	ListDeleted(ctx context.Context, offset, limit int) ([]model.User, error)
+	// Restore undeletes the account and cancels a pending deletion request.
+	Restore(ctx context.Context, id uuid.UUID) error
+	// PurgeDeleted hard-deletes accounts soft-deleted before the cutoff,
+	// with everything that references them, and returns their IDs together
+	// with the deleted profiles.
+	PurgeDeleted(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, []model.UserDetails, error)
+}
+
+// AddressRepository manages the users' saved addresses.
+type AddressRepository interface {
+	// ListAddresses returns the user's addresses, defaults first.
+	ListAddresses(ctx context.Context, userID uuid.UUID) ([]model.Address, error)
+	GetAddress(ctx context.Context, userID, id uuid.UUID) (*model.Address, error)
+	// CreateAddress and UpdateAddress clear the previous default of the
+	// same kind when a.IsDefault is set.
+	CreateAddress(ctx context.Context, a *model.Address) error
+	UpdateAddress(ctx context.Context, a *model.Address) error
+	// DeleteAddress deletes the address; when it was the default, the
+	// oldest remaining address of its kind becomes the default.
+	DeleteAddress(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// EmailChangeRepository stores pending login email changes.
+type EmailChangeRepository interface {
+	// SaveEmailChange replaces the user's pending change, if any.
+	SaveEmailChange(ctx context.Context, c *model.EmailChange) error
+	GetEmailChangeByToken(ctx context.Context, tokenHash string) (*model.EmailChange, error)
+	DeleteEmailChange(ctx context.Context, userID uuid.UUID) error
+	// ApplyEmailChange deletes the pending change, sets the user's email,
+	// deletes the user's sessions and adds e to the event outbox in one
+	// transaction. It fails with
+	// ErrEmailTaken when another active account uses the new email and
+	// with ErrEmailChangeNotFound when the change was consumed meanwhile.
+	ApplyEmailChange(ctx context.Context, c *model.EmailChange, e events.Event) error
+}
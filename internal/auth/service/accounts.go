@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+)
+
+var (
+	ErrEmailTaken = errors.New("email is used by another account")
+	ErrUserErased = errors.New("account has been erased and cannot be restored")
+)
+
+type DeletedAccountService interface {
+	ListDeletedUsers(ctx context.Context, offset, limit int) ([]model.User, error)
+	RestoreUser(ctx context.Context, id uuid.UUID) error
+}
+
+// Accounts manages soft-deleted accounts: listing, restoring and purging
+// them once the retention window has passed.
+type Accounts struct {
+	repo      repository.DeletedUserRepository
+	photos    *Photos       // may be nil
+	audit     *Audit        // may be nil
+	retention time.Duration // how long soft-deleted accounts are kept
+}
+
+// NewAccounts returns a new Accounts service.
+func NewAccounts(repo repository.DeletedUserRepository, photos *Photos, audit *Audit, retention time.Duration) *Accounts {
+	return &Accounts{repo: repo, photos: photos, audit: audit, retention: retention}
+}
+
+// ListDeletedUsers returns soft-deleted accounts, most recently deleted first.
+func (a *Accounts) ListDeletedUsers(ctx context.Context, offset, limit int) ([]model.User, error) {
+	return a.repo.ListDeleted(ctx, offset, limit)
+}
+
+// RestoreUser undeletes a soft-deleted account. It fails when the email has
+// been registered again or the personal data was already erased.
+func (a *Accounts) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	err := a.repo.Restore(ctx, id)
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		return ErrUserNotFound
+	case errors.Is(err, repository.ErrEmailTaken):
+		err = ErrEmailTaken
+	case errors.Is(err, repository.ErrUserErased):
+		err = ErrUserErased
+	}
+	a.audit.Record(ctx, AuditAccountRestore, id, err, "")
+	return err
+}
+
+// PurgeExpired permanently deletes accounts soft-deleted longer than the
+// retention window, together with their photos. It returns the number of
+// purged accounts; run it periodically.
+func (a *Accounts) PurgeExpired(ctx context.Context) (int, error) {
+	ids, details, err := a.repo.PurgeDeleted(ctx, time.Now().Add(-a.retention), purgeBatch)
+	if err != nil {
+		return 0, err
+	}
+	for i := range details {
+		a.photos.Remove(ctx, &details[i])
+	}
+	for _, id := range ids {
+		a.audit.RecordSystem(ctx, AuditAccountPurge, id, nil, "")
+	}
+	return len(ids), nil
+}
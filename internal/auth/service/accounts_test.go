@@ -0,0 +1,61 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- MOCKS ---
+
+type mockDeletedUserRepo struct{ mock.Mock }
+
+func (m *mockDeletedUserRepo) ListDeleted(ctx context.Context, offset, limit int) ([]model.User, error) {
+	args := m.Called(ctx, offset, limit)
+	return args.Get(0).([]model.User), args.Error(1)
+}
+func (m *mockDeletedUserRepo) Restore(ctx context.Context, id uuid.UUID) error {
+	return m.Called(ctx, id).Error(0)
+}
+func (m *mockDeletedUserRepo) PurgeDeleted(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, []model.UserDetails, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).([]uuid.UUID), args.Get(1).([]model.UserDetails), args.Error(2)
+}
+
+// --- TESTS ---
+
+func TestAccounts_RestoreUser(t *testing.T) {
+	repo := new(mockDeletedUserRepo)
+	svc := service.NewAccounts(repo, nil, nil, time.Hour)
+	ctx := context.Background()
+
+	ok, taken, erased := uuid.New(), uuid.New(), uuid.New()
+	repo.On("Restore", ctx, ok).Return(nil)
+	repo.On("Restore", ctx, taken).Return(repository.ErrEmailTaken)
+	repo.On("Restore", ctx, erased).Return(repository.ErrUserErased)
+
+	assert.NoError(t, svc.RestoreUser(ctx, ok))
+	assert.ErrorIs(t, svc.RestoreUser(ctx, taken), service.ErrEmailTaken)
+	assert.ErrorIs(t, svc.RestoreUser(ctx, erased), service.ErrUserErased)
+}
+
+func TestAccounts_PurgeExpired(t *testing.T) {
+	repo := new(mockDeletedUserRepo)
+	svc := service.NewAccounts(repo, nil, nil, 24*time.Hour)
+	ctx := context.Background()
+
+	repo.On("PurgeDeleted", ctx, mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= 24*time.Hour
+	}), mock.Anything).Return([]uuid.UUID{uuid.New(), uuid.New()}, []model.UserDetails{}, nil)
+
+	n, err := svc.PurgeExpired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+)
+
+// maxAddresses is the number of addresses a user can save.
+const maxAddresses = 20
+
+var (
+	ErrAddressNotFound  = errors.New("address not found")
+	ErrInvalidAddress   = errors.New("invalid address")
+	ErrTooManyAddresses = fmt.Errorf("at most %d addresses can be saved", maxAddresses)
+)
+
+type AddressService interface {
+	ListAddresses(ctx context.Context, userID uuid.UUID) ([]model.Address, error)
+	AddAddress(ctx context.Context, userID uuid.UUID, a *model.Address) error
+	UpdateAddress(ctx context.Context, userID uuid.UUID, a *model.Address) error
+	DeleteAddress(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// Addresses manages the users' saved shipping and billing addresses.
+type Addresses struct {
+	repo  repository.AddressRepository
+	audit *Audit // may be nil
+}
+
+// NewAddresses returns a new Addresses service.
+func NewAddresses(repo repository.AddressRepository, audit *Audit) *Addresses {
+	return &Addresses{repo: repo, audit: audit}
+}
+
+// ListAddresses returns the user's addresses, defaults first.
+func (s *Addresses) ListAddresses(ctx context.Context, userID uuid.UUID) ([]model.Address, error) {
+	return s.repo.ListAddresses(ctx, userID)
+}
+
+// AddAddress validates and saves a new address. The first address of a
+// kind always becomes its default.
+func (s *Addresses) AddAddress(ctx context.Context, userID uuid.UUID, a *model.Address) error {
+	if err := prepareAddress(a); err != nil {
+		return err
+	}
+	existing, err := s.repo.ListAddresses(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(existing) >= maxAddresses {
+		return ErrTooManyAddresses
+	}
+	if !hasDefaultAddress(existing, a.Kind) {
+		a.IsDefault = true
+	}
+	a.ID, a.UserID = uuid.New(), userID
+	err = s.repo.CreateAddress(ctx, a)
+	s.audit.Record(ctx, AuditAddressChange, userID, err, "added "+a.ID.String())
+	return err
+}
+
+// UpdateAddress replaces the address a.ID. The kind of an address cannot
+// change, and a default stays the default until another address of its
+// kind is made the default.
+func (s *Addresses) UpdateAddress(ctx context.Context, userID uuid.UUID, a *model.Address) error {
+	cur, err := s.repo.GetAddress(ctx, userID, a.ID)
+	if errors.Is(err, repository.ErrAddressNotFound) {
+		return ErrAddressNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if a.Kind == "" {
+		a.Kind = cur.Kind
+	}
+	if a.Kind != cur.Kind {
+		return fmt.Errorf("%w: the kind of an address cannot be changed", ErrInvalidAddress)
+	}
+	if err := prepareAddress(a); err != nil {
+		return err
+	}
+	a.UserID, a.CreatedAt = userID, cur.CreatedAt
+	a.IsDefault = a.IsDefault || cur.IsDefault
+	err = s.repo.UpdateAddress(ctx, a)
+	if errors.Is(err, repository.ErrAddressNotFound) {
+		return ErrAddressNotFound
+	}
+	s.audit.Record(ctx, AuditAddressChange, userID, err, "updated "+a.ID.String())
+	return err
+}
+
+// DeleteAddress deletes the address; when it was a default, the oldest
+// remaining address of the same kind takes its place.
+func (s *Addresses) DeleteAddress(ctx context.Context, userID, id uuid.UUID) error {
+	err := s.repo.DeleteAddress(ctx, userID, id)
+	if errors.Is(err, repository.ErrAddressNotFound) {
+		return ErrAddressNotFound
+	}
+	s.audit.Record(ctx, AuditAddressChange, userID, err, "deleted "+id.String())
+	return err
+}
+
+// prepareAddress defaults the kind to shipping, normalizes the address and
+// validates it. Validation failures wrap both ErrInvalidAddress and the
+// address.ValidationError listing the invalid fields.
+func prepareAddress(a *model.Address) error {
+	if a.Kind == "" {
+		a.Kind = model.AddressShipping
+	}
+	if !model.ValidAddressKind(a.Kind) {
+		return fmt.Errorf("%w: kind must be %q or %q", ErrInvalidAddress, model.AddressShipping, model.AddressBilling)
+	}
+	a.Normalize()
+	if err := a.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAddress, err)
+	}
+	return nil
+}
+
+// prepareAddresses prepares the addresses given at registration and makes
+// exactly one address of each kind the default.
+func prepareAddresses(list []model.Address) error {
+	if len(list) > maxAddresses {
+		return ErrTooManyAddresses
+	}
+	for i := range list {
+		if err := prepareAddress(&list[i]); err != nil {
+			return err
+		}
+		a := &list[i]
+		a.ID = uuid.New()
+		if a.IsDefault && hasDefaultAddress(list[:i], a.Kind) {
+			a.IsDefault = false
+		}
+	}
+	for i := range list {
+		if !hasDefaultAddress(list, list[i].Kind) {
+			list[i].IsDefault = true
+		}
+	}
+	return nil
+}
+
+func hasDefaultAddress(list []model.Address, kind string) bool {
+	for _, a := range list {
+		if a.Kind == kind && a.IsDefault {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/address"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memAddressRepo keeps addresses in memory, in creation order.
+type memAddressRepo struct{ list []model.Address }
+
+func (m *memAddressRepo) ListAddresses(_ context.Context, userID uuid.UUID) ([]model.Address, error) {
+	var out []model.Address
+	for _, a := range m.list {
+		if a.UserID == userID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+func (m *memAddressRepo) GetAddress(_ context.Context, userID, id uuid.UUID) (*model.Address, error) {
+	for _, a := range m.list {
+		if a.ID == id && a.UserID == userID {
+			return &a, nil
+		}
+	}
+	return nil, repository.ErrAddressNotFound
+}
+func (m *memAddressRepo) clearDefault(a *model.Address) {
+	for i := range m.list {
+		if a.IsDefault && m.list[i].UserID == a.UserID && m.list[i].Kind == a.Kind && m.list[i].ID != a.ID {
+			m.list[i].IsDefault = false
+		}
+	}
+}
+func (m *memAddressRepo) CreateAddress(_ context.Context, a *model.Address) error {
+	m.clearDefault(a)
+	m.list = append(m.list, *a)
+	return nil
+}
+func (m *memAddressRepo) UpdateAddress(_ context.Context, a *model.Address) error {
+	m.clearDefault(a)
+	for i := range m.list {
+		if m.list[i].ID == a.ID {
+			m.list[i] = *a
+			return nil
+		}
+	}
+	return repository.ErrAddressNotFound
+}
+func (m *memAddressRepo) DeleteAddress(_ context.Context, userID, id uuid.UUID) error {
+	for i, a := range m.list {
+		if a.ID == id && a.UserID == userID {
+			m.list = append(m.list[:i], m.list[i+1:]...)
+			for j := range m.list {
+				if a.IsDefault && m.list[j].UserID == userID && m.list[j].Kind == a.Kind {
+					m.list[j].IsDefault = true
+					break
+				}
+			}
+			return nil
+		}
+	}
+	return repository.ErrAddressNotFound
+}
+
+func newAddress(kind, city string) *model.Address {
+	return &model.Address{Kind: kind, Address: address.Address{
+		Lines: []string{"Hauptstraße 1"}, City: city, PostalCode: "10115", Country: "de",
+	}}
+}
+
+func defaults(list []model.Address) map[string]string {
+	m := map[string]string{}
+	for _, a := range list {
+		if a.IsDefault {
+			m[a.Kind] = a.City
+		}
+	}
+	return m
+}
+
+func TestAddresses_Defaults(t *testing.T) {
+	ctx := context.Background()
+	repo := &memAddressRepo{}
+	svc := service.NewAddresses(repo, nil)
+	userID := uuid.New()
+
+	berlin := newAddress("", "Berlin")
+	require.NoError(t, svc.AddAddress(ctx, userID, berlin))
+	assert.Equal(t, model.AddressShipping, berlin.Kind)
+	assert.Equal(t, "DE", berlin.Country)
+	assert.True(t, berlin.IsDefault, "the first address of a kind is the default")
+
+	hamburg := newAddress(model.AddressShipping, "Hamburg")
+	require.NoError(t, svc.AddAddress(ctx, userID, hamburg))
+	require.NoError(t, svc.AddAddress(ctx, userID, newAddress(model.AddressBilling, "Bonn")))
+	list, _ := svc.ListAddresses(ctx, userID)
+	assert.Equal(t, map[string]string{"shipping": "Berlin", "billing": "Bonn"}, defaults(list))
+
+	// making another address the default moves the flag
+	hamburg.IsDefault = true
+	require.NoError(t, svc.UpdateAddress(ctx, userID, hamburg))
+	list, _ = svc.ListAddresses(ctx, userID)
+	assert.Equal(t, "Hamburg", defaults(list)["shipping"])
+
+	// the kind is fixed and the default cannot simply be unset
+	hamburg.Kind = model.AddressBilling
+	assert.ErrorIs(t, svc.UpdateAddress(ctx, userID, hamburg), service.ErrInvalidAddress)
+	hamburg.Kind, hamburg.IsDefault = "", false
+	require.NoError(t, svc.UpdateAddress(ctx, userID, hamburg))
+	assert.True(t, hamburg.IsDefault)
+
+	// deleting the default promotes the remaining address
+	require.NoError(t, svc.DeleteAddress(ctx, userID, hamburg.ID))
+	list, _ = svc.ListAddresses(ctx, userID)
+	assert.Equal(t, "Berlin", defaults(list)["shipping"])
+
+	assert.ErrorIs(t, svc.DeleteAddress(ctx, uuid.New(), berlin.ID), service.ErrAddressNotFound)
+}
+
+func TestAddresses_Validation(t *testing.T) {
+	svc := service.NewAddresses(&memAddressRepo{}, nil)
+	a := newAddress(model.AddressShipping, "Berlin")
+	a.PostalCode = "1011"
+	err := svc.AddAddress(context.Background(), uuid.New(), a)
+	assert.ErrorIs(t, err, service.ErrInvalidAddress)
+	var verr address.ValidationError
+	if assert.True(t, errors.As(err, &verr)) {
+		assert.Contains(t, verr.Fields(), "postalCode")
+	}
+
+	a = newAddress("home", "Berlin")
+	assert.ErrorIs(t, svc.AddAddress(context.Background(), uuid.New(), a), service.ErrInvalidAddress)
+}
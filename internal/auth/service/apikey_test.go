@@ -0,0 +1,95 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- MOCKS ---
+
+type mockAPIKeyRepo struct{ mock.Mock }
+
+func (m *mockAPIKeyRepo) CreateAPIKey(ctx context.Context, k *model.APIKey) error {
+	return m.Called(ctx, k).Error(0)
+}
+func (m *mockAPIKeyRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	args := m.Called(ctx, prefix)
+	return args.Get(0).(*model.APIKey), args.Error(1)
+}
+func (m *mockAPIKeyRepo) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]model.APIKey, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]model.APIKey), args.Error(1)
+}
+func (m *mockAPIKeyRepo) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	return m.Called(ctx, userID, id).Error(0)
+}
+func (m *mockAPIKeyRepo) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+// --- TESTS ---
+
+func TestAPIKeys_CreateAndVerify(t *testing.T) {
+	repo := new(mockAPIKeyRepo)
+	svc := service.NewAPIKeys(repo)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	var stored *model.APIKey
+	repo.On("CreateAPIKey", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*model.APIKey)
+	}).Return(nil)
+
+	k, plain, err := svc.CreateAPIKey(ctx, userID, "inventory sync", []string{token.ScopeProductsWrite}, nil)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, "mk_"+k.Prefix+"_"))
+	assert.NotContains(t, stored.KeyHash, strings.TrimPrefix(plain, "mk_"+k.Prefix+"_"))
+
+	repo.On("GetAPIKeyByPrefix", ctx, k.Prefix).Return(stored, nil)
+	repo.On("TouchAPIKey", ctx, k.ID).Return(nil)
+
+	pl, err := svc.VerifyAPIKey(ctx, plain)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, pl.UserID)
+	assert.True(t, pl.IsAPIKey())
+	assert.True(t, pl.HasScope(token.ScopeProductsWrite))
+	assert.False(t, pl.HasScope(token.ScopeProductsRead))
+	repo.AssertCalled(t, "TouchAPIKey", ctx, k.ID)
+
+	_, err = svc.VerifyAPIKey(ctx, "mk_"+k.Prefix+"_wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+}
+
+func TestAPIKeys_Verify_RejectsRevokedAndExpired(t *testing.T) {
+	repo := new(mockAPIKeyRepo)
+	svc := service.NewAPIKeys(repo)
+	ctx := context.Background()
+
+	var stored *model.APIKey
+	repo.On("CreateAPIKey", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*model.APIKey)
+	}).Return(nil)
+	expires := time.Now().Add(time.Hour)
+	k, plain, err := svc.CreateAPIKey(ctx, uuid.New(), "batch", nil, &expires)
+	assert.NoError(t, err)
+	repo.On("GetAPIKeyByPrefix", ctx, k.Prefix).Return(stored, nil)
+
+	past := time.Now().Add(-time.Minute)
+	stored.ExpiresAt = &past
+	_, err = svc.VerifyAPIKey(ctx, plain)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+
+	stored.ExpiresAt = nil
+	stored.RevokedAt = &past
+	_, err = svc.VerifyAPIKey(ctx, plain)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/logger"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/requestinfo"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+)
+
+// Audit event types
+const (
+	AuditLogin           = "login"
+	AuditLogout          = "logout"
+	AuditRefresh         = "refresh"
+	AuditPasswordChange  = "password_change"
+	AuditRoleChange      = "role_change"
+	AuditPhotoUpload     = "photo_upload"
+	AuditProfileUpdate   = "profile_update"
+	AuditAccountDelete   = "account_delete"
+	AuditDeletionRequest = "deletion_request"
+	AuditAccountErase    = "account_erase"
+	AuditDataExport      = "data_export"
+	AuditAccountRestore  = "account_restore"
+	AuditAccountPurge    = "account_purge"
+	AuditAddressChange   = "address_change"
+	AuditEmailChange     = "email_change"
+)
+
+// Audit outcomes
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+type AuditService interface {
+	ListAuditEvents(ctx context.Context, f repository.AuditFilter) ([]model.AuditEvent, error)
+}
+
+// Audit writes and queries the security audit log.
+type Audit struct {
+	repo repository.AuditRepository
+}
+
+// NewAudit returns a new Audit service.
+func NewAudit(repo repository.AuditRepository) *Audit {
+	return &Audit{repo: repo}
+}
+
+// ListAuditEvents returns events matching f, newest first.
+func (a *Audit) ListAuditEvents(ctx context.Context, f repository.AuditFilter) ([]model.AuditEvent, error) {
+	return a.repo.ListAuditEvents(ctx, f)
+}
+
+// Record stores one event about target (uuid.Nil if unknown). The actor is
+// taken from the token in ctx (the administrator for impersonation tokens);
+// without a token a successful event is attributed to the target itself.
+// Client IP, user agent and request ID come from requestinfo.Info.
+// Write errors are logged and never fail the audited action. A nil *Audit
+// records nothing.
+func (a *Audit) Record(ctx context.Context, eventType string, target uuid.UUID, err error, detail string) {
+	a.record(ctx, eventType, target, err, detail, false)
+}
+
+// RecordSystem is like Record for actions taken by a background job; the
+// event has no actor.
+func (a *Audit) RecordSystem(ctx context.Context, eventType string, target uuid.UUID, err error, detail string) {
+	a.record(ctx, eventType, target, err, detail, true)
+}
+
+func (a *Audit) record(ctx context.Context, eventType string, target uuid.UUID, err error, detail string, system bool) {
+	if a == nil {
+		return
+	}
+	info := requestinfo.FromContext(ctx)
+	e := &model.AuditEvent{
+		ID:        uuid.New(),
+		Type:      eventType,
+		Outcome:   AuditSuccess,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		RequestID: info.ID,
+		Detail:    detail,
+		CreatedAt: time.Now(),
+	}
+	if err != nil {
+		e.Outcome = AuditFailure
+		if e.Detail == "" {
+			e.Detail = err.Error()
+		}
+	}
+	if target != uuid.Nil {
+		e.TargetID = &target
+	}
+	pl, _ := ctx.Value(token.CtxKey).(*token.Payload)
+	switch {
+	case system:
+		// background jobs have no actor
+	case pl != nil:
+		actor := pl.UserID
+		if pl.IsImpersonated() {
+			actor, _ = uuid.Parse(pl.ImpersonatorID)
+		}
+		if actor != uuid.Nil {
+			e.ActorID = &actor
+		}
+	case err == nil:
+		e.ActorID = e.TargetID
+	}
+	if werr := a.repo.CreateAuditEvent(context.WithoutCancel(ctx), e); werr != nil {
+		logger.Error("audit %s: %v", eventType, werr)
+	}
+}
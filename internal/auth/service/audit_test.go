@@ -0,0 +1,110 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/requestinfo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- MOCKS ---
+
+type mockAuditRepo struct{ mock.Mock }
+
+func (m *mockAuditRepo) CreateAuditEvent(ctx context.Context, e *model.AuditEvent) error {
+	return m.Called(ctx, e).Error(0)
+}
+func (m *mockAuditRepo) ListAuditEvents(ctx context.Context, f repository.AuditFilter) ([]model.AuditEvent, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]model.AuditEvent), args.Error(1)
+}
+
+// --- TESTS ---
+
+func TestService_Login_RecordsAudit(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	sessionRepo := new(mockSessionRepo)
+	auditRepo := new(mockAuditRepo)
+	svc := service.New(userRepo, sessionRepo, new(mockTokenMaker), time.Minute, time.Hour,
+		service.WithAudit(service.NewAudit(auditRepo)))
+
+	ctx := requestinfo.NewContext(context.Background(),
+		requestinfo.Info{ID: "req-1", IP: "10.0.0.1", UserAgent: "curl"})
+	hash, _ := service.HashPassword("secret")
+	user := &model.User{ID: uuid.New(), Email: "audit@abc.com", PasswordHash: hash}
+	userRepo.On("GetByEmail", ctx, user.Email).Return(user, &model.UserDetails{}, nil)
+	sessionRepo.On("CreateSession", ctx, mock.Anything).Return(nil)
+
+	var events []*model.AuditEvent
+	auditRepo.On("CreateAuditEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(*model.AuditEvent))
+	}).Return(nil)
+
+	_, _, _, _, err := svc.Login(ctx, user.Email, "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	_, _, _, _, err = svc.Login(ctx, user.Email, "secret")
+	assert.NoError(t, err)
+
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, service.AuditLogin, events[0].Type)
+		assert.Equal(t, service.AuditFailure, events[0].Outcome)
+		assert.Nil(t, events[0].ActorID)
+		assert.Equal(t, user.ID, *events[0].TargetID)
+		assert.Equal(t, "req-1", events[0].RequestID)
+		assert.Equal(t, "10.0.0.1", events[0].IP)
+
+		assert.Equal(t, service.AuditSuccess, events[1].Outcome)
+		assert.Equal(t, user.ID, *events[1].ActorID)
+	}
+}
+
+func TestService_Refresh_InvalidSessionIsAudited(t *testing.T) {
+	sessionRepo := new(mockSessionRepo)
+	auditRepo := new(mockAuditRepo)
+	svc := service.New(new(mockUserRepo), sessionRepo, new(mockTokenMaker), time.Minute, time.Hour,
+		service.WithAudit(service.NewAudit(auditRepo)))
+
+	ctx := context.Background()
+	sessionRepo.On("GetSessionByToken", ctx, "bogus").Return((*model.Session)(nil), errors.New("record not found"))
+	var events []*model.AuditEvent
+	auditRepo.On("CreateAuditEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(*model.AuditEvent))
+	}).Return(nil)
+
+	_, _, err := svc.Refresh(ctx, "bogus")
+	assert.ErrorIs(t, err, service.ErrSessionNotFound)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, service.AuditRefresh, events[0].Type)
+		assert.Equal(t, service.AuditFailure, events[0].Outcome)
+		assert.Nil(t, events[0].TargetID)
+	}
+}
+
+func TestService_Login_UnknownEmailIsNotStored(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	auditRepo := new(mockAuditRepo)
+	svc := service.New(userRepo, new(mockSessionRepo), new(mockTokenMaker), time.Minute, time.Hour,
+		service.WithAudit(service.NewAudit(auditRepo)))
+
+	ctx := context.Background()
+	userRepo.On("GetByEmail", ctx, "stranger@abc.com").Return((*model.User)(nil), (*model.UserDetails)(nil), repository.ErrUserNotFound)
+	var events []*model.AuditEvent
+	auditRepo.On("CreateAuditEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(*model.AuditEvent))
+	}).Return(nil)
+
+	_, _, _, _, err := svc.Login(ctx, "stranger@abc.com", "x")
+	assert.ErrorIs(t, err, service.ErrUserNotFound)
+	if assert.Len(t, events, 1) {
+		assert.NotContains(t, events[0].Detail, "stranger")
+		assert.Contains(t, events[0].Detail, "sha256:")
+	}
+}
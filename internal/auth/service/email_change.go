@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
+	mailer "github.com/ADRPUR/event-driven-marketplace/pkg/mail"
+)
+
+// EventSource names this service in published events.
+const EventSource = "auth-service"
+
+// EventEmailChanged is published when a user's login email changes. Its
+// data is EmailChangedData.
+const EventEmailChanged = "user.email_changed"
+
+// EmailChangedData is the payload of EventEmailChanged.
+type EmailChangedData struct {
+	UserID   uuid.UUID `json:"userId"`
+	Email    string    `json:"email"`
+	OldEmail string    `json:"oldEmail"`
+}
+
+var (
+	ErrInvalidEmail      = errors.New("invalid email address")
+	ErrSameEmail         = errors.New("new email is the current email")
+	ErrInvalidEmailToken = errors.New("invalid or expired confirmation token")
+)
+
+type EmailChangeService interface {
+	RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail, password string) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+}
+
+// EmailChanges changes login emails in two steps: a request mails a token
+// to the new address, and the email is changed once that token is
+// confirmed. The old address is notified of both steps.
+type EmailChanges struct {
+	users      repository.UserRepository
+	repo       repository.EmailChangeRepository
+	mail       mailer.Sender
+	confirmURL string        // page receiving the token as ?token=
+	ttl        time.Duration // validity of a confirmation token
+	audit      *Audit        // may be nil
+}
+
+// NewEmailChanges returns a new EmailChanges service.
+func NewEmailChanges(users repository.UserRepository, repo repository.EmailChangeRepository, mail mailer.Sender,
+	confirmURL string, ttl time.Duration, audit *Audit) *EmailChanges {
+	return &EmailChanges{users: users, repo: repo, mail: mail, confirmURL: confirmURL, ttl: ttl, audit: audit}
+}
+
+// RequestEmailChange re-checks the password, stores a pending change to
+// newEmail and mails the confirmation link. A new request replaces a
+// pending one.
+func (s *EmailChanges) RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail, password string) error {
+	user, _, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !CheckPasswordHash(user.PasswordHash, password) {
+		s.audit.Record(ctx, AuditEmailChange, userID, ErrInvalidCredentials, "request")
+		return ErrInvalidCredentials
+	}
+	newEmail = strings.TrimSpace(newEmail)
+	if a, err := mail.ParseAddress(newEmail); err != nil || a.Address != newEmail || a.Name != "" {
+		return ErrInvalidEmail
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrSameEmail
+	}
+	if _, _, err := s.users.GetByEmail(ctx, newEmail); err == nil {
+		return ErrEmailTaken
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return err
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	c := &model.EmailChange{
+		UserID:    userID,
+		NewEmail:  newEmail,
+		TokenHash: hashToken(token),
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.repo.SaveEmailChange(ctx, c); err != nil {
+		return err
+	}
+	err = s.mail.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: "To use this address to sign in, open the link below within " + s.ttl.String() + ":\n\n" +
+			s.link(token) + "\n\nIf you did not ask for this change, ignore this message.",
+		Secrets: []string{token},
+	})
+	s.audit.Record(ctx, AuditEmailChange, userID, err, "requested "+model.AuditEmail(newEmail))
+	if err != nil {
+		return fmt.Errorf("sending confirmation: %w", err)
+	}
+	s.notify(ctx, user.Email, "Email change requested",
+		"A change of your sign-in email to "+newEmail+" was requested. It takes effect once confirmed from that address.\n\n"+
+			"If this was not you, change your password now.")
+	return nil
+}
+
+// ConfirmEmailChange applies the change identified by token and publishes
+// EventEmailChanged.
+func (s *EmailChanges) ConfirmEmailChange(ctx context.Context, token string) error {
+	c, err := s.repo.GetEmailChangeByToken(ctx, hashToken(token))
+	if errors.Is(err, repository.ErrEmailChangeNotFound) {
+		return ErrInvalidEmailToken
+	}
+	if err != nil {
+		return err
+	}
+	if time.Now().After(c.ExpiresAt) {
+		_ = s.repo.DeleteEmailChange(ctx, c.UserID)
+		return ErrInvalidEmailToken
+	}
+	user, _, err := s.users.GetByID(ctx, c.UserID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	e, err := events.New(EventSource, EventEmailChanged, c.UserID.String(),
+		EmailChangedData{UserID: c.UserID, Email: c.NewEmail, OldEmail: user.Email})
+	if err != nil {
+		return err
+	}
+	err = s.repo.ApplyEmailChange(ctx, c, e)
+	switch {
+	case errors.Is(err, repository.ErrEmailTaken):
+		err = ErrEmailTaken
+	case errors.Is(err, repository.ErrEmailChangeNotFound):
+		err = ErrInvalidEmailToken
+	case errors.Is(err, repository.ErrUserNotFound):
+		err = ErrUserNotFound
+	}
+	s.audit.Record(ctx, AuditEmailChange, c.UserID, err, "confirmed "+model.AuditEmail(c.NewEmail))
+	if err != nil {
+		return err
+	}
+	s.notify(ctx, user.Email, "Your email address was changed",
+		"Your sign-in email is now "+c.NewEmail+". This address will no longer receive account messages.\n\n"+
+			"If this was not you, contact support.")
+	return nil
+}
+
+// link returns the confirmation URL carrying token.
+func (s *EmailChanges) link(token string) string {
+	u, err := url.Parse(s.confirmURL)
+	if err != nil {
+		return s.confirmURL + "?token=" + url.QueryEscape(token)
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// notify sends an informational message; failures are only logged.
+func (s *EmailChanges) notify(ctx context.Context, to, subject, body string) {
+	if err := s.mail.Send(ctx, mailer.Message{To: to, Subject: subject, Body: body}); err != nil {
+		log.Printf("mail to %s: %v", to, err)
+	}
+}
@@ -0,0 +1,143 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/mail"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- FAKES ---
+
+// memEmailChangeRepo keeps pending changes in memory and records applied ones.
+type memEmailChangeRepo struct {
+	pending map[uuid.UUID]model.EmailChange
+	applied []events.Event
+	taken   bool // ApplyEmailChange fails with ErrEmailTaken
+}
+
+func newMemEmailChangeRepo() *memEmailChangeRepo {
+	return &memEmailChangeRepo{pending: map[uuid.UUID]model.EmailChange{}}
+}
+
+func (m *memEmailChangeRepo) SaveEmailChange(_ context.Context, c *model.EmailChange) error {
+	m.pending[c.UserID] = *c
+	return nil
+}
+func (m *memEmailChangeRepo) GetEmailChangeByToken(_ context.Context, hash string) (*model.EmailChange, error) {
+	for _, c := range m.pending {
+		if c.TokenHash == hash {
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrEmailChangeNotFound
+}
+func (m *memEmailChangeRepo) DeleteEmailChange(_ context.Context, userID uuid.UUID) error {
+	delete(m.pending, userID)
+	return nil
+}
+func (m *memEmailChangeRepo) ApplyEmailChange(_ context.Context, c *model.EmailChange, e events.Event) error {
+	if m.taken {
+		return repository.ErrEmailTaken
+	}
+	delete(m.pending, c.UserID)
+	m.applied = append(m.applied, e)
+	return nil
+}
+
+// outbox records sent mail.
+type outbox struct{ sent []mail.Message }
+
+func (o *outbox) Send(_ context.Context, m mail.Message) error {
+	o.sent = append(o.sent, m)
+	return nil
+}
+
+// tokenFrom extracts the confirmation token from a mailed link.
+func tokenFrom(t *testing.T, body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "http") {
+			u, err := url.Parse(line)
+			require.NoError(t, err)
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatal("no link in message")
+	return ""
+}
+
+// --- TESTS ---
+
+func TestEmailChanges_RequestAndConfirm(t *testing.T) {
+	ctx := context.Background()
+	users, repo, box := new(mockUserRepo), newMemEmailChangeRepo(), &outbox{}
+	svc := service.NewEmailChanges(users, repo, box, "https://shop.test/confirm-email", time.Hour, nil)
+
+	hash, _ := service.HashPassword("secret")
+	user := &model.User{ID: uuid.New(), Email: "old@abc.com", PasswordHash: hash}
+	users.On("GetByID", ctx, user.ID).Return(user, &model.UserDetails{}, nil)
+	users.On("GetByEmail", ctx, "new@abc.com").Return((*model.User)(nil), (*model.UserDetails)(nil), repository.ErrUserNotFound)
+
+	require.NoError(t, svc.RequestEmailChange(ctx, user.ID, " new@abc.com ", "secret"))
+	require.Len(t, box.sent, 2)
+	assert.Equal(t, "new@abc.com", box.sent[0].To)
+	assert.Equal(t, "old@abc.com", box.sent[1].To, "the old address is told about the request")
+	token := tokenFrom(t, box.sent[0].Body)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, []string{token}, box.sent[0].Secrets, "the token is kept out of mail logs")
+	assert.NotEqual(t, token, repo.pending[user.ID].TokenHash, "only the hash is stored")
+
+	assert.ErrorIs(t, svc.ConfirmEmailChange(ctx, "wrong"), service.ErrInvalidEmailToken)
+	require.NoError(t, svc.ConfirmEmailChange(ctx, token))
+	require.Len(t, repo.applied, 1)
+	e := repo.applied[0]
+	assert.Equal(t, service.EventEmailChanged, e.Type)
+	assert.Equal(t, user.ID.String(), e.Subject)
+	var data service.EmailChangedData
+	require.NoError(t, json.Unmarshal(e.Data, &data))
+	assert.Equal(t, service.EmailChangedData{UserID: user.ID, Email: "new@abc.com", OldEmail: "old@abc.com"}, data)
+	assert.Equal(t, "old@abc.com", box.sent[len(box.sent)-1].To)
+
+	assert.ErrorIs(t, svc.ConfirmEmailChange(ctx, token), service.ErrInvalidEmailToken, "a token is used once")
+}
+
+func TestEmailChanges_Rejected(t *testing.T) {
+	ctx := context.Background()
+	users, repo, box := new(mockUserRepo), newMemEmailChangeRepo(), &outbox{}
+	svc := service.NewEmailChanges(users, repo, box, "https://shop.test/confirm-email", time.Hour, nil)
+
+	hash, _ := service.HashPassword("secret")
+	user := &model.User{ID: uuid.New(), Email: "old@abc.com", PasswordHash: hash}
+	users.On("GetByID", ctx, user.ID).Return(user, &model.UserDetails{}, nil)
+	users.On("GetByEmail", ctx, "taken@abc.com").Return(&model.User{ID: uuid.New()}, &model.UserDetails{}, nil)
+
+	assert.ErrorIs(t, svc.RequestEmailChange(ctx, user.ID, "new@abc.com", "wrong"), service.ErrInvalidCredentials)
+	assert.ErrorIs(t, svc.RequestEmailChange(ctx, user.ID, "Ana <new@abc.com>", "secret"), service.ErrInvalidEmail)
+	assert.ErrorIs(t, svc.RequestEmailChange(ctx, user.ID, "OLD@abc.com", "secret"), service.ErrSameEmail)
+	assert.ErrorIs(t, svc.RequestEmailChange(ctx, user.ID, "taken@abc.com", "secret"), service.ErrEmailTaken)
+	assert.Empty(t, box.sent)
+
+	// expired token
+	repo.pending[user.ID] = model.EmailChange{UserID: user.ID, NewEmail: "new@abc.com",
+		TokenHash: checksum([]byte("expired")), ExpiresAt: time.Now().Add(-time.Minute)}
+	assert.ErrorIs(t, svc.ConfirmEmailChange(ctx, "expired"), service.ErrInvalidEmailToken)
+	assert.Empty(t, repo.pending)
+
+	// the address was taken between request and confirmation
+	repo.taken = true
+	repo.pending[user.ID] = model.EmailChange{UserID: user.ID, NewEmail: "new@abc.com",
+		TokenHash: checksum([]byte("late")), ExpiresAt: time.Now().Add(time.Minute)}
+	assert.ErrorIs(t, svc.ConfirmEmailChange(ctx, "late"), service.ErrEmailTaken)
+	assert.Empty(t, repo.applied)
+}
@@ -0,0 +1,53 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- MOCKS ---
+
+type mockImpersonationRepo struct{ mock.Mock }
+
+func (m *mockImpersonationRepo) CreateImpersonationLog(ctx context.Context, l *model.ImpersonationLog) error {
+	return m.Called(ctx, l).Error(0)
+}
+
+// --- TESTS ---
+
+func TestImpersonation_Impersonate(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	logs := new(mockImpersonationRepo)
+	svc := service.NewImpersonation(userRepo, logs, &mockTokenMaker{}, 15*time.Minute)
+	ctx := context.Background()
+
+	admin := &model.User{ID: uuid.New(), Role: token.RoleAdmin}
+	user := &model.User{ID: uuid.New(), Role: "user"}
+	userRepo.On("GetByID", ctx, admin.ID).Return(admin, &model.UserDetails{}, nil)
+	userRepo.On("GetByID", ctx, user.ID).Return(user, &model.UserDetails{}, nil)
+	logs.On("CreateImpersonationLog", ctx, mock.MatchedBy(func(l *model.ImpersonationLog) bool {
+		return l.AdminID == admin.ID && l.UserID == user.ID && l.Reason == "ticket #42"
+	})).Return(nil)
+
+	at, pl, err := svc.Impersonate(ctx, admin.ID, user.ID, "ticket #42", "127.0.0.1", "curl")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, at)
+	assert.Equal(t, user.ID, pl.UserID)
+	assert.Equal(t, admin.ID.String(), pl.ImpersonatorID)
+	assert.True(t, pl.IsImpersonated())
+	logs.AssertExpectations(t)
+
+	// admins cannot be impersonated, and non-admins cannot impersonate
+	_, _, err = svc.Impersonate(ctx, admin.ID, admin.ID, "x", "", "")
+	assert.ErrorIs(t, err, service.ErrCannotImpersonate)
+	_, _, err = svc.Impersonate(ctx, user.ID, admin.ID, "x", "", "")
+	assert.ErrorIs(t, err, service.ErrForbidden)
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+)
+
+// OAuth2 errors. Their messages follow the RFC 6749 error codes so the
+// transport layer can return them verbatim.
+var (
+	ErrInvalidClient      = errors.New("invalid_client")
+	ErrInvalidGrant       = errors.New("invalid_grant")
+	ErrInvalidScope       = errors.New("invalid_scope")
+	ErrInvalidRedirectURI = errors.New("invalid_redirect_uri")
+	ErrInvalidRequest     = errors.New("invalid_request")
+	ErrConsentRequired    = errors.New("consent_required")
+)
+
+const authorizationCodeTTL = 5 * time.Minute
+
+type OAuthService interface {
+	RegisterClient(ctx context.Context, ownerID uuid.UUID, name string, redirectURIs, scopes []string) (*model.OAuthClient, string, error)
+	ListClients(ctx context.Context, ownerID uuid.UUID) ([]model.OAuthClient, error)
+	GetClient(ctx context.Context, clientID string) (*model.OAuthClient, error)
+	Authorize(ctx context.Context, userID uuid.UUID, req AuthorizeRequest) (string, error)
+	ExchangeCode(ctx context.Context, clientID, clientSecret, code, redirectURI, codeVerifier string) (string, *token.Payload, error)
+	ClientCredentials(ctx context.Context, clientID, clientSecret string, scopes []string) (string, *token.Payload, error)
+	ListConsents(ctx context.Context, userID uuid.UUID) ([]model.OAuthConsent, error)
+	RevokeConsent(ctx context.Context, userID uuid.UUID, clientID string) error
+}
+
+// AuthorizeRequest carries the parameters of the authorization endpoint.
+type AuthorizeRequest struct {
+	ClientID            string
+	RedirectURI         string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string // "S256" or "plain"
+	Approve             bool   // user explicitly granted the requested scopes
+}
+
+// OAuth implements the OAuth2 authorization server (authorization-code and
+// client-credentials grants). Issued access tokens are regular Paseto tokens
+// carrying ClientID and Scopes, so token.Maker verifies them everywhere.
+type OAuth struct {
+	repo  repository.OAuthRepository
+	maker token.Maker
+	ttl   time.Duration // access-token TTL for OAuth2 clients
+}
+
+// NewOAuth returns a new OAuth service.
+func NewOAuth(repo repository.OAuthRepository, maker token.Maker, ttl time.Duration) *OAuth {
+	return &OAuth{repo: repo, maker: maker, ttl: ttl}
+}
+
+// RegisterClient creates a client owned by ownerID and returns the plaintext
+// secret, which is shown only once.
+func (s *OAuth) RegisterClient(ctx context.Context, ownerID uuid.UUID, name string, redirectURIs, scopes []string) (*model.OAuthClient, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", ErrInvalidScope
+	}
+	for _, sc := range scopes {
+		if !token.IsKnownScope(sc) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+	for _, u := range redirectURIs {
+		parsed, err := url.Parse(u)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" {
+			return nil, "", ErrInvalidRedirectURI
+		}
+	}
+	clientID, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	c := &model.OAuthClient{
+		ID:           clientID,
+		SecretHash:   hashToken(secret),
+		OwnerID:      ownerID,
+		Name:         name,
+		RedirectURIs: strings.Join(redirectURIs, " "),
+		Scopes:       strings.Join(scopes, " "),
+	}
+	if err := s.repo.CreateClient(ctx, c); err != nil {
+		return nil, "", err
+	}
+	return c, secret, nil
+}
+
+// ListClients returns the clients registered by ownerID.
+func (s *OAuth) ListClients(ctx context.Context, ownerID uuid.UUID) ([]model.OAuthClient, error) {
+	return s.repo.ListClientsByOwner(ctx, ownerID)
+}
+
+// GetClient returns public information about a client, e.g. for the consent screen.
+func (s *OAuth) GetClient(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	c, err := s.repo.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrClientNotFound) {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Authorize validates an authorization request for the signed-in user and
+// returns the redirect URI carrying a fresh authorization code. It returns
+// ErrConsentRequired when the user has not yet granted the requested scopes
+// and req.Approve is false.
+func (s *OAuth) Authorize(ctx context.Context, userID uuid.UUID, req AuthorizeRequest) (string, error) {
+	client, err := s.GetClient(ctx, req.ClientID)
+	if err != nil {
+		return "", err
+	}
+	if !client.AllowsRedirect(req.RedirectURI) {
+		return "", ErrInvalidRedirectURI
+	}
+	if len(req.Scopes) == 0 || !client.AllowsScopes(req.Scopes) {
+		return "", ErrInvalidScope
+	}
+	switch req.CodeChallengeMethod {
+	case "":
+		if req.CodeChallenge != "" {
+			req.CodeChallengeMethod = "plain"
+		}
+	case "S256", "plain":
+		if req.CodeChallenge == "" {
+			return "", ErrInvalidRequest
+		}
+	default:
+		return "", ErrInvalidRequest
+	}
+
+	if req.Approve {
+		if err := s.repo.SaveConsent(ctx, &model.OAuthConsent{
+			UserID:   userID,
+			ClientID: client.ID,
+			Scopes:   strings.Join(req.Scopes, " "),
+		}); err != nil {
+			return "", err
+		}
+	} else {
+		consent, err := s.repo.GetConsent(ctx, userID, client.ID)
+		if err != nil && !errors.Is(err, repository.ErrConsentNotFound) {
+			return "", err
+		}
+		if consent == nil || !consent.Covers(req.Scopes) {
+			return "", ErrConsentRequired
+		}
+	}
+
+	code, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.CreateAuthorizationCode(ctx, &model.OAuthAuthorizationCode{
+		CodeHash:            hashToken(code),
+		ClientID:            client.ID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectURI,
+		Scopes:              strings.Join(req.Scopes, " "),
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		ExpiresAt:           time.Now().Add(authorizationCodeTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	redirect, _ := url.Parse(req.RedirectURI)
+	q := redirect.Query()
+	q.Set("code", code)
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	redirect.RawQuery = q.Encode()
+	return redirect.String(), nil
+}
+
+// ExchangeCode implements the authorization_code grant. Every client is
+// confidential and authenticates with its secret; a PKCE verifier is
+// checked on top of it when the code was issued with a challenge.
+func (s *OAuth) ExchangeCode(ctx context.Context, clientID, clientSecret, code, redirectURI, codeVerifier string) (string, *token.Payload, error) {
+	client, err := s.GetClient(ctx, clientID)
+	if err != nil {
+		return "", nil, err
+	}
+	ac, err := s.repo.ConsumeAuthorizationCode(ctx, hashToken(code))
+	if err != nil {
+		if errors.Is(err, repository.ErrCodeNotFound) {
+			return "", nil, ErrInvalidGrant
+		}
+		return "", nil, err
+	}
+	if ac.ClientID != client.ID || ac.RedirectURI != redirectURI || time.Now().After(ac.ExpiresAt) {
+		return "", nil, ErrInvalidGrant
+	}
+	if !secretMatches(client, clientSecret) {
+		return "", nil, ErrInvalidClient
+	}
+	if ac.CodeChallenge != "" && !verifyPKCE(ac.CodeChallenge, ac.CodeChallengeMethod, codeVerifier) {
+		return "", nil, ErrInvalidGrant
+	}
+
+	consent, err := s.repo.GetConsent(ctx, ac.UserID, client.ID)
+	if err != nil || !consent.Covers(strings.Fields(ac.Scopes)) {
+		return "", nil, ErrInvalidGrant
+	}
+	return s.maker.CreateTokenForPayload(&token.Payload{
+		UserID:   ac.UserID,
+		ClientID: client.ID,
+		Scopes:   strings.Fields(ac.Scopes),
+	}, s.ttl)
+}
+
+// ClientCredentials implements the client_credentials grant. The token acts
+// on behalf of the client owner, limited to the requested (or all registered) scopes.
+func (s *OAuth) ClientCredentials(ctx context.Context, clientID, clientSecret string, scopes []string) (string, *token.Payload, error) {
+	client, err := s.GetClient(ctx, clientID)
+	if err != nil {
+		return "", nil, err
+	}
+	if !secretMatches(client, clientSecret) {
+		return "", nil, ErrInvalidClient
+	}
+	if len(scopes) == 0 {
+		scopes = strings.Fields(client.Scopes)
+	}
+	if !client.AllowsScopes(scopes) {
+		return "", nil, ErrInvalidScope
+	}
+	return s.maker.CreateTokenForPayload(&token.Payload{
+		UserID:   client.OwnerID,
+		ClientID: client.ID,
+		Scopes:   scopes,
+	}, s.ttl)
+}
+
+// ListConsents returns the active consents granted by userID.
+func (s *OAuth) ListConsents(ctx context.Context, userID uuid.UUID) ([]model.OAuthConsent, error) {
+	return s.repo.ListConsents(ctx, userID)
+}
+
+// RevokeConsent withdraws the consent granted to clientID. Tokens already
+// issued stay valid until they expire.
+func (s *OAuth) RevokeConsent(ctx context.Context, userID uuid.UUID, clientID string) error {
+	return s.repo.RevokeConsent(ctx, userID, clientID)
+}
+
+// --------- Helpers ---------
+
+func secretMatches(c *model.OAuthClient, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.SecretHash), []byte(hashToken(secret))) == 1
+}
+
+// verifyPKCE checks a code_verifier against the stored challenge (RFC 7636).
+func verifyPKCE(challenge, method, verifier string) bool {
+	if verifier == "" {
+		return false
+	}
+	computed := verifier
+	if method == "S256" {
+		sum := sha256.Sum256([]byte(verifier))
+		computed = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(computed)) == 1
+}
+
+// randomToken returns n random bytes encoded as unpadded base64url.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 of a high-entropy secret. Secrets are
+// never stored in plaintext.
+func hashToken(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
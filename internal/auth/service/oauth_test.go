@@ -0,0 +1,151 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- MOCKS ---
+
+type mockOAuthRepo struct{ mock.Mock }
+
+func (m *mockOAuthRepo) CreateClient(ctx context.Context, c *model.OAuthClient) error {
+	return m.Called(ctx, c).Error(0)
+}
+func (m *mockOAuthRepo) GetClient(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	args := m.Called(ctx, clientID)
+	return args.Get(0).(*model.OAuthClient), args.Error(1)
+}
+func (m *mockOAuthRepo) ListClientsByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.OAuthClient, error) {
+	args := m.Called(ctx, ownerID)
+	return args.Get(0).([]model.OAuthClient), args.Error(1)
+}
+func (m *mockOAuthRepo) CreateAuthorizationCode(ctx context.Context, code *model.OAuthAuthorizationCode) error {
+	return m.Called(ctx, code).Error(0)
+}
+func (m *mockOAuthRepo) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.OAuthAuthorizationCode, error) {
+	args := m.Called(ctx, codeHash)
+	return args.Get(0).(*model.OAuthAuthorizationCode), args.Error(1)
+}
+func (m *mockOAuthRepo) GetConsent(ctx context.Context, userID uuid.UUID, clientID string) (*model.OAuthConsent, error) {
+	args := m.Called(ctx, userID, clientID)
+	return args.Get(0).(*model.OAuthConsent), args.Error(1)
+}
+func (m *mockOAuthRepo) SaveConsent(ctx context.Context, consent *model.OAuthConsent) error {
+	return m.Called(ctx, consent).Error(0)
+}
+func (m *mockOAuthRepo) ListConsents(ctx context.Context, userID uuid.UUID) ([]model.OAuthConsent, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]model.OAuthConsent), args.Error(1)
+}
+func (m *mockOAuthRepo) RevokeConsent(ctx context.Context, userID uuid.UUID, clientID string) error {
+	return m.Called(ctx, userID, clientID).Error(0)
+}
+
+// registerClient registers a client through the service so the test knows its secret.
+func registerClient(t *testing.T, svc *service.OAuth, repo *mockOAuthRepo, ownerID uuid.UUID) (*model.OAuthClient, string) {
+	ctx := context.Background()
+	repo.On("CreateClient", ctx, mock.Anything).Return(nil).Once()
+	client, secret, err := svc.RegisterClient(ctx, ownerID, "Seller tool",
+		[]string{"https://tool.example.com/cb"}, []string{token.ScopeProductsRead, token.ScopeProductsWrite})
+	assert.NoError(t, err)
+	repo.On("GetClient", ctx, client.ID).Return(client, nil)
+	return client, secret
+}
+
+// --- TESTS ---
+
+func TestOAuth_RegisterClient_UnknownScope(t *testing.T) {
+	svc := service.NewOAuth(new(mockOAuthRepo), new(mockTokenMaker), time.Hour)
+
+	_, _, err := svc.RegisterClient(context.Background(), uuid.New(), "x", nil, []string{"admin:all"})
+	assert.ErrorIs(t, err, service.ErrInvalidScope)
+}
+
+func TestOAuth_ClientCredentials(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	svc := service.NewOAuth(repo, new(mockTokenMaker), time.Hour)
+	ownerID := uuid.New()
+	client, secret := registerClient(t, svc, repo, ownerID)
+
+	_, pl, err := svc.ClientCredentials(context.Background(), client.ID, secret, []string{token.ScopeProductsRead})
+	assert.NoError(t, err)
+	assert.Equal(t, ownerID, pl.UserID)
+	assert.Equal(t, client.ID, pl.ClientID)
+	assert.True(t, pl.HasScope(token.ScopeProductsRead))
+	assert.False(t, pl.HasScope(token.ScopeProductsWrite))
+
+	_, _, err = svc.ClientCredentials(context.Background(), client.ID, "wrong", nil)
+	assert.ErrorIs(t, err, service.ErrInvalidClient)
+}
+
+func TestOAuth_Authorize_ConsentRequired(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	svc := service.NewOAuth(repo, new(mockTokenMaker), time.Hour)
+	client, _ := registerClient(t, svc, repo, uuid.New())
+	userID := uuid.New()
+	repo.On("GetConsent", mock.Anything, userID, client.ID).
+		Return((*model.OAuthConsent)(nil), repository.ErrConsentNotFound)
+
+	_, err := svc.Authorize(context.Background(), userID, service.AuthorizeRequest{
+		ClientID:    client.ID,
+		RedirectURI: "https://tool.example.com/cb",
+		Scopes:      []string{token.ScopeProductsRead},
+	})
+	assert.ErrorIs(t, err, service.ErrConsentRequired)
+}
+
+func TestOAuth_Authorize_RejectsUnregisteredRedirect(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	svc := service.NewOAuth(repo, new(mockTokenMaker), time.Hour)
+	client, _ := registerClient(t, svc, repo, uuid.New())
+
+	_, err := svc.Authorize(context.Background(), uuid.New(), service.AuthorizeRequest{
+		ClientID:    client.ID,
+		RedirectURI: "https://evil.example.com/cb",
+		Scopes:      []string{token.ScopeProductsRead},
+		Approve:     true,
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidRedirectURI)
+}
+
+func TestOAuth_ExchangeCode_PKCEDoesNotReplaceSecret(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	svc := service.NewOAuth(repo, new(mockTokenMaker), time.Hour)
+	client, secret := registerClient(t, svc, repo, uuid.New())
+	userID := uuid.New()
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	code := &model.OAuthAuthorizationCode{
+		ClientID:            client.ID,
+		UserID:              userID,
+		RedirectURI:         "https://tool.example.com/cb",
+		Scopes:              token.ScopeProductsRead,
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeChallengeMethod: "S256",
+		ExpiresAt:           time.Now().Add(time.Minute),
+	}
+	repo.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(code, nil)
+	repo.On("GetConsent", mock.Anything, userID, client.ID).
+		Return(&model.OAuthConsent{Scopes: token.ScopeProductsRead}, nil)
+
+	_, _, err := svc.ExchangeCode(context.Background(), client.ID, "", "code", code.RedirectURI, verifier)
+	assert.ErrorIs(t, err, service.ErrInvalidClient)
+	_, _, err = svc.ExchangeCode(context.Background(), client.ID, secret, "code", code.RedirectURI, "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidGrant)
+
+	_, pl, err := svc.ExchangeCode(context.Background(), client.ID, secret, "code", code.RedirectURI, verifier)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, pl.UserID)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/imageproc"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/logger"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/upload"
+)
+
+// Photos stores profile photos and their thumbnails in a blob.Store.
+// UserDetails keeps the blob keys; clients only ever see signed URLs.
+type Photos struct {
+	store  blob.Store
+	urlTTL time.Duration
+	images imageproc.Options
+	guard  *upload.Guard
+}
+
+// NewPhotos returns a new Photos store. urlTTL is the lifetime of the
+// download URLs handed to clients; guard checks uploads before they are
+// decoded (nil → upload.DefaultImagePolicy without scanning).
+func NewPhotos(store blob.Store, urlTTL time.Duration, images imageproc.Options, guard *upload.Guard) *Photos {
+	if guard == nil {
+		guard = upload.NewGuard(upload.DefaultImagePolicy, nil, nil)
+	}
+	return &Photos{store: store, urlTTL: urlTTL, images: images, guard: guard}
+}
+
+// Save reads an upload through the guard, processes it and stores the
+// photo and its thumbnails. It returns the keys of the photo and of the
+// first configured thumbnail.
+func (p *Photos) Save(ctx context.Context, userID uuid.UUID, r io.Reader) (photoKey, thumbKey string, err error) {
+	data, _, err := p.guard.Read(ctx, r)
+	if err != nil {
+		return "", "", err
+	}
+	img, err := imageproc.Process(data, p.images)
+	if err != nil {
+		return "", "", err
+	}
+	photoKey = blob.ContentKey(photoPrefix(userID), img.Image.Data, img.Image.Ext)
+	if err := p.put(ctx, photoKey, img.Image); err != nil {
+		return "", "", err
+	}
+	for _, size := range p.images.ThumbnailSizes {
+		thumb, ok := img.Thumbnails[size]
+		if !ok {
+			continue
+		}
+		key := thumbnailKey(photoKey, size, thumb.Ext)
+		if err := p.put(ctx, key, thumb); err != nil {
+			return "", "", err
+		}
+		if thumbKey == "" {
+			thumbKey = key
+		}
+	}
+	return photoKey, thumbKey, nil
+}
+
+func (p *Photos) put(ctx context.Context, key string, img imageproc.Image) error {
+	return p.store.Put(ctx, key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType)
+}
+
+// URL resolves a stored key to a signed download URL. Paths of photos
+// written before the blob store (starting with "/") are returned unchanged.
+func (p *Photos) URL(key string) string {
+	if p == nil || key == "" || isLegacyPhoto(key) {
+		return key
+	}
+	u, err := p.store.URL(key, p.urlTTL)
+	if err != nil {
+		logger.Error("photo url %s: %v", key, err)
+		return ""
+	}
+	return u
+}
+
+// Keys lists the blob keys of the photo in d and of its thumbnails.
+func (p *Photos) Keys(d *model.UserDetails) []string {
+	if p == nil || d == nil || d.PhotoPath == "" || isLegacyPhoto(d.PhotoPath) {
+		return nil
+	}
+	keys := []string{d.PhotoPath}
+	ext := path.Ext(d.PhotoPath)
+	for _, size := range p.images.ThumbnailSizes {
+		keys = append(keys, thumbnailKey(d.PhotoPath, size, ext))
+	}
+	// the thumbnail may come from a size that is no longer configured
+	if d.ThumbnailPath != "" && !isLegacyPhoto(d.ThumbnailPath) && !contains(keys, d.ThumbnailPath) {
+		keys = append(keys, d.ThumbnailPath)
+	}
+	return keys
+}
+
+// Open returns the content of a stored photo.
+func (p *Photos) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return p.store.Open(ctx, key)
+}
+
+// Remove deletes the photo and thumbnails referenced by d, including files
+// written to disk before the blob store. Errors are logged.
+func (p *Photos) Remove(ctx context.Context, d *model.UserDetails) {
+	if d == nil {
+		return
+	}
+	if isLegacyPhoto(d.PhotoPath) {
+		removeLegacyPhotos(d.UserID)
+	}
+	for _, key := range p.Keys(d) {
+		if err := p.store.Delete(ctx, key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			logger.Error("remove photo %s: %v", key, err)
+		}
+	}
+}
+
+// photoPrefix is the key namespace of a user's photos. Keys are content
+// addressed within it, so removing one user's photos never touches an
+// object another user uploaded too.
+func photoPrefix(userID uuid.UUID) string {
+	return "photos/users/" + userID.String()
+}
+
+// thumbnailKey derives the key of a thumbnail from the key of its photo.
+func thumbnailKey(photoKey string, size int, ext string) string {
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(photoKey, path.Ext(photoKey)), size, ext)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Photos uploaded before the blob store were written to legacyPhotoDir as
+// <userID><ext> (thumbnails <userID>_<size><ext>) and are still served
+// under legacyPhotoURLPrefix until the user uploads a new one.
+const (
+	legacyPhotoDir       = "./static/photos/users/"
+	legacyPhotoURLPrefix = "/static/photos/users/"
+)
+
+func isLegacyPhoto(p string) bool {
+	return strings.HasPrefix(p, legacyPhotoURLPrefix)
+}
+
+// legacyPhotoFiles lists the user's photo files left in legacyPhotoDir.
+func legacyPhotoFiles(userID uuid.UUID) []string {
+	files, _ := filepath.Glob(filepath.Join(legacyPhotoDir, userID.String()+"*"))
+	return files
+}
+
+func removeLegacyPhotos(userID uuid.UUID) {
+	for _, path := range legacyPhotoFiles(userID) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Error("remove photo %s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/logger"
+)
+
+var ErrDeletionRequested = errors.New("account deletion already requested")
+
+// purgeBatch is the number of accounts erased per PurgeDue call.
+const purgeBatch = 100
+
+// ProductExporter returns the products a user sells as a JSON array. The
+// product-service owns them; internal/product/client implements it.
+type ProductExporter interface {
+	ExportProducts(ctx context.Context, sellerID uuid.UUID) (json.RawMessage, error)
+}
+
+type PrivacyService interface {
+	Export(ctx context.Context, userID uuid.UUID, w io.Writer) error
+	RequestDeletion(ctx context.Context, userID uuid.UUID, password string) (*model.DeletionRequest, error)
+}
+
+// Privacy implements the GDPR data export and right-to-erasure workflow.
+type Privacy struct {
+	users    repository.UserRepository
+	privacy  repository.PrivacyRepository
+	apiKeys  repository.APIKeyRepository
+	oauth    repository.OAuthRepository
+	products ProductExporter // may be nil
+	photos   *Photos         // may be nil
+	audit    *Audit          // may be nil
+	grace    time.Duration   // time between a deletion request and the erasure
+}
+
+// NewPrivacy returns a new Privacy service.
+func NewPrivacy(users repository.UserRepository, privacy repository.PrivacyRepository, apiKeys repository.APIKeyRepository,
+	oauth repository.OAuthRepository, products ProductExporter, photos *Photos, audit *Audit, grace time.Duration) *Privacy {
+	return &Privacy{users: users, privacy: privacy, apiKeys: apiKeys, oauth: oauth, products: products, photos: photos, audit: audit, grace: grace}
+}
+
+// Export writes a zip archive with everything stored about the user: one
+// JSON file per table, the products they sell and the uploaded photos.
+// Secrets (password hash, session tokens, API key hashes) are left out.
+func (p *Privacy) Export(ctx context.Context, userID uuid.UUID, w io.Writer) error {
+	user, details, err := p.users.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	sessions, err := p.privacy.ListSessionsByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	keys, err := p.apiKeys.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return err
+	}
+	consents, err := p.oauth.ListConsents(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	files := map[string]any{
+		"account.json": map[string]any{
+			"id": user.ID, "email": user.Email, "role": user.Role,
+			"createdAt": user.CreatedAt, "updatedAt": user.UpdatedAt,
+		},
+		"sessions.json":       exportSessions(sessions),
+		"api_keys.json":       exportAPIKeys(keys),
+		"oauth_consents.json": exportConsents(consents),
+	}
+	if details != nil {
+		files["profile.json"] = map[string]any{
+			"firstName": details.FirstName, "lastName": details.LastName,
+			"dateOfBirth": details.DateOfBirth, "phone": details.Phone,
+			"photo": details.PhotoPath, "thumbnail": details.ThumbnailPath,
+			"createdAt": details.CreatedAt, "updatedAt": details.UpdatedAt,
+		}
+		files["addresses.json"] = exportAddresses(details.Addresses)
+	}
+	if p.products != nil {
+		products, err := p.products.ExportProducts(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("export products: %w", err)
+		}
+		files["products.json"] = products
+	}
+	if p.audit != nil {
+		events, err := p.audit.ListAuditEvents(ctx, repository.AuditFilter{TargetID: &userID})
+		if err != nil {
+			return err
+		}
+		files["audit_events.json"] = exportAuditEvents(events)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(files[name]); err != nil {
+			return err
+		}
+	}
+	for _, key := range p.photos.Keys(details) {
+		rc, err := p.photos.Open(ctx, key)
+		if errors.Is(err, blob.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = addFile(zw, "photos/"+path.Base(key), rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range legacyPhotoFiles(userID) {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = addFile(zw, "photos/"+filepath.Base(name), f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	p.audit.Record(ctx, AuditDataExport, userID, nil, "")
+	return nil
+}
+
+// RequestDeletion deactivates the account after re-checking the password
+// and schedules its erasure once the grace period has passed.
+func (p *Privacy) RequestDeletion(ctx context.Context, userID uuid.UUID, password string) (*model.DeletionRequest, error) {
+	user, _, err := p.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !CheckPasswordHash(user.PasswordHash, password) {
+		p.audit.Record(ctx, AuditDeletionRequest, userID, ErrInvalidCredentials, "")
+		return nil, ErrInvalidCredentials
+	}
+	now := time.Now()
+	req := &model.DeletionRequest{UserID: userID, RequestedAt: now, PurgeAfter: now.Add(p.grace)}
+	if err := p.privacy.RequestDeletion(ctx, req); err != nil {
+		if errors.Is(err, repository.ErrDeletionRequested) {
+			return nil, ErrDeletionRequested
+		}
+		return nil, err
+	}
+	p.audit.Record(ctx, AuditDeletionRequest, userID, nil, "purge after "+req.PurgeAfter.UTC().Format(time.RFC3339))
+	return req, nil
+}
+
+// PurgeDue erases accounts whose grace period is over and removes their
+// photos. It returns the number of erased accounts; run it periodically.
+// An account that cannot be erased is logged and retried on the next run,
+// without holding up the others.
+func (p *Privacy) PurgeDue(ctx context.Context) (int, error) {
+	due, err := p.privacy.ListDueDeletions(ctx, time.Now(), purgeBatch)
+	if err != nil {
+		return 0, err
+	}
+	n, failed := 0, 0
+	for _, req := range due {
+		if err := p.erase(ctx, req.UserID); err != nil {
+			logger.Error("erase account %s: %v", req.UserID, err)
+			failed++
+			continue
+		}
+		n++
+	}
+	if failed > 0 {
+		return n, fmt.Errorf("%d of %d account(s) could not be erased", failed, len(due))
+	}
+	return n, nil
+}
+
+func (p *Privacy) erase(ctx context.Context, userID uuid.UUID) error {
+	details, err := p.privacy.EraseUser(ctx, userID)
+	p.audit.RecordSystem(ctx, AuditAccountErase, userID, err, "")
+	if err != nil {
+		return err
+	}
+	p.photos.Remove(ctx, details)
+	return nil
+}
+
+func addFile(zw *zip.Writer, name string, r io.Reader) error {
+	dst, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, r)
+	return err
+}
+
+func exportSessions(list []model.Session) []map[string]any {
+	out := make([]map[string]any, 0, len(list))
+	for _, s := range list {
+		out = append(out, map[string]any{"id": s.ID, "createdAt": s.CreatedAt, "expiresAt": s.ExpiresAt})
+	}
+	return out
+}
+
+func exportAddresses(list []model.Address) []map[string]any {
+	out := make([]map[string]any, 0, len(list))
+	for _, a := range list {
+		out = append(out, map[string]any{
+			"id": a.ID, "kind": a.Kind, "isDefault": a.IsDefault,
+			"lines": a.Lines, "city": a.City, "region": a.Region, "postalCode": a.PostalCode, "country": a.Country,
+			"createdAt": a.CreatedAt, "updatedAt": a.UpdatedAt,
+		})
+	}
+	return out
+}
+
+func exportAPIKeys(list []model.APIKey) []map[string]any {
+	out := make([]map[string]any, 0, len(list))
+	for _, k := range list {
+		out = append(out, map[string]any{
+			"id": k.ID, "name": k.Name, "prefix": k.Prefix, "scopes": k.Scopes,
+			"createdAt": k.CreatedAt, "expiresAt": k.ExpiresAt, "lastUsedAt": k.LastUsedAt, "revokedAt": k.RevokedAt,
+		})
+	}
+	return out
+}
+
+func exportConsents(list []model.OAuthConsent) []map[string]any {
+	out := make([]map[string]any, 0, len(list))
+	for _, c := range list {
+		out = append(out, map[string]any{
+			"clientId": c.ClientID, "scopes": c.Scopes, "createdAt": c.CreatedAt, "updatedAt": c.UpdatedAt,
+		})
+	}
+	return out
+}
+
+func exportAuditEvents(list []model.AuditEvent) []map[string]any {
+	out := make([]map[string]any, 0, len(list))
+	for _, e := range list {
+		out = append(out, map[string]any{
+			"type": e.Type, "outcome": e.Outcome, "ip": e.IP, "userAgent": e.UserAgent,
+			"detail": e.Detail, "createdAt": e.CreatedAt,
+		})
+	}
+	return out
+}
@@ -0,0 +1,119 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- MOCKS ---
+
+type mockPrivacyRepo struct{ mock.Mock }
+
+func (m *mockPrivacyRepo) ListSessionsByUser(ctx context.Context, userID uuid.UUID) ([]model.Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]model.Session), args.Error(1)
+}
+func (m *mockPrivacyRepo) RequestDeletion(ctx context.Context, req *model.DeletionRequest) error {
+	return m.Called(ctx, req).Error(0)
+}
+func (m *mockPrivacyRepo) ListDueDeletions(ctx context.Context, now time.Time, limit int) ([]model.DeletionRequest, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]model.DeletionRequest), args.Error(1)
+}
+func (m *mockPrivacyRepo) EraseUser(ctx context.Context, userID uuid.UUID) (*model.UserDetails, error) {
+	args := m.Called(ctx, userID)
+	d, _ := args.Get(0).(*model.UserDetails)
+	return d, args.Error(1)
+}
+
+type stubProducts map[uuid.UUID]string
+
+func (s stubProducts) ExportProducts(ctx context.Context, sellerID uuid.UUID) (json.RawMessage, error) {
+	return json.RawMessage(s[sellerID]), nil
+}
+
+// --- TESTS ---
+
+func TestPrivacy_Export(t *testing.T) {
+	userRepo, privacyRepo, keyRepo, oauthRepo := new(mockUserRepo), new(mockPrivacyRepo), new(mockAPIKeyRepo), new(mockOAuthRepo)
+	user := &model.User{ID: uuid.New(), Email: "me@abc.com", PasswordHash: "secret-hash"}
+	products := stubProducts{user.ID: `[{"name":"Vintage lamp"}]`}
+	svc := service.NewPrivacy(userRepo, privacyRepo, keyRepo, oauthRepo, products, nil, nil, time.Hour)
+	ctx := context.Background()
+
+	userRepo.On("GetByID", ctx, user.ID).Return(user, &model.UserDetails{FirstName: "Ana"}, nil)
+	privacyRepo.On("ListSessionsByUser", ctx, user.ID).Return([]model.Session{{ID: uuid.New(), Token: "session-secret"}}, nil)
+	keyRepo.On("ListAPIKeys", ctx, user.ID).Return([]model.APIKey{}, nil)
+	oauthRepo.On("ListConsents", ctx, user.ID).Return([]model.OAuthConsent{}, nil)
+
+	var buf bytes.Buffer
+	assert.NoError(t, svc.Export(ctx, user.ID, &buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	var all []byte
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		all = append(all, b...)
+	}
+	assert.True(t, names["account.json"])
+	assert.True(t, names["profile.json"])
+	assert.True(t, names["sessions.json"])
+	assert.True(t, names["products.json"])
+	assert.Contains(t, string(all), "Vintage lamp")
+	assert.Contains(t, string(all), "me@abc.com")
+	assert.NotContains(t, string(all), "secret-hash")
+	assert.NotContains(t, string(all), "session-secret")
+}
+
+func TestPrivacy_RequestDeletion(t *testing.T) {
+	userRepo, privacyRepo := new(mockUserRepo), new(mockPrivacyRepo)
+	svc := service.NewPrivacy(userRepo, privacyRepo, new(mockAPIKeyRepo), new(mockOAuthRepo), nil, nil, nil, 24*time.Hour)
+	ctx := context.Background()
+
+	hash, _ := service.HashPassword("secret")
+	user := &model.User{ID: uuid.New(), PasswordHash: hash}
+	userRepo.On("GetByID", ctx, user.ID).Return(user, &model.UserDetails{}, nil)
+
+	_, err := svc.RequestDeletion(ctx, user.ID, "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	privacyRepo.On("RequestDeletion", ctx, mock.Anything).Return(nil)
+	req, err := svc.RequestDeletion(ctx, user.ID, "secret")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), req.PurgeAfter, time.Minute)
+	privacyRepo.AssertNumberOfCalls(t, "RequestDeletion", 1)
+}
+
+func TestPrivacy_PurgeDue_ContinuesAfterFailure(t *testing.T) {
+	privacyRepo := new(mockPrivacyRepo)
+	svc := service.NewPrivacy(new(mockUserRepo), privacyRepo, new(mockAPIKeyRepo), new(mockOAuthRepo), nil, nil, nil, time.Hour)
+	ctx := context.Background()
+
+	broken, ok := uuid.New(), uuid.New()
+	privacyRepo.On("ListDueDeletions", ctx, mock.Anything, mock.Anything).
+		Return([]model.DeletionRequest{{UserID: broken}, {UserID: ok}}, nil)
+	privacyRepo.On("EraseUser", ctx, broken).Return(nil, errors.New("db down"))
+	privacyRepo.On("EraseUser", ctx, ok).Return(&model.UserDetails{}, nil)
+
+	n, err := svc.PurgeDue(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+	privacyRepo.AssertCalled(t, "EraseUser", ctx, ok)
+}
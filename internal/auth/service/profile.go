@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+)
+
+var ErrInvalidProfile = errors.New("invalid profile")
+
+// Profile fields, named as in the JSON API.
+const (
+	ProfileFirstName   = "firstName"
+	ProfileLastName    = "lastName"
+	ProfileDateOfBirth = "dateOfBirth"
+	ProfilePhone       = "phone"
+)
+
+// FieldErrors maps each invalid field to the reason. Validation failures
+// wrap both the feature's sentinel error and a FieldErrors.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for f := range e {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	msgs := make([]string, len(fields))
+	for i, f := range fields {
+		msgs[i] = f + ": " + e[f]
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Fields returns the errors keyed by field name.
+func (e FieldErrors) Fields() map[string]string { return e }
+
+// ProfilePatch lists the profile fields to change. A nil field is left
+// unchanged and an empty string clears the field.
+type ProfilePatch struct {
+	FirstName   *string
+	LastName    *string
+	DateOfBirth *string // YYYY-MM-DD
+	Phone       *string
+}
+
+var (
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "")
+	phonePattern    = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+	minDateOfBirth  = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// columns validates the patch and returns the user_details columns to
+// write. Cleared columns are set to their empty value, the birth date to NULL.
+func (p ProfilePatch) columns() (map[string]any, error) {
+	cols := map[string]any{}
+	errs := FieldErrors{}
+	name := func(field, column string, v *string) {
+		if v == nil {
+			return
+		}
+		s := strings.TrimSpace(*v)
+		switch {
+		case utf8.RuneCountInString(s) > 100:
+			errs[field] = "limited to 100 characters"
+		case strings.IndexFunc(s, unicode.IsControl) >= 0:
+			errs[field] = "must not contain control characters"
+		default:
+			cols[column] = s
+		}
+	}
+	name(ProfileFirstName, "first_name", p.FirstName)
+	name(ProfileLastName, "last_name", p.LastName)
+
+	if p.DateOfBirth != nil {
+		s := strings.TrimSpace(*p.DateOfBirth)
+		dob, err := time.Parse("2006-01-02", s)
+		switch {
+		case s == "":
+			cols["date_of_birth"] = nil
+		case err != nil:
+			errs[ProfileDateOfBirth] = "must be a date in YYYY-MM-DD format"
+		case dob.After(time.Now()):
+			errs[ProfileDateOfBirth] = "must not be in the future"
+		case dob.Before(minDateOfBirth):
+			errs[ProfileDateOfBirth] = "must not be before 1900-01-01"
+		default:
+			cols["date_of_birth"] = dob
+		}
+	}
+	if p.Phone != nil {
+		s := phoneSeparators.Replace(strings.TrimSpace(*p.Phone))
+		if s != "" && !phonePattern.MatchString(s) {
+			errs[ProfilePhone] = "must have 6 to 15 digits, optionally starting with +"
+		} else {
+			cols["phone"] = s
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProfile, errs)
+	}
+	return cols, nil
+}
+
+// UpdateProfile applies p to the user's profile and returns the updated
+// details. Either every field in p is valid and written, or nothing is.
+func (s *Service) UpdateProfile(ctx context.Context, userID uuid.UUID, p ProfilePatch) (*model.UserDetails, error) {
+	cols, err := p.columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) > 0 {
+		err = s.users.UpdateDetails(ctx, userID, cols)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			err = ErrUserNotFound
+		}
+		changed := make([]string, 0, len(cols))
+		for c := range cols {
+			changed = append(changed, c)
+		}
+		sort.Strings(changed)
+		s.audit.Record(ctx, AuditProfileUpdate, userID, err, strings.Join(changed, ","))
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, details, err := s.GetUserWithDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return nil, ErrUserNotFound
+	}
+	return details, nil
+}
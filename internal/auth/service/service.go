@@ -3,8 +3,8 @@ package service
 import (
 	"context"
 	"errors"
-	"os"
-	"path/filepath"
+	"fmt"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,7 +12,9 @@ import (
 
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/imageproc"
 	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/upload"
 )
 
 // Sentinel errors
@@ -20,6 +22,11 @@ var (
 	ErrInvalidCredentials = errors.New("invalid email or password")
 	ErrSessionNotFound    = errors.New("session not found or expired")
 	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrInvalidImage       = errors.New("invalid image")
+	ErrPhotoTooLarge      = errors.New("photo is too large")
+	ErrUploadRejected     = errors.New("upload rejected by malware scan")
+	ErrScanUnavailable    = errors.New("malware scanner unavailable")
 )
 
 type AuthService interface {
@@ -28,9 +35,15 @@ type AuthService interface {
 	Refresh(ctx context.Context, sessionToken string) (string, *token.Payload, error)
 	Logout(ctx context.Context, sessionToken string) error
 	GetUserWithDetails(ctx context.Context, id uuid.UUID) (*model.User, *model.UserDetails, error)
-	UpdateUserDetails(ctx context.Context, userID uuid.UUID, details *model.UserDetails) error
+	UpdateProfile(ctx context.Context, userID uuid.UUID, p ProfilePatch) (*model.UserDetails, error)
 	ChangePassword(ctx context.Context, userID uuid.UUID, old, new string) error
-	UploadPhoto(ctx context.Context, userID uuid.UUID, data []byte, ext string) (string, string, error)
+	UploadPhoto(ctx context.Context, userID uuid.UUID, r io.Reader) (string, string, error)
+}
+
+// UserAdminService holds account operations reserved for administrators.
+type UserAdminService interface {
+	SetRole(ctx context.Context, userID uuid.UUID, role string) error
+	DeleteUser(ctx context.Context, userID uuid.UUID) error
 }
 
 // Service implements authentication and user management logic.
@@ -40,15 +53,38 @@ type Service struct {
 	maker    token.Maker
 	atTTL    time.Duration // access-token TTL
 	rtTTL    time.Duration // refresh-token TTL
+	audit    *Audit        // nil disables the audit log
+	photos   *Photos       // nil disables photo uploads
+}
+
+// Option customises a Service.
+type Option func(*Service)
+
+// WithAudit records authentication and account events in the audit log.
+func WithAudit(a *Audit) Option {
+	return func(s *Service) { s.audit = a }
+}
+
+// WithPhotos stores uploaded profile photos in p.
+func WithPhotos(p *Photos) Option {
+	return func(s *Service) { s.photos = p }
 }
 
 // New returns a new Service.
-func New(users repository.UserRepository, sessions repository.SessionRepository, maker token.Maker, atTTL, rtTTL time.Duration) *Service {
-	return &Service{users: users, sessions: sessions, maker: maker, atTTL: atTTL, rtTTL: rtTTL}
+func New(users repository.UserRepository, sessions repository.SessionRepository, maker token.Maker, atTTL, rtTTL time.Duration, opts ...Option) *Service {
+	s := &Service{users: users, sessions: sessions, maker: maker, atTTL: atTTL, rtTTL: rtTTL}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// Register creates a new user with details and hashes the password.
+// Register creates a new user with details and hashes the password. The
+// addresses in details are validated and saved with the user.
 func (s *Service) Register(ctx context.Context, user *model.User, details *model.UserDetails, password string) error {
+	if err := prepareAddresses(details.Addresses); err != nil {
+		return err
+	}
 	hashPassword, err := HashPassword(password)
 	if err != nil {
 		return err
@@ -56,6 +92,8 @@ func (s *Service) Register(ctx context.Context, user *model.User, details *model
 	user.PasswordHash = hashPassword
 	user.ID = uuid.New()
 	details.UserID = user.ID
+	// photos can only be set through UploadPhoto
+	details.PhotoPath, details.ThumbnailPath = "", ""
 	return s.users.Create(ctx, user, details)
 }
 
@@ -63,12 +101,14 @@ func (s *Service) Register(ctx context.Context, user *model.User, details *model
 func (s *Service) Login(ctx context.Context, email, password string) (accessToken, refreshToken, sessionToken string, pl *token.Payload, err error) {
 	user, _, err := s.users.GetByEmail(ctx, email)
 	if err != nil {
+		s.audit.Record(ctx, AuditLogin, uuid.Nil, ErrUserNotFound, "unknown email "+model.AuditEmail(email))
 		return "", "", "", nil, ErrUserNotFound
 	}
 	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		s.audit.Record(ctx, AuditLogin, user.ID, ErrInvalidCredentials, "")
 		return "", "", "", nil, ErrInvalidCredentials
 	}
-	accessToken, pl, err = s.maker.CreateToken(user.ID, s.atTTL)
+	accessToken, pl, err = s.maker.CreateTokenForPayload(&token.Payload{UserID: user.ID, Role: user.Role}, s.atTTL)
 	if err != nil {
 		return "", "", "", nil, err
 	}
@@ -85,31 +125,54 @@ func (s *Service) Login(ctx context.Context, email, password string) (accessToke
 	}
 	refreshToken = session.Token
 	sessionToken = session.Token
+	s.audit.Record(ctx, AuditLogin, user.ID, nil, "")
 	return accessToken, refreshToken, sessionToken, pl, nil
 }
 
 // Refresh checks session and issues new access token
 func (s *Service) Refresh(ctx context.Context, sessionToken string) (string, *token.Payload, error) {
 	session, err := s.sessions.GetSessionByToken(ctx, sessionToken)
-	if err != nil || session.ExpiresAt.Before(time.Now()) {
+	if err != nil {
+		s.audit.Record(ctx, AuditRefresh, uuid.Nil, ErrSessionNotFound, "unknown session")
 		return "", nil, ErrSessionNotFound
 	}
-	return s.maker.CreateToken(session.UserID, s.atTTL)
+	if session.ExpiresAt.Before(time.Now()) {
+		s.audit.Record(ctx, AuditRefresh, session.UserID, ErrSessionNotFound, "expired session")
+		return "", nil, ErrSessionNotFound
+	}
+	// Re-read the user so role changes take effect on the next refresh.
+	user, _, err := s.users.GetByID(ctx, session.UserID)
+	if err != nil {
+		s.audit.Record(ctx, AuditRefresh, session.UserID, ErrSessionNotFound, "")
+		return "", nil, ErrSessionNotFound
+	}
+	at, pl, err := s.maker.CreateTokenForPayload(&token.Payload{UserID: user.ID, Role: user.Role}, s.atTTL)
+	s.audit.Record(ctx, AuditRefresh, user.ID, err, "")
+	return at, pl, err
 }
 
 // Logout deletes session
 func (s *Service) Logout(ctx context.Context, sessionToken string) error {
-	return s.sessions.DeleteSession(ctx, sessionToken)
+	var userID uuid.UUID
+	if s.audit != nil {
+		if session, err := s.sessions.GetSessionByToken(ctx, sessionToken); err == nil {
+			userID = session.UserID
+		}
+	}
+	err := s.sessions.DeleteSession(ctx, sessionToken)
+	s.audit.Record(ctx, AuditLogout, userID, err, "")
+	return err
 }
 
-// GetUserWithDetails retrieves full user profile by id
+// GetUserWithDetails retrieves full user profile by id. Photo keys are
+// resolved to signed download URLs.
 func (s *Service) GetUserWithDetails(ctx context.Context, id uuid.UUID) (*model.User, *model.UserDetails, error) {
-	return s.users.GetByID(ctx, id)
-}
-
-// UpdateUserDetails updates the user's detailed data.
-func (s *Service) UpdateUserDetails(ctx context.Context, userID uuid.UUID, details *model.UserDetails) error {
-	return s.users.Update(ctx, &model.User{ID: userID}, details)
+	user, details, err := s.users.GetByID(ctx, id)
+	if err == nil && details != nil {
+		details.PhotoPath = s.photos.URL(details.PhotoPath)
+		details.ThumbnailPath = s.photos.URL(details.ThumbnailPath)
+	}
+	return user, details, err
 }
 
 // ChangePassword changes the user's password after verifying the old one.
@@ -119,6 +182,7 @@ func (s *Service) ChangePassword(ctx context.Context, userID uuid.UUID, oldPassw
 		return ErrUserNotFound
 	}
 	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)) != nil {
+		s.audit.Record(ctx, AuditPasswordChange, userID, ErrInvalidCredentials, "")
 		return ErrInvalidCredentials
 	}
 
@@ -127,30 +191,92 @@ func (s *Service) ChangePassword(ctx context.Context, userID uuid.UUID, oldPassw
 		return err
 	}
 	user.PasswordHash = password
-	return s.users.Update(ctx, user, nil)
+	err = s.users.Update(ctx, user, nil)
+	s.audit.Record(ctx, AuditPasswordChange, userID, err, "")
+	return err
 }
 
-// UploadPhoto save the file and update the path in UserDetails.
-func (s *Service) UploadPhoto(ctx context.Context, userID uuid.UUID, data []byte, ext string) (string, string, error) {
-	photoDir := "./static/photos/users/"
-	if err := os.MkdirAll(photoDir, 0755); err != nil {
+// UploadPhoto reads the image from r within the upload limits, scans and
+// re-encodes it (the format is detected from its content), stores it with
+// its thumbnails and updates UserDetails. It returns download URLs of the
+// photo and of the first thumbnail size.
+func (s *Service) UploadPhoto(ctx context.Context, userID uuid.UUID, r io.Reader) (string, string, error) {
+	if s.photos == nil {
+		return "", "", errors.New("photo storage is not configured")
+	}
+	_, details, err := s.users.GetByID(ctx, userID)
+	if err != nil || details == nil {
+		return "", "", ErrUserNotFound
+	}
+	photoKey, thumbKey, err := s.photos.Save(ctx, userID, r)
+	if err != nil {
+		err = uploadError(err)
+		s.audit.Record(ctx, AuditPhotoUpload, userID, err, "")
 		return "", "", err
 	}
-	filename := userID.String() + ext
-	savePath := filepath.Join(photoDir, filename)
-	if err := os.WriteFile(savePath, data, 0644); err != nil {
+	old := *details
+	details.PhotoPath = photoKey
+	details.ThumbnailPath = thumbKey
+	if err := s.users.Update(ctx, &model.User{ID: userID}, details); err != nil {
+		s.audit.Record(ctx, AuditPhotoUpload, userID, err, photoKey)
 		return "", "", err
 	}
-	// TODO: thumbnail generation (for demo, leave empty)
-	photoPath := "/static/photos/users/" + filename
-	thumbPath := ""
-	_, details, err := s.GetUserWithDetails(ctx, userID)
-	if err == nil && details != nil {
-		details.PhotoPath = photoPath
-		details.ThumbnailPath = thumbPath
-		_ = s.UpdateUserDetails(ctx, userID, details)
+	// Re-uploading the same image yields the same keys.
+	if old.PhotoPath != photoKey {
+		s.photos.Remove(ctx, &old)
+	}
+	s.audit.Record(ctx, AuditPhotoUpload, userID, nil, photoKey)
+	return s.photos.URL(photoKey), s.photos.URL(thumbKey), nil
+}
+
+// uploadError maps upload and image processing failures to service errors.
+func uploadError(err error) error {
+	var target error
+	switch {
+	case errors.Is(err, upload.ErrTooLarge):
+		target = ErrPhotoTooLarge
+	case errors.Is(err, upload.ErrTypeNotAllowed), errors.Is(err, imageproc.ErrUnsupportedFormat), errors.Is(err, imageproc.ErrImageTooLarge):
+		target = ErrInvalidImage
+	case errors.Is(err, upload.ErrInfected):
+		target = ErrUploadRejected
+	case errors.Is(err, upload.ErrScanFailed):
+		target = ErrScanUnavailable
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %v", target, err)
+}
+
+// SetRole changes a user's role and ends their sessions so the new role
+// applies from the next login.
+func (s *Service) SetRole(ctx context.Context, userID uuid.UUID, role string) error {
+	if role != token.RoleUser && role != token.RoleAdmin {
+		return ErrInvalidRole
+	}
+	user, _, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	detail := user.Role + " -> " + role
+	user.Role = role
+	if err = s.users.Update(ctx, user, nil); err == nil {
+		err = s.sessions.DeleteByUserID(ctx, userID)
+	}
+	s.audit.Record(ctx, AuditRoleChange, userID, err, detail)
+	return err
+}
+
+// DeleteUser deletes the account and all of its sessions.
+func (s *Service) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	if _, _, err := s.users.GetByID(ctx, userID); err != nil {
+		return ErrUserNotFound
+	}
+	err := s.sessions.DeleteByUserID(ctx, userID)
+	if err == nil {
+		err = s.users.Delete(ctx, userID)
 	}
-	return photoPath, thumbPath, nil
+	s.audit.Record(ctx, AuditAccountDelete, userID, err, "")
+	return err
 }
 
 // HashPassword hashes a password using bcrypt.
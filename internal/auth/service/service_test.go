@@ -1,13 +1,19 @@
 package service_test
 
 import (
+	"bytes"
 	"context"
-	"os"
+	"image"
+	"image/jpeg"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/model"
 	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/imageproc"
 	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -32,6 +38,9 @@ func (m *mockUserRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.User,
 func (m *mockUserRepo) Update(ctx context.Context, user *model.User, details *model.UserDetails) error {
 	return m.Called(ctx, user, details).Error(0)
 }
+func (m *mockUserRepo) UpdateDetails(ctx context.Context, id uuid.UUID, fields map[string]any) error {
+	return m.Called(ctx, id, fields).Error(0)
+}
 func (m *mockUserRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	return m.Called(ctx, id).Error(0)
 }
@@ -60,6 +69,10 @@ type mockTokenMaker struct{ token.Maker }
 func (m *mockTokenMaker) CreateToken(userID uuid.UUID, ttl time.Duration) (string, *token.Payload, error) {
 	return "at", &token.Payload{UserID: userID, ExpiredAt: time.Now().Add(ttl)}, nil
 }
+func (m *mockTokenMaker) CreateTokenForPayload(p *token.Payload, ttl time.Duration) (string, *token.Payload, error) {
+	p.ExpiredAt = time.Now().Add(ttl)
+	return "at", p, nil
+}
 
 // --- TESTS ---
 
@@ -123,6 +136,7 @@ func TestService_Refresh_Success(t *testing.T) {
 		ExpiresAt: time.Now().Add(time.Hour),
 	}
 	sessionRepo.On("GetSessionByToken", ctx, "token123").Return(session, nil)
+	userRepo.On("GetByID", ctx, userID).Return(&model.User{ID: userID, Role: "user"}, &model.UserDetails{}, nil)
 
 	at, payload, err := svc.Refresh(ctx, "token123")
 	assert.NoError(t, err)
@@ -142,18 +156,51 @@ func TestService_Logout_Success(t *testing.T) {
 	assert.NoError(t, svc.Logout(ctx, "tok"))
 }
 
-func TestService_UpdateUserDetails(t *testing.T) {
+func TestService_UpdateProfile(t *testing.T) {
 	userRepo := new(mockUserRepo)
 	sessionRepo := new(mockSessionRepo)
 	tokenMaker := new(mockTokenMaker)
 	svc := service.New(userRepo, sessionRepo, tokenMaker, time.Minute, time.Hour)
 
 	ctx := context.Background()
-	details := &model.UserDetails{UserID: uuid.New(), FirstName: "Nume"}
-	user := &model.User{ID: details.UserID}
-	userRepo.On("Update", ctx, user, details).Return(nil)
+	userID := uuid.New()
+	name, phone, dob := " Nume ", "+373 (69) 123-456", ""
+	// only the fields in the patch are written; an empty birth date is NULL
+	userRepo.On("UpdateDetails", ctx, userID, map[string]any{
+		"first_name": "Nume", "phone": "+37369123456", "date_of_birth": nil,
+	}).Return(nil)
+	userRepo.On("GetByID", ctx, userID).Return(&model.User{ID: userID}, &model.UserDetails{UserID: userID, FirstName: "Nume"}, nil)
+
+	d, err := svc.UpdateProfile(ctx, userID, service.ProfilePatch{FirstName: &name, Phone: &phone, DateOfBirth: &dob})
+	assert.NoError(t, err)
+	assert.Equal(t, "Nume", d.FirstName)
+	userRepo.AssertExpectations(t)
+}
+
+func TestService_UpdateProfile_Invalid(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	svc := service.New(userRepo, new(mockSessionRepo), new(mockTokenMaker), time.Minute, time.Hour)
+
+	dob, phone, future := "1990-13-01", "call me", time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+	_, err := svc.UpdateProfile(context.Background(), uuid.New(), service.ProfilePatch{DateOfBirth: &dob, Phone: &phone})
+	assert.ErrorIs(t, err, service.ErrInvalidProfile)
+	var fields service.FieldErrors
+	if assert.ErrorAs(t, err, &fields) {
+		assert.Equal(t, []string{"dateOfBirth", "phone"}, sortedKeys(fields))
+	}
 
-	assert.NoError(t, svc.UpdateUserDetails(ctx, details.UserID, details))
+	_, err = svc.UpdateProfile(context.Background(), uuid.New(), service.ProfilePatch{DateOfBirth: &future})
+	assert.ErrorIs(t, err, service.ErrInvalidProfile)
+	userRepo.AssertNotCalled(t, "UpdateDetails", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func TestService_ChangePassword_Success(t *testing.T) {
@@ -176,21 +223,34 @@ func TestService_UploadPhoto(t *testing.T) {
 	userRepo := new(mockUserRepo)
 	sessionRepo := new(mockSessionRepo)
 	tokenMaker := new(mockTokenMaker)
-	svc := service.New(userRepo, sessionRepo, tokenMaker, time.Minute, time.Hour)
+	store, err := blob.NewLocal(t.TempDir(), "/media", []byte("secret"))
+	assert.NoError(t, err)
+	photos := service.NewPhotos(store, time.Hour, imageproc.DefaultOptions, nil)
+	svc := service.New(userRepo, sessionRepo, tokenMaker, time.Minute, time.Hour, service.WithPhotos(photos))
 
 	ctx := context.Background()
 	userID := uuid.New()
-	userRepo.On("GetByID", ctx, userID).Return(&model.User{ID: userID}, &model.UserDetails{}, nil)
+	details := &model.UserDetails{UserID: userID}
+	userRepo.On("GetByID", ctx, userID).Return(&model.User{ID: userID}, details, nil)
 	userRepo.On("Update", ctx, mock.Anything, mock.Anything).Return(nil)
 
-	photo := []byte{1, 2, 3}
-	photoPath, thumbPath, err := svc.UploadPhoto(ctx, userID, photo, ".jpg")
+	_, _, err = svc.UploadPhoto(ctx, userID, bytes.NewReader([]byte{1, 2, 3}))
+	assert.ErrorIs(t, err, service.ErrInvalidImage)
+
+	var photo bytes.Buffer
+	_ = jpeg.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 400, 300)), nil)
+	photoURL, thumbURL, err := svc.UploadPhoto(ctx, userID, bytes.NewReader(photo.Bytes()))
 	assert.NoError(t, err)
-	assert.Contains(t, photoPath, ".jpg")
-	assert.Equal(t, "", thumbPath)
+	assert.True(t, strings.HasPrefix(photoURL, "/media/photos/users/"+userID.String()+"/"))
+	assert.Contains(t, thumbURL, "_256.jpg?")
+	assert.Contains(t, thumbURL, "signature=")
 
-	// Cleanup: delete the static folder created by the test
-	t.Cleanup(func() {
-		_ = os.RemoveAll("./static")
-	})
+	// the keys are stored, not the URLs
+	assert.True(t, strings.HasPrefix(details.PhotoPath, "photos/users/"))
+	rc, err := store.Open(ctx, details.ThumbnailPath)
+	assert.NoError(t, err)
+	thumb, _, err := image.Decode(rc)
+	rc.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, 256, thumb.Bounds().Dx())
 }
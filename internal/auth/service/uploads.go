@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/upload"
+)
+
+var (
+	ErrUploadNotFound   = upload.ErrNotFound
+	ErrUploadIDTaken    = upload.ErrIDTaken
+	ErrInvalidUpload    = upload.ErrInvalid
+	ErrUploadMismatch   = upload.ErrMismatch
+	ErrUploadOffset     = upload.ErrOffset
+	ErrUploadIncomplete = upload.ErrIncomplete
+	ErrChecksumMismatch = upload.ErrChecksumMismatch
+)
+
+// UploadKindPhoto marks the upload sessions of profile photos.
+const UploadKindPhoto = "profile_photo"
+
+type PhotoUploadService interface {
+	OpenPhotoUpload(ctx context.Context, userID, uploadID uuid.UUID, size int64, checksum string) (*PhotoUpload, error)
+	PhotoUploadStatus(ctx context.Context, userID, uploadID uuid.UUID) (*upload.Session, error)
+}
+
+// PhotoUploads implements resumable photo uploads on top of
+// upload.Resumable.
+type PhotoUploads struct {
+	uploads  *upload.Resumable
+	photos   AuthService // stores the completed upload
+	maxBytes int64
+}
+
+// NewPhotoUploads returns a new PhotoUploads service; ttl is how long an
+// unfinished upload can be resumed.
+func NewPhotoUploads(sessions upload.Sessions, store blob.Store, photos AuthService, maxBytes int64, ttl time.Duration) *PhotoUploads {
+	return &PhotoUploads{
+		uploads:  upload.NewResumable(sessions, store, UploadKindPhoto, maxBytes, ttl),
+		photos:   photos,
+		maxBytes: maxBytes,
+	}
+}
+
+// OpenPhotoUpload starts the upload uploadID or resumes it when it already
+// exists; size and checksum (hex SHA-256) must then match the first call.
+// An ID already used by another upload fails with ErrUploadIDTaken.
+func (u *PhotoUploads) OpenPhotoUpload(ctx context.Context, userID, uploadID uuid.UUID, size int64, checksum string) (*PhotoUpload, error) {
+	if size > u.maxBytes {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrPhotoTooLarge, u.maxBytes)
+	}
+	up, err := u.uploads.Open(ctx, userID, uploadID, "", size, checksum)
+	if err != nil {
+		return nil, err
+	}
+	return &PhotoUpload{Upload: up, photos: u.photos}, nil
+}
+
+// PhotoUploadStatus returns the upload so a client can resume it from
+// Received.
+func (u *PhotoUploads) PhotoUploadStatus(ctx context.Context, userID, uploadID uuid.UUID) (*upload.Session, error) {
+	return u.uploads.Status(ctx, userID, uploadID)
+}
+
+// PurgeExpired removes unfinished uploads whose resume window has passed.
+// It returns the number of removed uploads; run it periodically.
+func (u *PhotoUploads) PurgeExpired(ctx context.Context) (int, error) {
+	return u.uploads.PurgeExpired(ctx)
+}
+
+// PhotoUpload receives the chunks of one photo upload stream.
+type PhotoUpload struct {
+	*upload.Upload
+	photos AuthService
+}
+
+// Complete verifies the checksum of the assembled upload and stores it as
+// the user's profile photo, returning the photo and thumbnail URLs. The
+// upload is discarded unless the failure is temporary (storage or scanner
+// unavailable), in which case Complete can be retried by resuming.
+func (p *PhotoUpload) Complete(ctx context.Context) (string, string, error) {
+	var photoURL, thumbURL string
+	err := p.Upload.Complete(ctx, func(r io.Reader) (err error) {
+		photoURL, thumbURL, err = p.photos.UploadPhoto(ctx, p.Session().UserID, r)
+		return err
+	}, permanentUploadError)
+	return photoURL, thumbURL, err
+}
+
+func permanentUploadError(err error) bool {
+	for _, target := range []error{ErrChecksumMismatch, ErrInvalidImage, ErrPhotoTooLarge, ErrUploadRejected} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
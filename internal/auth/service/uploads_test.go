@@ -0,0 +1,192 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/auth/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/upload"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- FAKES ---
+
+// memUploadRepo keeps upload sessions in memory.
+type memUploadRepo struct {
+	sessions map[uuid.UUID]*upload.Session
+	parts    map[uuid.UUID][]upload.Part
+}
+
+func newMemUploadRepo() *memUploadRepo {
+	return &memUploadRepo{sessions: map[uuid.UUID]*upload.Session{}, parts: map[uuid.UUID][]upload.Part{}}
+}
+
+func (m *memUploadRepo) CreateSession(_ context.Context, s *upload.Session) error {
+	if _, ok := m.sessions[s.ID]; ok {
+		return upload.ErrIDTaken
+	}
+	c := *s
+	m.sessions[s.ID] = &c
+	return nil
+}
+func (m *memUploadRepo) GetSession(_ context.Context, id uuid.UUID) (*upload.Session, error) {
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, upload.ErrNotFound
+	}
+	c := *s
+	return &c, nil
+}
+func (m *memUploadRepo) AddPart(_ context.Context, p *upload.Part) error {
+	s := m.sessions[p.UploadID]
+	if s == nil || s.Received != p.StartOffset {
+		return upload.ErrOffset
+	}
+	s.Received += p.Size
+	m.parts[p.UploadID] = append(m.parts[p.UploadID], *p)
+	return nil
+}
+func (m *memUploadRepo) ListParts(_ context.Context, id uuid.UUID) ([]upload.Part, error) {
+	list := append([]upload.Part(nil), m.parts[id]...)
+	sort.Slice(list, func(i, j int) bool { return list[i].StartOffset < list[j].StartOffset })
+	return list, nil
+}
+func (m *memUploadRepo) DeleteSession(_ context.Context, id uuid.UUID) error {
+	delete(m.sessions, id)
+	delete(m.parts, id)
+	return nil
+}
+func (m *memUploadRepo) ListExpiredSessions(_ context.Context, kind string, now time.Time, _ int) ([]upload.Session, error) {
+	var list []upload.Session
+	for _, s := range m.sessions {
+		if s.Kind == kind && s.ExpiresAt.Before(now) {
+			list = append(list, *s)
+		}
+	}
+	return list, nil
+}
+
+// photoSink records what UploadPhoto reads.
+type photoSink struct {
+	service.AuthService
+	got []byte
+}
+
+func (p *photoSink) UploadPhoto(_ context.Context, _ uuid.UUID, r io.Reader) (string, string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", err
+	}
+	p.got = data
+	return "/media/photo.jpg", "/media/photo_256.jpg", nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// --- TESTS ---
+
+func TestPhotoUploads_ResumeAndComplete(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewLocal(t.TempDir(), "/media", []byte("secret"))
+	require.NoError(t, err)
+	repo := newMemUploadRepo()
+	sink := &photoSink{}
+	svc := service.NewPhotoUploads(repo, store, sink, 10<<20, time.Hour)
+
+	userID, uploadID := uuid.New(), uuid.New()
+	data := make([]byte, 3<<20+123)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	sum := checksum(data)
+
+	// first stream: 1.5 MiB in 512 KiB chunks, then the connection drops
+	up, err := svc.OpenPhotoUpload(ctx, userID, uploadID, int64(len(data)), sum)
+	require.NoError(t, err)
+	for off := 0; off < 3<<19; off += 1 << 19 {
+		require.NoError(t, up.Write(ctx, int64(off), data[off:off+1<<19]))
+	}
+	require.NoError(t, up.Flush(ctx))
+
+	st, err := svc.PhotoUploadStatus(ctx, userID, uploadID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3<<19), st.Received)
+	_, err = svc.PhotoUploadStatus(ctx, uuid.New(), uploadID)
+	assert.ErrorIs(t, err, service.ErrUploadNotFound)
+
+	// resuming requires the same metadata and the stored offset
+	_, err = svc.OpenPhotoUpload(ctx, userID, uploadID, int64(len(data)), checksum([]byte("other")))
+	assert.ErrorIs(t, err, service.ErrUploadMismatch)
+	up, err = svc.OpenPhotoUpload(ctx, userID, uploadID, int64(len(data)), sum)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3<<19), up.Offset())
+	assert.ErrorIs(t, up.Write(ctx, 0, data[:10]), service.ErrUploadOffset)
+	require.NoError(t, up.Write(ctx, up.Offset(), data[3<<19:]))
+
+	photo, thumb, err := up.Complete(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "/media/photo.jpg", photo)
+	assert.Equal(t, "/media/photo_256.jpg", thumb)
+	assert.Equal(t, data, sink.got)
+	assert.Empty(t, repo.sessions)
+}
+
+func TestPhotoUploads_ChecksumAndLimits(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewLocal(t.TempDir(), "/media", []byte("secret"))
+	require.NoError(t, err)
+	repo := newMemUploadRepo()
+	svc := service.NewPhotoUploads(repo, store, &photoSink{}, 1024, time.Hour)
+	userID := uuid.New()
+
+	_, err = svc.OpenPhotoUpload(ctx, userID, uuid.New(), 2048, checksum(nil))
+	assert.ErrorIs(t, err, service.ErrPhotoTooLarge)
+	_, err = svc.OpenPhotoUpload(ctx, userID, uuid.New(), 10, "not-a-checksum")
+	assert.ErrorIs(t, err, service.ErrInvalidUpload)
+
+	data := []byte("0123456789")
+	up, err := svc.OpenPhotoUpload(ctx, userID, uuid.New(), int64(len(data)), checksum([]byte("9876543210")))
+	require.NoError(t, err)
+	assert.ErrorIs(t, up.Write(ctx, 0, append(data, 'x')), service.ErrInvalidUpload)
+	require.NoError(t, up.Write(ctx, 0, data[:5]))
+	_, _, err = up.Complete(ctx)
+	assert.ErrorIs(t, err, service.ErrUploadIncomplete)
+
+	require.NoError(t, up.Write(ctx, 5, data[5:]))
+	_, _, err = up.Complete(ctx)
+	assert.ErrorIs(t, err, service.ErrChecksumMismatch)
+	assert.Empty(t, repo.sessions, "a corrupt upload is discarded")
+}
+
+func TestPhotoUploads_IDTaken(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewLocal(t.TempDir(), "/media", []byte("secret"))
+	require.NoError(t, err)
+	repo := newMemUploadRepo()
+	svc := service.NewPhotoUploads(repo, store, &photoSink{}, 1024, time.Hour)
+	userID, uploadID := uuid.New(), uuid.New()
+	data := []byte("0123456789")
+
+	_, err = svc.OpenPhotoUpload(ctx, userID, uploadID, int64(len(data)), checksum(data))
+	require.NoError(t, err)
+	_, err = svc.OpenPhotoUpload(ctx, uuid.New(), uploadID, int64(len(data)), checksum(data))
+	assert.ErrorIs(t, err, service.ErrUploadIDTaken, "another user's upload ID")
+
+	// an expired upload of the same user is started over
+	repo.sessions[uploadID].ExpiresAt = time.Now().Add(-time.Minute)
+	up, err := svc.OpenPhotoUpload(ctx, userID, uploadID, 5, checksum(data[:5]))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), up.Offset())
+	assert.True(t, up.Session().ExpiresAt.After(time.Now()))
+}
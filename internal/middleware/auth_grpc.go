@@ -5,12 +5,15 @@ package middleware
 
 import (
 	"context"
+	"crypto/tls"
 	"strings"
 
 	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
@@ -18,35 +21,88 @@ import (
 // and injects the payload into the context. Expected header format:
 //
 //	authorization: Bearer <token>
-func AuthUnaryInterceptor(maker token.Maker) grpc.UnaryServerInterceptor {
+//
+// With WithAPIKeys, an `x-api-key: <key>` entry is accepted instead. With
+// WithServicePeers, calls without credentials from an allowed mTLS peer are
+// authenticated as that service.
+func AuthUnaryInterceptor(maker token.Maker, opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
 	return func(
 		ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.Unauthenticated, "metadata missing")
+		payload, err := authenticateGRPC(ctx, maker, o)
+		if err != nil {
+			return nil, err
 		}
+		return handler(context.WithValue(ctx, token.CtxKey, payload), req)
+	}
+}
 
-		auths := md.Get("authorization")
-		if len(auths) == 0 {
-			return nil, status.Error(codes.Unauthenticated, "authorization header missing")
+// AuthStreamInterceptor is the streaming counterpart of AuthUnaryInterceptor.
+func AuthStreamInterceptor(maker token.Maker, opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		payload, err := authenticateGRPC(ss.Context(), maker, o)
+		if err != nil {
+			return err
 		}
+		return handler(srv, &contextStream{ss, context.WithValue(ss.Context(), token.CtxKey, payload)})
+	}
+}
 
-		fields := strings.Fields(auths[0])
-		if len(fields) != 2 || strings.ToLower(fields[0]) != "bearer" {
-			return nil, status.Error(codes.Unauthenticated, "invalid auth header")
-		}
+func authenticateGRPC(ctx context.Context, maker token.Maker, o *options) (*token.Payload, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
 
-		payload, err := maker.VerifyToken(fields[1])
+	if keys := md.Get(strings.ToLower(APIKeyHeader)); len(keys) > 0 && o.apiKeys != nil {
+		payload, err := o.apiKeys.VerifyAPIKey(ctx, keys[0])
 		if err != nil {
 			return nil, status.Errorf(codes.Unauthenticated, "%v", err)
 		}
+		return payload, nil
+	}
+
+	auths := md.Get("authorization")
+	if len(auths) == 0 {
+		if payload := o.servicePayload(PeerTLSState(ctx)); payload != nil {
+			return payload, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "authorization header missing")
+	}
+
+	fields := strings.Fields(auths[0])
+	if len(fields) != 2 || strings.ToLower(fields[0]) != "bearer" {
+		return nil, status.Error(codes.Unauthenticated, "invalid auth header")
+	}
 
-		newCtx := context.WithValue(ctx, token.CtxKey, payload)
-		return handler(newCtx, req)
+	payload, err := maker.VerifyToken(fields[1])
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	return payload, nil
+}
+
+// contextStream overrides the context of a server stream so stream
+// interceptors can pass values to the handler.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
+
+// PeerTLSState returns the TLS connection state of the gRPC peer in ctx, or
+// nil for plaintext connections.
+func PeerTLSState(ctx context.Context) *tls.ConnectionState {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
 	}
+	return &info.State
 }
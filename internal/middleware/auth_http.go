@@ -8,9 +8,36 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(maker token.Maker) gin.HandlerFunc {
+// AuthMiddleware verifies the Bearer Paseto token and stores the payload under
+// "payload". Depending on opts it also accepts the X-API-Key header, the
+// access-token cookie or, when no credentials are sent, a service principal's
+// mTLS client certificate.
+func AuthMiddleware(maker token.Maker, opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts)
 	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" && o.apiKeys != nil {
+			payload, err := o.apiKeys.VerifyAPIKey(c, key)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.Set("payload", payload)
+			c.Next()
+			return
+		}
 		auth := c.GetHeader("Authorization")
+		if auth == "" && o.cookie {
+			if v, err := c.Cookie(AccessTokenCookie); err == nil && v != "" {
+				auth = "Bearer " + v
+			}
+		}
+		if auth == "" {
+			if payload := o.servicePayload(c.Request.TLS); payload != nil {
+				c.Set("payload", payload)
+				c.Next()
+				return
+			}
+		}
 		fields := strings.Fields(auth)
 		if len(fields) != 2 || strings.ToLower(fields[0]) != "bearer" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or malformed auth header"})
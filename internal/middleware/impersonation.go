@@ -0,0 +1,107 @@
+package middleware
+
+// Audit trail for requests made with impersonation tokens.
+
+import (
+	"context"
+	"net"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/logger"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RequestRecord describes one request made with an impersonation token.
+type RequestRecord struct {
+	Payload   *token.Payload
+	Method    string // HTTP method or "GRPC"
+	Path      string // route template or full gRPC method
+	Status    int    // HTTP status or gRPC code
+	IP        string
+	UserAgent string
+}
+
+// ImpersonationRecorder persists RequestRecords.
+type ImpersonationRecorder interface {
+	RecordImpersonatedRequest(ctx context.Context, r RequestRecord) error
+}
+
+// ImpersonationAudit records every request made with an impersonation token
+// once the handler has run. It must run after AuthMiddleware.
+func ImpersonationAudit(rec ImpersonationRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		payload, _ := c.Get("payload")
+		pl, ok := payload.(*token.Payload)
+		if !ok || !pl.IsImpersonated() {
+			return
+		}
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		if err := rec.RecordImpersonatedRequest(context.WithoutCancel(c.Request.Context()), RequestRecord{
+			Payload:   pl,
+			Method:    c.Request.Method,
+			Path:      path,
+			Status:    c.Writer.Status(),
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}); err != nil {
+			logger.Error("impersonation audit: %v", err)
+		}
+	}
+}
+
+// ImpersonationAuditInterceptor is the gRPC counterpart of ImpersonationAudit.
+// It must run after AuthUnaryInterceptor.
+func ImpersonationAuditInterceptor(rec ImpersonationRecorder) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		recordImpersonatedCall(ctx, rec, info.FullMethod, err)
+		return resp, err
+	}
+}
+
+// ImpersonationAuditStreamInterceptor is the streaming counterpart of
+// ImpersonationAuditInterceptor. It must run after AuthStreamInterceptor.
+func ImpersonationAuditStreamInterceptor(rec ImpersonationRecorder) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		recordImpersonatedCall(ss.Context(), rec, info.FullMethod, err)
+		return err
+	}
+}
+
+func recordImpersonatedCall(ctx context.Context, rec ImpersonationRecorder, method string, err error) {
+	pl, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || !pl.IsImpersonated() {
+		return
+	}
+	r := RequestRecord{
+		Payload: pl,
+		Method:  "GRPC",
+		Path:    method,
+		Status:  int(status.Code(err)),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.IP, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			r.UserAgent = ua[0]
+		}
+	}
+	if recErr := rec.RecordImpersonatedRequest(context.WithoutCancel(ctx), r); recErr != nil {
+		logger.Error("impersonation audit: %v", recErr)
+	}
+}
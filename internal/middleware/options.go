@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/tlsconfig"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/google/uuid"
+)
+
+// APIKeyHeader is the HTTP header (and lower-cased gRPC metadata key) that
+// carries a personal API key.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyVerifier resolves a plaintext API key to the payload it acts as.
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (*token.Payload, error)
+}
+
+// Option customises AuthMiddleware and AuthUnaryInterceptor.
+type Option func(*options)
+
+type options struct {
+	apiKeys      APIKeyVerifier
+	servicePeers []string
+	serviceScope map[string][]string
+	cookie       bool
+}
+
+// WithAPIKeys accepts personal API keys as an alternative to Bearer tokens.
+func WithAPIKeys(v APIKeyVerifier) Option {
+	return func(o *options) { o.apiKeys = v }
+}
+
+// WithServicePeers authenticates callers that present no token but a verified
+// mTLS client certificate whose SAN is listed in allowedSANs. They get a
+// service-principal payload (see token.Payload.IsService) holding the scopes
+// listed for their SAN in scopes, and nothing else.
+func WithServicePeers(allowedSANs []string, scopes map[string][]string) Option {
+	return func(o *options) { o.servicePeers, o.serviceScope = allowedSANs, scopes }
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// servicePayload returns a service-principal payload for a verified peer, or
+// nil when the peer is not an allowed service.
+func (o *options) servicePayload(state *tls.ConnectionState) *token.Payload {
+	if len(o.servicePeers) == 0 {
+		return nil
+	}
+	san, ok := tlsconfig.PeerIdentity(state, o.servicePeers)
+	if !ok {
+		return nil
+	}
+	return &token.Payload{
+		ID:        uuid.New(),
+		Service:   san,
+		Scopes:    o.serviceScope[san],
+		IssuedAt:  time.Now(),
+		ExpiredAt: state.VerifiedChains[0][0].NotAfter,
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"net"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/requestinfo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// RequestIDHeader is the HTTP header (and lower-cased gRPC metadata key) that
+// carries the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID assigns every request an ID (reusing a client-supplied
+// X-Request-ID), echoes it in the response and stores requestinfo.Info.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+		info := requestinfo.Info{ID: id, IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+		c.Set(requestinfo.CtxKey, info)
+		// Also on the request context, which outlives c in deferred audits.
+		c.Request = c.Request.WithContext(requestinfo.NewContext(c.Request.Context(), info))
+		c.Next()
+	}
+}
+
+// RequestIDUnaryInterceptor is the gRPC counterpart of RequestID.
+func RequestIDUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(withGRPCRequestInfo(ctx), req)
+	}
+}
+
+// RequestIDStreamInterceptor is the streaming counterpart of RequestIDUnaryInterceptor.
+func RequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ss, withGRPCRequestInfo(ss.Context())})
+	}
+}
+
+func withGRPCRequestInfo(ctx context.Context) context.Context {
+	var ri requestinfo.Info
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RequestIDHeader); len(v) > 0 && len(v[0]) <= 128 {
+			ri.ID = v[0]
+		}
+		if v := md.Get("user-agent"); len(v) > 0 {
+			ri.UserAgent = v[0]
+		}
+	}
+	if ri.ID == "" {
+		ri.ID = uuid.NewString()
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ri.IP, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, ri.ID))
+	return requestinfo.NewContext(ctx, ri)
+}
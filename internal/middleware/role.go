@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole rejects requests whose token does not carry role. Impersonation
+// tokens never pass, even when the impersonated user has the role.
+// It must run after AuthMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload, _ := c.Get("payload")
+		pl, ok := payload.(*token.Payload)
+		if !ok || pl == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+			return
+		}
+		if pl.Role != role || pl.IsImpersonated() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// DenyImpersonation blocks sensitive actions (password, credentials, 2FA
+// changes) for impersonation tokens.
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload, _ := c.Get("payload")
+		if pl, ok := payload.(*token.Payload); ok && pl.IsImpersonated() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating"})
+			return
+		}
+		c.Next()
+	}
+}
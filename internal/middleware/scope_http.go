@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope rejects requests whose OAuth2 token, API key or service
+// principal does not grant the given scope. It must run after AuthMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload, ok := c.Get("payload")
+		pl, _ := payload.(*token.Payload)
+		if !ok || pl == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+			return
+		}
+		if !pl.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope", "scope": scope})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireFirstParty rejects tokens issued to OAuth2 clients, API keys and
+// service principals. Use it for account-management routes that only
+// interactive sessions may reach.
+func RequireFirstParty() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload, _ := c.Get("payload")
+		if pl, ok := payload.(*token.Payload); ok && pl.IsScoped() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not available to third-party clients, API keys or services"})
+			return
+		}
+		c.Next()
+	}
+}
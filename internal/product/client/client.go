@@ -0,0 +1,68 @@
+// Package client lets the other marketplace services read from the
+// product-service over gRPC instead of its tables. The connection must
+// present a client certificate that the product-service accepts as a service
+// principal with the products:read scope.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	pb "github.com/ADRPUR/event-driven-marketplace/api/proto/product/v1"
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/requestinfo"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// exportPageSize is the number of products fetched per ListProducts call.
+const exportPageSize = 100
+
+// Client reads products through product-service RPCs.
+type Client struct {
+	rpc pb.ProductServiceClient
+}
+
+// New returns a Client using conn.
+func New(conn grpc.ClientConnInterface) *Client {
+	return &Client{rpc: pb.NewProductServiceClient(conn)}
+}
+
+// ExportProducts returns every product sellerID lists as a JSON array, for
+// the data export of the auth-service.
+func (c *Client) ExportProducts(ctx context.Context, sellerID uuid.UUID) (json.RawMessage, error) {
+	req := &pb.ListProductsRequest{
+		PageSize: exportPageSize,
+		Filter:   &pb.ProductFilter{SellerId: sellerID.String()},
+	}
+	out := []json.RawMessage{}
+	for {
+		resp, err := c.rpc.ListProducts(outgoing(ctx), req)
+		if err != nil {
+			return nil, fmt.Errorf("list products: %w", err)
+		}
+		for _, p := range resp.Products {
+			b, err := protojson.Marshal(p)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b)
+		}
+		if resp.NextPageToken == "" {
+			return json.Marshal(out)
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+// outgoing forwards the ID of the request being served, so that both
+// services log the same request ID.
+func outgoing(ctx context.Context) context.Context {
+	if id := requestinfo.FromContext(ctx).ID; id != "" {
+		return metadata.AppendToOutgoingContext(ctx, middleware.RequestIDHeader, id)
+	}
+	return ctx
+}
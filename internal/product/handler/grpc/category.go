@@ -0,0 +1,134 @@
+package grpc
+
+import (
+	"context"
+
+	pb "github.com/ADRPUR/event-driven-marketplace/api/proto/product/v1"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *grpcServer) SetProductCategory(ctx context.Context, in *pb.SetProductCategoryRequest) (*pb.Product, error) {
+	categoryID, err := parseOptionalID(in.CategoryId, "category_id")
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.svc.SetCategory(ctx, in.Id, categoryID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.toProto(p), nil
+}
+
+func (s *grpcServer) CreateCategory(ctx context.Context, in *pb.CreateCategoryRequest) (*pb.Category, error) {
+	if s.categories == nil {
+		return nil, status.Error(codes.Unimplemented, "categories are disabled")
+	}
+	parentID, err := parseOptionalID(in.ParentId, "parent_id")
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.categories.Create(ctx, in.Name, in.Slug, parentID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoCategory(c), nil
+}
+
+func (s *grpcServer) GetCategory(ctx context.Context, in *pb.GetCategoryRequest) (*pb.Category, error) {
+	if s.categories == nil {
+		return nil, status.Error(codes.Unimplemented, "categories are disabled")
+	}
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid id")
+	}
+	c, err := s.categories.Get(ctx, id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoCategory(c), nil
+}
+
+func (s *grpcServer) ListCategories(ctx context.Context, _ *emptypb.Empty) (*pb.ListCategoriesResponse, error) {
+	if s.categories == nil {
+		return nil, status.Error(codes.Unimplemented, "categories are disabled")
+	}
+	list, err := s.categories.List(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	out := make([]*pb.Category, len(list))
+	for i := range list {
+		out[i] = toProtoCategory(&list[i])
+	}
+	return &pb.ListCategoriesResponse{Categories: out}, nil
+}
+
+func (s *grpcServer) UpdateCategory(ctx context.Context, in *pb.UpdateCategoryRequest) (*pb.Category, error) {
+	if s.categories == nil {
+		return nil, status.Error(codes.Unimplemented, "categories are disabled")
+	}
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid id")
+	}
+	parentID, err := parseOptionalID(in.ParentId, "parent_id")
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.categories.Update(ctx, id, in.Name, in.Slug, parentID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoCategory(c), nil
+}
+
+func (s *grpcServer) DeleteCategory(ctx context.Context, in *pb.DeleteCategoryRequest) (*emptypb.Empty, error) {
+	if s.categories == nil {
+		return nil, status.Error(codes.Unimplemented, "categories are disabled")
+	}
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid id")
+	}
+	if err := s.categories.Delete(ctx, id); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func toProtoCategory(c *model.Category) *pb.Category {
+	return &pb.Category{
+		Id:        c.ID.String(),
+		ParentId:  optionalID(c.ParentID),
+		Name:      c.Name,
+		Slug:      c.Slug,
+		Path:      c.Path,
+		Depth:     int32(c.Depth()),
+		CreatedAt: timestamppb.New(c.CreatedAt),
+	}
+}
+
+// parseOptionalID reads a UUID field where "" means none.
+func parseOptionalID(s, field string) (*uuid.UUID, error) {
+	if s == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s", field)
+	}
+	return &id, nil
+}
+
+func optionalID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
@@ -0,0 +1,137 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	pb "github.com/ADRPUR/event-driven-marketplace/api/proto/product/v1"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UploadProductImage keeps whatever was received when the stream breaks,
+// so the client can reopen it with the same metadata and continue from the
+// stored offset.
+func (s *grpcServer) UploadProductImage(stream pb.ProductService_UploadProductImageServer) error {
+	if s.images == nil {
+		return status.Error(codes.Unimplemented, "product images are disabled")
+	}
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	meta := first.GetMetadata()
+	if meta == nil {
+		return status.Error(codes.InvalidArgument, "the first message must carry the metadata")
+	}
+	uploadID, err := uuid.Parse(meta.UploadId)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "invalid upload_id")
+	}
+	up, err := s.images.OpenUpload(ctx, meta.ProductId, uploadID, meta.Size, meta.Sha256)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err == nil && msg.GetChunk() == nil {
+			err = status.Error(codes.InvalidArgument, "expected a chunk")
+		}
+		if err == nil {
+			if werr := up.Write(ctx, msg.GetChunk().Offset, msg.GetChunk().Data); werr != nil {
+				err = toStatus(werr)
+			}
+		}
+		if err != nil {
+			if ferr := up.Flush(ctx); ferr != nil {
+				return status.Errorf(codes.Internal, "%v", ferr)
+			}
+			return err
+		}
+	}
+
+	resp := &pb.UploadProductImageResponse{UploadId: uploadID.String()}
+	if up.Offset() < up.Session().Size {
+		if err := up.Flush(ctx); err != nil {
+			return toStatus(err)
+		}
+		resp.Received = up.Offset()
+		return stream.SendAndClose(resp)
+	}
+	img, err := up.Complete(ctx)
+	if err != nil {
+		return toStatus(err)
+	}
+	resp.Received = up.Offset()
+	resp.Complete = true
+	resp.Image = s.toProtoImage(img)
+	return stream.SendAndClose(resp)
+}
+
+func (s *grpcServer) GetProductImageUploadStatus(ctx context.Context, in *pb.ProductImageUploadStatusRequest) (*pb.ProductImageUploadStatusResponse, error) {
+	if s.images == nil {
+		return nil, status.Error(codes.Unimplemented, "product images are disabled")
+	}
+	uploadID, err := uuid.Parse(in.UploadId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid upload_id")
+	}
+	sess, err := s.images.UploadStatus(ctx, uploadID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ProductImageUploadStatusResponse{
+		UploadId:  sess.ID.String(),
+		ProductId: sess.Target,
+		Size:      sess.Size,
+		Received:  sess.Received,
+		ExpiresAt: timestamppb.New(sess.ExpiresAt),
+	}, nil
+}
+
+func (s *grpcServer) DeleteProductImage(ctx context.Context, in *pb.DeleteProductImageRequest) (*emptypb.Empty, error) {
+	if s.images == nil {
+		return nil, status.Error(codes.Unimplemented, "product images are disabled")
+	}
+	id, err := uuid.Parse(in.ImageId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid image_id")
+	}
+	if err := s.images.Delete(ctx, in.ProductId, id); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// toProtoImages converts images with signed URLs; without image storage
+// products carry none.
+func (s *grpcServer) toProtoImages(list []model.Image) []*pb.ProductImage {
+	if s.images == nil {
+		return nil
+	}
+	out := make([]*pb.ProductImage, len(list))
+	for i := range list {
+		out[i] = s.toProtoImage(&list[i])
+	}
+	return out
+}
+
+func (s *grpcServer) toProtoImage(img *model.Image) *pb.ProductImage {
+	return &pb.ProductImage{
+		Id:           img.ID.String(),
+		Url:          s.images.URL(img.Key),
+		ThumbnailUrl: s.images.URL(img.ThumbnailKey),
+		Position:     int32(img.Position),
+		CreatedAt:    timestamppb.New(img.CreatedAt),
+	}
+}
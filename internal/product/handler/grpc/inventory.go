@@ -0,0 +1,170 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/ADRPUR/event-driven-marketplace/api/proto/product/v1"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *grpcServer) SetStock(ctx context.Context, in *pb.SetStockRequest) (*pb.Product, error) {
+	if s.inventory == nil {
+		return nil, status.Error(codes.Unimplemented, "inventory is disabled")
+	}
+	variantID, err := parseOptionalID(in.VariantId, "variant_id")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.inventory.SetStock(ctx, in.ProductId, variantID, int(in.Stock), in.Reason); err != nil {
+		return nil, toStatus(err)
+	}
+	p, err := s.svc.Get(ctx, in.ProductId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.toProto(p), nil
+}
+
+func (s *grpcServer) RecordStockMovement(ctx context.Context, in *pb.RecordStockMovementRequest) (*pb.StockMovement, error) {
+	if s.inventory == nil {
+		return nil, status.Error(codes.Unimplemented, "inventory is disabled")
+	}
+	variantID, err := parseOptionalID(in.VariantId, "variant_id")
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.inventory.RecordMovement(ctx, in.ProductId, variantID, in.Kind, int(in.Quantity), in.Reason)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoMovement(m), nil
+}
+
+func (s *grpcServer) ListStockMovements(ctx context.Context, in *pb.ListStockMovementsRequest) (*pb.ListStockMovementsResponse, error) {
+	if s.inventory == nil {
+		return nil, status.Error(codes.Unimplemented, "inventory is disabled")
+	}
+	var (
+		hist *service.StockHistory
+		err  error
+	)
+	if in.Sku != "" {
+		hist, err = s.inventory.SKUMovements(ctx, in.Sku, int(in.Page), int(in.PageSize))
+	} else {
+		variantID, perr := parseOptionalID(in.VariantId, "variant_id")
+		if perr != nil {
+			return nil, perr
+		}
+		hist, err = s.inventory.Movements(ctx, in.ProductId, variantID, int(in.Page), int(in.PageSize))
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.ListStockMovementsResponse{
+		Movements:  make([]*pb.StockMovement, len(hist.Movements)),
+		Stock:      int32(hist.Level.Stock),
+		Reserved:   int32(hist.Level.Reserved),
+		Available:  int32(hist.Level.Available()),
+		MovementId: hist.Level.MovementID,
+	}
+	for i := range hist.Movements {
+		resp.Movements[i] = toProtoMovement(&hist.Movements[i])
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) ReserveStock(ctx context.Context, in *pb.ReserveStockRequest) (*pb.Reservation, error) {
+	if s.inventory == nil {
+		return nil, status.Error(codes.Unimplemented, "inventory is disabled")
+	}
+	items := make([]model.ReservationItem, len(in.Items))
+	for i, it := range in.Items {
+		productID, err := uuid.Parse(it.ProductId)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid product_id")
+		}
+		variantID, err := parseOptionalID(it.VariantId, "variant_id")
+		if err != nil {
+			return nil, err
+		}
+		items[i] = model.ReservationItem{ProductID: productID, VariantID: variantID, Quantity: int(it.Quantity)}
+	}
+	res, err := s.inventory.Reserve(ctx, items, time.Duration(in.TtlSeconds)*time.Second)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoReservation(res), nil
+}
+
+func (s *grpcServer) GetReservation(ctx context.Context, in *pb.ReservationRequest) (*pb.Reservation, error) {
+	if s.inventory == nil {
+		return nil, status.Error(codes.Unimplemented, "inventory is disabled")
+	}
+	return onReservation(ctx, in, s.inventory.Get)
+}
+
+func (s *grpcServer) CommitReservation(ctx context.Context, in *pb.ReservationRequest) (*pb.Reservation, error) {
+	if s.inventory == nil {
+		return nil, status.Error(codes.Unimplemented, "inventory is disabled")
+	}
+	return onReservation(ctx, in, s.inventory.Commit)
+}
+
+func (s *grpcServer) ReleaseReservation(ctx context.Context, in *pb.ReservationRequest) (*pb.Reservation, error) {
+	if s.inventory == nil {
+		return nil, status.Error(codes.Unimplemented, "inventory is disabled")
+	}
+	return onReservation(ctx, in, s.inventory.Release)
+}
+
+// onReservation runs op on the requested reservation.
+func onReservation(ctx context.Context, in *pb.ReservationRequest,
+	op func(context.Context, uuid.UUID) (*model.Reservation, error)) (*pb.Reservation, error) {
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid id")
+	}
+	res, err := op(ctx, id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoReservation(res), nil
+}
+
+func toProtoReservation(r *model.Reservation) *pb.Reservation {
+	items := make([]*pb.ReservationItem, len(r.Items))
+	for i, it := range r.Items {
+		items[i] = &pb.ReservationItem{ProductId: it.ProductID.String(), VariantId: optionalID(it.VariantID), Quantity: int32(it.Quantity)}
+	}
+	return &pb.Reservation{
+		Id:        r.ID.String(),
+		Status:    r.Status,
+		Items:     items,
+		ExpiresAt: timestamppb.New(r.ExpiresAt),
+		CreatedAt: timestamppb.New(r.CreatedAt),
+	}
+}
+
+func toProtoMovement(m *model.StockMovement) *pb.StockMovement {
+	return &pb.StockMovement{
+		Id:            m.ID,
+		ProductId:     m.ProductID.String(),
+		VariantId:     optionalID(m.VariantID),
+		Sku:           m.SKU,
+		Kind:          m.Kind,
+		StockDelta:    int32(m.StockDelta),
+		ReservedDelta: int32(m.ReservedDelta),
+		StockAfter:    int32(m.StockAfter),
+		ReservedAfter: int32(m.ReservedAfter),
+		ReservationId: optionalID(m.ReservationID),
+		ActorId:       optionalID(m.ActorID),
+		Reason:        m.Reason,
+		CreatedAt:     timestamppb.New(m.CreatedAt),
+	}
+}
@@ -0,0 +1,134 @@
+package grpc
+
+import (
+	"context"
+	"math"
+
+	pb "github.com/ADRPUR/event-driven-marketplace/api/proto/product/v1"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+)
+
+// facetOrder fixes the order of facets in responses.
+var facetOrder = []string{
+	model.FacetCategory, model.FacetSeller, model.FacetTags, model.FacetAvailability,
+	model.FacetCurrency, model.FacetPrice, model.FacetCreated,
+}
+
+func (s *grpcServer) SetProductTags(ctx context.Context, in *pb.SetProductTagsRequest) (*pb.Product, error) {
+	p, err := s.svc.SetTags(ctx, in.Id, in.Tags)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.toProto(p), nil
+}
+
+// productQuery builds a listing query from the shared request fields.
+func productQuery(categoryID string, f *pb.ProductFilter, sort string, facets bool) (service.ProductQuery, error) {
+	q := service.ProductQuery{Sort: sort, Facets: facets}
+	var err error
+	if q.CategoryID, err = parseOptionalID(categoryID, "category_id"); err != nil {
+		return q, err
+	}
+	if f == nil {
+		return q, nil
+	}
+	if q.SellerID, err = parseOptionalID(f.SellerId, "seller_id"); err != nil {
+		return q, err
+	}
+	q.PriceCurrency = f.PriceCurrency
+	if q.MinPrice, err = optionalMoney(f.MinPrice); err != nil {
+		return q, err
+	}
+	if q.MaxPrice, err = optionalMoney(f.MaxPrice); err != nil {
+		return q, err
+	}
+	switch f.Availability {
+	case pb.Availability_AVAILABILITY_IN_STOCK, pb.Availability_AVAILABILITY_OUT_OF_STOCK:
+		in := f.Availability == pb.Availability_AVAILABILITY_IN_STOCK
+		q.InStock = &in
+	}
+	q.Tags = f.Tags
+	if f.CreatedAfter != nil {
+		t := f.CreatedAfter.AsTime()
+		q.CreatedAfter = &t
+	}
+	if f.CreatedBefore != nil {
+		t := f.CreatedBefore.AsTime()
+		q.CreatedBefore = &t
+	}
+	return q, nil
+}
+
+func optionalMoney(m *pb.Money) (*money.Money, error) {
+	if m == nil {
+		return nil, nil
+	}
+	v, err := fromProtoMoney(m)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &v, nil
+}
+
+func (s *grpcServer) listResponse(ctx context.Context, l *service.Listing, currency string) (*pb.ListProductsResponse, error) {
+	out, err := s.toProtoList(ctx, listingProducts(l), currency)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListProductsResponse{
+		Products:       out,
+		Total:          deprecatedTotal(l),
+		Facets:         toProtoFacets(l.Facets),
+		NextPageToken:  l.NextPageToken,
+		TotalSize:      l.Total,
+		TotalEstimated: l.TotalEstimated,
+	}, nil
+}
+
+// deprecatedTotal fills the deprecated int32 total: all matches, saturated
+// to the field size, or 0 when no count was requested.
+func deprecatedTotal(l *service.Listing) int32 {
+	if l.Total == nil {
+		return 0
+	}
+	return int32(min(*l.Total, math.MaxInt32))
+}
+
+func listingProducts(l *service.Listing) []model.Product {
+	list := make([]model.Product, len(l.Hits))
+	for i := range l.Hits {
+		list[i] = l.Hits[i].Product
+	}
+	return list
+}
+
+func totalMode(t pb.TotalCount) string {
+	switch t {
+	case pb.TotalCount_TOTAL_COUNT_EXACT:
+		return service.TotalExact
+	case pb.TotalCount_TOTAL_COUNT_ESTIMATED:
+		return service.TotalEstimate
+	}
+	return ""
+}
+
+func toProtoFacets(f model.Facets) []*pb.Facet {
+	if f == nil {
+		return nil
+	}
+	out := make([]*pb.Facet, 0, len(f))
+	for _, name := range facetOrder {
+		values, ok := f[name]
+		if !ok {
+			continue
+		}
+		pf := &pb.Facet{Name: name, Values: make([]*pb.FacetValue, len(values))}
+		for i, v := range values {
+			pf.Values[i] = &pb.FacetValue{Value: v.Value, Count: v.Count}
+		}
+		out = append(out, pf)
+	}
+	return out
+}
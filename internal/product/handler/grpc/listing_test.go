@@ -0,0 +1,36 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+)
+
+func TestListResponse_TotalCountsAllMatches(t *testing.T) {
+	s := &grpcServer{}
+	hits := []model.SearchHit{{Product: model.Product{ID: uuid.New(), Name: "Mug"}}}
+
+	n := int64(42)
+	resp, err := s.listResponse(context.Background(), &service.Listing{Hits: hits, Total: &n}, "")
+	require.NoError(t, err)
+	assert.Len(t, resp.Products, 1)
+	assert.Equal(t, int32(42), resp.Total, "all matches, not the page length")
+	assert.Equal(t, int64(42), resp.GetTotalSize())
+
+	n = math.MaxInt32 + 1
+	resp, err = s.listResponse(context.Background(), &service.Listing{Hits: hits, Total: &n}, "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(math.MaxInt32), resp.Total)
+
+	resp, err = s.listResponse(context.Background(), &service.Listing{Hits: hits}, "")
+	require.NoError(t, err)
+	assert.Zero(t, resp.Total, "no count was requested")
+	assert.Nil(t, resp.TotalSize)
+}
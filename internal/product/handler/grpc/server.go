@@ -2,70 +2,296 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/ADRPUR/event-driven-marketplace/api/proto/product/v1"
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
+// MethodScopes maps each RPC to the OAuth2 scope a third-party token needs.
+// Pass it to middleware.ScopeUnaryInterceptor.
+var MethodScopes = map[string]string{
+	"/product.v1.ProductService/CreateProduct":  token.ScopeProductsWrite,
+	"/product.v1.ProductService/GetProduct":     token.ScopeProductsRead,
+	"/product.v1.ProductService/ListProducts":   token.ScopeProductsRead,
+	"/product.v1.ProductService/ListMyProducts": token.ScopeProductsRead,
+	"/product.v1.ProductService/SearchProducts": token.ScopeProductsRead,
+	"/product.v1.ProductService/UpdateProduct":  token.ScopeProductsWrite,
+	"/product.v1.ProductService/DeleteProduct":  token.ScopeProductsWrite,
+
+	"/product.v1.ProductService/SetProductCategory":          token.ScopeProductsWrite,
+	"/product.v1.ProductService/SetProductTags":              token.ScopeProductsWrite,
+	"/product.v1.ProductService/SetProductOptions":           token.ScopeProductsWrite,
+	"/product.v1.ProductService/ListVariants":                token.ScopeProductsRead,
+	"/product.v1.ProductService/CreateVariant":               token.ScopeProductsWrite,
+	"/product.v1.ProductService/UpdateVariant":               token.ScopeProductsWrite,
+	"/product.v1.ProductService/DeleteVariant":               token.ScopeProductsWrite,
+	"/product.v1.ProductService/UploadProductImage":          token.ScopeProductsWrite,
+	"/product.v1.ProductService/GetProductImageUploadStatus": token.ScopeProductsWrite,
+	"/product.v1.ProductService/DeleteProductImage":          token.ScopeProductsWrite,
+	"/product.v1.ProductService/SetStock":                    token.ScopeProductsWrite,
+	"/product.v1.ProductService/RecordStockMovement":         token.ScopeProductsWrite,
+	"/product.v1.ProductService/ListStockMovements":          token.ScopeProductsRead,
+	"/product.v1.ProductService/ReserveStock":                token.ScopeProductsWrite,
+	"/product.v1.ProductService/GetReservation":              token.ScopeProductsRead,
+	"/product.v1.ProductService/CommitReservation":           token.ScopeProductsWrite,
+	"/product.v1.ProductService/ReleaseReservation":          token.ScopeProductsWrite,
+	"/product.v1.ProductService/CreateCategory":              token.ScopeProductsWrite,
+	"/product.v1.ProductService/GetCategory":                 token.ScopeProductsRead,
+	"/product.v1.ProductService/ListCategories":              token.ScopeProductsRead,
+	"/product.v1.ProductService/UpdateCategory":              token.ScopeProductsWrite,
+	"/product.v1.ProductService/DeleteCategory":              token.ScopeProductsWrite,
+}
+
 type grpcServer struct {
 	pb.UnimplementedProductServiceServer
-	svc *service.ProductService
+	svc        *service.ProductService
+	categories *service.CategoryService  // nil → category RPCs are unimplemented
+	inventory  *service.InventoryService // nil → stock and reservation RPCs are unimplemented
+	images     *service.ImageService     // nil → image RPCs are unimplemented, products carry no images
+}
+
+// Option customises the gRPC server.
+type Option func(*grpcServer)
+
+// WithCategories enables the category RPCs.
+func WithCategories(c *service.CategoryService) Option {
+	return func(s *grpcServer) { s.categories = c }
+}
+
+// WithInventory enables the stock, ledger and reservation RPCs.
+func WithInventory(i *service.InventoryService) Option {
+	return func(s *grpcServer) { s.inventory = i }
+}
+
+// WithImages enables the product image RPCs.
+func WithImages(i *service.ImageService) Option {
+	return func(s *grpcServer) { s.images = i }
 }
 
-func NewGRPCServer(svc *service.ProductService) pb.ProductServiceServer {
-	return &grpcServer{svc: svc}
+func NewGRPCServer(svc *service.ProductService, opts ...Option) pb.ProductServiceServer {
+	s := &grpcServer{svc: svc}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *grpcServer) CreateProduct(ctx context.Context, in *pb.CreateProductRequest) (*pb.CreateProductResponse, error) {
-	p, err := s.svc.Create(ctx, in.Name, in.Description, in.Price)
+	price, err := fromProtoMoney(in.Price)
 	if err != nil {
-		return nil, err
+		return nil, toStatus(err)
+	}
+	p, err := s.svc.Create(ctx, in.Name, in.Description, price)
+	if err != nil {
+		return nil, toStatus(err)
 	}
-	return &pb.CreateProductResponse{Product: toProto(p)}, nil
+	return &pb.CreateProductResponse{Product: s.toProto(p)}, nil
 }
 
 func (s *grpcServer) GetProduct(ctx context.Context, in *pb.GetProductRequest) (*pb.GetProductResponse, error) {
 	p, err := s.svc.Get(ctx, in.Id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	out, err := s.toProtoList(ctx, []model.Product{*p}, in.Currency)
 	if err != nil {
 		return nil, err
 	}
-	return &pb.GetProductResponse{Product: toProto(p)}, nil
+	return &pb.GetProductResponse{Product: out[0]}, nil
 }
 
 func (s *grpcServer) ListProducts(ctx context.Context, in *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
-	list, err := s.svc.List(ctx, int(in.Page), int(in.PageSize))
+	q, err := productQuery(in.CategoryId, in.Filter, in.Sort, in.IncludeFacets)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]*pb.Product, len(list))
-	for i, p := range list {
-		out[i] = toProto(&p)
+	q.PageToken, q.Total = in.PageToken, totalMode(in.TotalCount)
+	l, err := s.svc.Browse(ctx, q, int(in.Page), int(in.PageSize))
+	if err != nil {
+		return nil, toStatus(err)
 	}
-	return &pb.ListProductsResponse{Products: out, Total: int32(len(out))}, nil
+	return s.listResponse(ctx, l, in.Currency)
 }
 
-func (s *grpcServer) UpdateProduct(ctx context.Context, in *pb.UpdateProductRequest) (*pb.UpdateProductResponse, error) {
-	p, err := s.svc.Update(ctx, in.Id, &in.Name, &in.Description, &in.Price)
+func (s *grpcServer) ListMyProducts(ctx context.Context, in *pb.ListMyProductsRequest) (*pb.ListProductsResponse, error) {
+	q := service.ProductQuery{PageToken: in.PageToken, Total: totalMode(in.TotalCount)}
+	l, err := s.svc.ListMine(ctx, q, int(in.Page), int(in.PageSize))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.listResponse(ctx, l, in.Currency)
+}
+
+func (s *grpcServer) SearchProducts(ctx context.Context, in *pb.SearchProductsRequest) (*pb.SearchProductsResponse, error) {
+	if strings.TrimSpace(in.Query) == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	q, err := productQuery(in.CategoryId, in.Filter, in.Sort, in.IncludeFacets)
+	if err != nil {
+		return nil, err
+	}
+	q.Text = in.Query
+	q.PageToken, q.Total = in.PageToken, totalMode(in.TotalCount)
+	l, err := s.svc.Browse(ctx, q, int(in.Page), int(in.PageSize))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	products, err := s.toProtoList(ctx, listingProducts(l), in.Currency)
 	if err != nil {
 		return nil, err
 	}
-	return &pb.UpdateProductResponse{Product: toProto(p)}, nil
+	resp := &pb.SearchProductsResponse{
+		Results:        make([]*pb.SearchResult, len(l.Hits)),
+		Facets:         toProtoFacets(l.Facets),
+		NextPageToken:  l.NextPageToken,
+		TotalSize:      l.Total,
+		TotalEstimated: l.TotalEstimated,
+	}
+	for i, h := range l.Hits {
+		resp.Results[i] = &pb.SearchResult{
+			Product: products[i], Rank: h.Rank, NameHighlight: h.NameHighlight, Snippet: h.Snippet,
+		}
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) UpdateProduct(ctx context.Context, in *pb.UpdateProductRequest) (*pb.UpdateProductResponse, error) {
+	var price *money.Money
+	if in.Price != nil {
+		m, err := fromProtoMoney(in.Price)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		price = &m
+	}
+	p, err := s.svc.Update(ctx, in.Id, &in.Name, &in.Description, price)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.UpdateProductResponse{Product: s.toProto(p)}, nil
 }
 
 func (s *grpcServer) DeleteProduct(ctx context.Context, in *pb.DeleteProductRequest) (*emptypb.Empty, error) {
-	return &emptypb.Empty{}, s.svc.Delete(ctx, in.Id)
+	if err := s.svc.Delete(ctx, in.Id); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// helpers
+
+// toStatus maps service errors to gRPC status codes.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrCategoryNotFound),
+		errors.Is(err, service.ErrVariantNotFound), errors.Is(err, service.ErrReservationNotFound),
+		errors.Is(err, service.ErrImageNotFound), errors.Is(err, service.ErrUploadNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrUnauthenticated):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, service.ErrForbidden), errors.Is(err, service.ErrAdminOnly):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrInvalidPrice), errors.Is(err, service.ErrUnsupportedCurrency),
+		errors.Is(err, service.ErrInvalidCategory), errors.Is(err, service.ErrInvalidVariant),
+		errors.Is(err, service.ErrInvalidStock), errors.Is(err, service.ErrInvalidSearch),
+		errors.Is(err, service.ErrInvalidFilter), errors.Is(err, service.ErrInvalidTags),
+		errors.Is(err, service.ErrInvalidPageToken), errors.Is(err, service.ErrInvalidImage),
+		errors.Is(err, service.ErrImageRejected), errors.Is(err, service.ErrInvalidUpload),
+		errors.Is(err, service.ErrUploadMismatch):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrInsufficientStock), errors.Is(err, service.ErrReservationClosed),
+		errors.Is(err, service.ErrPriceMissing), errors.Is(err, service.ErrTooManyImages),
+		errors.Is(err, service.ErrUploadOffset), errors.Is(err, service.ErrUploadIncomplete):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrSlugTaken), errors.Is(err, service.ErrSKUTaken),
+		errors.Is(err, service.ErrDuplicateVariant), errors.Is(err, service.ErrDuplicateImage),
+		errors.Is(err, service.ErrUploadIDTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrChecksumMismatch):
+		return status.Error(codes.DataLoss, err.Error())
+	case errors.Is(err, service.ErrImageTooLarge):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, service.ErrCategoryNotEmpty):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrRatesUnavailable), errors.Is(err, service.ErrScanUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
+
+// toProtoList converts products, adding display prices when currency is set.
+func (s *grpcServer) toProtoList(ctx context.Context, list []model.Product, currency string) ([]*pb.Product, error) {
+	out := make([]*pb.Product, len(list))
+	for i, p := range list {
+		out[i] = s.toProto(&p)
+	}
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return out, nil
+	}
+	prices, err := s.svc.DisplayPrices(ctx, list, currency)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	for i, p := range prices {
+		rate := &pb.ExchangeRate{FromCurrency: p.Rate.From, ToCurrency: p.Rate.To, Rate: p.Rate.Value}
+		if !p.Rate.AsOf.IsZero() {
+			rate.AsOf = timestamppb.New(p.Rate.AsOf)
+		}
+		out[i].DisplayPrice = toProtoMoney(p.Price)
+		out[i].ExchangeRate = rate
+	}
+	return out, nil
 }
 
-// helper
-func toProto(m *model.Product) *pb.Product {
+func (s *grpcServer) toProto(m *model.Product) *pb.Product {
+	var seller string
+	if m.SellerID != uuid.Nil {
+		seller = m.SellerID.String()
+	}
 	return &pb.Product{
-		Id:          m.ID.String(),
-		Name:        m.Name,
-		Description: m.Description,
-		Price:       m.Price,
-		CreatedAt:   timestamppb.New(m.CreatedAt),
+		Id:           m.ID.String(),
+		Name:         m.Name,
+		Description:  m.Description,
+		Price:        toProtoMoney(m.Price),
+		CreatedAt:    timestamppb.New(m.CreatedAt),
+		SellerId:     seller,
+		CategoryId:   optionalID(m.CategoryID),
+		Options:      toProtoOptions(m.Options),
+		Variants:     toProtoVariants(m.Variants),
+		Stock:        int32(m.Stock),
+		Available:    int32(m.Available()),
+		Tags:         m.Tags,
+		Images:       s.toProtoImages(m.Images),
+		PriceMissing: m.PriceMissing,
+	}
+}
+
+func toProtoMoney(m money.Money) *pb.Money {
+	units, nanos := m.UnitsNanos()
+	return &pb.Money{CurrencyCode: m.Currency, Units: units, Nanos: nanos}
+}
+
+// fromProtoMoney converts a request price; a missing price is invalid.
+func fromProtoMoney(m *pb.Money) (money.Money, error) {
+	if m == nil {
+		return money.Money{}, fmt.Errorf("%w: missing", service.ErrInvalidPrice)
+	}
+	v, err := money.FromUnitsNanos(m.CurrencyCode, m.Units, m.Nanos)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("%w: %w", service.ErrInvalidPrice, err)
 	}
+	return v, nil
 }
@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+
+	pb "github.com/ADRPUR/event-driven-marketplace/api/proto/product/v1"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *grpcServer) SetProductOptions(ctx context.Context, in *pb.SetProductOptionsRequest) (*pb.Product, error) {
+	options := make([]model.Option, len(in.Options))
+	for i, o := range in.Options {
+		options[i] = model.Option{Name: o.Name, Values: o.Values}
+	}
+	p, err := s.svc.SetOptions(ctx, in.Id, options)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.toProto(p), nil
+}
+
+func (s *grpcServer) ListVariants(ctx context.Context, in *pb.ListVariantsRequest) (*pb.ListVariantsResponse, error) {
+	list, err := s.svc.ListVariants(ctx, in.ProductId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ListVariantsResponse{Variants: toProtoVariants(list)}, nil
+}
+
+func (s *grpcServer) CreateVariant(ctx context.Context, in *pb.CreateVariantRequest) (*pb.Variant, error) {
+	price, err := fromProtoMoney(in.Price)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	v, err := s.svc.CreateVariant(ctx, in.ProductId, model.Variant{
+		SKU: in.Sku, Options: in.Options, Price: price, Stock: int(in.Stock),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoVariant(v), nil
+}
+
+func (s *grpcServer) UpdateVariant(ctx context.Context, in *pb.UpdateVariantRequest) (*pb.Variant, error) {
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid id")
+	}
+	price, err := fromProtoMoney(in.Price)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	v, err := s.svc.UpdateVariant(ctx, in.ProductId, id, model.Variant{
+		SKU: in.Sku, Options: in.Options, Price: price,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoVariant(v), nil
+}
+
+func (s *grpcServer) DeleteVariant(ctx context.Context, in *pb.DeleteVariantRequest) (*emptypb.Empty, error) {
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid id")
+	}
+	if err := s.svc.DeleteVariant(ctx, in.ProductId, id); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func toProtoOptions(options []model.Option) []*pb.ProductOption {
+	out := make([]*pb.ProductOption, len(options))
+	for i, o := range options {
+		out[i] = &pb.ProductOption{Name: o.Name, Values: o.Values}
+	}
+	return out
+}
+
+func toProtoVariants(list []model.Variant) []*pb.Variant {
+	out := make([]*pb.Variant, len(list))
+	for i := range list {
+		out[i] = toProtoVariant(&list[i])
+	}
+	return out
+}
+
+func toProtoVariant(v *model.Variant) *pb.Variant {
+	return &pb.Variant{
+		Id:        v.ID.String(),
+		Sku:       v.SKU,
+		Options:   v.Options,
+		Price:     toProtoMoney(v.Price),
+		Stock:     int32(v.Stock),
+		Available: int32(v.Available()),
+		CreatedAt: timestamppb.New(v.CreatedAt),
+	}
+}
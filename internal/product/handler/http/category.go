@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CategoryHandler exposes the product taxonomy.
+type CategoryHandler struct {
+	svc *service.CategoryService
+}
+
+// NewCategories creates a CategoryHandler.
+func NewCategories(svc *service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{svc: svc}
+}
+
+// RegisterRoutes mounts:
+//
+// GET    /categories        → whole tree, parents before children
+// GET    /categories/:id    → one category
+// POST   /categories        → create (admin)
+// PUT    /categories/:id    → rename or move with its subtree (admin)
+// DELETE /categories/:id    → delete a category without subcategories (admin)
+//
+// Products of a category and its descendants are listed with
+// GET /products?category=<id>.
+func (h *CategoryHandler) RegisterRoutes(r *gin.Engine) {
+	read := middleware.RequireScope(token.ScopeProductsRead)
+	admin := middleware.RequireRole(token.RoleAdmin)
+	g := r.Group("/categories")
+	{
+		g.GET("", read, h.list)
+		g.GET(":id", read, h.get)
+		g.POST("", admin, h.create)
+		g.PUT(":id", admin, h.update)
+		g.DELETE(":id", admin, h.delete)
+	}
+}
+
+// categoryReq is the body of POST and PUT. An empty slug is derived from
+// the name; a null parentId makes a root.
+type categoryReq struct {
+	Name     string     `json:"name" binding:"required"`
+	Slug     string     `json:"slug"`
+	ParentID *uuid.UUID `json:"parentId"`
+}
+
+func (h *CategoryHandler) list(c *gin.Context) {
+	list, err := h.svc.List(c)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, len(list))
+	for i := range list {
+		resp[i] = categoryJSON(&list[i])
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *CategoryHandler) get(c *gin.Context) {
+	id, ok := categoryID(c)
+	if !ok {
+		return
+	}
+	cat, err := h.svc.Get(c, id)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, categoryJSON(cat))
+}
+
+func (h *CategoryHandler) create(c *gin.Context) {
+	var req categoryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cat, err := h.svc.Create(c, req.Name, req.Slug, req.ParentID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, categoryJSON(cat))
+}
+
+func (h *CategoryHandler) update(c *gin.Context) {
+	id, ok := categoryID(c)
+	if !ok {
+		return
+	}
+	var req categoryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cat, err := h.svc.Update(c, id, req.Name, req.Slug, req.ParentID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, categoryJSON(cat))
+}
+
+func (h *CategoryHandler) delete(c *gin.Context) {
+	id, ok := categoryID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c, id); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func categoryID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func categoryJSON(m *model.Category) gin.H {
+	return gin.H{
+		"id":        m.ID,
+		"parentId":  m.ParentID,
+		"name":      m.Name,
+		"slug":      m.Slug,
+		"path":      m.Path,
+		"depth":     m.Depth(),
+		"createdAt": m.CreatedAt,
+	}
+}
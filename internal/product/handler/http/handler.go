@@ -9,63 +9,149 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Handler wires the HTTP endpoints to ProductService.
 // Prefer using the RegisterHTTPRoutes helper for readability in main().
 
 type Handler struct {
-	svc *service.ProductService
+	svc       *service.ProductService
+	images    *service.ImageService // nil → no image routes, products carry no images
+	maxUpload int64                 // body limit of POST /products/:id/images; 0 → unlimited
+}
+
+// Option customises a Handler.
+type Option func(*Handler)
+
+// WithImages enables the product image routes.
+func WithImages(i *service.ImageService) Option {
+	return func(h *Handler) { h.images = i }
+}
+
+// WithMaxUploadBytes limits the request body of image uploads to n bytes
+// plus the multipart framing, so oversized uploads are refused while they
+// are read.
+func WithMaxUploadBytes(n int64) Option {
+	return func(h *Handler) { h.maxUpload = n }
 }
 
 // New creates a new HTTP handler.
-func New(svc *service.ProductService) *Handler {
-	return &Handler{svc: svc}
+func New(svc *service.ProductService, opts ...Option) *Handler {
+	h := &Handler{svc: svc}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // RegisterHTTPRoutes is a convenience wrapper used by main.go.
 // It instantiates a Handler and mounts `/products` under the supplied Gin engine.
-func RegisterHTTPRoutes(r *gin.Engine, svc *service.ProductService) {
-	New(svc).RegisterRoutes(r)
+func RegisterHTTPRoutes(r *gin.Engine, svc *service.ProductService, opts ...Option) {
+	New(svc, opts...).RegisterRoutes(r)
 }
 
 // RegisterRoutes attaches all product routes under the provided router group.
 // Example tree:
 //
 // POST   /products          → create product
-// GET    /products/:id      → get product by id
-// GET    /products          → list products (page, pageSize)
-// PUT    /products/:id      → full update product
-// DELETE /products/:id      → delete product
+// GET    /products/:id      → get product by id (currency)
+// GET    /products          → list products (page, pageSize, currency, filters, sort, facets)
+// GET    /products/mine     → list the caller's own products (page, pageSize, currency, pageToken, total)
+// PUT    /products/:id      → full update product (seller or admin)
+// PUT    /products/:id/category → assign or clear the category (seller or admin)
+// PUT    /products/:id/tags     → replace the tags (seller or admin)
+// PUT    /products/:id/options  → replace the variant option definitions (seller or admin)
+// GET    /products/:id/variants → list variants
+// POST   /products/:id/variants → add a variant (seller or admin)
+// PUT    /products/:id/variants/:variantId → replace a variant (seller or admin)
+// DELETE /products/:id/variants/:variantId → delete a variant (seller or admin)
+// POST   /products/:id/images → add an image, multipart field "image" (seller or admin)
+// DELETE /products/:id/images/:imageId → delete an image (seller or admin)
+// DELETE /products/:id      → delete product (seller or admin)
+//
+// With ?currency=XXX every product also carries its price converted into
+// that currency ("displayPrice") and the rate used ("exchangeRate").
+// GET /products filters with:
+//
+//	q=<text>                 full-text search; products also carry "rank" and "highlight"
+//	category=<id>            the category and its descendants
+//	seller=<id>
+//	minPrice, maxPrice       decimal amounts in priceCurrency, which they require
+//	priceCurrency=EUR        products priced in that currency
+//	inStock=true|false
+//	tags=a,b                 any of them
+//	createdAfter, createdBefore  RFC 3339 times or YYYY-MM-DD dates
+//	sort=newest|price_asc|price_desc|name|relevance
+//
+// Both listings answer {"products": [...], "nextPageToken": "..."}, with
+// "facets" when facets=true. They page with pageToken, taken from the
+// nextPageToken of the previous page, which is empty on the last one.
+// Unlike page numbers, tokens neither skip nor repeat products added or
+// removed in between. total=exact or total=estimate adds "total" and
+// "totalEstimated". The token and the total are also sent in the
+// X-Next-Page-Token, X-Total-Count and X-Total-Count-Estimated headers.
+//
+// Tokens issued to OAuth2 clients need products:read for reads and
+// products:write for writes.
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
+	read := middleware.RequireScope(token.ScopeProductsRead)
+	write := middleware.RequireScope(token.ScopeProductsWrite)
 	g := r.Group("/products")
 	{
-		g.POST("", h.create)
-		g.GET(":id", h.get)
-		g.GET("", h.list)
-		g.PUT(":id", h.update)
-		g.DELETE(":id", h.delete)
+		g.POST("", write, h.create)
+		g.GET(":id", read, h.get)
+		g.GET("", read, h.list)
+		g.GET("mine", read, h.listMine)
+		g.PUT(":id", write, h.update)
+		g.PUT(":id/category", write, h.setCategory)
+		g.PUT(":id/tags", write, h.setTags)
+		g.PUT(":id/options", write, h.setOptions)
+		g.GET(":id/variants", read, h.listVariants)
+		g.POST(":id/variants", write, h.createVariant)
+		g.PUT(":id/variants/:variantId", write, h.updateVariant)
+		g.DELETE(":id/variants/:variantId", write, h.deleteVariant)
+		if h.images != nil {
+			g.POST(":id/images", write, h.addImage)
+			g.DELETE(":id/images/:imageId", write, h.deleteImage)
+		}
+		g.DELETE(":id", write, h.delete)
 	}
 }
 
 // ---- request / response DTOs ----
 
+// Prices are {"amount": "19.99", "currency": "EUR"}; see money.Money.
 type createProductReq struct {
-	Name        string  `json:"name" binding:"required,min=2,max=255"`
-	Description string  `json:"description" binding:"max=1024"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
+	Name        string      `json:"name" binding:"required,min=2,max=255"`
+	Description string      `json:"description" binding:"max=1024"`
+	Price       money.Money `json:"price"`
 }
 
 type updateProductReq struct {
-	Name        *string  `json:"name"`
-	Description *string  `json:"description"`
-	Price       *float64 `json:"price"`
+	Name        *string      `json:"name"`
+	Description *string      `json:"description"`
+	Price       *money.Money `json:"price"`
+}
+
+// setCategoryReq assigns a category; null clears it.
+type setCategoryReq struct {
+	CategoryID *uuid.UUID `json:"categoryId"`
+}
+
+type setTagsReq struct {
+	Tags []string `json:"tags"`
 }
 
 // ---- handlers ----
@@ -79,48 +165,179 @@ func (h *Handler) create(c *gin.Context) {
 
 	prod, err := h.svc.Create(c, req.Name, req.Description, req.Price)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, toJSON(prod))
+	c.JSON(http.StatusCreated, h.toJSON(prod))
 }
 
 func (h *Handler) get(c *gin.Context) {
 	id := c.Param("id")
 	prod, err := h.svc.Get(c, id)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if errors.Is(err, service.ErrNotFound) {
-			status = http.StatusNotFound
-		}
-		c.JSON(status, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, toJSON(prod))
+	resp := []gin.H{h.toJSON(prod)}
+	if !h.addDisplayPrices(c, []model.Product{*prod}, resp) {
+		return
+	}
+	c.JSON(http.StatusOK, resp[0])
 }
 
 func (h *Handler) list(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
-	if page <= 0 {
-		page = 1
+	page, pageSize := pagination(c)
+	q, err := productQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if pageSize <= 0 || pageSize > 100 {
-		pageSize = 20
+	l, err := h.svc.Browse(c, q, page, pageSize)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	products := make([]model.Product, len(l.Hits))
+	resp := make([]gin.H, len(l.Hits))
+	for i := range l.Hits {
+		products[i] = l.Hits[i].Product
+		resp[i] = h.toJSON(&l.Hits[i].Product)
+		if q.Text != "" {
+			resp[i]["rank"] = l.Hits[i].Rank
+			resp[i]["highlight"] = gin.H{"name": l.Hits[i].NameHighlight, "description": l.Hits[i].Snippet}
+		}
 	}
+	if !h.addDisplayPrices(c, products, resp) {
+		return
+	}
+	body := pageJSON(c, l, resp)
+	if q.Facets {
+		body["facets"] = l.Facets
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// productQuery reads the filters, sort order and facets flag of GET /products.
+func productQuery(c *gin.Context) (service.ProductQuery, error) {
+	q := service.ProductQuery{
+		Text:          c.Query("q"),
+		PriceCurrency: strings.ToUpper(strings.TrimSpace(c.Query("priceCurrency"))),
+		Sort:          c.Query("sort"),
+		Facets:        c.Query("facets") == "true",
+		PageToken:     c.Query("pageToken"),
+		Total:         c.Query("total"),
+	}
+	var err error
+	if q.CategoryID, err = optionalUUID(c, "category"); err != nil {
+		return q, err
+	}
+	if q.SellerID, err = optionalUUID(c, "seller"); err != nil {
+		return q, err
+	}
+	if q.MinPrice, err = optionalPrice(c, "minPrice", q.PriceCurrency); err != nil {
+		return q, err
+	}
+	if q.MaxPrice, err = optionalPrice(c, "maxPrice", q.PriceCurrency); err != nil {
+		return q, err
+	}
+	if raw := c.Query("inStock"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return q, errors.New("inStock must be true or false")
+		}
+		q.InStock = &v
+	}
+	if raw := c.Query("tags"); raw != "" {
+		q.Tags = strings.Split(raw, ",")
+	}
+	if q.CreatedAfter, err = optionalTime(c, "createdAfter"); err != nil {
+		return q, err
+	}
+	if q.CreatedBefore, err = optionalTime(c, "createdBefore"); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+func optionalUUID(c *gin.Context, param string) (*uuid.UUID, error) {
+	raw := c.Query(param)
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s UUID", param)
+	}
+	return &id, nil
+}
 
-	products, err := h.svc.List(c, page, pageSize)
+// optionalPrice reads a decimal amount in currency.
+func optionalPrice(c *gin.Context, param, currency string) (*money.Money, error) {
+	raw := c.Query(param)
+	if raw == "" {
+		return nil, nil
+	}
+	if currency == "" {
+		return nil, fmt.Errorf("%s needs priceCurrency", param)
+	}
+	m, err := money.Parse(raw, currency)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, fmt.Errorf("%s: %w", param, err)
+	}
+	return &m, nil
+}
+
+// optionalTime reads an RFC 3339 time or a YYYY-MM-DD date (midnight UTC).
+func optionalTime(c *gin.Context, param string) (*time.Time, error) {
+	raw := c.Query(param)
+	if raw == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s must be an RFC 3339 time or a YYYY-MM-DD date", param)
+}
+
+func (h *Handler) listMine(c *gin.Context) {
+	page, pageSize := pagination(c)
+	q := service.ProductQuery{PageToken: c.Query("pageToken"), Total: c.Query("total")}
+	l, err := h.svc.ListMine(c, q, page, pageSize)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	products := make([]model.Product, len(l.Hits))
+	for i := range l.Hits {
+		products[i] = l.Hits[i].Product
+	}
+	resp := h.listJSON(products)
+	if !h.addDisplayPrices(c, products, resp) {
 		return
 	}
+	c.JSON(http.StatusOK, pageJSON(c, l, resp))
+}
 
-	resp := make([]gin.H, len(products))
-	for i, p := range products {
-		resp[i] = toJSON(&p)
+// pageJSON wraps a page of products with the token of the next page and
+// the total count, which are repeated in headers for clients that read
+// them there.
+func pageJSON(c *gin.Context, l *service.Listing, products []gin.H) gin.H {
+	body := gin.H{"products": products, "nextPageToken": l.NextPageToken}
+	if l.NextPageToken != "" {
+		c.Header("X-Next-Page-Token", l.NextPageToken)
 	}
-	c.JSON(http.StatusOK, resp)
+	if l.Total != nil {
+		body["total"] = *l.Total
+		body["totalEstimated"] = l.TotalEstimated
+		c.Header("X-Total-Count", strconv.FormatInt(*l.Total, 10))
+		if l.TotalEstimated {
+			c.Header("X-Total-Count-Estimated", "true")
+		}
+	}
+	return body
 }
 
 func (h *Handler) update(c *gin.Context) {
@@ -133,15 +350,39 @@ func (h *Handler) update(c *gin.Context) {
 
 	prod, err := h.svc.Update(c, id, req.Name, req.Description, req.Price)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if errors.Is(err, service.ErrNotFound) {
-			status = http.StatusNotFound
-		}
-		c.JSON(status, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.toJSON(prod))
+}
+
+func (h *Handler) setCategory(c *gin.Context) {
+	var req setCategoryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	prod, err := h.svc.SetCategory(c, c.Param("id"), req.CategoryID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, h.toJSON(prod))
+}
 
-	c.JSON(http.StatusOK, toJSON(prod))
+func (h *Handler) setTags(c *gin.Context) {
+	var req setTagsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	prod, err := h.svc.SetTags(c, c.Param("id"), req.Tags)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.toJSON(prod))
 }
 
 func (h *Handler) delete(c *gin.Context) {
@@ -152,11 +393,7 @@ func (h *Handler) delete(c *gin.Context) {
 	}
 
 	if err := h.svc.Delete(c, id); err != nil {
-		status := http.StatusInternalServerError
-		if errors.Is(err, service.ErrNotFound) {
-			status = http.StatusNotFound
-		}
-		c.JSON(status, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -164,12 +401,113 @@ func (h *Handler) delete(c *gin.Context) {
 
 // ---- helpers ----
 
-func toJSON(m *model.Product) gin.H {
+// pagination reads page (default 1) and pageSize (default 20, at most 100).
+func pagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrCategoryNotFound),
+		errors.Is(err, service.ErrVariantNotFound), errors.Is(err, service.ErrImageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrForbidden), errors.Is(err, service.ErrAdminOnly):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidPrice), errors.Is(err, service.ErrUnsupportedCurrency),
+		errors.Is(err, service.ErrInvalidCategory), errors.Is(err, service.ErrInvalidVariant),
+		errors.Is(err, service.ErrInvalidSearch), errors.Is(err, service.ErrInvalidFilter),
+		errors.Is(err, service.ErrInvalidTags), errors.Is(err, service.ErrInvalidPageToken):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrSlugTaken), errors.Is(err, service.ErrCategoryNotEmpty),
+		errors.Is(err, service.ErrSKUTaken), errors.Is(err, service.ErrDuplicateVariant),
+		errors.Is(err, service.ErrDuplicateImage), errors.Is(err, service.ErrTooManyImages):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidImage):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, service.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrImageRejected):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrRatesUnavailable), errors.Is(err, service.ErrScanUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// addDisplayPrices adds displayPrice and exchangeRate to the JSON of each
+// product when ?currency= is set. It writes the error response and returns
+// false when the prices cannot be converted.
+func (h *Handler) addDisplayPrices(c *gin.Context, products []model.Product, resp []gin.H) bool {
+	currency := strings.ToUpper(strings.TrimSpace(c.Query("currency")))
+	if currency == "" {
+		return true
+	}
+	prices, err := h.svc.DisplayPrices(c, products, currency)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return false
+	}
+	for i, p := range prices {
+		var asOf any // null when the price was already in that currency
+		if !p.Rate.AsOf.IsZero() {
+			asOf = p.Rate.AsOf
+		}
+		resp[i]["displayPrice"] = p.Price
+		resp[i]["exchangeRate"] = gin.H{
+			"from": p.Rate.From,
+			"to":   p.Rate.To,
+			"rate": p.Rate.Value,
+			"asOf": asOf,
+		}
+	}
+	return true
+}
+
+func tagsJSON(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+func (h *Handler) listJSON(products []model.Product) []gin.H {
+	resp := make([]gin.H, len(products))
+	for i, p := range products {
+		resp[i] = h.toJSON(&p)
+	}
+	return resp
+}
+
+func (h *Handler) toJSON(m *model.Product) gin.H {
+	var seller any // null for products listed before ownership existed
+	if m.SellerID != uuid.Nil {
+		seller = m.SellerID
+	}
 	return gin.H{
-		"id":          m.ID,
-		"name":        m.Name,
-		"description": m.Description,
-		"price":       m.Price,
-		"createdAt":   m.CreatedAt,
+		"id":           m.ID,
+		"name":         m.Name,
+		"description":  m.Description,
+		"price":        m.Price,
+		"priceMissing": m.PriceMissing, // set a price before it can be reserved
+		"sellerId":     seller,
+		"categoryId":   m.CategoryID, // null when uncategorised
+		"tags":         tagsJSON(m.Tags),
+		"options":      optionsJSON(m.Options),
+		"variants":     variantsJSON(m.Variants),
+		"images":       h.imagesJSON(m.Images),
+		"stock":        m.Stock, // unused when the product has variants
+		"reserved":     m.Reserved,
+		"available":    m.Available(),
+		"createdAt":    m.CreatedAt,
 	}
 }
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// multipartOverhead is allowed on top of the image for boundaries, part
+// headers and other form fields.
+const multipartOverhead = 64 << 10
+
+// addImage reads the "image" file of a multipart form. Large images are
+// better sent over gRPC with the resumable UploadProductImage stream.
+func (h *Handler) addImage(c *gin.Context) {
+	if h.maxUpload > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUpload+multipartOverhead)
+	}
+	part, err := formPart(c, "image")
+	if bodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrImageTooLarge.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file required"})
+		return
+	}
+	defer part.Close()
+	// The format is detected from the content, not from the file name.
+	img, err := h.images.Add(c, c.Param("id"), part)
+	if err != nil {
+		if bodyTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrImageTooLarge.Error()})
+			return
+		}
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, h.imageJSON(img))
+}
+
+func (h *Handler) deleteImage(c *gin.Context) {
+	imageID, err := uuid.Parse(c.Param("imageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid image id"})
+		return
+	}
+	if err := h.images.Delete(c, c.Param("id"), imageID); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// formPart returns the file part called name without buffering the form.
+func formPart(c *gin.Context, name string) (*multipart.Part, error) {
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == name && part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// bodyTooLarge reports whether err comes from an http.MaxBytesReader.
+func bodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+// imagesJSON lists images with signed URLs; without image storage
+// products carry none.
+func (h *Handler) imagesJSON(list []model.Image) []gin.H {
+	resp := []gin.H{}
+	if h.images == nil {
+		return resp
+	}
+	for i := range list {
+		resp = append(resp, h.imageJSON(&list[i]))
+	}
+	return resp
+}
+
+func (h *Handler) imageJSON(img *model.Image) gin.H {
+	return gin.H{
+		"id":           img.ID,
+		"url":          h.images.URL(img.Key),
+		"thumbnailUrl": h.images.URL(img.ThumbnailKey),
+		"position":     img.Position,
+		"createdAt":    img.CreatedAt,
+	}
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/middleware"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// InventoryHandler exposes stock levels and reservations.
+type InventoryHandler struct {
+	svc *service.InventoryService
+}
+
+// NewInventory creates an InventoryHandler.
+func NewInventory(svc *service.InventoryService) *InventoryHandler {
+	return &InventoryHandler{svc: svc}
+}
+
+// RegisterRoutes mounts:
+//
+// PUT  /products/:id/stock                         → set stock of a product without variants after a count (seller or admin)
+// PUT  /products/:id/variants/:variantId/stock     → set stock of a variant after a count (seller or admin)
+// GET  /products/:id/movements                     → stock ledger of a product without variants (seller or admin)
+// POST /products/:id/movements                     → record goods received, returned or an adjustment (seller or admin)
+// GET  /products/:id/variants/:variantId/movements → stock ledger of a variant (seller or admin)
+// POST /products/:id/variants/:variantId/movements → record a movement of a variant (seller or admin)
+// GET  /skus/:sku/movements                        → stock ledger of the variant with that SKU (seller or admin)
+// POST /reservations                               → reserve items, all or nothing
+// GET  /reservations/:id                           → reservation status
+// POST /reservations/:id/commit                    → turn a pending reservation into a sale
+// POST /reservations/:id/release                   → cancel a pending reservation
+//
+// Reservations can be read and closed by whoever made them, admins and
+// service principals.
+func (h *InventoryHandler) RegisterRoutes(r *gin.Engine) {
+	read := middleware.RequireScope(token.ScopeProductsRead)
+	write := middleware.RequireScope(token.ScopeProductsWrite)
+	r.PUT("/products/:id/stock", write, h.setStock)
+	r.PUT("/products/:id/variants/:variantId/stock", write, h.setStock)
+	r.GET("/products/:id/movements", read, h.movements)
+	r.POST("/products/:id/movements", write, h.recordMovement)
+	r.GET("/products/:id/variants/:variantId/movements", read, h.movements)
+	r.POST("/products/:id/variants/:variantId/movements", write, h.recordMovement)
+	r.GET("/skus/:sku/movements", read, h.skuMovements)
+	g := r.Group("/reservations")
+	{
+		g.POST("", write, h.reserve)
+		g.GET(":id", read, h.get)
+		g.POST(":id/commit", write, h.commit)
+		g.POST(":id/release", write, h.release)
+	}
+}
+
+type setStockReq struct {
+	Stock  *int   `json:"stock" binding:"required"`
+	Reason string `json:"reason"` // defaults to "stock count"
+}
+
+// movementReq records goods received or returned (quantity > 0), or an
+// adjustment (quantity ≠ 0, with a reason).
+type movementReq struct {
+	Kind     string `json:"kind" binding:"required"`
+	Quantity int    `json:"quantity"`
+	Reason   string `json:"reason"`
+}
+
+type reserveReq struct {
+	Items []struct {
+		ProductID uuid.UUID  `json:"productId" binding:"required"`
+		VariantID *uuid.UUID `json:"variantId"`
+		Quantity  int        `json:"quantity" binding:"required"`
+	} `json:"items" binding:"required"`
+	TTLSeconds int `json:"ttlSeconds"` // 0 → server default
+}
+
+func (h *InventoryHandler) setStock(c *gin.Context) {
+	variantID, ok := variantParam(c)
+	if !ok {
+		return
+	}
+	var req setStockReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.SetStock(c, c.Param("id"), variantID, *req.Stock, req.Reason); err != nil {
+		c.JSON(inventoryStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *InventoryHandler) recordMovement(c *gin.Context) {
+	variantID, ok := variantParam(c)
+	if !ok {
+		return
+	}
+	var req movementReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m, err := h.svc.RecordMovement(c, c.Param("id"), variantID, req.Kind, req.Quantity, req.Reason)
+	if err != nil {
+		c.JSON(inventoryStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, movementJSON(m))
+}
+
+func (h *InventoryHandler) movements(c *gin.Context) {
+	variantID, ok := variantParam(c)
+	if !ok {
+		return
+	}
+	page, pageSize := pagination(c)
+	hist, err := h.svc.Movements(c, c.Param("id"), variantID, page, pageSize)
+	if err != nil {
+		c.JSON(inventoryStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, historyJSON(hist))
+}
+
+func (h *InventoryHandler) skuMovements(c *gin.Context) {
+	page, pageSize := pagination(c)
+	hist, err := h.svc.SKUMovements(c, c.Param("sku"), page, pageSize)
+	if err != nil {
+		c.JSON(inventoryStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, historyJSON(hist))
+}
+
+// variantParam parses the optional :variantId path parameter. It writes the
+// error response and returns false when it is malformed.
+func variantParam(c *gin.Context) (*uuid.UUID, bool) {
+	raw := c.Param("variantId")
+	if raw == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant UUID"})
+		return nil, false
+	}
+	return &id, true
+}
+
+func (h *InventoryHandler) reserve(c *gin.Context) {
+	var req reserveReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items := make([]model.ReservationItem, len(req.Items))
+	for i, it := range req.Items {
+		items[i] = model.ReservationItem{ProductID: it.ProductID, VariantID: it.VariantID, Quantity: it.Quantity}
+	}
+	res, err := h.svc.Reserve(c, items, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		c.JSON(inventoryStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, reservationJSON(res))
+}
+
+func (h *InventoryHandler) get(c *gin.Context) {
+	h.reservation(c, h.svc.Get)
+}
+
+func (h *InventoryHandler) commit(c *gin.Context) {
+	h.reservation(c, h.svc.Commit)
+}
+
+func (h *InventoryHandler) release(c *gin.Context) {
+	h.reservation(c, h.svc.Release)
+}
+
+// reservation runs op on the reservation in the path and writes the result.
+func (h *InventoryHandler) reservation(c *gin.Context, op func(ctx context.Context, id uuid.UUID) (*model.Reservation, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+		return
+	}
+	res, err := op(c, id)
+	if err != nil {
+		c.JSON(inventoryStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reservationJSON(res))
+}
+
+func inventoryStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrReservationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInsufficientStock), errors.Is(err, service.ErrReservationClosed),
+		errors.Is(err, service.ErrPriceMissing):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidStock):
+		return http.StatusBadRequest
+	}
+	return errorStatus(err)
+}
+
+func reservationJSON(r *model.Reservation) gin.H {
+	items := make([]gin.H, len(r.Items))
+	for i, it := range r.Items {
+		items[i] = gin.H{"productId": it.ProductID, "variantId": it.VariantID, "quantity": it.Quantity}
+	}
+	return gin.H{
+		"id":        r.ID,
+		"status":    r.Status,
+		"items":     items,
+		"expiresAt": r.ExpiresAt,
+		"createdAt": r.CreatedAt,
+	}
+}
+
+// historyJSON writes the ledger page with the stock projected from it.
+func historyJSON(h *service.StockHistory) gin.H {
+	list := make([]gin.H, len(h.Movements))
+	for i := range h.Movements {
+		list[i] = movementJSON(&h.Movements[i])
+	}
+	return gin.H{
+		"productId":  h.Level.ProductID,
+		"variantId":  h.Level.VariantID,
+		"stock":      h.Level.Stock,
+		"reserved":   h.Level.Reserved,
+		"available":  h.Level.Available(),
+		"movementId": h.Level.MovementID, // last entry included
+		"movements":  list,
+	}
+}
+
+func movementJSON(m *model.StockMovement) gin.H {
+	return gin.H{
+		"id":            m.ID,
+		"productId":     m.ProductID,
+		"variantId":     m.VariantID,
+		"sku":           m.SKU,
+		"kind":          m.Kind,
+		"stockDelta":    m.StockDelta,
+		"reservedDelta": m.ReservedDelta,
+		"stockAfter":    m.StockAfter,
+		"reservedAfter": m.ReservedAfter,
+		"reservationId": m.ReservationID,
+		"actorId":       m.ActorID,
+		"reason":        m.Reason,
+		"createdAt":     m.CreatedAt,
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// setOptionsReq replaces the option definitions:
+// {"options": [{"name": "size", "values": ["S", "M", "L"]}]}.
+type setOptionsReq struct {
+	Options []model.Option `json:"options"`
+}
+
+// variantReq is the body of POST and PUT; options picks one value of each
+// product option, e.g. {"size": "M"}. Stock is the initial stock and is
+// ignored by PUT; later changes go through PUT .../stock.
+type variantReq struct {
+	SKU     string            `json:"sku" binding:"required"`
+	Options map[string]string `json:"options"`
+	Price   money.Money       `json:"price"`
+	Stock   int               `json:"stock"`
+}
+
+func (r variantReq) model() model.Variant {
+	return model.Variant{SKU: r.SKU, Options: r.Options, Price: r.Price, Stock: r.Stock}
+}
+
+func (h *Handler) setOptions(c *gin.Context) {
+	var req setOptionsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	prod, err := h.svc.SetOptions(c, c.Param("id"), req.Options)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.toJSON(prod))
+}
+
+func (h *Handler) listVariants(c *gin.Context) {
+	list, err := h.svc.ListVariants(c, c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, variantsJSON(list))
+}
+
+func (h *Handler) createVariant(c *gin.Context) {
+	var req variantReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	v, err := h.svc.CreateVariant(c, c.Param("id"), req.model())
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, variantJSON(v))
+}
+
+func (h *Handler) updateVariant(c *gin.Context) {
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant UUID"})
+		return
+	}
+	var req variantReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	v, err := h.svc.UpdateVariant(c, c.Param("id"), variantID, req.model())
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, variantJSON(v))
+}
+
+func (h *Handler) deleteVariant(c *gin.Context) {
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant UUID"})
+		return
+	}
+	if err := h.svc.DeleteVariant(c, c.Param("id"), variantID); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func optionsJSON(options []model.Option) []model.Option {
+	if options == nil {
+		return []model.Option{}
+	}
+	return options
+}
+
+func variantsJSON(list []model.Variant) []gin.H {
+	resp := make([]gin.H, len(list))
+	for i := range list {
+		resp[i] = variantJSON(&list[i])
+	}
+	return resp
+}
+
+func variantJSON(v *model.Variant) gin.H {
+	return gin.H{
+		"id":        v.ID,
+		"sku":       v.SKU,
+		"options":   v.Options,
+		"price":     v.Price,
+		"stock":     v.Stock,
+		"reserved":  v.Reserved,
+		"available": v.Available(),
+		"createdAt": v.CreatedAt,
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Category is a node of the product taxonomy. Path is the materialized path
+// of ids from the root down to the category, e.g. "/<root>/<child>/", so
+// the descendants of a category are the rows whose path starts with its own.
+type Category struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	ParentID  *uuid.UUID `gorm:"type:uuid;index" json:"parent_id"` // nil for roots
+	Name      string     `gorm:"not null" json:"name"`
+	Slug      string     `gorm:"not null;uniqueIndex" json:"slug"`
+	Path      string     `gorm:"not null;index" json:"path"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Depth is 0 for roots, 1 for their children and so on.
+func (c *Category) Depth() int {
+	n := 0
+	for _, r := range c.Path {
+		if r == '/' {
+			n++
+		}
+	}
+	return max(n-2, 0)
+}
@@ -0,0 +1,23 @@
+package model
+
+// Facets of product listings.
+const (
+	FacetCategory     = "category"     // category IDs; counts include descendants
+	FacetSeller       = "seller"       // seller IDs
+	FacetTags         = "tags"         // tags
+	FacetAvailability = "availability" // "in_stock" or "out_of_stock"
+	FacetCurrency     = "currency"     // price currencies
+	FacetPrice        = "price"        // price ranges in major units, e.g. "10-50" or "500-"; only with a currency filter
+	FacetCreated      = "created"      // listed within "24h", "7d", "30d" or "365d"
+)
+
+// FacetValue counts the products of a listing with one value of a facet.
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets maps facet names to their values, most frequent first except for
+// ordered buckets. Each facet is counted with every filter applied except
+// its own, so that it shows the alternatives to the current choice.
+type Facets map[string][]FacetValue
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Image is a picture of a product. Key and ThumbnailKey name the processed
+// image and its thumbnail in the blob store; clients only see signed URLs.
+type Image struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	ProductID    uuid.UUID `gorm:"type:uuid;not null" json:"product_id"`
+	Key          string    `gorm:"not null" json:"-"`
+	ThumbnailKey string    `gorm:"not null" json:"-"`        // empty without configured thumbnail sizes
+	Position     int       `gorm:"not null" json:"position"` // display order, 0 first
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (Image) TableName() string { return "product_images" }
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of stock movement.
+const (
+	MovementReceived = "received" // goods arrived: stock up
+	MovementReserved = "reserved" // held for a checkout: reserved up
+	MovementReleased = "released" // hold cancelled or expired: reserved down
+	MovementSold     = "sold"     // hold committed: stock and reserved down
+	MovementAdjusted = "adjusted" // correction after a count, loss or damage
+	MovementReturned = "returned" // goods came back: stock up
+)
+
+// StockMovement is an immutable entry of the inventory ledger. Stock and
+// reserved of a product or variant are the sums of its deltas; the after
+// values record the balance right after the entry.
+type StockMovement struct {
+	ID            int64      `gorm:"primaryKey;autoIncrement" json:"id"` // ledger order
+	ProductID     uuid.UUID  `gorm:"type:uuid;not null" json:"product_id"`
+	VariantID     *uuid.UUID `gorm:"type:uuid" json:"variant_id"`
+	SKU           string     `gorm:"column:sku;not null" json:"sku"` // empty for products without variants
+	Kind          string     `gorm:"not null" json:"kind"`
+	StockDelta    int        `gorm:"not null" json:"stock_delta"`
+	ReservedDelta int        `gorm:"not null" json:"reserved_delta"`
+	StockAfter    int        `gorm:"not null" json:"stock_after"`
+	ReservedAfter int        `gorm:"not null" json:"reserved_after"`
+	ReservationID *uuid.UUID `gorm:"type:uuid" json:"reservation_id"`
+	ActorID       *uuid.UUID `gorm:"type:uuid" json:"actor_id"` // nil for service principals and jobs
+	Reason        string     `gorm:"not null" json:"reason"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (StockMovement) TableName() string { return "stock_movements" }
+
+// StockSnapshot is the projection of the ledger of a product or variant up
+// to and including MovementID.
+type StockSnapshot struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	ProductID  uuid.UUID  `gorm:"type:uuid;not null" json:"product_id"`
+	VariantID  *uuid.UUID `gorm:"type:uuid" json:"variant_id"`
+	MovementID int64      `gorm:"not null" json:"movement_id"`
+	Stock      int        `gorm:"not null" json:"stock"`
+	Reserved   int        `gorm:"not null" json:"reserved"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (StockSnapshot) TableName() string { return "stock_snapshots" }
+
+// StockLevel is the stock of a product or variant as projected from the
+// ledger up to MovementID.
+type StockLevel struct {
+	ProductID  uuid.UUID
+	VariantID  *uuid.UUID
+	MovementID int64
+	Stock      int
+	Reserved   int
+}
+
+// Available is the quantity that can still be reserved.
+func (l StockLevel) Available() int { return l.Stock - l.Reserved }
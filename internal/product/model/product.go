@@ -3,6 +3,7 @@ package model
 import (
 	"time"
 
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -10,15 +11,38 @@ import (
 // Product represents a marketplace item.
 // `gorm` tags map struct fields to table columns.
 type Product struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `gorm:"not null" json:"price"`
-	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID          uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	Name        string      `gorm:"not null" json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+	// PriceMissing marks legacy products whose price was not positive; they
+	// cannot be reserved until a price is set.
+	PriceMissing bool       `gorm:"not null" json:"price_missing"`
+	SellerID     uuid.UUID  `gorm:"type:uuid;index" json:"seller_id"` // user who listed the product; nil for legacy rows
+	CategoryID   *uuid.UUID `gorm:"type:uuid;index" json:"category_id"`
+	Tags         []string   `gorm:"type:jsonb;serializer:json;not null" json:"tags"`    // lowercase
+	Options      []Option   `gorm:"type:jsonb;serializer:json;not null" json:"options"` // what variants differ in
+	Variants     []Variant  `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"variants"`
+	Images       []Image    `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"images"`
+	Stock        int        `gorm:"not null" json:"stock"`    // on hand; unused when there are variants
+	Reserved     int        `gorm:"not null" json:"reserved"` // held by pending reservations
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	// SearchConfig is the text search configuration search_vector is built
+	// with. It is set when the product is created and never read back.
+	SearchConfig string `gorm:"type:regconfig;<-:create;->:false" json:"-"`
 }
 
+// Available is the quantity that can still be reserved.
+func (p *Product) Available() int { return p.Stock - p.Reserved }
+
 // BeforeCreate generates a UUID before inserting the record.
 func (p *Product) BeforeCreate(tx *gorm.DB) (err error) {
 	p.ID = uuid.New()
+	if p.Options == nil {
+		p.Options = []Option{} // stored as [], not null
+	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
 	return
 }
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reservation statuses. Only pending reservations hold stock.
+const (
+	ReservationPending   = "pending"
+	ReservationCommitted = "committed" // sold: the stock left the warehouse
+	ReservationReleased  = "released"  // cancelled by the caller
+	ReservationExpired   = "expired"   // released after ExpiresAt
+)
+
+// Reservation holds stock for a checkout until it is committed, released
+// or expires.
+type Reservation struct {
+	ID                uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"`
+	Status            string            `gorm:"not null" json:"status"`
+	ReservedBy        *uuid.UUID        `gorm:"type:uuid" json:"reserved_by"`  // nil for service principals
+	ReservedByService *string           `json:"reserved_by_service,omitempty"` // SAN of the service principal; nil for users
+	ExpiresAt         time.Time         `gorm:"not null" json:"expires_at"`
+	Items             []ReservationItem `gorm:"foreignKey:ReservationID;constraint:OnDelete:CASCADE" json:"items"`
+	CreatedAt         time.Time         `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time         `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Reservation) TableName() string { return "stock_reservations" }
+
+// ReservationItem is a quantity of a product, or of one of its variants
+// when the product has variants.
+type ReservationItem struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	ReservationID uuid.UUID  `gorm:"type:uuid;not null;index" json:"reservation_id"`
+	ProductID     uuid.UUID  `gorm:"type:uuid;not null" json:"product_id"`
+	VariantID     *uuid.UUID `gorm:"type:uuid" json:"variant_id"`
+	Quantity      int        `gorm:"not null" json:"quantity"`
+}
+
+func (ReservationItem) TableName() string { return "stock_reservation_items" }
+
+// StockChange describes a change of the quantity available to reserve
+// (stock − reserved) of a product or variant.
+type StockChange struct {
+	ProductID uuid.UUID
+	VariantID *uuid.UUID
+	SKU       string // empty for products without variants
+	Before    int
+	After     int
+}
@@ -0,0 +1,12 @@
+package model
+
+// SearchHit is a product matching a full-text search.
+type SearchHit struct {
+	Product
+	Rank float64 // higher is more relevant
+	// NameHighlight is the HTML-escaped name with matches wrapped in
+	// <mark>…</mark>; Snippet is the same for the best fragments of the
+	// description.
+	NameHighlight string
+	Snippet       string
+}
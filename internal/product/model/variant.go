@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/google/uuid"
+)
+
+// Option is a dimension the variants of a product differ in, such as
+// "size" with the values S, M and L.
+type Option struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// Variant is a purchasable version of a product with its own SKU, price
+// and stock. Options picks one value of each of the product's options,
+// e.g. {"size": "M", "color": "red"}; the combination is unique per product.
+type Variant struct {
+	ID        uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"`
+	ProductID uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:product_variants_options_key,priority:1" json:"product_id"`
+	SKU       string            `gorm:"column:sku;not null;uniqueIndex:product_variants_sku_key" json:"sku"`
+	Options   map[string]string `gorm:"type:jsonb;serializer:json;not null;uniqueIndex:product_variants_options_key,priority:2" json:"options"`
+	Price     money.Money       `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+	Stock     int               `gorm:"not null;default:0" json:"stock"`
+	Reserved  int               `gorm:"not null;default:0" json:"reserved"` // held by pending reservations
+	CreatedAt time.Time         `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Available is the quantity that can still be reserved.
+func (v *Variant) Available() int { return v.Stock - v.Reserved }
+
+func (Variant) TableName() string { return "product_variants" }
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrCategoryNotFound is returned when a category cannot be located.
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrSlugTaken is returned when another category already uses the slug.
+	ErrSlugTaken = errors.New("category slug already in use")
+	// ErrCategoryNotEmpty is returned when deleting a category that has subcategories.
+	ErrCategoryNotEmpty = errors.New("category has subcategories")
+	// ErrParentNotFound is returned when the parent of a category does not exist.
+	ErrParentNotFound = errors.New("parent category not found")
+	// ErrCategoryCycle is returned when a category would be moved under
+	// itself or one of its descendants.
+	ErrCategoryCycle = errors.New("category cannot be moved under itself or its descendants")
+)
+
+// CategoryRepository persists the category tree.
+type CategoryRepository interface {
+	// CreateCategory and UpdateCategory set c.Path from the parent as read
+	// under a row lock, and fail with ErrParentNotFound or ErrCategoryCycle.
+	CreateCategory(ctx context.Context, c *model.Category) error
+	GetCategory(ctx context.Context, id uuid.UUID) (*model.Category, error)
+	// ListCategories returns the whole tree ordered by path, so every
+	// category comes after its parent.
+	ListCategories(ctx context.Context) ([]model.Category, error)
+	// UpdateCategory saves c. When its path differs from oldPath (the
+	// category moved), the paths of its descendants are rewritten in the
+	// same transaction.
+	UpdateCategory(ctx context.Context, c *model.Category, oldPath string) error
+	DeleteCategory(ctx context.Context, id uuid.UUID) error
+}
+
+// NewGormCategoryRepository returns a CategoryRepository implemented with GORM.
+func NewGormCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &gormRepo{db: db}
+}
+
+func (r *gormRepo) CreateCategory(ctx context.Context, c *model.Category) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := placeUnderParent(tx, c); err != nil {
+			return err
+		}
+		return tx.Create(c).Error
+	})
+	return slugErr(err)
+}
+
+func (r *gormRepo) GetCategory(ctx context.Context, id uuid.UUID) (*model.Category, error) {
+	var c model.Category
+	if err := r.db.WithContext(ctx).First(&c, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *gormRepo) ListCategories(ctx context.Context) ([]model.Category, error) {
+	var list []model.Category
+	if err := r.db.WithContext(ctx).Order("path").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *gormRepo) UpdateCategory(ctx context.Context, c *model.Category, oldPath string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the category and its new parent, always in id order, so that
+		// two moves of one under the other run one after the other and the
+		// second sees the cycle.
+		ids := []uuid.UUID{c.ID}
+		if c.ParentID != nil {
+			ids = append(ids, *c.ParentID)
+		}
+		var locked []model.Category
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("id IN ?", ids).Order("id").Find(&locked).Error; err != nil {
+			return err
+		}
+		if err := placeUnderParent(tx, c); err != nil {
+			return err
+		}
+		res := tx.Model(&model.Category{}).
+			Where("id = ? AND path = ?", c.ID, oldPath).
+			Updates(map[string]any{
+				"parent_id": c.ParentID,
+				"name":      c.Name,
+				"slug":      c.Slug,
+				"path":      c.Path,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// deleted, or moved by someone else since it was read
+			return ErrCategoryNotFound
+		}
+		if c.Path == oldPath {
+			return nil
+		}
+		return tx.Exec(`UPDATE categories SET path = ? || substr(path, ?) WHERE path LIKE ? AND id <> ?`,
+			c.Path, len(oldPath)+1, likePrefix(oldPath), c.ID).Error
+	})
+	return slugErr(err)
+}
+
+func (r *gormRepo) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var children int64
+		if err := tx.Model(&model.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrCategoryNotEmpty
+		}
+		res := tx.Delete(&model.Category{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrCategoryNotFound
+		}
+		return nil
+	})
+}
+
+// placeUnderParent sets the path of c below its parent, read with a share
+// lock so that the parent cannot move before the transaction ends.
+func placeUnderParent(tx *gorm.DB, c *model.Category) error {
+	c.Path = "/" + c.ID.String() + "/"
+	if c.ParentID == nil {
+		return nil
+	}
+	var parent model.Category
+	err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("id", "path").First(&parent, "id = ?", *c.ParentID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrParentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if strings.Contains(parent.Path, "/"+c.ID.String()+"/") {
+		return ErrCategoryCycle
+	}
+	c.Path = parent.Path + c.ID.String() + "/"
+	return nil
+}
+
+// likePrefix returns the LIKE pattern matching strings that start with
+// path. Paths hold only UUIDs and slashes, so nothing needs escaping.
+func likePrefix(path string) string {
+	return path + "%"
+}
+
+// slugErr maps a unique violation, which can only come from the slug, to
+// ErrSlugTaken.
+func slugErr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrSlugTaken
+	}
+	return err
+}
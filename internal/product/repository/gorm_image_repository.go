@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrImageNotFound is returned when an image cannot be located.
+	ErrImageNotFound = errors.New("image not found")
+	// ErrDuplicateImage is returned when the product already has the image.
+	ErrDuplicateImage = errors.New("product already has this image")
+	// ErrTooManyImages is returned when the product has no room for another image.
+	ErrTooManyImages = errors.New("product has too many images")
+)
+
+// ImageRepository persists the pictures of products.
+type ImageRepository interface {
+	// CreateImage appends img to the images of its product, which must
+	// have fewer than limit.
+	CreateImage(ctx context.Context, img *model.Image, limit int) error
+	GetImage(ctx context.Context, productID, id uuid.UUID) (*model.Image, error)
+	DeleteImage(ctx context.Context, productID, id uuid.UUID) error
+}
+
+type gormImageRepo struct {
+	db *gorm.DB
+}
+
+// NewGormImageRepository returns an ImageRepository implemented with GORM.
+func NewGormImageRepository(db *gorm.DB) ImageRepository {
+	return &gormImageRepo{db: db}
+}
+
+// CreateImage locks the product so that concurrent uploads get distinct
+// positions and cannot exceed limit together.
+func (r *gormImageRepo) CreateImage(ctx context.Context, img *model.Image, limit int) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var p model.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			First(&p, "id = ?", img.ProductID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		var stats struct {
+			N    int
+			Next int
+		}
+		if err := tx.Model(&model.Image{}).
+			Select("count(*) AS n, coalesce(max(position) + 1, 0) AS next").
+			Where("product_id = ?", img.ProductID).
+			Scan(&stats).Error; err != nil {
+			return err
+		}
+		if stats.N >= limit {
+			return ErrTooManyImages
+		}
+		img.Position = stats.Next
+		return tx.Create(img).Error
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateImage
+	}
+	return err
+}
+
+func (r *gormImageRepo) GetImage(ctx context.Context, productID, id uuid.UUID) (*model.Image, error) {
+	var img model.Image
+	if err := r.db.WithContext(ctx).First(&img, "id = ? AND product_id = ?", id, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImageNotFound
+		}
+		return nil, err
+	}
+	return &img, nil
+}
+
+func (r *gormImageRepo) DeleteImage(ctx context.Context, productID, id uuid.UUID) error {
+	tx := r.db.WithContext(ctx).Delete(&model.Image{}, "id = ? AND product_id = ?", id, productID)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return ErrImageNotFound
+	}
+	return nil
+}
+
+// imagesInOrder preloads images in display order.
+func imagesInOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
@@ -0,0 +1,470 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInsufficientStock is returned when less is available than requested.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrVariantRequired is returned when stock of a product with variants
+	// is addressed without a variant.
+	ErrVariantRequired = errors.New("product has variants; pick one")
+	// ErrStockBelowReserved is returned when stock would drop below the
+	// quantity held by pending reservations.
+	ErrStockBelowReserved = errors.New("stock cannot be lower than the reserved quantity")
+	// ErrReservationNotFound is returned when a reservation cannot be located.
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrReservationClosed is returned when a reservation is no longer pending.
+	ErrReservationClosed = errors.New("reservation is no longer pending")
+	// ErrReservationExpired is returned when committing a reservation past its expiry.
+	ErrReservationExpired = errors.New("reservation has expired")
+	// ErrPriceMissing is returned when reserving a product flagged as having
+	// no valid price.
+	ErrPriceMissing = errors.New("product has no price")
+)
+
+// StockEvents returns the events to queue for a change of available stock.
+// It is called inside the transaction making the change, so the events are
+// stored atomically with it.
+type StockEvents func(c model.StockChange) ([]events.Event, error)
+
+// InventoryRepository changes stock under row locks. Every change is
+// appended to the stock_movements ledger, and the stock and reserved
+// columns are updated in the same transaction as its projection. Rows are
+// always locked in (product, variant) order so that concurrent reservations
+// cannot deadlock.
+type InventoryRepository interface {
+	// SetStock sets the on-hand quantity of a product without variants, or
+	// of one variant, recording the difference as an adjustment m.
+	SetStock(ctx context.Context, m *model.StockMovement, stock int, onChange StockEvents) error
+	// Move applies the deltas of m and appends it to the ledger.
+	Move(ctx context.Context, m *model.StockMovement, onChange StockEvents) error
+	// Reserve holds the quantities of every item of r, or of none, and
+	// stores r.
+	Reserve(ctx context.Context, r *model.Reservation, onChange StockEvents) error
+	GetReservation(ctx context.Context, id uuid.UUID) (*model.Reservation, error)
+	// CommitReservation turns the held quantities into sales.
+	CommitReservation(ctx context.Context, id uuid.UUID, now time.Time, actor *uuid.UUID) error
+	// ReleaseReservation returns the held quantities and closes the
+	// reservation with status (released or expired).
+	ReleaseReservation(ctx context.Context, id uuid.UUID, status string, actor *uuid.UUID) error
+	// ExpiredReservations lists up to limit pending reservations past their expiry.
+	ExpiredReservations(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
+
+	// ListMovements returns ledger entries of a product without variants,
+	// or of one variant, newest first.
+	ListMovements(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, offset, limit int) ([]model.StockMovement, error)
+	// FindSKU returns the variant using sku, or that last used it if it
+	// was deleted or renamed.
+	FindSKU(ctx context.Context, sku string) (productID uuid.UUID, variantID *uuid.UUID, err error)
+	// ProjectStock computes stock and reserved from the latest snapshot and
+	// the ledger entries after it.
+	ProjectStock(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) (model.StockLevel, error)
+	// SnapshotStock stores snapshots of up to limit products or variants
+	// with entries after their latest snapshot, and returns how many.
+	SnapshotStock(ctx context.Context, limit int) (int, error)
+}
+
+// NewGormInventoryRepository returns an InventoryRepository implemented with GORM.
+func NewGormInventoryRepository(db *gorm.DB) InventoryRepository {
+	return &gormRepo{db: db}
+}
+
+// stockRow is a locked product or variant row.
+type stockRow struct {
+	model    any // *model.Product or *model.Variant, for updates
+	sku      string
+	stock    int
+	reserved int
+}
+
+// lockStock locks the stock row of a product without variants, or of a
+// variant.
+func lockStock(tx *gorm.DB, productID uuid.UUID, variantID *uuid.UUID) (*stockRow, error) {
+	row, err := lockRow(tx, productID, variantID)
+	if err != nil || variantID != nil {
+		return row, err
+	}
+	var variants int64
+	if err := tx.Model(&model.Variant{}).Where("product_id = ?", productID).Count(&variants).Error; err != nil {
+		return nil, err
+	}
+	if variants > 0 {
+		return nil, ErrVariantRequired
+	}
+	return row, nil
+}
+
+// lockRow locks the stock row of a product, or of its variant.
+func lockRow(tx *gorm.DB, productID uuid.UUID, variantID *uuid.UUID) (*stockRow, error) {
+	lock := clause.Locking{Strength: "UPDATE"}
+	if variantID != nil {
+		var v model.Variant
+		err := tx.Clauses(lock).Select("id", "sku", "stock", "reserved").
+			First(&v, "id = ? AND product_id = ?", *variantID, productID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVariantNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &stockRow{model: &model.Variant{ID: v.ID}, sku: v.SKU, stock: v.Stock, reserved: v.Reserved}, nil
+	}
+	var p model.Product
+	err := tx.Clauses(lock).Select("id", "stock", "reserved").First(&p, "id = ?", productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stockRow{model: &model.Product{ID: p.ID}, stock: p.Stock, reserved: p.Reserved}, nil
+}
+
+// record applies the deltas of m to a locked row, updates the projection
+// and appends m to the ledger.
+func record(tx *gorm.DB, row *stockRow, m *model.StockMovement, onChange StockEvents) error {
+	m.SKU = row.sku
+	m.StockAfter, m.ReservedAfter = row.stock+m.StockDelta, row.reserved+m.ReservedDelta
+	if m.ReservedAfter < 0 || m.StockAfter < m.ReservedAfter {
+		return fmt.Errorf("%w (%d reserved)", ErrStockBelowReserved, row.reserved)
+	}
+	if err := tx.Model(row.model).Updates(map[string]any{
+		"stock": m.StockAfter, "reserved": m.ReservedAfter,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(m).Error; err != nil {
+		return err
+	}
+	before, after := row.stock-row.reserved, m.StockAfter-m.ReservedAfter
+	row.stock, row.reserved = m.StockAfter, m.ReservedAfter
+	return addStockEvents(tx, onChange, model.StockChange{
+		ProductID: m.ProductID, VariantID: m.VariantID, SKU: row.sku, Before: before, After: after,
+	})
+}
+
+// addStockEvents queues the events onChange returns for a change.
+func addStockEvents(tx *gorm.DB, onChange StockEvents, c model.StockChange) error {
+	if onChange == nil || c.Before == c.After {
+		return nil
+	}
+	list, err := onChange(c)
+	if err != nil {
+		return err
+	}
+	for _, e := range list {
+		if err := events.Add(tx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *gormRepo) SetStock(ctx context.Context, m *model.StockMovement, stock int, onChange StockEvents) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row, err := lockStock(tx, m.ProductID, m.VariantID)
+		if err != nil {
+			return err
+		}
+		if stock == row.stock {
+			return nil
+		}
+		m.Kind, m.StockDelta, m.ReservedDelta = model.MovementAdjusted, stock-row.stock, 0
+		return record(tx, row, m, onChange)
+	})
+}
+
+func (r *gormRepo) Move(ctx context.Context, m *model.StockMovement, onChange StockEvents) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row, err := lockStock(tx, m.ProductID, m.VariantID)
+		if err != nil {
+			return err
+		}
+		return record(tx, row, m, onChange)
+	})
+}
+
+func (r *gormRepo) Reserve(ctx context.Context, res *model.Reservation, onChange StockEvents) error {
+	items := lockOrder(res.Items)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(res).Error; err != nil {
+			return err
+		}
+		if err := checkPriced(tx, items); err != nil {
+			return err
+		}
+		for _, it := range items {
+			row, err := lockStock(tx, it.ProductID, it.VariantID)
+			if err != nil {
+				return err
+			}
+			if available := row.stock - row.reserved; available < it.Quantity {
+				return fmt.Errorf("%w: %s has %d available", ErrInsufficientStock, itemLabel(it, row.sku), available)
+			}
+			if err := record(tx, row, &model.StockMovement{
+				ProductID: it.ProductID, VariantID: it.VariantID, Kind: model.MovementReserved,
+				ReservedDelta: it.Quantity, ReservationID: &res.ID, ActorID: res.ReservedBy,
+			}, onChange); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkPriced fails with ErrPriceMissing when an item's product has no
+// valid price.
+func checkPriced(tx *gorm.DB, items []model.ReservationItem) error {
+	ids := make([]uuid.UUID, len(items))
+	for i, it := range items {
+		ids[i] = it.ProductID
+	}
+	var missing []uuid.UUID
+	if err := tx.Model(&model.Product{}).Where("id IN ? AND price_missing", ids).Limit(1).Pluck("id", &missing).Error; err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: product %s", ErrPriceMissing, missing[0])
+	}
+	return nil
+}
+
+func (r *gormRepo) GetReservation(ctx context.Context, id uuid.UUID) (*model.Reservation, error) {
+	var res model.Reservation
+	if err := r.db.WithContext(ctx).Preload("Items").First(&res, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReservationNotFound
+		}
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *gormRepo) CommitReservation(ctx context.Context, id uuid.UUID, now time.Time, actor *uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res, err := lockPending(tx, id)
+		if err != nil {
+			return err
+		}
+		if !now.Before(res.ExpiresAt) {
+			return ErrReservationExpired
+		}
+		if err := closeItems(tx, res, func(it model.ReservationItem) *model.StockMovement {
+			return &model.StockMovement{Kind: model.MovementSold, StockDelta: -it.Quantity, ReservedDelta: -it.Quantity, ActorID: actor}
+		}); err != nil {
+			return err
+		}
+		return tx.Model(res).Update("status", model.ReservationCommitted).Error
+	})
+}
+
+func (r *gormRepo) ReleaseReservation(ctx context.Context, id uuid.UUID, status string, actor *uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res, err := lockPending(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := closeItems(tx, res, func(it model.ReservationItem) *model.StockMovement {
+			return &model.StockMovement{Kind: model.MovementReleased, ReservedDelta: -it.Quantity, ActorID: actor, Reason: status}
+		}); err != nil {
+			return err
+		}
+		return tx.Model(res).Update("status", status).Error
+	})
+}
+
+// closeItems records the movement of each item of a reservation. Items
+// whose product or variant was deleted meanwhile have no stock to move.
+func closeItems(tx *gorm.DB, res *model.Reservation, movement func(model.ReservationItem) *model.StockMovement) error {
+	for _, it := range res.Items {
+		row, err := lockRow(tx, it.ProductID, it.VariantID)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrVariantNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m := movement(it)
+		m.ProductID, m.VariantID, m.ReservationID = it.ProductID, it.VariantID, &res.ID
+		if err := record(tx, row, m, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *gormRepo) ExpiredReservations(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).Model(&model.Reservation{}).
+		Where("status = ? AND expires_at <= ?", model.ReservationPending, now).
+		Order("expires_at").Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// lockPending locks a pending reservation and loads its items. The
+// reservation lock serialises commit, release and expiry of the same
+// reservation.
+func lockPending(tx *gorm.DB, id uuid.UUID) (*model.Reservation, error) {
+	var res model.Reservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&res, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.Status != model.ReservationPending {
+		return nil, ErrReservationClosed
+	}
+	if err := tx.Where("reservation_id = ?", id).Find(&res.Items).Error; err != nil {
+		return nil, err
+	}
+	res.Items = lockOrder(res.Items)
+	return &res, nil
+}
+
+// lockOrder returns the items sorted by product, then variant: the order
+// in which their stock rows are locked.
+func lockOrder(items []model.ReservationItem) []model.ReservationItem {
+	items = slices.Clone(items)
+	slices.SortFunc(items, func(a, b model.ReservationItem) int {
+		if c := bytes.Compare(a.ProductID[:], b.ProductID[:]); c != 0 {
+			return c
+		}
+		return bytes.Compare(variantKey(a.VariantID), variantKey(b.VariantID))
+	})
+	return items
+}
+
+func variantKey(id *uuid.UUID) []byte {
+	if id == nil {
+		return nil
+	}
+	return id[:]
+}
+
+func itemLabel(it model.ReservationItem, sku string) string {
+	if sku != "" {
+		return "SKU " + sku
+	}
+	return "product " + it.ProductID.String()
+}
+
+func (r *gormRepo) ListMovements(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, offset, limit int) ([]model.StockMovement, error) {
+	var list []model.StockMovement
+	if err := forStock(r.db.WithContext(ctx), productID, variantID).
+		Order("id DESC").Offset(offset).Limit(limit).
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *gormRepo) FindSKU(ctx context.Context, sku string) (uuid.UUID, *uuid.UUID, error) {
+	db := r.db.WithContext(ctx)
+	var v model.Variant
+	res := db.Select("id", "product_id").Where("sku = ?", sku).Limit(1).Find(&v)
+	if res.Error != nil {
+		return uuid.Nil, nil, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return v.ProductID, &v.ID, nil
+	}
+	var m model.StockMovement
+	res = db.Where("sku = ?", sku).Order("id DESC").Limit(1).Find(&m)
+	if res.Error != nil {
+		return uuid.Nil, nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return uuid.Nil, nil, ErrVariantNotFound
+	}
+	return m.ProductID, m.VariantID, nil
+}
+
+func (r *gormRepo) ProjectStock(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) (model.StockLevel, error) {
+	return projectStock(r.db.WithContext(ctx), productID, variantID)
+}
+
+func (r *gormRepo) SnapshotStock(ctx context.Context, limit int) (int, error) {
+	db := r.db.WithContext(ctx)
+	var due []struct {
+		ProductID uuid.UUID
+		VariantID *uuid.UUID
+	}
+	if err := db.Raw(`SELECT m.product_id, m.variant_id FROM stock_movements m
+		GROUP BY m.product_id, m.variant_id
+		HAVING MAX(m.id) > COALESCE((SELECT MAX(s.movement_id) FROM stock_snapshots s
+			WHERE s.product_id = m.product_id AND s.variant_id IS NOT DISTINCT FROM m.variant_id), 0)
+		LIMIT ?`, limit).Scan(&due).Error; err != nil {
+		return 0, err
+	}
+	for i, d := range due {
+		level, err := projectStock(db, d.ProductID, d.VariantID)
+		if err != nil {
+			return i, err
+		}
+		if err := db.Create(&model.StockSnapshot{
+			ID: uuid.New(), ProductID: d.ProductID, VariantID: d.VariantID,
+			MovementID: level.MovementID, Stock: level.Stock, Reserved: level.Reserved,
+		}).Error; err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+// projectStock adds the ledger entries after the latest snapshot to it.
+func projectStock(db *gorm.DB, productID uuid.UUID, variantID *uuid.UUID) (model.StockLevel, error) {
+	level := model.StockLevel{ProductID: productID, VariantID: variantID}
+	var snap model.StockSnapshot
+	res := forStock(db, productID, variantID).Order("movement_id DESC").Limit(1).Find(&snap)
+	if res.Error != nil {
+		return level, res.Error
+	}
+	if res.RowsAffected == 1 {
+		level.MovementID, level.Stock, level.Reserved = snap.MovementID, snap.Stock, snap.Reserved
+	}
+	var sum struct {
+		MovementID int64
+		Stock      int
+		Reserved   int
+	}
+	if err := forStock(db.Model(&model.StockMovement{}), productID, variantID).
+		Where("id > ?", level.MovementID).
+		Select("COALESCE(MAX(id), 0) AS movement_id, COALESCE(SUM(stock_delta), 0) AS stock, COALESCE(SUM(reserved_delta), 0) AS reserved").
+		Scan(&sum).Error; err != nil {
+		return level, err
+	}
+	if sum.MovementID > 0 {
+		level.MovementID = sum.MovementID
+	}
+	level.Stock += sum.Stock
+	level.Reserved += sum.Reserved
+	return level, nil
+}
+
+// forStock restricts q to the rows of a product without variants, or of one
+// variant.
+func forStock(q *gorm.DB, productID uuid.UUID, variantID *uuid.UUID) *gorm.DB {
+	q = q.Where("product_id = ?", productID)
+	if variantID == nil {
+		return q.Where("variant_id IS NULL")
+	}
+	return q.Where("variant_id = ?", *variantID)
+}
@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sort orders of product listings. Ties are broken by newest first.
+const (
+	SortNewest    = "newest"
+	SortPriceAsc  = "price_asc"  // by currency, then amount
+	SortPriceDesc = "price_desc" // by currency, then amount
+	SortName      = "name"
+	SortRelevance = "relevance" // needs Filter.Text
+)
+
+// ProductFilter selects products. Zero fields do not filter.
+type ProductFilter struct {
+	Text          string // full-text match, every word as a prefix
+	CategoryPath  string // a category and its descendants
+	SellerID      *uuid.UUID
+	Currency      string     // products priced in this currency
+	MinPrice      *int64     // minor units of Currency, inclusive
+	MaxPrice      *int64     // minor units of Currency, inclusive
+	InStock       *bool      // whether any of the product can be reserved
+	Tags          []string   // any of them
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+}
+
+// ListQuery is a filtered and sorted product listing.
+type ListQuery struct {
+	Filter ProductFilter
+	Sort   string  // SortNewest when empty
+	After  *Cursor // continue after this product; ignored by SortRelevance
+}
+
+// Cursor is the sort key of a product. A listing that continues after the
+// last product of a page neither skips nor repeats products when others
+// are added or removed in between.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Currency  string // price sorts
+	Amount    int64  // price sorts
+	Name      string // name sort
+}
+
+// CursorOf returns the sort key of p.
+func CursorOf(p *model.Product) Cursor {
+	return Cursor{CreatedAt: p.CreatedAt, ID: p.ID, Currency: p.Price.Currency, Amount: p.Price.Amount, Name: p.Name}
+}
+
+// availableSQL is true when a product, or one of its variants if it has
+// any, has stock that is not reserved.
+const availableSQL = `(EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = products.id AND v.stock > v.reserved)
+	OR (products.stock > products.reserved AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = products.id)))`
+
+// priceBuckets are the upper bounds, in major units, of the price facet.
+var priceBuckets = []int64{10, 50, 100, 500}
+
+// createdBuckets are the periods of the created facet.
+var createdBuckets = []struct {
+	label string
+	age   time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+	{"365d", 365 * 24 * time.Hour},
+}
+
+func (r *gormRepo) List(ctx context.Context, q ListQuery, offset, limit int) ([]model.Product, error) {
+	var list []model.Product
+	if err := r.orderBy(seek(r.applyFilter(r.db.WithContext(ctx).Model(&model.Product{}), q.Filter), q), q).
+		Preload("Variants", variantsInOrder).
+		Preload("Images", imagesInOrder).
+		Offset(offset).
+		Limit(limit).
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// applyFilter adds the conditions of f to q, a query over products.
+func (r *gormRepo) applyFilter(q *gorm.DB, f ProductFilter) *gorm.DB {
+	if f.Text != "" {
+		if tsq := prefixQuery(f.Text); tsq != "" {
+			q = q.Where("products.search_vector @@ to_tsquery(?::regconfig, ?)", r.searchConfig, tsq)
+		} else {
+			q = q.Where("false") // nothing searchable
+		}
+	}
+	if f.CategoryPath != "" {
+		q = q.Where("products.category_id IN (SELECT id FROM categories WHERE path LIKE ?)", likePrefix(f.CategoryPath))
+	}
+	if f.SellerID != nil {
+		q = q.Where("products.seller_id = ?", *f.SellerID)
+	}
+	if f.Currency != "" {
+		q = q.Where("products.price_currency = ?", f.Currency)
+	}
+	if f.MinPrice != nil {
+		q = q.Where("products.price_amount >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		q = q.Where("products.price_amount <= ?", *f.MaxPrice)
+	}
+	if f.InStock != nil {
+		if *f.InStock {
+			q = q.Where(availableSQL)
+		} else {
+			q = q.Where("NOT " + availableSQL)
+		}
+	}
+	if len(f.Tags) > 0 {
+		conds := make([]string, len(f.Tags))
+		args := make([]any, len(f.Tags))
+		for i, t := range f.Tags {
+			b, _ := json.Marshal([]string{t})
+			conds[i], args[i] = "products.tags @> ?::jsonb", string(b)
+		}
+		q = q.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+	if f.CreatedAfter != nil {
+		q = q.Where("products.created_at >= ?", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		q = q.Where("products.created_at < ?", *f.CreatedBefore)
+	}
+	return q
+}
+
+// seek restricts q, a query over products, to those after lq.After in
+// lq.Sort order. The conditions mirror orderBy.
+func seek(q *gorm.DB, lq ListQuery) *gorm.DB {
+	a := lq.After
+	if a == nil || lq.Sort == SortRelevance {
+		return q
+	}
+	const tie = "(products.created_at, products.id) < (?, ?)"
+	switch lq.Sort {
+	case SortPriceAsc, SortPriceDesc:
+		cmp := ">"
+		if lq.Sort == SortPriceDesc {
+			cmp = "<"
+		}
+		return q.Where("(products.price_currency > ? OR (products.price_currency = ? AND (products.price_amount "+cmp+" ? OR (products.price_amount = ? AND "+tie+"))))",
+			a.Currency, a.Currency, a.Amount, a.Amount, a.CreatedAt, a.ID)
+	case SortName:
+		return q.Where("(lower(products.name) > lower(?) OR (lower(products.name) = lower(?) AND "+tie+"))",
+			a.Name, a.Name, a.CreatedAt, a.ID)
+	}
+	return q.Where(tie, a.CreatedAt, a.ID)
+}
+
+func (r *gormRepo) Count(ctx context.Context, f ProductFilter) (int64, error) {
+	var n int64
+	if err := r.applyFilter(r.db.WithContext(ctx).Model(&model.Product{}), f).Count(&n).Error; err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (r *gormRepo) EstimateCount(ctx context.Context, f ProductFilter) (int64, error) {
+	stmt := r.applyFilter(r.db.Session(&gorm.Session{DryRun: true}).Model(&model.Product{}), f).
+		Select("products.id").
+		Find(&[]model.Product{}).Statement
+	var plan []byte
+	// The statement is already bound with $n placeholders, which Raw would
+	// not pass through, so it goes to the connection directly.
+	if err := r.db.Statement.ConnPool.
+		QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).
+		Scan(&plan); err != nil {
+		return 0, err
+	}
+	var out []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		}
+	}
+	if err := json.Unmarshal(plan, &out); err != nil || len(out) == 0 {
+		return 0, fmt.Errorf("unexpected query plan: %s", plan)
+	}
+	return int64(out[0].Plan.Rows), nil
+}
+
+// orderBy sorts q, a query over products, in lq.Sort order.
+func (r *gormRepo) orderBy(q *gorm.DB, lq ListQuery) *gorm.DB {
+	switch lq.Sort {
+	case SortPriceAsc:
+		return q.Order("products.price_currency, products.price_amount, products.created_at DESC, products.id DESC")
+	case SortPriceDesc:
+		return q.Order("products.price_currency, products.price_amount DESC, products.created_at DESC, products.id DESC")
+	case SortName:
+		return q.Order("lower(products.name), products.created_at DESC, products.id DESC")
+	case SortRelevance:
+		return q.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "ts_rank_cd(products.search_vector, to_tsquery(?::regconfig, ?)) DESC, products.created_at DESC, products.id DESC",
+			Vars:               []any{r.searchConfig, prefixQuery(lq.Filter.Text)},
+			WithoutParentheses: true,
+		}})
+	}
+	return q.Order("products.created_at DESC, products.id DESC")
+}
+
+// facetRow is one value of a facet.
+type facetRow struct {
+	Value string
+	Count int64
+}
+
+func (r *gormRepo) Facets(ctx context.Context, f ProductFilter) (model.Facets, error) {
+	db := r.db.WithContext(ctx)
+	products := func(f ProductFilter) *gorm.DB {
+		return r.applyFilter(db.Model(&model.Product{}), f)
+	}
+	facets := model.Facets{}
+	scan := func(name string, q *gorm.DB) error {
+		var rows []facetRow
+		if err := q.Scan(&rows).Error; err != nil {
+			return err
+		}
+		values := make([]model.FacetValue, len(rows))
+		for i, row := range rows {
+			values[i] = model.FacetValue{Value: row.Value, Count: row.Count}
+		}
+		facets[name] = values
+		return nil
+	}
+
+	g := f
+	g.CategoryPath = ""
+	if err := scan(model.FacetCategory, products(g).
+		Joins("JOIN categories c ON c.id = products.category_id").
+		Joins("JOIN categories a ON c.path LIKE a.path || '%'").
+		Select("a.id::text AS value, count(*) AS count").
+		Group("a.id").Order("count DESC, value").Limit(100)); err != nil {
+		return nil, err
+	}
+
+	g = f
+	g.SellerID = nil
+	if err := scan(model.FacetSeller, products(g).
+		Where("products.seller_id <> ?", uuid.Nil).
+		Select("products.seller_id::text AS value, count(*) AS count").
+		Group("products.seller_id").Order("count DESC, value").Limit(20)); err != nil {
+		return nil, err
+	}
+
+	g = f
+	g.Tags = nil
+	if err := scan(model.FacetTags, products(g).
+		Joins("CROSS JOIN LATERAL jsonb_array_elements_text(products.tags) AS t(tag)").
+		Select("t.tag AS value, count(*) AS count").
+		Group("t.tag").Order("count DESC, value").Limit(30)); err != nil {
+		return nil, err
+	}
+
+	g = f
+	g.InStock = nil
+	if err := scan(model.FacetAvailability, products(g).
+		Select("CASE WHEN "+availableSQL+" THEN 'in_stock' ELSE 'out_of_stock' END AS value, count(*) AS count").
+		Group("value").Order("value")); err != nil {
+		return nil, err
+	}
+
+	g = f
+	g.Currency, g.MinPrice, g.MaxPrice = "", nil, nil
+	if err := scan(model.FacetCurrency, products(g).
+		Select("products.price_currency AS value, count(*) AS count").
+		Group("products.price_currency").Order("count DESC, value")); err != nil {
+		return nil, err
+	}
+
+	if exp := money.Exponent(f.Currency); exp >= 0 && f.Currency != "" {
+		g = f
+		g.MinPrice, g.MaxPrice = nil, nil
+		var (
+			sql  strings.Builder
+			args []any
+		)
+		sql.WriteString("CASE")
+		lower := "0"
+		for _, b := range priceBuckets {
+			upper := strconv.FormatInt(b, 10)
+			sql.WriteString(" WHEN products.price_amount < ? THEN '" + lower + "-" + upper + "'")
+			args = append(args, b*pow10(exp))
+			lower = upper
+		}
+		sql.WriteString(" ELSE '" + lower + "-' END")
+		if err := scan(model.FacetPrice, products(g).
+			Select(sql.String()+" AS value, count(*) AS count", args...).
+			Group("value").Order("min(products.price_amount)")); err != nil {
+			return nil, err
+		}
+	}
+
+	g = f
+	g.CreatedAfter, g.CreatedBefore = nil, nil
+	now := time.Now()
+	cols := make([]string, len(createdBuckets))
+	args := make([]any, len(createdBuckets))
+	for i, b := range createdBuckets {
+		cols[i] = "count(*) FILTER (WHERE products.created_at >= ?)"
+		args[i] = now.Add(-b.age)
+	}
+	counts := make([]int64, len(createdBuckets))
+	row := products(g).Select(strings.Join(cols, ", "), args...).Row()
+	dest := make([]any, len(counts))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	created := make([]model.FacetValue, 0, len(createdBuckets))
+	for i, b := range createdBuckets {
+		if counts[i] > 0 {
+			created = append(created, model.FacetValue{Value: b.label, Count: counts[i]})
+		}
+	}
+	facets[model.FacetCreated] = created
+	return facets, nil
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for range n {
+		p *= 10
+	}
+	return p
+}
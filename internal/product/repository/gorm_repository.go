@@ -7,13 +7,21 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// ErrNotFound is returned when a product cannot be located in the datastore.
-var ErrNotFound = errors.New("product not found")
+var (
+	// ErrNotFound is returned when a product cannot be located in the datastore.
+	ErrNotFound = errors.New("product not found")
+	// ErrCurrencyMismatch is returned when a product and its variants would
+	// be priced in different currencies.
+	ErrCurrencyMismatch = errors.New("product and variants must share a currency")
+)
 
 // Repository abstracts CRUD operations for products.
 // Keeping the interface small keeps the service layer decoupled from the
@@ -21,30 +29,59 @@ var ErrNotFound = errors.New("product not found")
 type Repository interface {
 	Create(ctx context.Context, p *model.Product) error
 	GetByID(ctx context.Context, id string) (*model.Product, error)
-	List(ctx context.Context, offset, limit int) ([]model.Product, error)
+	// List returns a page of the products matching q.Filter in q.Sort order.
+	List(ctx context.Context, q ListQuery, offset, limit int) ([]model.Product, error)
+	// Count returns the number of products matching f.
+	Count(ctx context.Context, f ProductFilter) (int64, error)
+	// EstimateCount returns the query planner's estimate of the number of
+	// products matching f. It scans nothing, but may be far off.
+	EstimateCount(ctx context.Context, f ProductFilter) (int64, error)
+	// Facets counts the products matching f per value of each facet.
+	Facets(ctx context.Context, f ProductFilter) (model.Facets, error)
+	// Update saves p. A change of its price currency fails with
+	// ErrCurrencyMismatch while variants are priced in another currency.
 	Update(ctx context.Context, p *model.Product) error
+	UpdateTags(ctx context.Context, id uuid.UUID, tags []string) error
 	Delete(ctx context.Context, id string) error
+	VariantRepository
+	SearchRepository
 }
 
 // gormRepo is a concrete Repository using *gorm.DB.
 // It purposefully lives in the same package to stay unexported; callers depend
 // on the Repository interface, not the struct.
 type gormRepo struct {
-	db *gorm.DB
+	db           *gorm.DB
+	searchConfig string // text search configuration of new products and queries
+}
+
+// Option configures the GORM repository.
+type Option func(*gormRepo)
+
+// WithSearchConfig sets the PostgreSQL text search configuration, such as
+// "german", that new products are indexed with and searches use. It
+// defaults to "english"; see ValidateSearchConfig.
+func WithSearchConfig(name string) Option {
+	return func(r *gormRepo) { r.searchConfig = name }
 }
 
 // NewGormRepository returns a Repository implemented with GORM.
-func NewGormRepository(db *gorm.DB) Repository {
-	return &gormRepo{db: db}
+func NewGormRepository(db *gorm.DB, opts ...Option) Repository {
+	r := &gormRepo{db: db, searchConfig: "english"}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *gormRepo) Create(ctx context.Context, p *model.Product) error {
+	p.SearchConfig = r.searchConfig
 	return r.db.WithContext(ctx).Create(p).Error
 }
 
 func (r *gormRepo) GetByID(ctx context.Context, id string) (*model.Product, error) {
 	var p model.Product
-	if err := r.db.WithContext(ctx).First(&p, "id = ?", id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Variants", variantsInOrder).Preload("Images", imagesInOrder).First(&p, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotFound
 		}
@@ -53,26 +90,53 @@ func (r *gormRepo) GetByID(ctx context.Context, id string) (*model.Product, erro
 	return &p, nil
 }
 
-func (r *gormRepo) List(ctx context.Context, offset, limit int) ([]model.Product, error) {
-	var list []model.Product
-	if err := r.db.WithContext(ctx).
-		Offset(offset).
-		Limit(limit).
-		Order("created_at DESC").
-		Find(&list).Error; err != nil {
-		return nil, err
+func (r *gormRepo) Update(ctx context.Context, p *model.Product) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Variant writes share-lock the product (see checkCurrency), so they
+		// wait for this lock and the count below sees all committed ones.
+		currency, err := lockCurrency(tx, p.ID, "UPDATE")
+		if err != nil {
+			return err
+		}
+		if currency != p.Price.Currency {
+			var others int64
+			if err := tx.Model(&model.Variant{}).
+				Where("product_id = ? AND price_currency <> ?", p.ID, p.Price.Currency).
+				Count(&others).Error; err != nil {
+				return err
+			}
+			if others > 0 {
+				return fmt.Errorf("%w: %d variants are priced in %s", ErrCurrencyMismatch, others, currency)
+			}
+		}
+		return tx.Model(&model.Product{}).
+			Where("id = ?", p.ID).
+			Updates(map[string]any{
+				"name":           p.Name,
+				"description":    p.Description,
+				"price_amount":   p.Price.Amount,
+				"price_currency": p.Price.Currency,
+				"price_missing":  p.PriceMissing,
+				"category_id":    p.CategoryID,
+			}).Error
+	})
+}
+
+// lockCurrency locks the product row with strength ("UPDATE" or "SHARE")
+// and returns its price currency.
+func lockCurrency(tx *gorm.DB, id uuid.UUID, strength string) (string, error) {
+	var p model.Product
+	err := tx.Clauses(clause.Locking{Strength: strength}).Select("id", "price_currency").First(&p, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNotFound
 	}
-	return list, nil
+	return p.Price.Currency, err
 }
 
-func (r *gormRepo) Update(ctx context.Context, p *model.Product) error {
-	tx := r.db.WithContext(ctx).Model(&model.Product{}).
-		Where("id = ?", p.ID).
-		Updates(map[string]any{
-			"name":        p.Name,
-			"description": p.Description,
-			"price":       p.Price,
-		})
+func (r *gormRepo) UpdateTags(ctx context.Context, id uuid.UUID, tags []string) error {
+	tx := r.db.WithContext(ctx).Model(&model.Product{ID: id}).
+		Select("tags").
+		Updates(&model.Product{Tags: tags})
 	if tx.Error != nil {
 		return tx.Error
 	}
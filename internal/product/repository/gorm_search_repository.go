@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ValidateSearchConfig fails when PostgreSQL has no text search
+// configuration called name. Products keep the configuration they were
+// indexed with in products.search_config; after changing it, reindex the
+// older ones with UPDATE products SET search_config = '<name>'.
+func ValidateSearchConfig(ctx context.Context, db *gorm.DB, name string) error {
+	var n int64
+	if err := db.WithContext(ctx).Raw("SELECT count(*) FROM pg_ts_config WHERE cfgname = ?", name).Scan(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("unknown text search configuration %q", name)
+	}
+	return nil
+}
+
+// Matches are marked with control characters so that the text around them
+// can be HTML-escaped before the markers become <mark> tags.
+const (
+	markStart = "\x02"
+	markStop  = "\x03"
+)
+
+var (
+	nameHeadline    = `HighlightAll=true, StartSel="` + markStart + `", StopSel="` + markStop + `"`
+	snippetHeadline = `MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=" … ", StartSel="` + markStart + `", StopSel="` + markStop + `"`
+	markReplacer    = strings.NewReplacer(markStart, "<mark>", markStop, "</mark>")
+)
+
+// SearchRepository runs full-text searches over products.
+type SearchRepository interface {
+	// Search returns a page of the products matching q.Filter, which must
+	// include Text, in q.Sort order, with rank and highlights. It returns no
+	// hits when the text has no searchable words.
+	Search(ctx context.Context, q ListQuery, offset, limit int) ([]model.SearchHit, error)
+}
+
+func (r *gormRepo) Search(ctx context.Context, q ListQuery, offset, limit int) ([]model.SearchHit, error) {
+	tsq := prefixQuery(q.Filter.Text)
+	if tsq == "" {
+		return nil, nil
+	}
+	db := r.db.WithContext(ctx)
+
+	var ranked []struct {
+		ID   uuid.UUID
+		Rank float64
+	}
+	if err := r.orderBy(seek(r.applyFilter(db.Model(&model.Product{}), q.Filter), q), q).
+		Select("products.id, ts_rank_cd(products.search_vector, to_tsquery(?::regconfig, ?)) AS rank", r.searchConfig, tsq).
+		Offset(offset).
+		Limit(limit).
+		Scan(&ranked).Error; err != nil {
+		return nil, err
+	}
+	if len(ranked) == 0 {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, len(ranked))
+	for i, row := range ranked {
+		ids[i] = row.ID
+	}
+
+	// Headlines are costly, so they are built for the page only.
+	var headlines []struct {
+		ID            uuid.UUID
+		NameHighlight string
+		Snippet       string
+	}
+	if err := db.Model(&model.Product{}).
+		Select(`products.id,
+			ts_headline(?::regconfig, products.name, to_tsquery(?::regconfig, ?), ?) AS name_highlight,
+			ts_headline(?::regconfig, coalesce(products.description, ''), to_tsquery(?::regconfig, ?), ?) AS snippet`,
+			r.searchConfig, r.searchConfig, tsq, nameHeadline, r.searchConfig, r.searchConfig, tsq, snippetHeadline).
+		Where("products.id IN ?", ids).
+		Scan(&headlines).Error; err != nil {
+		return nil, err
+	}
+	var list []model.Product
+	if err := db.Preload("Variants", variantsInOrder).Preload("Images", imagesInOrder).Where("id IN ?", ids).Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]*model.SearchHit, len(list))
+	for _, p := range list {
+		byID[p.ID] = &model.SearchHit{Product: p}
+	}
+	for _, h := range headlines {
+		if hit, ok := byID[h.ID]; ok {
+			hit.NameHighlight, hit.Snippet = highlight(h.NameHighlight), highlight(h.Snippet)
+		}
+	}
+	hits := make([]model.SearchHit, 0, len(ranked))
+	for _, row := range ranked {
+		hit, ok := byID[row.ID]
+		if !ok {
+			continue // deleted in between
+		}
+		hit.Rank = row.Rank
+		hits = append(hits, *hit)
+	}
+	return hits, nil
+}
+
+// prefixQuery turns free text into a tsquery matching every word as a
+// prefix: "blue runn" becomes "blue:* & runn:*". Only letters and digits
+// are kept, so the result is always valid tsquery syntax.
+func prefixQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = strings.ToLower(w) + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// highlight HTML-escapes a headline and turns its markers into <mark> tags.
+func highlight(s string) string {
+	return markReplacer.Replace(html.EscapeString(s))
+}
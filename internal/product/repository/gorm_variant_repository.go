@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrVariantNotFound is returned when a variant cannot be located.
+	ErrVariantNotFound = errors.New("variant not found")
+	// ErrSKUTaken is returned when another variant already uses the SKU.
+	ErrSKUTaken = errors.New("SKU already in use")
+	// ErrDuplicateVariant is returned when the product already has a
+	// variant with the same option values.
+	ErrDuplicateVariant = errors.New("variant with these options already exists")
+	// ErrProductReserved is returned when adding the first variant to a
+	// product whose own stock is reserved.
+	ErrProductReserved = errors.New("units of the product are reserved")
+	// ErrVariantReserved is returned when deleting a variant whose stock is
+	// held by pending reservations.
+	ErrVariantReserved = errors.New("units of the variant are reserved")
+)
+
+// VariantRepository persists option definitions and variants. It is part
+// of Repository.
+type VariantRepository interface {
+	UpdateOptions(ctx context.Context, productID uuid.UUID, options []model.Option) error
+	ListVariants(ctx context.Context, productID uuid.UUID) ([]model.Variant, error)
+	CreateVariant(ctx context.Context, v *model.Variant) error
+	UpdateVariant(ctx context.Context, v *model.Variant) error
+	// DeleteVariant fails with ErrVariantReserved while units are reserved.
+	// Remaining stock is written off with an adjustment by actor; so is the
+	// product's own stock when the last variant goes.
+	DeleteVariant(ctx context.Context, productID, id uuid.UUID, actor *uuid.UUID) error
+}
+
+func (r *gormRepo) UpdateOptions(ctx context.Context, productID uuid.UUID, options []model.Option) error {
+	tx := r.db.WithContext(ctx).Model(&model.Product{ID: productID}).
+		Select("options").
+		Updates(&model.Product{Options: options})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormRepo) ListVariants(ctx context.Context, productID uuid.UUID) ([]model.Variant, error) {
+	var list []model.Variant
+	if err := r.db.WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("created_at").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// CreateVariant stores v and records its initial stock as received. It
+// fails with ErrProductReserved while units of the product itself are
+// reserved, since stock is kept per variant from then on.
+func (r *gormRepo) CreateVariant(ctx context.Context, v *model.Variant) error {
+	return variantErr(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkCurrency(tx, v); err != nil {
+			return err
+		}
+		// The product row is share-locked now, so a product-level Reserve
+		// either committed before this read or waits and then finds the
+		// variant.
+		var reserved int
+		if err := tx.Model(&model.Product{}).Select("reserved").Where("id = ?", v.ProductID).Scan(&reserved).Error; err != nil {
+			return err
+		}
+		if reserved > 0 {
+			return fmt.Errorf("%w: %d units", ErrProductReserved, reserved)
+		}
+		if err := tx.Create(v).Error; err != nil {
+			return err
+		}
+		if v.Stock == 0 {
+			return nil
+		}
+		return tx.Create(&model.StockMovement{
+			ProductID: v.ProductID, VariantID: &v.ID, SKU: v.SKU, Kind: model.MovementReceived,
+			StockDelta: v.Stock, StockAfter: v.Stock, Reason: "initial stock",
+		}).Error
+	}))
+}
+
+func (r *gormRepo) UpdateVariant(ctx context.Context, v *model.Variant) error {
+	return variantErr(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkCurrency(tx, v); err != nil {
+			return err
+		}
+		res := tx.Model(&model.Variant{}).
+			Where("id = ? AND product_id = ?", v.ID, v.ProductID).
+			Select("sku", "options", "price_amount", "price_currency").
+			Updates(v)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVariantNotFound
+		}
+		return nil
+	}))
+}
+
+// checkCurrency share-locks the product of v, so that its currency cannot
+// change before the transaction ends, and fails with ErrCurrencyMismatch
+// when v is priced in another currency.
+func checkCurrency(tx *gorm.DB, v *model.Variant) error {
+	currency, err := lockCurrency(tx, v.ProductID, "SHARE")
+	if err != nil {
+		return err
+	}
+	if currency != v.Price.Currency {
+		return fmt.Errorf("%w: the product is priced in %s", ErrCurrencyMismatch, currency)
+	}
+	return nil
+}
+
+func (r *gormRepo) DeleteVariant(ctx context.Context, productID, id uuid.UUID, actor *uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// (product, variant) order, as everywhere; the product lock also
+		// keeps variants from being added while the last one goes.
+		product, err := lockRow(tx, productID, nil)
+		if err != nil {
+			return err
+		}
+		variant, err := lockRow(tx, productID, &id)
+		if err != nil {
+			return err
+		}
+		if variant.reserved > 0 {
+			return fmt.Errorf("%w: %d units", ErrVariantReserved, variant.reserved)
+		}
+		if err := writeOff(tx, variant, &model.StockMovement{ProductID: productID, VariantID: &id, ActorID: actor, Reason: "variant deleted"}); err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.Variant{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		var left int64
+		if err := tx.Model(&model.Variant{}).Where("product_id = ?", productID).Count(&left).Error; err != nil {
+			return err
+		}
+		if left > 0 {
+			return nil
+		}
+		// The product tracks its own stock again; what it held before it
+		// had variants is long out of date.
+		return writeOff(tx, product, &model.StockMovement{ProductID: productID, ActorID: actor, Reason: "last variant deleted"})
+	})
+}
+
+// writeOff records an adjustment m that zeroes the stock of a locked row
+// without reservations.
+func writeOff(tx *gorm.DB, row *stockRow, m *model.StockMovement) error {
+	if row.stock == 0 {
+		return nil
+	}
+	m.Kind, m.StockDelta = model.MovementAdjusted, -row.stock
+	return record(tx, row, m, nil)
+}
+
+// variantsInOrder preloads variants oldest first.
+func variantsInOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at")
+}
+
+// variantErr tells the two unique indexes of product_variants apart.
+func variantErr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		switch pgErr.ConstraintName {
+		case "product_variants_sku_key":
+			return ErrSKUTaken
+		case "product_variants_options_key":
+			return ErrDuplicateVariant
+		}
+	}
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
@@ -13,6 +14,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
 )
 
 func setupDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, func()) {
@@ -42,7 +44,7 @@ func TestCreate_OK(t *testing.T) {
 	p := &model.Product{
 		ID:    uuid.New(),
 		Name:  "Mouse",
-		Price: 25.5,
+		Price: money.New(2550, "USD"),
 	}
 
 	mock.ExpectBegin()
@@ -54,3 +56,249 @@ func TestCreate_OK(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestCreate_IndexesWithTheSearchConfig(t *testing.T) {
+	db, mock, closeFn := setupDB(t)
+	defer closeFn()
+
+	repo := NewGormRepository(db, WithSearchConfig("german"))
+	p := &model.Product{Name: "Laufschuh", Price: money.New(8999, "EUR")}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "products" \(.*"search_config".*\)`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Create(context.Background(), p))
+	require.Equal(t, "german", p.SearchConfig)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPrefixQuery(t *testing.T) {
+	for in, want := range map[string]string{
+		"Blue runn":           "blue:* & runn:*",
+		"  wi-fi  router!  ":  "wi:* & fi:* & router:*",
+		"café 4k":             "café:* & 4k:*",
+		"'); DROP TABLE x --": "drop:* & table:* & x:*",
+		"&|!:*()":             "",
+	} {
+		require.Equal(t, want, prefixQuery(in), in)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := highlight("<b>" + markStart + "Blue" + markStop + "</b> & co")
+	require.Equal(t, "&lt;b&gt;<mark>Blue</mark>&lt;/b&gt; &amp; co", got)
+}
+
+func TestSearch_RanksThenLoads(t *testing.T) {
+	db, mock, closeFn := setupDB(t)
+	defer closeFn()
+	repo := NewGormRepository(db)
+
+	first, second := uuid.New(), uuid.New()
+	mock.ExpectQuery(`SELECT products.id, ts_rank_cd\(.*search_vector @@ to_tsquery.*category_id IN .*ORDER BY ts_rank_cd\(.*\) DESC.*LIMIT \$\d+ OFFSET \$\d+`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rank"}).AddRow(first, 0.9).AddRow(second, 0.5))
+	mock.ExpectQuery(`ts_headline\(.*WHERE products.id IN \(\$\d+,\$\d+\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name_highlight", "snippet"}).
+			AddRow(first, markStart+"Blue"+markStop+" mug", "a "+markStart+"blue"+markStop+" <mug>"))
+	// Loaded in table order, returned in rank order.
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "products" WHERE id IN ($1,$2)`)).
+		WithArgs(first, second).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(second, "Blue cup").AddRow(first, "Blue mug"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_images"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "product_variants"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	q := ListQuery{Filter: ProductFilter{Text: "Blue", CategoryPath: "/c/"}, Sort: SortRelevance}
+	hits, err := repo.Search(context.Background(), q, 20, 10)
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	require.Equal(t, "Blue mug", hits[0].Name)
+	require.Equal(t, 0.9, hits[0].Rank)
+	require.Equal(t, "<mark>Blue</mark> mug", hits[0].NameHighlight)
+	require.Equal(t, "a <mark>blue</mark> &lt;mug&gt;", hits[0].Snippet)
+	require.Equal(t, "Blue cup", hits[1].Name)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestList_FiltersAndSorts(t *testing.T) {
+	db, mock, closeFn := setupDB(t)
+	defer closeFn()
+	repo := NewGormRepository(db)
+
+	seller := uuid.New()
+	min, inStock := int64(1000), true
+	mock.ExpectQuery(`SELECT \* FROM "products" WHERE products.seller_id = \$1 AND products.price_currency = \$2 AND products.price_amount >= \$3 AND \(\(EXISTS .*\)\) AND \(\(products.tags @> \$4::jsonb OR products.tags @> \$5::jsonb\)\) ORDER BY products.price_currency, products.price_amount DESC`).
+		WithArgs(seller, "EUR", min, `["red"]`, `["blue"]`, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	q := ListQuery{
+		Filter: ProductFilter{SellerID: &seller, Currency: "EUR", MinPrice: &min, InStock: &inStock, Tags: []string{"red", "blue"}},
+		Sort:   SortPriceDesc,
+	}
+	list, err := repo.List(context.Background(), q, 0, 10)
+	require.NoError(t, err)
+	require.Empty(t, list)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestList_SeeksPastCursor(t *testing.T) {
+	db, mock, closeFn := setupDB(t)
+	defer closeFn()
+	repo := NewGormRepository(db)
+
+	at := time.Date(2025, 9, 20, 9, 0, 0, 0, time.UTC)
+	id := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "products" WHERE (lower(products.name) > lower($1) OR (lower(products.name) = lower($2) AND (products.created_at, products.id) < ($3, $4))) ORDER BY lower(products.name), products.created_at DESC, products.id DESC LIMIT $5`)).
+		WithArgs("Mug", "Mug", at, id, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	q := ListQuery{Sort: SortName, After: &Cursor{CreatedAt: at, ID: id, Name: "Mug"}}
+	_, err := repo.List(context.Background(), q, 0, 11)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEstimateCount_ReadsPlan(t *testing.T) {
+	db, mock, closeFn := setupDB(t)
+	defer closeFn()
+	repo := NewGormRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`EXPLAIN (FORMAT JSON) SELECT products.id FROM "products" WHERE products.price_currency = $1`)).
+		WithArgs("EUR").
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow(`[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 1234}}]`))
+
+	n, err := repo.EstimateCount(context.Background(), ProductFilter{Currency: "EUR"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1234), n)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserve_RefusesProductsWithoutPrice(t *testing.T) {
+	db, mock, closeFn := setupDB(t)
+	defer closeFn()
+
+	repo := NewGormInventoryRepository(db)
+	productID := uuid.New()
+	res := &model.Reservation{ID: uuid.New(), Status: model.ReservationPending, ExpiresAt: time.Now().Add(time.Minute),
+		Items: []model.ReservationItem{{ID: uuid.New(), ProductID: productID, Quantity: 1}}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "stock_reservations"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "stock_reservation_items"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "products" WHERE id IN ($1) AND price_missing LIMIT $2`)).
+		WithArgs(productID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(productID))
+	mock.ExpectRollback()
+
+	err := repo.Reserve(context.Background(), res, nil)
+	require.ErrorIs(t, err, ErrPriceMissing)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateCategory_RechecksTheParentUnderLock(t *testing.T) {
+	db, mock, closeFn := setupDB(t)
+	defer closeFn()
+
+	repo := NewGormCategoryRepository(db)
+	root, child := uuid.New(), uuid.New()
+	// the service saw root as a root, but child was meanwhile moved under it
+	c := &model.Category{ID: root, ParentID: &child, Name: "Root", Slug: "root"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "categories" WHERE id IN ($1,$2) ORDER BY id FOR UPDATE`)).
+		WithArgs(root, child).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(root).AddRow(child))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","path" FROM "categories" WHERE id = $1 ORDER BY "categories"."id" LIMIT $2 FOR SHARE`)).
+		WithArgs(child, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "path"}).AddRow(child, "/"+root.String()+"/"+child.String()+"/"))
+	mock.ExpectRollback()
+
+	err := repo.UpdateCategory(context.Background(), c, "/"+root.String()+"/")
+	require.ErrorIs(t, err, ErrCategoryCycle)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdate_RefusesACurrencyTheVariantsDoNotUse(t *testing.T) {
+	db, mock, closeFn := setupDB(t)
+	defer closeFn()
+
+	repo := NewGormRepository(db)
+	p := &model.Product{ID: uuid.New(), Name: "T-shirt", Price: money.New(1999, "EUR")}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","price_currency" FROM "products" WHERE id = $1 ORDER BY "products"."id" LIMIT $2 FOR UPDATE`)).
+		WithArgs(p.ID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "price_currency"}).AddRow(p.ID, "USD"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "product_variants" WHERE product_id = $1 AND price_currency <> $2`)).
+		WithArgs(p.ID, "EUR").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	err := repo.Update(context.Background(), p)
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateVariant_RechecksReservedUnderLock(t *testing.T) {
+	db, mock, closeFn := setupDB(t)
+	defer closeFn()
+
+	repo := NewGormRepository(db)
+	v := &model.Variant{ID: uuid.New(), ProductID: uuid.New(), SKU: "TS-M", Price: money.New(1999, "EUR"), Stock: 3}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","price_currency" FROM "products" WHERE id = $1 ORDER BY "products"."id" LIMIT $2 FOR SHARE`)).
+		WithArgs(v.ProductID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "price_currency"}).AddRow(v.ProductID, "EUR"))
+	// a product-level reservation committed after the service read the product
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "reserved" FROM "products" WHERE id = $1`)).
+		WithArgs(v.ProductID).
+		WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(2))
+	mock.ExpectRollback()
+
+	err := repo.CreateVariant(context.Background(), v)
+	require.ErrorIs(t, err, ErrProductReserved)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteVariant_WritesOffStock(t *testing.T) {
+	db, mock, closeFn := setupDB(t)
+	defer closeFn()
+
+	repo := NewGormRepository(db)
+	productID, variantID := uuid.New(), uuid.New()
+	lockProduct := regexp.QuoteMeta(`SELECT "id","stock","reserved" FROM "products" WHERE id = $1 ORDER BY "products"."id" LIMIT $2 FOR UPDATE`)
+	lockVariant := regexp.QuoteMeta(`SELECT "id","sku","stock","reserved" FROM "product_variants" WHERE id = $1 AND product_id = $2 ORDER BY "product_variants"."id" LIMIT $3 FOR UPDATE`)
+
+	// reserved units block the delete
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockProduct).WithArgs(productID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "stock", "reserved"}).AddRow(productID, 0, 0))
+	mock.ExpectQuery(lockVariant).WithArgs(variantID, productID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku", "stock", "reserved"}).AddRow(variantID, "TS-M", 5, 2))
+	mock.ExpectRollback()
+	require.ErrorIs(t, repo.DeleteVariant(context.Background(), productID, variantID, nil), ErrVariantReserved)
+
+	// otherwise the stock is written off before the variant goes
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockProduct).WithArgs(productID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "stock", "reserved"}).AddRow(productID, 0, 0))
+	mock.ExpectQuery(lockVariant).WithArgs(variantID, productID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku", "stock", "reserved"}).AddRow(variantID, "TS-M", 5, 0))
+	mock.ExpectExec(`UPDATE "product_variants" SET "reserved"=\$1,"stock"=\$2`).WithArgs(0, 0, variantID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "stock_movements" .*RETURNING "id"`).
+		WithArgs(productID, variantID, "TS-M", model.MovementAdjusted, -5, 0, 0, 0, nil, nil, "variant deleted", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "product_variants" WHERE id = $1`)).WithArgs(variantID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "product_variants" WHERE product_id = $1`)).WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+	require.NoError(t, repo.DeleteVariant(context.Background(), productID, variantID, nil))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrCategoryNotFound is returned when the requested category does not exist.
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrInvalidCategory is returned for bad names, slugs or parents.
+	ErrInvalidCategory = errors.New("invalid category")
+	// ErrSlugTaken is returned when another category already uses the slug.
+	ErrSlugTaken = errors.New("category slug already in use")
+	// ErrCategoryNotEmpty is returned when deleting a category with subcategories.
+	ErrCategoryNotEmpty = errors.New("category has subcategories; move or delete them first")
+	// ErrAdminOnly is returned when a non-admin tries to change the taxonomy.
+	ErrAdminOnly = errors.New("only admins can manage categories")
+)
+
+var slugRe = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CategoryService manages the product taxonomy. Anyone can read it; only
+// admins can change it.
+type CategoryService struct {
+	repo repository.CategoryRepository
+}
+
+// NewCategories returns a CategoryService.
+func NewCategories(repo repository.CategoryRepository) *CategoryService {
+	return &CategoryService{repo: repo}
+}
+
+// Create adds a category under parentID, or a root when parentID is nil.
+// An empty slug is derived from the name.
+func (s *CategoryService) Create(ctx context.Context, name, slug string, parentID *uuid.UUID) (*model.Category, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	c := &model.Category{ID: uuid.New(), ParentID: parentID}
+	if err := s.apply(ctx, c, name, slug); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateCategory(ctx, c); err != nil {
+		return nil, categoryErr(err)
+	}
+	return c, nil
+}
+
+// Get returns a category by ID.
+func (s *CategoryService) Get(ctx context.Context, id uuid.UUID) (*model.Category, error) {
+	c, err := s.repo.GetCategory(ctx, id)
+	if err != nil {
+		return nil, categoryErr(err)
+	}
+	return c, nil
+}
+
+// List returns the whole taxonomy, parents before their children.
+func (s *CategoryService) List(ctx context.Context) ([]model.Category, error) {
+	return s.repo.ListCategories(ctx)
+}
+
+// Update renames a category and moves it, with its subtree, under parentID
+// (nil makes it a root).
+func (s *CategoryService) Update(ctx context.Context, id uuid.UUID, name, slug string, parentID *uuid.UUID) (*model.Category, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	c, err := s.repo.GetCategory(ctx, id)
+	if err != nil {
+		return nil, categoryErr(err)
+	}
+	oldPath := c.Path
+	c.ParentID = parentID
+	if err := s.apply(ctx, c, name, slug); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateCategory(ctx, c, oldPath); err != nil {
+		return nil, categoryErr(err)
+	}
+	return c, nil
+}
+
+// Delete removes a category without subcategories. Its products become
+// uncategorised.
+func (s *CategoryService) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	return categoryErr(s.repo.DeleteCategory(ctx, id))
+}
+
+// apply validates and sets the name, slug and path of c from its ParentID.
+// The repository checks the parent again under a lock when saving c.
+func (s *CategoryService) apply(ctx context.Context, c *model.Category, name, slug string) error {
+	c.Name = strings.TrimSpace(name)
+	if len(c.Name) < 2 || len(c.Name) > 100 {
+		return fmt.Errorf("%w: name must be 2 to 100 characters", ErrInvalidCategory)
+	}
+	c.Slug = strings.TrimSpace(slug)
+	if c.Slug == "" {
+		c.Slug = slugify(c.Name)
+	}
+	if !slugRe.MatchString(c.Slug) || len(c.Slug) > 100 {
+		return fmt.Errorf("%w: slug must be lowercase letters, digits and dashes", ErrInvalidCategory)
+	}
+
+	c.Path = "/" + c.ID.String() + "/"
+	if c.ParentID == nil {
+		return nil
+	}
+	parent, err := s.repo.GetCategory(ctx, *c.ParentID)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		return fmt.Errorf("%w: parent not found", ErrInvalidCategory)
+	}
+	if err != nil {
+		return err
+	}
+	if strings.Contains(parent.Path, "/"+c.ID.String()+"/") {
+		return fmt.Errorf("%w: a category cannot be moved under itself or its descendants", ErrInvalidCategory)
+	}
+	c.Path = parent.Path + c.ID.String() + "/"
+	return nil
+}
+
+// slugify lowercases name and joins its letters and digits with dashes;
+// "Home & Garden" becomes "home-garden".
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// requireAdmin allows admins acting as themselves.
+func requireAdmin(ctx context.Context) error {
+	pl, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || pl == nil {
+		return ErrUnauthenticated
+	}
+	if !pl.IsAdmin() || pl.IsImpersonated() {
+		return ErrAdminOnly
+	}
+	return nil
+}
+
+// categoryErr maps repository errors to service errors.
+func categoryErr(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		return ErrCategoryNotFound
+	case errors.Is(err, repository.ErrSlugTaken):
+		return ErrSlugTaken
+	case errors.Is(err, repository.ErrCategoryNotEmpty):
+		return ErrCategoryNotEmpty
+	case errors.Is(err, repository.ErrParentNotFound):
+		return fmt.Errorf("%w: parent not found", ErrInvalidCategory)
+	case errors.Is(err, repository.ErrCategoryCycle):
+		return fmt.Errorf("%w: a category cannot be moved under itself or its descendants", ErrInvalidCategory)
+	}
+	return err
+}
+
+// SetCategory assigns product id to a category, or clears it when
+// categoryID is nil. Only the seller or an admin may.
+func (s *ProductService) SetCategory(ctx context.Context, id string, categoryID *uuid.UUID) (*model.Product, error) {
+	p, err := s.owned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if categoryID != nil {
+		if _, err := s.category(ctx, *categoryID); err != nil {
+			return nil, err
+		}
+	}
+	p.CategoryID = categoryID
+	if err := s.repo.Update(ctx, p); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *ProductService) category(ctx context.Context, id uuid.UUID) (*model.Category, error) {
+	if s.categories == nil {
+		return nil, ErrCategoryNotFound
+	}
+	c, err := s.categories.GetCategory(ctx, id)
+	if err != nil {
+		return nil, categoryErr(err)
+	}
+	return c, nil
+}
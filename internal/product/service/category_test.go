@@ -0,0 +1,149 @@
+package service_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+)
+
+// memCategoryRepo keeps the tree in memory, rewriting descendant paths on
+// moves like the GORM implementation.
+type memCategoryRepo struct{ rows map[uuid.UUID]model.Category }
+
+func newMemCategoryRepo() *memCategoryRepo {
+	return &memCategoryRepo{rows: map[uuid.UUID]model.Category{}}
+}
+
+func (m *memCategoryRepo) CreateCategory(_ context.Context, c *model.Category) error {
+	for _, r := range m.rows {
+		if r.Slug == c.Slug {
+			return repository.ErrSlugTaken
+		}
+	}
+	m.rows[c.ID] = *c
+	return nil
+}
+
+func (m *memCategoryRepo) GetCategory(_ context.Context, id uuid.UUID) (*model.Category, error) {
+	c, ok := m.rows[id]
+	if !ok {
+		return nil, repository.ErrCategoryNotFound
+	}
+	return &c, nil
+}
+
+func (m *memCategoryRepo) ListCategories(context.Context) ([]model.Category, error) {
+	var list []model.Category
+	for _, c := range m.rows {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list, nil
+}
+
+func (m *memCategoryRepo) UpdateCategory(_ context.Context, c *model.Category, oldPath string) error {
+	for id, r := range m.rows {
+		if id != c.ID && strings.HasPrefix(r.Path, oldPath) {
+			r.Path = c.Path + strings.TrimPrefix(r.Path, oldPath)
+			m.rows[id] = r
+		}
+	}
+	m.rows[c.ID] = *c
+	return nil
+}
+
+func (m *memCategoryRepo) DeleteCategory(_ context.Context, id uuid.UUID) error {
+	for _, r := range m.rows {
+		if r.ParentID != nil && *r.ParentID == id {
+			return repository.ErrCategoryNotEmpty
+		}
+	}
+	delete(m.rows, id)
+	return nil
+}
+
+func TestCategories_Tree(t *testing.T) {
+	admin := asUser(uuid.New(), token.RoleAdmin)
+	repo := newMemCategoryRepo()
+	svc := service.NewCategories(repo)
+
+	_, err := svc.Create(asUser(uuid.New(), token.RoleUser), "Electronics", "", nil)
+	assert.ErrorIs(t, err, service.ErrAdminOnly)
+
+	electronics, err := svc.Create(admin, "Electronics", "", nil)
+	require.NoError(t, err)
+	phones, err := svc.Create(admin, "Phones & Tablets", "", &electronics.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "phones-tablets", phones.Slug)
+	assert.Equal(t, "/"+electronics.ID.String()+"/"+phones.ID.String()+"/", phones.Path)
+	assert.Equal(t, 1, phones.Depth())
+	android, err := svc.Create(admin, "Android", "", &phones.ID)
+	require.NoError(t, err)
+
+	_, err = svc.Create(admin, "Other phones", "phones-tablets", nil)
+	assert.ErrorIs(t, err, service.ErrSlugTaken)
+	_, err = svc.Create(admin, "Bad", "Not A Slug", nil)
+	assert.ErrorIs(t, err, service.ErrInvalidCategory)
+	missing := uuid.New()
+	_, err = svc.Create(admin, "Orphan", "", &missing)
+	assert.ErrorIs(t, err, service.ErrInvalidCategory)
+
+	// a category cannot move into its own subtree
+	_, err = svc.Update(admin, electronics.ID, electronics.Name, electronics.Slug, &android.ID)
+	assert.ErrorIs(t, err, service.ErrInvalidCategory)
+
+	// moving phones to the root moves android along
+	_, err = svc.Update(admin, phones.ID, "Phones", "phones", nil)
+	require.NoError(t, err)
+	got, err := svc.Get(admin, android.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "/"+phones.ID.String()+"/"+android.ID.String()+"/", got.Path)
+
+	assert.ErrorIs(t, svc.Delete(admin, phones.ID), service.ErrCategoryNotEmpty)
+	assert.NoError(t, svc.Delete(admin, android.ID))
+}
+
+func TestProducts_ByCategory(t *testing.T) {
+	seller := uuid.New()
+	ctx := asUser(seller, token.RoleUser)
+	cats := newMemCategoryRepo()
+	phones := model.Category{ID: uuid.New(), Name: "Phones", Slug: "phones"}
+	phones.Path = "/" + phones.ID.String() + "/"
+	cats.rows[phones.ID] = phones
+
+	repo := new(mockRepo)
+	svc := service.New(repo, service.WithCategories(cats))
+	prod := &model.Product{ID: uuid.New(), Name: "Phone", Price: money.New(19900, "EUR"), SellerID: seller}
+	repo.On("GetByID", mock.Anything, prod.ID.String()).Return(prod, nil)
+	repo.On("Update", mock.Anything, prod).Return(nil)
+	repo.On("List", ctx, repository.ListQuery{Filter: repository.ProductFilter{CategoryPath: phones.Path}, Sort: service.SortNewest}, 0, 11).
+		Return([]model.Product{*prod}, nil)
+
+	missing := uuid.New()
+	_, err := svc.SetCategory(ctx, prod.ID.String(), &missing)
+	assert.ErrorIs(t, err, service.ErrCategoryNotFound)
+	_, err = svc.SetCategory(asUser(uuid.New(), token.RoleUser), prod.ID.String(), &phones.ID)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	got, err := svc.SetCategory(ctx, prod.ID.String(), &phones.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &phones.ID, got.CategoryID)
+
+	list, err := svc.Browse(ctx, service.ProductQuery{CategoryID: &phones.ID}, 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, list.Hits, 1)
+	_, err = svc.Browse(ctx, service.ProductQuery{CategoryID: &missing}, 1, 10)
+	assert.ErrorIs(t, err, service.ErrCategoryNotFound)
+}
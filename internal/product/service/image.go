@@ -0,0 +1,272 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/imageproc"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/logger"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/upload"
+	"github.com/google/uuid"
+)
+
+// maxImages is the number of pictures a product can have.
+const maxImages = 10
+
+// UploadKindImage marks the upload sessions of product images.
+const UploadKindImage = "product_image"
+
+var (
+	// ErrImageNotFound is returned when the product has no such image.
+	ErrImageNotFound = errors.New("image not found")
+	// ErrInvalidImage is returned for files that are not a supported image.
+	ErrInvalidImage = errors.New("invalid image")
+	// ErrImageTooLarge is returned for files over the upload size limit.
+	ErrImageTooLarge = errors.New("image is too large")
+	// ErrImageRejected is returned when the malware scanner rejects a file.
+	ErrImageRejected = errors.New("upload rejected by malware scan")
+	// ErrScanUnavailable is returned when the malware scanner cannot be reached.
+	ErrScanUnavailable = errors.New("malware scanner unavailable")
+	// ErrTooManyImages is returned when the product already has maxImages.
+	ErrTooManyImages = fmt.Errorf("a product can have at most %d images", maxImages)
+	// ErrDuplicateImage is returned when the product already has the image.
+	ErrDuplicateImage = errors.New("product already has this image")
+
+	// Errors of resumable uploads; see package upload.
+	ErrUploadNotFound   = upload.ErrNotFound
+	ErrUploadIDTaken    = upload.ErrIDTaken
+	ErrInvalidUpload    = upload.ErrInvalid
+	ErrUploadMismatch   = upload.ErrMismatch
+	ErrUploadOffset     = upload.ErrOffset
+	ErrUploadIncomplete = upload.ErrIncomplete
+	ErrChecksumMismatch = upload.ErrChecksumMismatch
+)
+
+// ImageService manages product pictures. Images are checked by an
+// upload.Guard, re-encoded without metadata and stored with a thumbnail in
+// a blob.Store; large files can be uploaded in resumable chunks.
+type ImageService struct {
+	products repository.Repository
+	repo     repository.ImageRepository
+	store    blob.Store
+	urlTTL   time.Duration
+	images   imageproc.Options
+	guard    *upload.Guard
+	uploads  *upload.Resumable
+	maxBytes int64
+}
+
+// NewImages returns a new ImageService. urlTTL is the lifetime of the
+// download URLs handed to clients; guard checks uploads before they are
+// decoded (nil → upload.DefaultImagePolicy without scanning) and its size
+// limit also applies to resumable uploads, which can be resumed for
+// uploadTTL.
+func NewImages(products repository.Repository, repo repository.ImageRepository, store blob.Store, urlTTL time.Duration,
+	images imageproc.Options, guard *upload.Guard, maxBytes int64, sessions upload.Sessions, uploadTTL time.Duration) *ImageService {
+	if guard == nil {
+		guard = upload.NewGuard(upload.DefaultImagePolicy, nil, nil)
+	}
+	return &ImageService{
+		products: products,
+		repo:     repo,
+		store:    store,
+		urlTTL:   urlTTL,
+		images:   images,
+		guard:    guard,
+		uploads:  upload.NewResumable(sessions, store, UploadKindImage, maxBytes, uploadTTL),
+		maxBytes: maxBytes,
+	}
+}
+
+// Add reads an image from r and appends it to the product's images. Only
+// the seller or an admin may.
+func (s *ImageService) Add(ctx context.Context, productID string, r io.Reader) (*model.Image, error) {
+	p, err := ownedProduct(ctx, s.products, productID)
+	if err != nil {
+		return nil, err
+	}
+	return s.save(ctx, p.ID, r)
+}
+
+// save processes and stores the image and records it. The blobs are named
+// after their content, so a failed insert leaves at most an orphan that a
+// retry overwrites.
+func (s *ImageService) save(ctx context.Context, productID uuid.UUID, r io.Reader) (*model.Image, error) {
+	data, _, err := s.guard.Read(ctx, r)
+	if err != nil {
+		return nil, imageError(err)
+	}
+	processed, err := imageproc.Process(data, s.images)
+	if err != nil {
+		return nil, imageError(err)
+	}
+	img := &model.Image{ID: uuid.New(), ProductID: productID}
+	img.Key = blob.ContentKey(imagePrefix(productID), processed.Image.Data, processed.Image.Ext)
+	if err := s.put(ctx, img.Key, processed.Image); err != nil {
+		return nil, err
+	}
+	if len(s.images.ThumbnailSizes) > 0 {
+		size := s.images.ThumbnailSizes[0]
+		if thumb, ok := processed.Thumbnails[size]; ok {
+			img.ThumbnailKey = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(img.Key, path.Ext(img.Key)), size, thumb.Ext)
+			if err := s.put(ctx, img.ThumbnailKey, thumb); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := s.repo.CreateImage(ctx, img, maxImages); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, ErrNotFound
+		case errors.Is(err, repository.ErrTooManyImages):
+			return nil, ErrTooManyImages
+		case errors.Is(err, repository.ErrDuplicateImage):
+			return nil, ErrDuplicateImage
+		}
+		return nil, err
+	}
+	return img, nil
+}
+
+func (s *ImageService) put(ctx context.Context, key string, img imageproc.Image) error {
+	return s.store.Put(ctx, key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType)
+}
+
+// Delete removes an image of a product and its blobs. Only the seller or
+// an admin may.
+func (s *ImageService) Delete(ctx context.Context, productID string, imageID uuid.UUID) error {
+	p, err := ownedProduct(ctx, s.products, productID)
+	if err != nil {
+		return err
+	}
+	img, err := s.repo.GetImage(ctx, p.ID, imageID)
+	if err == nil {
+		err = s.repo.DeleteImage(ctx, p.ID, imageID)
+	}
+	if errors.Is(err, repository.ErrImageNotFound) {
+		return ErrImageNotFound
+	}
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{img.Key, img.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.store.Delete(ctx, key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			logger.Error("remove product image %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// URL resolves a stored key to a signed download URL; "" for an empty key.
+func (s *ImageService) URL(key string) string {
+	if s == nil || key == "" {
+		return ""
+	}
+	u, err := s.store.URL(key, s.urlTTL)
+	if err != nil {
+		logger.Error("product image url %s: %v", key, err)
+		return ""
+	}
+	return u
+}
+
+// OpenUpload starts the resumable upload uploadID of an image for the
+// product, or resumes it; size and checksum (hex SHA-256) must then match
+// the first call. Only the seller or an admin may.
+func (s *ImageService) OpenUpload(ctx context.Context, productID string, uploadID uuid.UUID, size int64, checksum string) (*ImageUpload, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ownedProduct(ctx, s.products, productID)
+	if err != nil {
+		return nil, err
+	}
+	if size > s.maxBytes {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrImageTooLarge, s.maxBytes)
+	}
+	up, err := s.uploads.Open(ctx, userID, uploadID, p.ID.String(), size, checksum)
+	if err != nil {
+		return nil, err
+	}
+	return &ImageUpload{Upload: up, images: s, productID: p.ID}, nil
+}
+
+// UploadStatus returns the caller's upload so it can be resumed from
+// Received. Session.Target is the product ID.
+func (s *ImageService) UploadStatus(ctx context.Context, uploadID uuid.UUID) (*upload.Session, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.uploads.Status(ctx, userID, uploadID)
+}
+
+// PurgeExpired removes unfinished uploads whose resume window has passed.
+// It returns the number of removed uploads; run it periodically.
+func (s *ImageService) PurgeExpired(ctx context.Context) (int, error) {
+	return s.uploads.PurgeExpired(ctx)
+}
+
+// ImageUpload receives the chunks of one image upload stream.
+type ImageUpload struct {
+	*upload.Upload
+	images    *ImageService
+	productID uuid.UUID
+}
+
+// Complete verifies the checksum of the assembled upload and adds it to
+// the product's images. The upload is discarded unless the failure is
+// temporary (storage or scanner unavailable), in which case Complete can
+// be retried by resuming.
+func (u *ImageUpload) Complete(ctx context.Context) (*model.Image, error) {
+	var img *model.Image
+	err := u.Upload.Complete(ctx, func(r io.Reader) (err error) {
+		img, err = u.images.save(ctx, u.productID, r)
+		return err
+	}, permanentImageError)
+	return img, err
+}
+
+func permanentImageError(err error) bool {
+	for _, target := range []error{ErrInvalidImage, ErrImageTooLarge, ErrImageRejected, ErrTooManyImages, ErrDuplicateImage, ErrNotFound} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// imageError maps upload and image processing failures to service errors.
+func imageError(err error) error {
+	var target error
+	switch {
+	case errors.Is(err, upload.ErrTooLarge):
+		target = ErrImageTooLarge
+	case errors.Is(err, upload.ErrTypeNotAllowed), errors.Is(err, imageproc.ErrUnsupportedFormat), errors.Is(err, imageproc.ErrImageTooLarge):
+		target = ErrInvalidImage
+	case errors.Is(err, upload.ErrInfected):
+		target = ErrImageRejected
+	case errors.Is(err, upload.ErrScanFailed):
+		target = ErrScanUnavailable
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %v", target, err)
+}
+
+func imagePrefix(productID uuid.UUID) string {
+	return "images/products/" + productID.String()
+}
@@ -0,0 +1,200 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/png"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/imageproc"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/upload"
+	"github.com/google/uuid"
+)
+
+// memImages keeps product images in memory.
+type memImages struct{ rows []model.Image }
+
+func (m *memImages) CreateImage(_ context.Context, img *model.Image, limit int) error {
+	n := 0
+	for _, r := range m.rows {
+		if r.ProductID == img.ProductID {
+			if r.Key == img.Key {
+				return repository.ErrDuplicateImage
+			}
+			n++
+		}
+	}
+	if n >= limit {
+		return repository.ErrTooManyImages
+	}
+	img.Position = n
+	m.rows = append(m.rows, *img)
+	return nil
+}
+func (m *memImages) GetImage(_ context.Context, productID, id uuid.UUID) (*model.Image, error) {
+	for _, r := range m.rows {
+		if r.ID == id && r.ProductID == productID {
+			return &r, nil
+		}
+	}
+	return nil, repository.ErrImageNotFound
+}
+func (m *memImages) DeleteImage(_ context.Context, productID, id uuid.UUID) error {
+	for i, r := range m.rows {
+		if r.ID == id && r.ProductID == productID {
+			m.rows = append(m.rows[:i], m.rows[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrImageNotFound
+}
+
+// memSessions keeps upload sessions in memory.
+type memSessions struct {
+	sessions map[uuid.UUID]*upload.Session
+	parts    map[uuid.UUID][]upload.Part
+}
+
+func (m *memSessions) CreateSession(_ context.Context, s *upload.Session) error {
+	if _, ok := m.sessions[s.ID]; ok {
+		return upload.ErrIDTaken
+	}
+	c := *s
+	m.sessions[s.ID] = &c
+	return nil
+}
+func (m *memSessions) GetSession(_ context.Context, id uuid.UUID) (*upload.Session, error) {
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, upload.ErrNotFound
+	}
+	c := *s
+	return &c, nil
+}
+func (m *memSessions) AddPart(_ context.Context, p *upload.Part) error {
+	s := m.sessions[p.UploadID]
+	if s == nil || s.Received != p.StartOffset {
+		return upload.ErrOffset
+	}
+	s.Received += p.Size
+	m.parts[p.UploadID] = append(m.parts[p.UploadID], *p)
+	return nil
+}
+func (m *memSessions) ListParts(_ context.Context, id uuid.UUID) ([]upload.Part, error) {
+	list := append([]upload.Part(nil), m.parts[id]...)
+	sort.Slice(list, func(i, j int) bool { return list[i].StartOffset < list[j].StartOffset })
+	return list, nil
+}
+func (m *memSessions) DeleteSession(_ context.Context, id uuid.UUID) error {
+	delete(m.sessions, id)
+	delete(m.parts, id)
+	return nil
+}
+func (m *memSessions) ListExpiredSessions(context.Context, string, time.Time, int) ([]upload.Session, error) {
+	return nil, nil
+}
+
+func testPNG(t *testing.T, shade uint8) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for i := range img.Pix {
+		img.Pix[i] = shade
+	}
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func newImages(t *testing.T, repo *mockRepo) (*service.ImageService, *memImages, *memSessions, blob.Store) {
+	t.Helper()
+	store, err := blob.NewLocal(t.TempDir(), "/media", []byte("secret"))
+	require.NoError(t, err)
+	rows := &memImages{}
+	sessions := &memSessions{sessions: map[uuid.UUID]*upload.Session{}, parts: map[uuid.UUID][]upload.Part{}}
+	svc := service.NewImages(repo, rows, store, time.Hour, imageproc.DefaultOptions, nil, 1<<20, sessions, time.Hour)
+	return svc, rows, sessions, store
+}
+
+func TestImages_AddAndDelete(t *testing.T) {
+	seller := uuid.New()
+	p := &model.Product{ID: uuid.New(), SellerID: seller}
+	repo := new(mockRepo)
+	repo.On("GetByID", mock.Anything, p.ID.String()).Return(p, nil)
+	svc, rows, _, store := newImages(t, repo)
+	ctx := asUser(seller, token.RoleUser)
+
+	_, err := svc.Add(asUser(uuid.New(), token.RoleUser), p.ID.String(), bytes.NewReader(testPNG(t, 10)))
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = svc.Add(ctx, p.ID.String(), bytes.NewReader([]byte("not an image")))
+	assert.ErrorIs(t, err, service.ErrInvalidImage)
+
+	img, err := svc.Add(ctx, p.ID.String(), bytes.NewReader(testPNG(t, 10)))
+	require.NoError(t, err)
+	assert.Equal(t, 0, img.Position)
+	assert.NotEmpty(t, img.ThumbnailKey)
+	assert.Contains(t, svc.URL(img.Key), "/media/")
+	_, err = svc.Add(ctx, p.ID.String(), bytes.NewReader(testPNG(t, 10)))
+	assert.ErrorIs(t, err, service.ErrDuplicateImage)
+
+	require.NoError(t, svc.Delete(ctx, p.ID.String(), img.ID))
+	assert.Empty(t, rows.rows)
+	_, err = store.Open(context.Background(), img.Key)
+	assert.ErrorIs(t, err, blob.ErrNotFound, "the blobs are removed with the image")
+	assert.ErrorIs(t, svc.Delete(ctx, p.ID.String(), img.ID), service.ErrImageNotFound)
+}
+
+func TestImages_ResumableUpload(t *testing.T) {
+	seller := uuid.New()
+	p := &model.Product{ID: uuid.New(), SellerID: seller}
+	repo := new(mockRepo)
+	repo.On("GetByID", mock.Anything, p.ID.String()).Return(p, nil)
+	svc, rows, sessions, _ := newImages(t, repo)
+	ctx := asUser(seller, token.RoleAdmin)
+
+	data := testPNG(t, 200)
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	uploadID := uuid.New()
+
+	up, err := svc.OpenUpload(ctx, p.ID.String(), uploadID, int64(len(data)), checksum)
+	require.NoError(t, err)
+	require.NoError(t, up.Write(ctx, 0, data[:100]))
+	require.NoError(t, up.Flush(ctx))
+
+	// another user cannot take over the upload ID
+	admin := asUser(uuid.New(), token.RoleAdmin)
+	_, err = svc.OpenUpload(admin, p.ID.String(), uploadID, int64(len(data)), checksum)
+	assert.ErrorIs(t, err, service.ErrUploadIDTaken)
+	_, err = svc.UploadStatus(admin, uploadID)
+	assert.ErrorIs(t, err, service.ErrUploadNotFound)
+
+	st, err := svc.UploadStatus(ctx, uploadID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), st.Received)
+	assert.Equal(t, p.ID.String(), st.Target)
+
+	up, err = svc.OpenUpload(ctx, p.ID.String(), uploadID, int64(len(data)), checksum)
+	require.NoError(t, err)
+	require.NoError(t, up.Write(ctx, up.Offset(), data[100:]))
+	img, err := up.Complete(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, img.ProductID)
+	assert.Len(t, rows.rows, 1)
+	assert.Empty(t, sessions.sessions, "a completed upload is removed")
+}
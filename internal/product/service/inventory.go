@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/google/uuid"
+)
+
+// EventSource identifies the events of the product service.
+const EventSource = "product-service"
+
+// Inventory events, published through the outbox when the quantity
+// available to reserve crosses the low-stock threshold or reaches zero.
+const (
+	EventStockLow   = "inventory.stock_low"
+	EventOutOfStock = "inventory.out_of_stock"
+)
+
+const (
+	maxReservationItems = 100
+	maxReservationTTL   = time.Hour
+	expiryBatch         = 100
+)
+
+var (
+	// ErrInsufficientStock is returned when less is available than requested.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrInvalidStock is returned for negative stock, bad quantities or a
+	// missing or superfluous variant.
+	ErrInvalidStock = errors.New("invalid stock request")
+	// ErrReservationNotFound is returned when the reservation does not exist
+	// or belongs to someone else.
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrReservationClosed is returned when the reservation was already
+	// committed, released or expired.
+	ErrReservationClosed = errors.New("reservation is no longer pending")
+	// ErrPriceMissing is returned when reserving a product that has no
+	// valid price yet.
+	ErrPriceMissing = errors.New("product has no price")
+)
+
+// StockEventData is the payload of inventory events. VariantID and SKU are
+// empty for products without variants.
+type StockEventData struct {
+	ProductID uuid.UUID  `json:"productId"`
+	VariantID *uuid.UUID `json:"variantId,omitempty"`
+	SKU       string     `json:"sku,omitempty"`
+	Available int        `json:"available"`
+	Threshold int        `json:"threshold"`
+}
+
+// InventoryService tracks stock and reservations. Sellers and admins set
+// stock; any signed-in user or service principal reserves it, and only the
+// one who reserved, an admin or a service holding token.ScopeInventoryManage
+// can commit or release.
+type InventoryService struct {
+	repo     repository.InventoryRepository
+	products repository.Repository
+	lowStock int           // StockLow fires when available drops to this or below
+	ttl      time.Duration // default reservation lifetime
+	now      func() time.Time
+}
+
+// NewInventory returns an InventoryService.
+func NewInventory(repo repository.InventoryRepository, products repository.Repository, lowStock int, ttl time.Duration) *InventoryService {
+	return &InventoryService{repo: repo, products: products, lowStock: lowStock, ttl: ttl, now: time.Now}
+}
+
+// SetStock sets the on-hand quantity of product id, or of one of its
+// variants, after a count. The difference is recorded as an adjustment with
+// reason. Only the seller or an admin may.
+func (s *InventoryService) SetStock(ctx context.Context, id string, variantID *uuid.UUID, stock int, reason string) error {
+	p, err := ownedProduct(ctx, s.products, id)
+	if err != nil {
+		return err
+	}
+	if stock < 0 {
+		return fmt.Errorf("%w: stock cannot be negative", ErrInvalidStock)
+	}
+	reason, err = cleanReason(reason, "stock count")
+	if err != nil {
+		return err
+	}
+	m := &model.StockMovement{ProductID: p.ID, VariantID: variantID, ActorID: actorID(ctx), Reason: reason}
+	return inventoryErr(s.repo.SetStock(ctx, m, stock, s.stockEvents))
+}
+
+// Reserve holds the items until ttl from now (the default TTL when zero).
+// Either every item is reserved or none is.
+func (s *InventoryService) Reserve(ctx context.Context, items []model.ReservationItem, ttl time.Duration) (*model.Reservation, error) {
+	pl, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || pl == nil {
+		return nil, ErrUnauthenticated
+	}
+	if ttl == 0 {
+		ttl = s.ttl
+	}
+	if ttl < 0 || ttl > maxReservationTTL {
+		return nil, fmt.Errorf("%w: ttl must be at most %s", ErrInvalidStock, maxReservationTTL)
+	}
+	if len(items) == 0 || len(items) > maxReservationItems {
+		return nil, fmt.Errorf("%w: reserve 1 to %d items", ErrInvalidStock, maxReservationItems)
+	}
+	res := &model.Reservation{ID: uuid.New(), Status: model.ReservationPending, ExpiresAt: s.now().Add(ttl)}
+	if pl.UserID != uuid.Nil {
+		res.ReservedBy = &pl.UserID
+	}
+	if pl.IsService() {
+		res.ReservedByService = &pl.Service
+	}
+	seen := map[[2]uuid.UUID]bool{}
+	for _, it := range items {
+		if it.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantities must be positive", ErrInvalidStock)
+		}
+		key := [2]uuid.UUID{it.ProductID}
+		if it.VariantID != nil {
+			key[1] = *it.VariantID
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: each product or variant may appear once", ErrInvalidStock)
+		}
+		seen[key] = true
+		res.Items = append(res.Items, model.ReservationItem{
+			ID: uuid.New(), ReservationID: res.ID, ProductID: it.ProductID, VariantID: it.VariantID, Quantity: it.Quantity,
+		})
+	}
+	if err := s.repo.Reserve(ctx, res, s.stockEvents); err != nil {
+		return nil, inventoryErr(err)
+	}
+	return res, nil
+}
+
+// Get returns a reservation of the caller.
+func (s *InventoryService) Get(ctx context.Context, id uuid.UUID) (*model.Reservation, error) {
+	pl, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || pl == nil {
+		return nil, ErrUnauthenticated
+	}
+	res, err := s.repo.GetReservation(ctx, id)
+	if err != nil {
+		return nil, inventoryErr(err)
+	}
+	if !canManage(pl, res) {
+		return nil, ErrReservationNotFound
+	}
+	return res, nil
+}
+
+// Commit turns a pending reservation into a sale, removing its quantities
+// from stock. An expired reservation cannot be committed.
+func (s *InventoryService) Commit(ctx context.Context, id uuid.UUID) (*model.Reservation, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CommitReservation(ctx, id, s.now(), actorID(ctx)); err != nil {
+		if errors.Is(err, repository.ErrReservationExpired) {
+			// return the stock now rather than at the next sweep
+			_ = s.repo.ReleaseReservation(ctx, id, model.ReservationExpired, nil)
+		}
+		return nil, inventoryErr(err)
+	}
+	return s.repo.GetReservation(ctx, id)
+}
+
+// Release cancels a pending reservation and returns its quantities.
+func (s *InventoryService) Release(ctx context.Context, id uuid.UUID) (*model.Reservation, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReleaseReservation(ctx, id, model.ReservationReleased, actorID(ctx)); err != nil {
+		return nil, inventoryErr(err)
+	}
+	return s.repo.GetReservation(ctx, id)
+}
+
+// ExpireDue releases pending reservations past their expiry and returns how
+// many it released. Run it periodically.
+func (s *InventoryService) ExpireDue(ctx context.Context) (int, error) {
+	ids, err := s.repo.ExpiredReservations(ctx, s.now(), expiryBatch)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, id := range ids {
+		err := s.repo.ReleaseReservation(ctx, id, model.ReservationExpired, nil)
+		if errors.Is(err, repository.ErrReservationClosed) {
+			continue // committed or released meanwhile
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// stockEvents announces available stock reaching zero or crossing the
+// low-stock threshold downwards.
+func (s *InventoryService) stockEvents(c model.StockChange) ([]events.Event, error) {
+	var typ string
+	switch {
+	case c.After <= 0 && c.Before > 0:
+		typ = EventOutOfStock
+	case c.After <= s.lowStock && c.Before > s.lowStock:
+		typ = EventStockLow
+	default:
+		return nil, nil
+	}
+	subject := c.ProductID.String()
+	if c.VariantID != nil {
+		subject = c.VariantID.String()
+	}
+	e, err := events.New(EventSource, typ, subject, StockEventData{
+		ProductID: c.ProductID, VariantID: c.VariantID, SKU: c.SKU, Available: c.After, Threshold: s.lowStock,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []events.Event{e}, nil
+}
+
+// actorID returns the signed-in user for the ledger, or nil for service
+// principals.
+func actorID(ctx context.Context) *uuid.UUID {
+	if id, err := callerID(ctx); err == nil {
+		return &id
+	}
+	return nil
+}
+
+// canManage allows the user or service that reserved, admins and service
+// principals granted token.ScopeInventoryManage.
+func canManage(pl *token.Payload, res *model.Reservation) bool {
+	if pl.IsAdmin() {
+		return true
+	}
+	if pl.IsService() {
+		return pl.HasScope(token.ScopeInventoryManage) ||
+			res.ReservedByService != nil && *res.ReservedByService == pl.Service
+	}
+	return res.ReservedBy != nil && pl.UserID != uuid.Nil && *res.ReservedBy == pl.UserID
+}
+
+// inventoryErr maps repository errors to service errors.
+func inventoryErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, repository.ErrVariantNotFound):
+		return ErrVariantNotFound
+	case errors.Is(err, repository.ErrInsufficientStock):
+		// keep which item ran short: ": SKU X has 2 available"
+		return fmt.Errorf("%w%s", ErrInsufficientStock, strings.TrimPrefix(err.Error(), repository.ErrInsufficientStock.Error()))
+	case errors.Is(err, repository.ErrVariantRequired), errors.Is(err, repository.ErrStockBelowReserved):
+		return fmt.Errorf("%w: %w", ErrInvalidStock, err)
+	case errors.Is(err, repository.ErrPriceMissing):
+		return fmt.Errorf("%w%s", ErrPriceMissing, strings.TrimPrefix(err.Error(), repository.ErrPriceMissing.Error()))
+	case errors.Is(err, repository.ErrReservationNotFound):
+		return ErrReservationNotFound
+	case errors.Is(err, repository.ErrReservationClosed):
+		return ErrReservationClosed
+	case errors.Is(err, repository.ErrReservationExpired):
+		return fmt.Errorf("%w: it expired", ErrReservationClosed)
+	}
+	return err
+}
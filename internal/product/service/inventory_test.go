@@ -0,0 +1,330 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+)
+
+// memInventory keeps the stock of products without variants in memory,
+// with its ledger, and records queued events.
+type memInventory struct {
+	stock, reserved map[uuid.UUID]int
+	reservations    map[uuid.UUID]*model.Reservation
+	ledger          []model.StockMovement
+	snapshots       []model.StockSnapshot
+	queued          []events.Event
+}
+
+func newMemInventory() *memInventory {
+	return &memInventory{stock: map[uuid.UUID]int{}, reserved: map[uuid.UUID]int{}, reservations: map[uuid.UUID]*model.Reservation{}}
+}
+
+// record applies and appends mv like the GORM repository does.
+func (m *memInventory) record(mv *model.StockMovement, onChange repository.StockEvents) error {
+	id := mv.ProductID
+	mv.StockAfter, mv.ReservedAfter = m.stock[id]+mv.StockDelta, m.reserved[id]+mv.ReservedDelta
+	if mv.ReservedAfter < 0 || mv.StockAfter < mv.ReservedAfter {
+		return repository.ErrStockBelowReserved
+	}
+	before := m.stock[id] - m.reserved[id]
+	m.stock[id], m.reserved[id] = mv.StockAfter, mv.ReservedAfter
+	mv.ID = int64(len(m.ledger) + 1)
+	m.ledger = append(m.ledger, *mv)
+	if onChange == nil || before == mv.StockAfter-mv.ReservedAfter {
+		return nil
+	}
+	list, err := onChange(model.StockChange{ProductID: id, Before: before, After: mv.StockAfter - mv.ReservedAfter})
+	m.queued = append(m.queued, list...)
+	return err
+}
+
+func (m *memInventory) SetStock(_ context.Context, mv *model.StockMovement, stock int, onChange repository.StockEvents) error {
+	if stock == m.stock[mv.ProductID] {
+		return nil
+	}
+	mv.Kind, mv.StockDelta = model.MovementAdjusted, stock-m.stock[mv.ProductID]
+	return m.record(mv, onChange)
+}
+func (m *memInventory) Move(_ context.Context, mv *model.StockMovement, onChange repository.StockEvents) error {
+	return m.record(mv, onChange)
+}
+func (m *memInventory) Reserve(_ context.Context, r *model.Reservation, onChange repository.StockEvents) error {
+	for _, it := range r.Items {
+		if avail := m.stock[it.ProductID] - m.reserved[it.ProductID]; avail < it.Quantity {
+			return fmt.Errorf("%w: %d available", repository.ErrInsufficientStock, avail)
+		}
+	}
+	for _, it := range r.Items {
+		if err := m.record(&model.StockMovement{
+			ProductID: it.ProductID, Kind: model.MovementReserved, ReservedDelta: it.Quantity, ReservationID: &r.ID,
+		}, onChange); err != nil {
+			return err
+		}
+	}
+	m.reservations[r.ID] = r
+	return nil
+}
+func (m *memInventory) GetReservation(_ context.Context, id uuid.UUID) (*model.Reservation, error) {
+	r, ok := m.reservations[id]
+	if !ok {
+		return nil, repository.ErrReservationNotFound
+	}
+	return r, nil
+}
+func (m *memInventory) CommitReservation(_ context.Context, id uuid.UUID, now time.Time, actor *uuid.UUID) error {
+	r := m.reservations[id]
+	if r.Status != model.ReservationPending {
+		return repository.ErrReservationClosed
+	}
+	if !now.Before(r.ExpiresAt) {
+		return repository.ErrReservationExpired
+	}
+	for _, it := range r.Items {
+		_ = m.record(&model.StockMovement{
+			ProductID: it.ProductID, Kind: model.MovementSold, StockDelta: -it.Quantity, ReservedDelta: -it.Quantity,
+			ReservationID: &id, ActorID: actor,
+		}, nil)
+	}
+	r.Status = model.ReservationCommitted
+	return nil
+}
+func (m *memInventory) ReleaseReservation(_ context.Context, id uuid.UUID, status string, actor *uuid.UUID) error {
+	r := m.reservations[id]
+	if r.Status != model.ReservationPending {
+		return repository.ErrReservationClosed
+	}
+	for _, it := range r.Items {
+		_ = m.record(&model.StockMovement{
+			ProductID: it.ProductID, Kind: model.MovementReleased, ReservedDelta: -it.Quantity,
+			ReservationID: &id, ActorID: actor, Reason: status,
+		}, nil)
+	}
+	r.Status = status
+	return nil
+}
+func (m *memInventory) ExpiredReservations(_ context.Context, now time.Time, _ int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for id, r := range m.reservations {
+		if r.Status == model.ReservationPending && !now.Before(r.ExpiresAt) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+func (m *memInventory) ListMovements(_ context.Context, productID uuid.UUID, _ *uuid.UUID, offset, limit int) ([]model.StockMovement, error) {
+	var list []model.StockMovement
+	for i := len(m.ledger) - 1; i >= 0; i-- {
+		if m.ledger[i].ProductID == productID {
+			list = append(list, m.ledger[i])
+		}
+	}
+	list = list[min(offset, len(list)):]
+	return list[:min(limit, len(list))], nil
+}
+func (m *memInventory) FindSKU(context.Context, string) (uuid.UUID, *uuid.UUID, error) {
+	return uuid.Nil, nil, repository.ErrVariantNotFound
+}
+func (m *memInventory) ProjectStock(_ context.Context, productID uuid.UUID, variantID *uuid.UUID) (model.StockLevel, error) {
+	level := model.StockLevel{ProductID: productID, VariantID: variantID}
+	for _, s := range m.snapshots {
+		if s.ProductID == productID && s.MovementID > level.MovementID {
+			level.MovementID, level.Stock, level.Reserved = s.MovementID, s.Stock, s.Reserved
+		}
+	}
+	for _, mv := range m.ledger {
+		if mv.ProductID == productID && mv.ID > level.MovementID {
+			level.MovementID = mv.ID
+			level.Stock += mv.StockDelta
+			level.Reserved += mv.ReservedDelta
+		}
+	}
+	return level, nil
+}
+func (m *memInventory) SnapshotStock(ctx context.Context, limit int) (int, error) {
+	n := 0
+	for id := range m.stock {
+		level, _ := m.ProjectStock(ctx, id, nil)
+		if n < limit && level.MovementID > 0 && !slices.ContainsFunc(m.snapshots, func(s model.StockSnapshot) bool {
+			return s.ProductID == id && s.MovementID == level.MovementID
+		}) {
+			m.snapshots = append(m.snapshots, model.StockSnapshot{
+				ID: uuid.New(), ProductID: id, MovementID: level.MovementID, Stock: level.Stock, Reserved: level.Reserved,
+			})
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestInventory_ReserveCommitRelease(t *testing.T) {
+	seller, buyer := uuid.New(), uuid.New()
+	products, inv := new(mockRepo), newMemInventory()
+	svc := service.NewInventory(inv, products, 5, 15*time.Minute)
+	prod := &model.Product{ID: uuid.New(), Price: money.New(1000, "EUR"), SellerID: seller}
+	products.On("GetByID", mock.Anything, prod.ID.String()).Return(prod, nil)
+
+	assert.ErrorIs(t, svc.SetStock(asUser(buyer, token.RoleUser), prod.ID.String(), nil, 10, ""), service.ErrForbidden)
+	assert.ErrorIs(t, svc.SetStock(asUser(seller, token.RoleUser), prod.ID.String(), nil, -1, ""), service.ErrInvalidStock)
+	require.NoError(t, svc.SetStock(asUser(seller, token.RoleUser), prod.ID.String(), nil, 8, ""))
+
+	ctx := asUser(buyer, token.RoleUser)
+	item := func(q int) []model.ReservationItem {
+		return []model.ReservationItem{{ProductID: prod.ID, Quantity: q}}
+	}
+	_, err := svc.Reserve(ctx, item(9), 0)
+	assert.ErrorIs(t, err, service.ErrInsufficientStock)
+	assert.Contains(t, err.Error(), "8 available")
+	_, err = svc.Reserve(ctx, append(item(1), item(1)...), 0)
+	assert.ErrorIs(t, err, service.ErrInvalidStock, "duplicate items")
+	_, err = svc.Reserve(ctx, item(1), 2*time.Hour)
+	assert.ErrorIs(t, err, service.ErrInvalidStock, "ttl too long")
+
+	first, err := svc.Reserve(ctx, item(4), 0) // 8 → 4 available: low
+	require.NoError(t, err)
+	second, err := svc.Reserve(ctx, item(4), 0) // 4 → 0: out of stock
+	require.NoError(t, err)
+	require.Len(t, inv.queued, 2)
+	assert.Equal(t, service.EventStockLow, inv.queued[0].Type)
+	assert.Equal(t, service.EventOutOfStock, inv.queued[1].Type)
+	var data service.StockEventData
+	require.NoError(t, json.Unmarshal(inv.queued[1].Data, &data))
+	assert.Equal(t, service.StockEventData{ProductID: prod.ID, Available: 0, Threshold: 5}, data)
+
+	other := asUser(uuid.New(), token.RoleUser)
+	_, err = svc.Commit(other, first.ID)
+	assert.ErrorIs(t, err, service.ErrReservationNotFound, "only whoever reserved can commit")
+
+	got, err := svc.Commit(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ReservationCommitted, got.Status)
+	assert.Equal(t, 4, inv.stock[prod.ID])
+	_, err = svc.Release(ctx, first.ID)
+	assert.ErrorIs(t, err, service.ErrReservationClosed)
+
+	got, err = svc.Release(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ReservationReleased, got.Status)
+	assert.Equal(t, 0, inv.reserved[prod.ID])
+}
+
+func TestInventory_ServicePrincipals(t *testing.T) {
+	inv := newMemInventory()
+	svc := service.NewInventory(inv, new(mockRepo), 0, time.Minute)
+	prod := uuid.New()
+	inv.stock[prod] = 3
+	asService := func(san string, scopes ...string) context.Context {
+		return context.WithValue(context.Background(), token.CtxKey, &token.Payload{Service: san, Scopes: scopes})
+	}
+
+	orders := asService("spiffe://marketplace/order-service", token.ScopeProductsWrite)
+	res, err := svc.Reserve(orders, []model.ReservationItem{{ProductID: prod, Quantity: 1}}, 0)
+	require.NoError(t, err)
+	_, err = svc.Get(orders, res.ID)
+	assert.NoError(t, err, "the service that reserved")
+	_, err = svc.Release(asService("spiffe://marketplace/search-service", token.ScopeProductsWrite), res.ID)
+	assert.ErrorIs(t, err, service.ErrReservationNotFound, "another service without the inventory scope")
+	_, err = svc.Release(asUser(uuid.New(), token.RoleUser), res.ID)
+	assert.ErrorIs(t, err, service.ErrReservationNotFound)
+
+	got, err := svc.Commit(asService("spiffe://marketplace/payment-service", token.ScopeInventoryManage), res.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ReservationCommitted, got.Status)
+}
+
+func TestInventory_Expiry(t *testing.T) {
+	buyer := uuid.New()
+	inv := newMemInventory()
+	svc := service.NewInventory(inv, new(mockRepo), 0, time.Minute)
+	prod := uuid.New()
+	inv.stock[prod] = 3
+
+	ctx := asUser(buyer, token.RoleUser)
+	res, err := svc.Reserve(ctx, []model.ReservationItem{{ProductID: prod, Quantity: 2}}, time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+
+	_, err = svc.Commit(ctx, res.ID)
+	assert.ErrorIs(t, err, service.ErrReservationClosed, "expired reservations cannot be committed")
+	assert.Equal(t, model.ReservationExpired, res.Status)
+	assert.Equal(t, 0, inv.reserved[prod])
+
+	res, err = svc.Reserve(ctx, []model.ReservationItem{{ProductID: prod, Quantity: 3}}, time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+	n, err := svc.ExpireDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, model.ReservationExpired, res.Status)
+}
+
+func TestInventory_Ledger(t *testing.T) {
+	seller, buyer := uuid.New(), uuid.New()
+	products, inv := new(mockRepo), newMemInventory()
+	svc := service.NewInventory(inv, products, 0, 15*time.Minute)
+	prod := &model.Product{ID: uuid.New(), Price: money.New(1000, "EUR"), SellerID: seller}
+	products.On("GetByID", mock.Anything, prod.ID.String()).Return(prod, nil)
+	ctx := asUser(seller, token.RoleUser)
+	id := prod.ID.String()
+
+	_, err := svc.RecordMovement(ctx, id, nil, model.MovementReceived, 10, "")
+	require.NoError(t, err)
+	_, err = svc.RecordMovement(ctx, id, nil, model.MovementAdjusted, -2, " ")
+	assert.ErrorIs(t, err, service.ErrInvalidStock, "adjustments need a reason")
+	_, err = svc.RecordMovement(ctx, id, nil, model.MovementSold, 1, "")
+	assert.ErrorIs(t, err, service.ErrInvalidStock, "sales come from reservations")
+	_, err = svc.RecordMovement(ctx, id, nil, model.MovementReceived, -1, "")
+	assert.ErrorIs(t, err, service.ErrInvalidStock)
+	m, err := svc.RecordMovement(ctx, id, nil, model.MovementAdjusted, -2, "water damage")
+	require.NoError(t, err)
+	assert.Equal(t, 8, m.StockAfter)
+	assert.Equal(t, &seller, m.ActorID)
+
+	res, err := svc.Reserve(asUser(buyer, token.RoleUser), []model.ReservationItem{{ProductID: prod.ID, Quantity: 3}}, 0)
+	require.NoError(t, err)
+	_, err = svc.Commit(asUser(buyer, token.RoleUser), res.ID)
+	require.NoError(t, err)
+	_, err = svc.RecordMovement(ctx, id, nil, model.MovementReturned, 1, "RMA-17")
+	require.NoError(t, err)
+
+	_, err = svc.Movements(asUser(buyer, token.RoleUser), id, nil, 1, 10)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	hist, err := svc.Movements(ctx, id, nil, 1, 10)
+	require.NoError(t, err)
+	var kinds []string
+	for _, m := range hist.Movements {
+		kinds = append(kinds, m.Kind)
+	}
+	assert.Equal(t, []string{"returned", "sold", "reserved", "adjusted", "received"}, kinds)
+	assert.Equal(t, model.StockLevel{ProductID: prod.ID, MovementID: 5, Stock: 6}, hist.Level)
+	assert.Equal(t, res.ID, *hist.Movements[1].ReservationID)
+
+	n, err := svc.Snapshot(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.NoError(t, svc.SetStock(ctx, id, nil, 4, ""))
+	hist, err = svc.Movements(ctx, id, nil, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, hist.Movements, 1)
+	assert.Equal(t, model.MovementAdjusted, hist.Movements[0].Kind)
+	assert.Equal(t, -2, hist.Movements[0].StockDelta)
+	assert.Equal(t, "stock count", hist.Movements[0].Reason)
+	assert.Equal(t, 4, hist.Level.Stock, "snapshot plus later entries")
+	assert.Equal(t, inv.stock[prod.ID], hist.Level.Stock, "projection matches the stock column")
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/google/uuid"
+)
+
+const (
+	maxReasonLen  = 500
+	snapshotBatch = 500
+)
+
+// StockHistory is a page of the ledger of a product or variant, newest
+// first, with the stock projected from the whole ledger.
+type StockHistory struct {
+	Level     model.StockLevel
+	Movements []model.StockMovement
+}
+
+// RecordMovement records goods received or returned (quantity > 0) or an
+// adjustment (quantity ≠ 0, with a reason) for product id or one of its
+// variants. Reserved, released and sold are recorded by reservations. Only
+// the seller or an admin may.
+func (s *InventoryService) RecordMovement(ctx context.Context, id string, variantID *uuid.UUID, kind string, quantity int, reason string) (*model.StockMovement, error) {
+	p, err := ownedProduct(ctx, s.products, id)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case model.MovementReceived, model.MovementReturned:
+		if quantity <= 0 {
+			return nil, fmt.Errorf("%w: %s quantity must be positive", ErrInvalidStock, kind)
+		}
+		reason, err = cleanReason(reason, "")
+	case model.MovementAdjusted:
+		if quantity == 0 {
+			return nil, fmt.Errorf("%w: adjustment cannot be zero", ErrInvalidStock)
+		}
+		reason, err = cleanReason(reason, "")
+		if err == nil && reason == "" {
+			err = fmt.Errorf("%w: adjustments need a reason", ErrInvalidStock)
+		}
+	default:
+		return nil, fmt.Errorf("%w: kind must be received, returned or adjusted", ErrInvalidStock)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := &model.StockMovement{
+		ProductID: p.ID, VariantID: variantID, Kind: kind,
+		StockDelta: quantity, ActorID: actorID(ctx), Reason: reason,
+	}
+	if err := s.repo.Move(ctx, m, s.stockEvents); err != nil {
+		return nil, inventoryErr(err)
+	}
+	return m, nil
+}
+
+// Movements returns the ledger of product id, or of one of its variants.
+// Only the seller or an admin may; admins also see deleted products.
+func (s *InventoryService) Movements(ctx context.Context, id string, variantID *uuid.UUID, page, pageSize int) (*StockHistory, error) {
+	productID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return s.history(ctx, productID, variantID, page, pageSize)
+}
+
+// SKUMovements returns the ledger of the variant using sku, or that last
+// used it.
+func (s *InventoryService) SKUMovements(ctx context.Context, sku string, page, pageSize int) (*StockHistory, error) {
+	productID, variantID, err := s.repo.FindSKU(ctx, strings.TrimSpace(sku))
+	if err != nil {
+		return nil, inventoryErr(err)
+	}
+	return s.history(ctx, productID, variantID, page, pageSize)
+}
+
+func (s *InventoryService) history(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, page, pageSize int) (*StockHistory, error) {
+	if _, err := ownedProduct(ctx, s.products, productID.String()); err != nil {
+		if !errors.Is(err, ErrNotFound) || requireAdmin(ctx) != nil {
+			return nil, err
+		}
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	level, err := s.repo.ProjectStock(ctx, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListMovements(ctx, productID, variantID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &StockHistory{Level: level, Movements: list}, nil
+}
+
+// Snapshot stores snapshots of every product and variant with ledger
+// entries since its last one and returns how many. Run it periodically.
+func (s *InventoryService) Snapshot(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := s.repo.SnapshotStock(ctx, snapshotBatch)
+		total += n
+		if err != nil || n < snapshotBatch {
+			return total, err
+		}
+	}
+}
+
+// cleanReason trims reason and substitutes def when it is empty.
+func cleanReason(reason, def string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if len(reason) > maxReasonLen {
+		return "", fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidStock, maxReasonLen)
+	}
+	if reason == "" {
+		reason = def
+	}
+	return reason, nil
+}
@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+)
+
+func TestProducts_Browse(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.New(repo)
+	ctx := context.Background()
+	seller := uuid.New()
+	min, max := money.New(1000, "EUR"), money.New(5000, "EUR")
+	inStock := true
+	want := repository.ListQuery{
+		Filter: repository.ProductFilter{
+			SellerID: &seller, Currency: "EUR", MinPrice: &min.Amount, MaxPrice: &max.Amount,
+			InStock: &inStock, Tags: []string{"red", "blue"},
+		},
+		Sort: service.SortPriceAsc,
+	}
+	facets := model.Facets{model.FacetTags: {{Value: "red", Count: 3}}}
+	repo.On("List", ctx, want, 10, 11).Return([]model.Product{{Name: "Mug"}}, nil)
+	repo.On("Facets", ctx, want.Filter).Return(facets, nil)
+
+	got, err := svc.Browse(ctx, service.ProductQuery{
+		SellerID: &seller, PriceCurrency: " eur", MinPrice: &min, MaxPrice: &max, InStock: &inStock,
+		Tags: []string{"Red", " blue", "red"}, Sort: service.SortPriceAsc, Facets: true,
+	}, 2, 10)
+	require.NoError(t, err)
+	require.Len(t, got.Hits, 1)
+	assert.Equal(t, "Mug", got.Hits[0].Name)
+	assert.Equal(t, facets, got.Facets)
+
+	usd := money.New(100, "USD")
+	after, before := time.Now(), time.Now().Add(-time.Hour)
+	for name, q := range map[string]service.ProductQuery{
+		"mixed currencies":  {MinPrice: &min, MaxPrice: &usd},
+		"currency mismatch": {PriceCurrency: "USD", MinPrice: &min},
+		"unknown currency":  {PriceCurrency: "XYZ"},
+		"min above max":     {MinPrice: &max, MaxPrice: &min},
+		"bad tag":           {Tags: []string{"<b>"}},
+		"empty period":      {CreatedAfter: &after, CreatedBefore: &before},
+		"relevance no text": {Sort: service.SortRelevance},
+		"unknown sort":      {Sort: "random"},
+	} {
+		_, err := svc.Browse(ctx, q, 1, 10)
+		assert.ErrorIs(t, err, service.ErrInvalidFilter, name)
+	}
+	repo.AssertExpectations(t)
+}
+
+func TestProducts_SetTags(t *testing.T) {
+	seller := uuid.New()
+	ctx := asUser(seller, token.RoleUser)
+	repo := new(mockRepo)
+	svc := service.New(repo)
+	prod := &model.Product{ID: uuid.New(), Name: "Mug", SellerID: seller}
+	repo.On("GetByID", mock.Anything, prod.ID.String()).Return(prod, nil)
+	repo.On("UpdateTags", ctx, prod.ID, []string{"kitchen", "hand-made"}).Return(nil)
+
+	got, err := svc.SetTags(ctx, prod.ID.String(), []string{" Kitchen", "hand-made", "kitchen"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kitchen", "hand-made"}, got.Tags)
+
+	_, err = svc.SetTags(asUser(uuid.New(), token.RoleUser), prod.ID.String(), nil)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = svc.SetTags(ctx, prod.ID.String(), []string{""})
+	assert.ErrorIs(t, err, service.ErrInvalidTags)
+	_, err = svc.SetTags(ctx, prod.ID.String(), []string{strings.Repeat("a", 33)})
+	assert.ErrorIs(t, err, service.ErrInvalidTags)
+	_, err = svc.SetTags(ctx, prod.ID.String(), make([]string, 21))
+	assert.ErrorIs(t, err, service.ErrInvalidTags)
+	repo.AssertExpectations(t)
+}
+
+func TestProducts_PageTokens(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.New(repo)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	mug := model.Product{ID: uuid.New(), Name: "Mug", Price: money.New(900, "EUR"), CreatedAt: now}
+	cup := model.Product{ID: uuid.New(), Name: "Cup", Price: money.New(900, "EUR"), CreatedAt: now.Add(-time.Minute)}
+	jar := model.Product{ID: uuid.New(), Name: "Jar", Price: money.New(1200, "EUR"), CreatedAt: now.Add(-time.Hour)}
+
+	first := repository.ListQuery{Sort: service.SortPriceAsc}
+	next := first
+	next.After = &repository.Cursor{CreatedAt: cup.CreatedAt, ID: cup.ID, Currency: "EUR", Amount: 900}
+	repo.On("List", ctx, first, 0, 3).Return([]model.Product{mug, cup, jar}, nil)
+	repo.On("List", ctx, next, 0, 3).Return([]model.Product{jar}, nil)
+	repo.On("EstimateCount", ctx, first.Filter).Return(int64(1), nil)
+
+	q := service.ProductQuery{Sort: service.SortPriceAsc, Total: service.TotalEstimate}
+	page, err := svc.Browse(ctx, q, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Hits, 2)
+	require.NotEmpty(t, page.NextPageToken)
+	assert.Equal(t, int64(2), *page.Total, "an estimate is at least what was seen")
+	assert.True(t, page.TotalEstimated)
+
+	// The page number is ignored once there is a token.
+	q = service.ProductQuery{Sort: service.SortPriceAsc, PageToken: page.NextPageToken}
+	page, err = svc.Browse(ctx, q, 7, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Hits, 1)
+	assert.Equal(t, "Jar", page.Hits[0].Name)
+	assert.Empty(t, page.NextPageToken)
+	assert.Nil(t, page.Total)
+
+	for name, q := range map[string]service.ProductQuery{
+		"other sort":   {Sort: service.SortName, PageToken: q.PageToken},
+		"other filter": {Sort: service.SortPriceAsc, PriceCurrency: "EUR", PageToken: q.PageToken},
+		"garbage":      {PageToken: "not a token"},
+	} {
+		_, err := svc.Browse(ctx, q, 1, 2)
+		assert.ErrorIs(t, err, service.ErrInvalidPageToken, name)
+	}
+	_, err = svc.Browse(ctx, service.ProductQuery{Total: "all"}, 1, 2)
+	assert.ErrorIs(t, err, service.ErrInvalidFilter)
+	repo.AssertExpectations(t)
+}
+
+func TestProducts_PageTokensByRelevance(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.New(repo)
+	ctx := context.Background()
+	q := repository.ListQuery{Filter: repository.ProductFilter{Text: "mug"}, Sort: service.SortRelevance}
+	hits := []model.SearchHit{{Product: model.Product{ID: uuid.New()}}, {Product: model.Product{ID: uuid.New()}}}
+	repo.On("Search", ctx, q, 0, 2).Return(hits, nil)
+	repo.On("Search", ctx, q, 1, 2).Return(hits[1:], nil)
+
+	page, err := svc.Browse(ctx, service.ProductQuery{Text: "mug"}, 1, 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextPageToken)
+	page, err = svc.Browse(ctx, service.ProductQuery{Text: "mug", PageToken: page.NextPageToken}, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, hits[1].ID, page.Hits[0].ID)
+	assert.Empty(t, page.NextPageToken)
+	repo.AssertExpectations(t)
+}
+
+func TestProducts_PageSizeIsCapped(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.New(repo)
+	ctx := context.Background()
+	repo.On("List", ctx, repository.ListQuery{Sort: service.SortNewest}, 0, 21).Return([]model.Product{}, nil)
+	repo.On("List", ctx, repository.ListQuery{Sort: service.SortNewest}, 100, 101).Return([]model.Product{}, nil)
+
+	_, err := svc.Browse(ctx, service.ProductQuery{}, 0, 0)
+	require.NoError(t, err)
+	_, err = svc.Browse(ctx, service.ProductQuery{}, 2, math.MaxInt)
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPageToken is returned for a malformed page token or one issued
+// for a different listing.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// Page sizes of listings, the same over HTTP and gRPC.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageBounds defaults page to 1 and pageSize to defaultPageSize, and caps
+// pageSize at maxPageSize.
+func pageBounds(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	return page, min(pageSize, maxPageSize)
+}
+
+// Total counts of listings.
+const (
+	TotalExact    = "exact"    // counts every match
+	TotalEstimate = "estimate" // the query planner's estimate; cheap but approximate
+)
+
+// pageToken is where a listing continues. The opaque form is base64url
+// JSON. Query ties it to the filters and sort order of the listing that
+// issued it, so that it cannot continue a different one.
+type pageToken struct {
+	Query     string    `json:"q"`
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"i"`
+	Currency  string    `json:"c,omitempty"`
+	Amount    int64     `json:"a,omitempty"`
+	Name      string    `json:"n,omitempty"`
+	// Offset continues relevance order, whose ranks are no stable key.
+	Offset int `json:"o,omitempty"`
+}
+
+// queryKey fingerprints the filters and sort order of lq.
+func queryKey(lq repository.ListQuery) string {
+	b, _ := json.Marshal(struct {
+		Filter repository.ProductFilter
+		Sort   string
+	}{lq.Filter, lq.Sort})
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// nextPageToken returns the token of the page after last, which ended at
+// offset in relevance order.
+func nextPageToken(lq repository.ListQuery, last repository.Cursor, offset int) string {
+	t := pageToken{Query: queryKey(lq)}
+	if lq.Sort == SortRelevance {
+		t.Offset = offset
+	} else {
+		t.CreatedAt, t.ID = last.CreatedAt, last.ID
+		switch lq.Sort {
+		case SortPriceAsc, SortPriceDesc:
+			t.Currency, t.Amount = last.Currency, last.Amount
+		case SortName:
+			t.Name = last.Name
+		}
+	}
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// continueAt sets lq.After from token and returns the offset to continue
+// at.
+func continueAt(lq *repository.ListQuery, token string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidPageToken
+	}
+	var t pageToken
+	if err := json.Unmarshal(b, &t); err != nil {
+		return 0, ErrInvalidPageToken
+	}
+	if t.Query != queryKey(*lq) {
+		return 0, fmt.Errorf("%w: filters or sort order changed", ErrInvalidPageToken)
+	}
+	if lq.Sort == SortRelevance {
+		if t.Offset < 0 {
+			return 0, ErrInvalidPageToken
+		}
+		return t.Offset, nil
+	}
+	lq.After = &repository.Cursor{CreatedAt: t.CreatedAt, ID: t.ID, Currency: t.Currency, Amount: t.Amount, Name: t.Name}
+	return 0, nil
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/google/uuid"
+)
+
+const (
+	maxSearchLen = 200
+	maxTags      = 20
+)
+
+var (
+	// ErrInvalidSearch is returned for empty or overlong search text.
+	ErrInvalidSearch = errors.New("invalid search")
+	// ErrInvalidFilter is returned for contradictory or malformed filters
+	// and unknown sort orders.
+	ErrInvalidFilter = errors.New("invalid filter")
+	// ErrInvalidTags is returned for malformed or too many tags.
+	ErrInvalidTags = errors.New("invalid tags")
+)
+
+var tagRe = regexp.MustCompile(`^[\p{Ll}\p{N}][\p{Ll}\p{N} _-]{0,31}$`)
+
+// Sort orders of product listings.
+const (
+	SortNewest    = repository.SortNewest
+	SortPriceAsc  = repository.SortPriceAsc
+	SortPriceDesc = repository.SortPriceDesc
+	SortName      = repository.SortName
+	SortRelevance = repository.SortRelevance
+)
+
+// ProductQuery filters and sorts a product listing. Zero fields do not
+// filter.
+type ProductQuery struct {
+	Text          string // full-text search; every word matches as a prefix
+	CategoryID    *uuid.UUID
+	SellerID      *uuid.UUID
+	PriceCurrency string       // only products priced in this currency
+	MinPrice      *money.Money // inclusive; implies its currency
+	MaxPrice      *money.Money // inclusive; implies its currency
+	InStock       *bool
+	Tags          []string // any of them
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          string // relevance with Text, newest otherwise
+	Facets        bool   // also count the matches per facet value
+	PageToken     string // continues a previous listing; page is then ignored
+	Total         string // TotalExact or TotalEstimate to count the matches
+}
+
+// Listing is a page of products. Rank and highlights of the hits are only
+// set for text searches.
+type Listing struct {
+	Hits           []model.SearchHit
+	Facets         model.Facets // nil unless requested
+	NextPageToken  string       // empty on the last page
+	Total          *int64       // nil unless requested
+	TotalEstimated bool
+}
+
+// Browse returns a page of the products matching q, with facet counts over
+// all matches when q.Facets is set. Pages follow q.PageToken when set, and
+// are numbered from 1 otherwise; only tokens keep pages stable while
+// products are added or removed. pageSize defaults to 20 and is at most 100.
+func (s *ProductService) Browse(ctx context.Context, q ProductQuery, page, pageSize int) (*Listing, error) {
+	lq, err := s.listQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	switch q.Total {
+	case "", TotalExact, TotalEstimate:
+	default:
+		return nil, fmt.Errorf("%w: unknown total %q", ErrInvalidFilter, q.Total)
+	}
+	page, pageSize = pageBounds(page, pageSize)
+	offset := (page - 1) * pageSize
+	if q.PageToken != "" {
+		if offset, err = continueAt(&lq, q.PageToken); err != nil {
+			return nil, err
+		}
+	}
+
+	// One extra product tells whether there is a next page.
+	out := &Listing{}
+	if lq.Filter.Text != "" {
+		out.Hits, err = s.repo.Search(ctx, lq, offset, pageSize+1)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		list, err := s.repo.List(ctx, lq, offset, pageSize+1)
+		if err != nil {
+			return nil, err
+		}
+		out.Hits = make([]model.SearchHit, len(list))
+		for i := range list {
+			out.Hits[i].Product = list[i]
+		}
+	}
+	if len(out.Hits) > pageSize {
+		out.Hits = out.Hits[:pageSize]
+		last := repository.CursorOf(&out.Hits[pageSize-1].Product)
+		out.NextPageToken = nextPageToken(lq, last, offset+pageSize)
+	}
+
+	if q.Facets {
+		if out.Facets, err = s.repo.Facets(ctx, lq.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if q.Total != "" {
+		count := s.repo.Count
+		if q.Total == TotalEstimate {
+			count, out.TotalEstimated = s.repo.EstimateCount, true
+		}
+		n, err := count(ctx, lq.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if out.TotalEstimated {
+			// The matches seen so far are a lower bound.
+			n = max(n, int64(offset+len(out.Hits)))
+		}
+		out.Total = &n
+	}
+	return out, nil
+}
+
+// listQuery validates q and translates it for the repository.
+func (s *ProductService) listQuery(ctx context.Context, q ProductQuery) (repository.ListQuery, error) {
+	var f repository.ProductFilter
+	f.Text = strings.TrimSpace(q.Text)
+	if len(f.Text) > maxSearchLen {
+		return repository.ListQuery{}, fmt.Errorf("%w: search text must be 1 to %d characters", ErrInvalidSearch, maxSearchLen)
+	}
+	if q.CategoryID != nil {
+		c, err := s.category(ctx, *q.CategoryID)
+		if err != nil {
+			return repository.ListQuery{}, err
+		}
+		f.CategoryPath = c.Path
+	}
+	f.SellerID = q.SellerID
+
+	f.Currency = strings.ToUpper(strings.TrimSpace(q.PriceCurrency))
+	for _, p := range []*money.Money{q.MinPrice, q.MaxPrice} {
+		if p == nil {
+			continue
+		}
+		if f.Currency == "" {
+			f.Currency = p.Currency
+		}
+		if p.Currency != f.Currency {
+			return repository.ListQuery{}, fmt.Errorf("%w: price bounds must be in the filtered currency", ErrInvalidFilter)
+		}
+	}
+	if f.Currency != "" && !money.ValidCurrency(f.Currency) {
+		return repository.ListQuery{}, fmt.Errorf("%w: unknown currency %q", ErrInvalidFilter, f.Currency)
+	}
+	if q.MinPrice != nil {
+		f.MinPrice = &q.MinPrice.Amount
+	}
+	if q.MaxPrice != nil {
+		f.MaxPrice = &q.MaxPrice.Amount
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return repository.ListQuery{}, fmt.Errorf("%w: minimum price above maximum", ErrInvalidFilter)
+	}
+
+	f.InStock = q.InStock
+	if len(q.Tags) > 0 {
+		tags, err := cleanTags(q.Tags)
+		if err != nil {
+			return repository.ListQuery{}, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+		}
+		f.Tags = tags
+	}
+	f.CreatedAfter, f.CreatedBefore = q.CreatedAfter, q.CreatedBefore
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && !f.CreatedAfter.Before(*f.CreatedBefore) {
+		return repository.ListQuery{}, fmt.Errorf("%w: createdAfter must be before createdBefore", ErrInvalidFilter)
+	}
+
+	lq := repository.ListQuery{Filter: f, Sort: q.Sort}
+	switch lq.Sort {
+	case "":
+		lq.Sort = SortNewest
+		if f.Text != "" {
+			lq.Sort = SortRelevance
+		}
+	case SortRelevance:
+		if f.Text == "" {
+			return repository.ListQuery{}, fmt.Errorf("%w: sorting by relevance needs search text", ErrInvalidFilter)
+		}
+	case SortNewest, SortPriceAsc, SortPriceDesc, SortName:
+	default:
+		return repository.ListQuery{}, fmt.Errorf("%w: unknown sort %q", ErrInvalidFilter, q.Sort)
+	}
+	return lq, nil
+}
+
+// SetTags replaces the tags of product id. Only the seller or an admin may.
+func (s *ProductService) SetTags(ctx context.Context, id string, tags []string) (*model.Product, error) {
+	p, err := s.owned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tags, err = cleanTags(tags); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateTags(ctx, p.ID, tags); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	p.Tags = tags
+	return p, nil
+}
+
+// cleanTags lowercases and trims tags and drops repeats.
+func cleanTags(tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags", ErrInvalidTags, maxTags)
+	}
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !tagRe.MatchString(t) {
+			return nil, fmt.Errorf("%w: %q must be 1 to 32 letters, digits, spaces, dashes or underscores", ErrInvalidTags, t)
+		}
+		if !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,44 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+)
+
+func TestProducts_Search(t *testing.T) {
+	cats := newMemCategoryRepo()
+	phones := model.Category{ID: uuid.New(), Name: "Phones", Slug: "phones"}
+	phones.Path = "/" + phones.ID.String() + "/"
+	cats.rows[phones.ID] = phones
+
+	repo := new(mockRepo)
+	svc := service.New(repo, service.WithCategories(cats))
+	ctx := context.Background()
+	hit := model.SearchHit{Product: model.Product{ID: uuid.New(), Name: "Blue phone"}, NameHighlight: "<mark>Blue</mark> phone"}
+	repo.On("Search", ctx, repository.ListQuery{Filter: repository.ProductFilter{Text: "blu"}, Sort: service.SortRelevance}, 0, 21).
+		Return([]model.SearchHit{hit}, nil)
+	repo.On("Search", ctx, repository.ListQuery{Filter: repository.ProductFilter{Text: "blu", CategoryPath: phones.Path}, Sort: service.SortRelevance}, 20, 21).
+		Return([]model.SearchHit{}, nil)
+
+	got, err := svc.Browse(ctx, service.ProductQuery{Text: "  blu "}, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []model.SearchHit{hit}, got.Hits)
+	_, err = svc.Browse(ctx, service.ProductQuery{Text: "blu", CategoryID: &phones.ID}, 2, 20)
+	require.NoError(t, err)
+
+	_, err = svc.Browse(ctx, service.ProductQuery{Text: strings.Repeat("a", 201)}, 1, 10)
+	assert.ErrorIs(t, err, service.ErrInvalidSearch)
+	missing := uuid.New()
+	_, err = svc.Browse(ctx, service.ProductQuery{Text: "blu", CategoryID: &missing}, 1, 10)
+	assert.ErrorIs(t, err, service.ErrCategoryNotFound)
+	repo.AssertExpectations(t)
+}
@@ -7,33 +7,105 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/fx"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
 	"github.com/google/uuid"
 )
 
-// ErrNotFound is returned when the requested product does not exist.
-var ErrNotFound = errors.New("product not found")
+var (
+	// ErrNotFound is returned when the requested product does not exist.
+	ErrNotFound = errors.New("product not found")
+	// ErrUnauthenticated is returned when an operation needs a signed-in user.
+	ErrUnauthenticated = errors.New("not authenticated as a user")
+	// ErrForbidden is returned when the caller neither sells the product nor is an admin.
+	ErrForbidden = errors.New("only the seller or an admin can change this product")
+	// ErrInvalidPrice is returned for prices that are not positive or have no valid currency.
+	ErrInvalidPrice = errors.New("invalid price")
+	// ErrUnsupportedCurrency is returned when prices cannot be shown in the requested currency.
+	ErrUnsupportedCurrency = errors.New("unsupported display currency")
+	// ErrRatesUnavailable is returned when the exchange rate provider fails.
+	ErrRatesUnavailable = errors.New("exchange rates unavailable")
+)
 
 // ProductService is the façade exposed to the transport layers.
 // It orchestrates validation and delegates persistence to the repository layer.
 type ProductService struct {
 	repo repository.Repository
+	fx   *fx.Converter // nil disables display currencies
+
+	categories repository.CategoryRepository // nil: no category exists
+}
+
+// Option customises a ProductService.
+type Option func(*ProductService)
+
+// WithFX enables display prices in other currencies.
+func WithFX(c *fx.Converter) Option {
+	return func(s *ProductService) { s.fx = c }
+}
+
+// WithCategories enables assigning and browsing products by category.
+func WithCategories(repo repository.CategoryRepository) Option {
+	return func(s *ProductService) { s.categories = repo }
 }
 
 // New returns a new ProductService.
-func New(repo repository.Repository) *ProductService {
-	return &ProductService{repo: repo}
+func New(repo repository.Repository, opts ...Option) *ProductService {
+	s := &ProductService{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// DisplayPrice is a product price converted for display, with the rate
+// that was used.
+type DisplayPrice struct {
+	Price money.Money
+	Rate  fx.Rate
+}
+
+// DisplayPrices converts the price of each product into currency. Prices
+// already in that currency are returned unchanged with a rate of 1.
+func (s *ProductService) DisplayPrices(ctx context.Context, products []model.Product, currency string) ([]DisplayPrice, error) {
+	if s.fx == nil {
+		return nil, fmt.Errorf("%w: currency conversion is not configured", ErrUnsupportedCurrency)
+	}
+	out := make([]DisplayPrice, len(products))
+	for i, p := range products {
+		price, rate, err := s.fx.Convert(ctx, p.Price, currency)
+		switch {
+		case errors.Is(err, fx.ErrUnavailable):
+			return nil, fmt.Errorf("%w: %w", ErrRatesUnavailable, err)
+		case err != nil:
+			return nil, fmt.Errorf("%w: %w", ErrUnsupportedCurrency, err)
+		}
+		out[i] = DisplayPrice{Price: price, Rate: rate}
+	}
+	return out, nil
 }
 
-// Create inserts a new product and returns the persisted entity.
-func (s *ProductService) Create(ctx context.Context, name, description string, price float64) (*model.Product, error) {
+// Create inserts a new product sold by the calling user and returns the
+// persisted entity.
+func (s *ProductService) Create(ctx context.Context, name, description string, price money.Money) (*model.Product, error) {
+	seller, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePrice(price); err != nil {
+		return nil, err
+	}
 	p := &model.Product{
 		ID:          uuid.New(),
 		Name:        name,
 		Description: description,
 		Price:       price,
+		SellerID:    seller,
 	}
 	if err := s.repo.Create(ctx, p); err != nil {
 		return nil, err
@@ -53,25 +125,25 @@ func (s *ProductService) Get(ctx context.Context, id string) (*model.Product, er
 	return p, nil
 }
 
-// List returns a paginated set of products.
-func (s *ProductService) List(ctx context.Context, page, pageSize int) ([]model.Product, error) {
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 {
-		pageSize = 10
+// ListMine is Browse restricted to the calling user's products.
+func (s *ProductService) ListMine(ctx context.Context, q ProductQuery, page, pageSize int) (*Listing, error) {
+	seller, err := callerID(ctx)
+	if err != nil {
+		return nil, err
 	}
-	offset := (page - 1) * pageSize
-	return s.repo.List(ctx, offset, pageSize)
+	q.SellerID = &seller
+	return s.Browse(ctx, q, page, pageSize)
 }
 
-// Update modifies an existing product.
-func (s *ProductService) Update(ctx context.Context, id string, name, description *string, price *float64) (*model.Product, error) {
-	p, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return nil, ErrNotFound
+// Update modifies an existing product. Only its seller or an admin may.
+func (s *ProductService) Update(ctx context.Context, id string, name, description *string, price *money.Money) (*model.Product, error) {
+	if price != nil {
+		if err := validatePrice(*price); err != nil {
+			return nil, err
 		}
+	}
+	p, err := s.owned(ctx, id)
+	if err != nil {
 		return nil, err
 	}
 	if name != nil {
@@ -81,16 +153,30 @@ func (s *ProductService) Update(ctx context.Context, id string, name, descriptio
 		p.Description = *description
 	}
 	if price != nil {
+		if price.Currency != p.Price.Currency && len(p.Variants) > 0 {
+			return nil, fmt.Errorf("%w: the variants are priced in %s", ErrInvalidPrice, p.Price.Currency)
+		}
 		p.Price = *price
+		p.PriceMissing = false
 	}
 	if err := s.repo.Update(ctx, p); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, ErrNotFound
+		case errors.Is(err, repository.ErrCurrencyMismatch):
+			// a variant was added since p was read
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+		}
 		return nil, err
 	}
 	return p, nil
 }
 
-// Delete removes a product.
+// Delete removes a product. Only its seller or an admin may.
 func (s *ProductService) Delete(ctx context.Context, id string) error {
+	if _, err := s.owned(ctx, id); err != nil {
+		return err
+	}
 	if err := s.repo.Delete(ctx, id); err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return ErrNotFound
@@ -99,3 +185,49 @@ func (s *ProductService) Delete(ctx context.Context, id string) error {
 	}
 	return nil
 }
+
+// validatePrice accepts positive amounts in an ISO 4217 currency.
+func validatePrice(m money.Money) error {
+	if err := m.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	if m.Amount <= 0 {
+		return fmt.Errorf("%w: must be greater than zero", ErrInvalidPrice)
+	}
+	return nil
+}
+
+// owned loads the product and checks that the caller may change it.
+func (s *ProductService) owned(ctx context.Context, id string) (*model.Product, error) {
+	return ownedProduct(ctx, s.repo, id)
+}
+
+// ownedProduct loads a product and checks that the caller is its seller or
+// an admin.
+func ownedProduct(ctx context.Context, repo repository.Repository, id string) (*model.Product, error) {
+	pl, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || pl == nil {
+		return nil, ErrUnauthenticated
+	}
+	p, err := repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if pl.IsAdmin() || (pl.UserID != uuid.Nil && pl.UserID == p.SellerID) {
+		return p, nil
+	}
+	return nil, ErrForbidden
+}
+
+// callerID returns the signed-in user from the token payload in ctx.
+// Service principals have no user and cannot own products.
+func callerID(ctx context.Context) (uuid.UUID, error) {
+	pl, ok := ctx.Value(token.CtxKey).(*token.Payload)
+	if !ok || pl == nil || pl.UserID == uuid.Nil {
+		return uuid.Nil, ErrUnauthenticated
+	}
+	return pl.UserID, nil
+}
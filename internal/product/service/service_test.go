@@ -4,13 +4,22 @@ package service_test
 
 import (
 	"context"
+	"errors"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/fx"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+	"github.com/google/uuid"
 )
 
 // ---- Mock repository ----
@@ -28,30 +37,202 @@ func (m *mockRepo) GetByID(ctx context.Context, id string) (*model.Product, erro
 	}
 	return nil, args.Error(1)
 }
-func (m *mockRepo) List(ctx context.Context, off, lim int) ([]model.Product, error) {
-	args := m.Called(ctx, off, lim)
+func (m *mockRepo) List(ctx context.Context, q repository.ListQuery, off, lim int) ([]model.Product, error) {
+	args := m.Called(ctx, q, off, lim)
 	if args.Get(0) != nil {
 		return args.Get(0).([]model.Product), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
+func (m *mockRepo) Count(ctx context.Context, f repository.ProductFilter) (int64, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *mockRepo) EstimateCount(ctx context.Context, f repository.ProductFilter) (int64, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *mockRepo) Facets(ctx context.Context, f repository.ProductFilter) (model.Facets, error) {
+	args := m.Called(ctx, f)
+	if args.Get(0) != nil {
+		return args.Get(0).(model.Facets), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+func (m *mockRepo) Search(ctx context.Context, q repository.ListQuery, off, lim int) ([]model.SearchHit, error) {
+	args := m.Called(ctx, q, off, lim)
+	if args.Get(0) != nil {
+		return args.Get(0).([]model.SearchHit), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
 func (m *mockRepo) Update(ctx context.Context, p *model.Product) error {
 	return m.Called(ctx, p).Error(0)
 }
 func (m *mockRepo) Delete(ctx context.Context, id string) error { return m.Called(ctx, id).Error(0) }
+func (m *mockRepo) UpdateTags(ctx context.Context, id uuid.UUID, tags []string) error {
+	return m.Called(ctx, id, tags).Error(0)
+}
+func (m *mockRepo) UpdateOptions(ctx context.Context, id uuid.UUID, options []model.Option) error {
+	return m.Called(ctx, id, options).Error(0)
+}
+func (m *mockRepo) ListVariants(ctx context.Context, id uuid.UUID) ([]model.Variant, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).([]model.Variant), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+func (m *mockRepo) CreateVariant(ctx context.Context, v *model.Variant) error {
+	return m.Called(ctx, v).Error(0)
+}
+func (m *mockRepo) UpdateVariant(ctx context.Context, v *model.Variant) error {
+	return m.Called(ctx, v).Error(0)
+}
+func (m *mockRepo) DeleteVariant(ctx context.Context, productID, id uuid.UUID, actor *uuid.UUID) error {
+	return m.Called(ctx, productID, id, actor).Error(0)
+}
+
+// asUser returns a context carrying the token payload of a signed-in user.
+func asUser(id uuid.UUID, role string) context.Context {
+	return context.WithValue(context.Background(), token.CtxKey, &token.Payload{UserID: id, Role: role})
+}
+
+// staticRates is an in-memory fx.Provider.
+type staticRates struct {
+	table *fx.Table
+	err   error
+}
+
+func (r staticRates) Rates(context.Context) (*fx.Table, error) { return r.table, r.err }
 
 // ---- Tests ----
 
 func TestCreate_Success(t *testing.T) {
-	ctx := context.Background()
+	seller := uuid.New()
+	ctx := asUser(seller, token.RoleUser)
 	repo := new(mockRepo)
 	svc := service.New(repo)
 
 	repo.On("Create", ctx, mock.AnythingOfType("*model.Product")).Return(nil)
 
-	got, err := svc.Create(ctx, "Laptop", "Gaming laptop", 999.99)
+	got, err := svc.Create(ctx, "Laptop", "Gaming laptop", money.New(99999, "EUR"))
 
 	assert.NoError(t, err)
 	assert.Equal(t, "Laptop", got.Name)
+	assert.Equal(t, seller, got.SellerID)
+	repo.AssertExpectations(t)
+}
+
+func TestCreate_RequiresUser(t *testing.T) {
+	svc := service.New(new(mockRepo))
+	_, err := svc.Create(context.Background(), "Laptop", "", money.New(99999, "EUR"))
+	assert.ErrorIs(t, err, service.ErrUnauthenticated)
+}
+
+func TestCreate_InvalidPrice(t *testing.T) {
+	ctx := asUser(uuid.New(), token.RoleUser)
+	svc := service.New(new(mockRepo))
+	for _, price := range []money.Money{money.New(0, "EUR"), money.New(-100, "EUR"), money.New(100, "EURO"), {Amount: 100}} {
+		_, err := svc.Create(ctx, "Laptop", "", price)
+		assert.ErrorIs(t, err, service.ErrInvalidPrice, price.String())
+	}
+}
+
+func TestUpdateDelete_OwnerOrAdmin(t *testing.T) {
+	seller, other := uuid.New(), uuid.New()
+	repo := new(mockRepo)
+	svc := service.New(repo)
+	prod := &model.Product{ID: uuid.New(), Name: "Laptop", Price: money.New(99999, "USD"), SellerID: seller}
+	id := prod.ID.String()
+	repo.On("GetByID", mock.Anything, id).Return(prod, nil)
+	repo.On("Update", mock.Anything, prod).Return(nil)
+	repo.On("Delete", mock.Anything, id).Return(nil)
+
+	name := "Gaming laptop"
+	_, err := svc.Update(asUser(other, token.RoleUser), id, &name, nil, nil)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	assert.ErrorIs(t, svc.Delete(asUser(other, token.RoleUser), id), service.ErrForbidden)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	got, err := svc.Update(asUser(seller, token.RoleUser), id, &name, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, name, got.Name)
+	assert.NoError(t, svc.Delete(asUser(other, token.RoleAdmin), id))
+}
+
+func TestUpdate_SettingAPriceClearsPriceMissing(t *testing.T) {
+	seller := uuid.New()
+	repo := new(mockRepo)
+	svc := service.New(repo)
+	prod := &model.Product{ID: uuid.New(), Price: money.New(0, "USD"), PriceMissing: true, SellerID: seller}
+	repo.On("GetByID", mock.Anything, prod.ID.String()).Return(prod, nil)
+	repo.On("Update", mock.Anything, prod).Return(nil)
+
+	name := "Lamp"
+	got, err := svc.Update(asUser(seller, token.RoleUser), prod.ID.String(), &name, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, got.PriceMissing, "only a price clears the flag")
+
+	price := money.New(1500, "USD")
+	got, err = svc.Update(asUser(seller, token.RoleUser), prod.ID.String(), nil, nil, &price)
+	require.NoError(t, err)
+	assert.False(t, got.PriceMissing)
+}
+
+func TestListMine(t *testing.T) {
+	seller := uuid.New()
+	ctx := asUser(seller, token.RoleUser)
+	repo := new(mockRepo)
+	svc := service.New(repo)
+	q := repository.ListQuery{Filter: repository.ProductFilter{SellerID: &seller}, Sort: service.SortNewest}
+	repo.On("List", ctx, q, 20, 11).Return([]model.Product{{Name: "Mouse", SellerID: seller}}, nil)
+	repo.On("Count", ctx, q.Filter).Return(int64(21), nil)
+
+	l, err := svc.ListMine(ctx, service.ProductQuery{Total: service.TotalExact}, 3, 10)
+	assert.NoError(t, err)
+	assert.Len(t, l.Hits, 1)
+	assert.Empty(t, l.NextPageToken)
+	assert.Equal(t, int64(21), *l.Total)
+	assert.False(t, l.TotalEstimated)
 	repo.AssertExpectations(t)
 }
+
+func TestDisplayPrices(t *testing.T) {
+	ctx := context.Background()
+	asOf := time.Date(2025, 8, 29, 0, 0, 0, 0, time.UTC)
+	rates := staticRates{table: &fx.Table{Base: "EUR", AsOf: asOf, Rates: map[string]*big.Rat{"USD": big.NewRat(10842, 10000)}}}
+	svc := service.New(new(mockRepo), service.WithFX(fx.NewConverter(rates)))
+	products := []model.Product{{Price: money.New(1999, "EUR")}, {Price: money.New(500, "USD")}}
+
+	prices, err := svc.DisplayPrices(ctx, products, "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(2167, "USD"), prices[0].Price) // 19.99 · 1.0842 = 21.673
+	assert.Equal(t, fx.Rate{From: "EUR", To: "USD", Value: "1.0842", AsOf: asOf}, prices[0].Rate)
+	assert.Equal(t, money.New(500, "USD"), prices[1].Price)
+	assert.Equal(t, "1", prices[1].Rate.Value)
+
+	_, err = svc.DisplayPrices(ctx, products, "GBP")
+	assert.ErrorIs(t, err, service.ErrUnsupportedCurrency)
+
+	down := service.New(new(mockRepo), service.WithFX(fx.NewConverter(staticRates{err: errors.New("timeout")})))
+	_, err = down.DisplayPrices(ctx, products, "USD")
+	assert.ErrorIs(t, err, service.ErrRatesUnavailable)
+
+	_, err = service.New(new(mockRepo)).DisplayPrices(ctx, products, "USD")
+	assert.ErrorIs(t, err, service.ErrUnsupportedCurrency, "conversion is disabled without a provider")
+}
+
+func TestUpdate_CurrencyMustMatchTheVariants(t *testing.T) {
+	seller := uuid.New()
+	repo := new(mockRepo)
+	svc := service.New(repo)
+	prod := &model.Product{ID: uuid.New(), Price: money.New(1999, "EUR"), SellerID: seller}
+	repo.On("GetByID", mock.Anything, prod.ID.String()).Return(prod, nil)
+	repo.On("Update", mock.Anything, prod).Return(repository.ErrCurrencyMismatch) // a variant was added meanwhile
+
+	price := money.New(2199, "USD")
+	_, err := svc.Update(asUser(seller, token.RoleUser), prod.ID.String(), nil, nil, &price)
+	assert.ErrorIs(t, err, service.ErrInvalidPrice)
+}
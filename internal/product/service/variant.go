@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	maxOptions      = 5
+	maxOptionValues = 100
+)
+
+var (
+	// ErrVariantNotFound is returned when the requested variant does not exist.
+	ErrVariantNotFound = errors.New("variant not found")
+	// ErrInvalidVariant is returned for bad options, SKUs or stock.
+	ErrInvalidVariant = errors.New("invalid variant")
+	// ErrDuplicateVariant is returned when another variant of the product has the same options.
+	ErrDuplicateVariant = errors.New("variant with these options already exists")
+	// ErrSKUTaken is returned when another variant already uses the SKU.
+	ErrSKUTaken = errors.New("SKU already in use")
+)
+
+var skuRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// SetOptions replaces the option definitions of a product. Every existing
+// variant must still pick one defined value of each option. Only the seller
+// or an admin may.
+func (s *ProductService) SetOptions(ctx context.Context, id string, options []model.Option) (*model.Product, error) {
+	p, err := s.owned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	options, err = cleanOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range p.Variants {
+		got, err := matchOptions(options, v.Options)
+		if err != nil {
+			return nil, fmt.Errorf("%w (variant %s)", err, v.SKU)
+		}
+		if !maps.Equal(got, v.Options) {
+			return nil, fmt.Errorf("%w: variant %s uses different spelling", ErrInvalidVariant, v.SKU)
+		}
+	}
+	if err := s.repo.UpdateOptions(ctx, p.ID, options); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	p.Options = options
+	return p, nil
+}
+
+// ListVariants returns the variants of a product, oldest first.
+func (s *ProductService) ListVariants(ctx context.Context, id string) ([]model.Variant, error) {
+	p, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return p.Variants, nil
+}
+
+// CreateVariant adds v to product id. Only the seller or an admin may.
+func (s *ProductService) CreateVariant(ctx context.Context, id string, v model.Variant) (*model.Variant, error) {
+	p, err := s.owned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Reserved > 0 {
+		// from now on stock is kept per variant
+		return nil, fmt.Errorf("%w: %d units of the product are reserved", ErrInvalidVariant, p.Reserved)
+	}
+	v.ID, v.ProductID = uuid.New(), p.ID
+	if err := validateVariant(p, &v); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateVariant(ctx, &v); err != nil {
+		return nil, variantErr(err)
+	}
+	return &v, nil
+}
+
+// UpdateVariant replaces the SKU, options and price of a variant. Stock is
+// changed through InventoryService.SetStock. Only the seller or an admin may.
+func (s *ProductService) UpdateVariant(ctx context.Context, id string, variantID uuid.UUID, v model.Variant) (*model.Variant, error) {
+	p, err := s.owned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	existing := findVariant(p, variantID)
+	if existing == nil {
+		return nil, ErrVariantNotFound
+	}
+	v.ID, v.ProductID, v.CreatedAt = existing.ID, p.ID, existing.CreatedAt
+	v.Stock, v.Reserved = existing.Stock, existing.Reserved
+	if err := validateVariant(p, &v); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateVariant(ctx, &v); err != nil {
+		return nil, variantErr(err)
+	}
+	return &v, nil
+}
+
+// DeleteVariant removes a variant and writes off its stock. Only the seller
+// or an admin may, and not while units of the variant are reserved.
+func (s *ProductService) DeleteVariant(ctx context.Context, id string, variantID uuid.UUID) error {
+	p, err := s.owned(ctx, id)
+	if err != nil {
+		return err
+	}
+	return variantErr(s.repo.DeleteVariant(ctx, p.ID, variantID, actorID(ctx)))
+}
+
+// cleanOptions trims names and values and rejects empty or repeated ones.
+func cleanOptions(options []model.Option) ([]model.Option, error) {
+	if len(options) > maxOptions {
+		return nil, fmt.Errorf("%w: at most %d options", ErrInvalidVariant, maxOptions)
+	}
+	out := make([]model.Option, len(options))
+	names := map[string]bool{}
+	for i, o := range options {
+		name := strings.TrimSpace(o.Name)
+		if name == "" || names[strings.ToLower(name)] {
+			return nil, fmt.Errorf("%w: option names must be set and unique", ErrInvalidVariant)
+		}
+		names[strings.ToLower(name)] = true
+		if len(o.Values) == 0 || len(o.Values) > maxOptionValues {
+			return nil, fmt.Errorf("%w: option %s needs 1 to %d values", ErrInvalidVariant, name, maxOptionValues)
+		}
+		values := make([]string, len(o.Values))
+		seen := map[string]bool{}
+		for j, v := range o.Values {
+			v = strings.TrimSpace(v)
+			if v == "" || seen[strings.ToLower(v)] {
+				return nil, fmt.Errorf("%w: values of option %s must be set and unique", ErrInvalidVariant, name)
+			}
+			seen[strings.ToLower(v)] = true
+			values[j] = v
+		}
+		out[i] = model.Option{Name: name, Values: values}
+	}
+	return out, nil
+}
+
+// matchOptions checks that picked names exactly the defined options with
+// one of their values, ignoring case, and returns it spelled as defined.
+func matchOptions(defined []model.Option, picked map[string]string) (map[string]string, error) {
+	if len(picked) != len(defined) {
+		return nil, fmt.Errorf("%w: pick exactly one value of each option", ErrInvalidVariant)
+	}
+	out := make(map[string]string, len(defined))
+	for _, o := range defined {
+		var value string
+		found := false
+		for name, v := range picked {
+			if strings.EqualFold(name, o.Name) {
+				value, found = strings.TrimSpace(v), true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: missing option %s", ErrInvalidVariant, o.Name)
+		}
+		i := indexFold(o.Values, value)
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %q is not a value of option %s", ErrInvalidVariant, value, o.Name)
+		}
+		out[o.Name] = o.Values[i]
+	}
+	return out, nil
+}
+
+func indexFold(list []string, s string) int {
+	for i, v := range list {
+		if strings.EqualFold(v, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// validateVariant checks v against its product and normalises its options.
+// Variants are priced in the product's currency.
+func validateVariant(p *model.Product, v *model.Variant) error {
+	v.SKU = strings.TrimSpace(v.SKU)
+	if !skuRe.MatchString(v.SKU) {
+		return fmt.Errorf("%w: SKU must be 1 to 64 letters, digits, dots, dashes or underscores", ErrInvalidVariant)
+	}
+	if err := validatePrice(v.Price); err != nil {
+		return err
+	}
+	if v.Price.Currency != p.Price.Currency {
+		return fmt.Errorf("%w: variants are priced in %s like the product", ErrInvalidPrice, p.Price.Currency)
+	}
+	if v.Stock < 0 {
+		return fmt.Errorf("%w: stock cannot be negative", ErrInvalidVariant)
+	}
+	options, err := matchOptions(p.Options, v.Options)
+	if err != nil {
+		return err
+	}
+	v.Options = options
+	for _, other := range p.Variants {
+		if other.ID != v.ID && maps.Equal(other.Options, v.Options) {
+			return fmt.Errorf("%w: %s", ErrDuplicateVariant, other.SKU)
+		}
+	}
+	return nil
+}
+
+func findVariant(p *model.Product, id uuid.UUID) *model.Variant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// variantErr maps repository errors to service errors.
+func variantErr(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrVariantNotFound):
+		return ErrVariantNotFound
+	case errors.Is(err, repository.ErrSKUTaken):
+		return ErrSKUTaken
+	case errors.Is(err, repository.ErrDuplicateVariant):
+		return ErrDuplicateVariant
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, repository.ErrCurrencyMismatch):
+		// the product's currency changed since it was read
+		return fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	case errors.Is(err, repository.ErrProductReserved), errors.Is(err, repository.ErrVariantReserved):
+		return fmt.Errorf("%w: %w", ErrInvalidVariant, err)
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
+	"github.com/ADRPUR/event-driven-marketplace/internal/product/service"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/token"
+)
+
+func TestVariants(t *testing.T) {
+	seller := uuid.New()
+	ctx := asUser(seller, token.RoleUser)
+	repo := new(mockRepo)
+	svc := service.New(repo)
+	prod := &model.Product{ID: uuid.New(), Name: "T-shirt", Price: money.New(1999, "EUR"), SellerID: seller}
+	id := prod.ID.String()
+	repo.On("GetByID", mock.Anything, id).Return(prod, nil)
+	repo.On("UpdateOptions", mock.Anything, prod.ID, mock.Anything).Return(nil)
+	repo.On("CreateVariant", mock.Anything, mock.AnythingOfType("*model.Variant")).Return(nil)
+
+	_, err := svc.SetOptions(ctx, id, []model.Option{{Name: "size", Values: []string{"S", "s"}}})
+	assert.ErrorIs(t, err, service.ErrInvalidVariant, "values are unique ignoring case")
+	_, err = svc.SetOptions(ctx, id, []model.Option{
+		{Name: " size ", Values: []string{"S", "M"}},
+		{Name: "color", Values: []string{"Red", "Blue"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "size", prod.Options[0].Name)
+
+	v, err := svc.CreateVariant(ctx, id, model.Variant{
+		SKU: "TS-M-RED", Options: map[string]string{"Size": "m", "color": "red"}, Price: money.New(2199, "EUR"), Stock: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"size": "M", "color": "Red"}, v.Options, "spelled as defined")
+	prod.Variants = append(prod.Variants, *v)
+
+	for name, bad := range map[string]model.Variant{
+		"missing option": {SKU: "A", Options: map[string]string{"size": "S"}, Price: money.New(100, "EUR")},
+		"unknown value":  {SKU: "A", Options: map[string]string{"size": "XL", "color": "Red"}, Price: money.New(100, "EUR")},
+		"negative stock": {SKU: "A", Options: map[string]string{"size": "S", "color": "Red"}, Price: money.New(100, "EUR"), Stock: -1},
+		"bad SKU":        {SKU: "a b", Options: map[string]string{"size": "S", "color": "Red"}, Price: money.New(100, "EUR")},
+	} {
+		_, err := svc.CreateVariant(ctx, id, bad)
+		assert.ErrorIs(t, err, service.ErrInvalidVariant, name)
+	}
+	_, err = svc.CreateVariant(ctx, id, model.Variant{SKU: "A", Options: map[string]string{"size": "S", "color": "Red"}, Price: money.New(100, "USD")})
+	assert.ErrorIs(t, err, service.ErrInvalidPrice, "variants use the product currency")
+	_, err = svc.CreateVariant(ctx, id, model.Variant{SKU: "TS-M-RED-2", Options: map[string]string{"size": "M", "color": "RED"}, Price: money.New(2199, "EUR")})
+	assert.ErrorIs(t, err, service.ErrDuplicateVariant)
+	_, err = svc.CreateVariant(asUser(uuid.New(), token.RoleUser), id, model.Variant{SKU: "X"})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	// options still used by a variant cannot be dropped
+	_, err = svc.SetOptions(ctx, id, []model.Option{{Name: "size", Values: []string{"S", "M"}}})
+	assert.ErrorIs(t, err, service.ErrInvalidVariant)
+	_, err = svc.SetOptions(ctx, id, []model.Option{
+		{Name: "size", Values: []string{"S", "M", "L"}},
+		{Name: "color", Values: []string{"Red", "Blue"}},
+	})
+	assert.NoError(t, err)
+
+	_, err = svc.UpdateVariant(ctx, id, uuid.New(), *v)
+	assert.ErrorIs(t, err, service.ErrVariantNotFound)
+}
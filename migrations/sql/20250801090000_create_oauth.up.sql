@@ -0,0 +1,36 @@
+CREATE TABLE oauth_clients
+(
+    id            TEXT PRIMARY KEY,
+    secret_hash   TEXT        NOT NULL,
+    owner_id      UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name          TEXT        NOT NULL,
+    redirect_uris TEXT        NOT NULL,
+    scopes        TEXT        NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_oauth_clients_owner_id ON oauth_clients (owner_id);
+
+CREATE TABLE oauth_authorization_codes
+(
+    code_hash             TEXT PRIMARY KEY,
+    client_id             TEXT        NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    user_id               UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    redirect_uri          TEXT        NOT NULL,
+    scopes                TEXT        NOT NULL,
+    code_challenge        TEXT,
+    code_challenge_method TEXT,
+    expires_at            TIMESTAMPTZ NOT NULL,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_oauth_authorization_codes_client_id ON oauth_authorization_codes (client_id);
+
+CREATE TABLE oauth_consents
+(
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_id  TEXT        NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    scopes     TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ          DEFAULT NULL,
+    PRIMARY KEY (user_id, client_id)
+);
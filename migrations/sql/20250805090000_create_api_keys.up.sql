@@ -0,0 +1,14 @@
+CREATE TABLE api_keys
+(
+    id           UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    user_id      UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         TEXT        NOT NULL,
+    prefix       TEXT        NOT NULL UNIQUE,
+    key_hash     TEXT        NOT NULL,
+    scopes       TEXT,
+    expires_at   TIMESTAMPTZ          DEFAULT NULL,
+    last_used_at TIMESTAMPTZ          DEFAULT NULL,
+    revoked_at   TIMESTAMPTZ          DEFAULT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
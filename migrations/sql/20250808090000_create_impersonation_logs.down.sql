@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS impersonation_logs;
+DROP FUNCTION IF EXISTS impersonation_logs_immutable();
@@ -0,0 +1,30 @@
+CREATE TABLE impersonation_logs
+(
+    id         UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    admin_id   UUID        NOT NULL REFERENCES users (id),
+    user_id    UUID        NOT NULL REFERENCES users (id),
+    token_id   UUID        NOT NULL,
+    service    TEXT        NOT NULL,
+    method     TEXT        NOT NULL,
+    path       TEXT        NOT NULL,
+    status     INT,
+    reason     TEXT,
+    ip         TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_impersonation_logs_admin_id ON impersonation_logs (admin_id);
+CREATE INDEX idx_impersonation_logs_user_id ON impersonation_logs (user_id);
+CREATE INDEX idx_impersonation_logs_token_id ON impersonation_logs (token_id);
+
+-- The audit trail is append-only.
+CREATE OR REPLACE FUNCTION impersonation_logs_immutable() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'impersonation_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER impersonation_logs_immutable
+    BEFORE UPDATE OR DELETE ON impersonation_logs
+    FOR EACH ROW EXECUTE FUNCTION impersonation_logs_immutable();
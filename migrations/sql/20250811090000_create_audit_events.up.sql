@@ -0,0 +1,40 @@
+CREATE TABLE audit_events
+(
+    id         UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    type       TEXT        NOT NULL,
+    outcome    TEXT        NOT NULL CHECK (outcome IN ('success', 'failure')),
+    actor_id   UUID,
+    target_id  UUID,
+    ip         TEXT,
+    user_agent TEXT,
+    request_id TEXT,
+    detail     TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_audit_events_type ON audit_events (type);
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX idx_audit_events_target_id ON audit_events (target_id);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+
+-- The audit log is append-only. The one exception is the erasure of a
+-- user's personal data, which blanks ip and user_agent and redacts parts of
+-- detail to '[erased]'.
+CREATE OR REPLACE FUNCTION audit_events_immutable() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND (NEW.id, NEW.type, NEW.outcome, NEW.actor_id, NEW.target_id, NEW.request_id, NEW.created_at)
+            IS NOT DISTINCT FROM
+            (OLD.id, OLD.type, OLD.outcome, OLD.actor_id, OLD.target_id, OLD.request_id, OLD.created_at)
+        AND (NEW.ip IS NOT DISTINCT FROM OLD.ip OR NEW.ip = '')
+        AND (NEW.user_agent IS NOT DISTINCT FROM OLD.user_agent OR NEW.user_agent = '')
+        AND (NEW.detail IS NOT DISTINCT FROM OLD.detail OR strpos(NEW.detail, '[erased]') > 0) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_immutable
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_immutable();
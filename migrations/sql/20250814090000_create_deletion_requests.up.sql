@@ -0,0 +1,8 @@
+CREATE TABLE deletion_requests
+(
+    user_id      UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    purge_after  TIMESTAMPTZ NOT NULL,
+    erased_at    TIMESTAMPTZ          DEFAULT NULL
+);
+CREATE INDEX idx_deletion_requests_purge_after ON deletion_requests (purge_after) WHERE erased_at IS NULL;
@@ -0,0 +1,24 @@
+-- Entries about purged accounts have no users row any more; the restored
+-- foreign keys only apply to new entries.
+ALTER TABLE impersonation_logs
+    ADD CONSTRAINT impersonation_logs_admin_id_fkey FOREIGN KEY (admin_id) REFERENCES users (id) NOT VALID,
+    ADD CONSTRAINT impersonation_logs_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) NOT VALID;
+
+DROP INDEX IF EXISTS idx_user_details_deleted_at;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_users_email_active;
+
+-- A soft-deleted account may share its email with an account registered
+-- later (or with another soft-deleted one). Keep the email on the active or
+-- oldest account and rename the others to "deleted-<id>-<email>", so that
+-- the unique constraint can be restored.
+UPDATE users u
+SET email = 'deleted-' || u.id || '-' || u.email
+WHERE u.deleted_at IS NOT NULL
+  AND EXISTS (SELECT 1
+              FROM users o
+              WHERE o.email = u.email
+                AND o.id <> u.id
+                AND (o.deleted_at IS NULL OR o.id < u.id));
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
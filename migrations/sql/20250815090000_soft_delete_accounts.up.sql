@@ -0,0 +1,12 @@
+-- Emails only need to be unique among active accounts, so a soft-deleted
+-- account does not block re-registration.
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+CREATE UNIQUE INDEX idx_users_email_active ON users (email) WHERE deleted_at IS NULL;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX idx_user_details_deleted_at ON user_details (deleted_at);
+
+-- Purged accounts are hard-deleted; the impersonation trail keeps the IDs
+-- like audit_events does.
+ALTER TABLE impersonation_logs
+    DROP CONSTRAINT impersonation_logs_admin_id_fkey,
+    DROP CONSTRAINT impersonation_logs_user_id_fkey;
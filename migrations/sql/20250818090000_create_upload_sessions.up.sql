@@ -0,0 +1,26 @@
+-- No foreign key to users: the expiry job must still find the sessions of
+-- purged accounts to delete their stored parts. Each service purges the
+-- kinds of upload it owns.
+CREATE TABLE upload_sessions
+(
+    id         UUID PRIMARY KEY,
+    user_id    UUID        NOT NULL,
+    kind       TEXT        NOT NULL, -- profile_photo, product_image
+    target     TEXT        NOT NULL DEFAULT '', -- e.g. the product ID of a product image
+    size       BIGINT      NOT NULL CHECK (size > 0),
+    sha256     CHAR(64)    NOT NULL,
+    received   BIGINT      NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_upload_sessions_user_id ON upload_sessions (user_id);
+CREATE INDEX idx_upload_sessions_kind_expires_at ON upload_sessions (kind, expires_at);
+
+CREATE TABLE upload_parts
+(
+    upload_id    UUID   NOT NULL REFERENCES upload_sessions (id) ON DELETE CASCADE,
+    start_offset BIGINT NOT NULL,
+    size         BIGINT NOT NULL,
+    key          TEXT   NOT NULL,
+    PRIMARY KEY (upload_id, start_offset)
+);
@@ -0,0 +1,32 @@
+ALTER TABLE user_details ADD COLUMN address JSONB;
+
+-- The free-form address is the default shipping address, else the oldest
+-- one. All addresses are kept under "addresses" so that migrating up again
+-- restores them.
+WITH main AS (SELECT DISTINCT ON (user_id) *
+              FROM user_addresses
+              ORDER BY user_id, (kind = 'shipping' AND is_default) DESC, created_at),
+     saved AS (SELECT user_id,
+                      jsonb_agg(jsonb_build_object(
+                              'kind', kind,
+                              'is_default', is_default,
+                              'lines', lines,
+                              'city', city,
+                              'region', region,
+                              'postal_code', postal_code,
+                              'country', country,
+                              'created_at', created_at) ORDER BY created_at) AS addresses
+               FROM user_addresses
+               GROUP BY user_id)
+UPDATE user_details d
+SET address = jsonb_build_object(
+        'line', (SELECT string_agg(l, ', ') FROM jsonb_array_elements_text(m.lines) l),
+        'city', m.city,
+        'postal_code', m.postal_code,
+        'country', m.country,
+        'addresses', s.addresses)
+FROM main m
+         JOIN saved s ON s.user_id = m.user_id
+WHERE m.user_id = d.user_id;
+
+DROP TABLE IF EXISTS user_addresses;
@@ -0,0 +1,348 @@
+CREATE TABLE user_addresses
+(
+    id          UUID PRIMARY KEY,
+    user_id     UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind        TEXT        NOT NULL CHECK (kind IN ('shipping', 'billing')),
+    is_default  BOOLEAN     NOT NULL DEFAULT false,
+    lines       JSONB       NOT NULL,
+    city        TEXT        NOT NULL,
+    region      TEXT        NOT NULL DEFAULT '',
+    postal_code TEXT        NOT NULL DEFAULT '',
+    country     CHAR(2)     NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_user_addresses_user_id ON user_addresses (user_id);
+CREATE UNIQUE INDEX idx_user_addresses_default ON user_addresses (user_id, kind) WHERE is_default;
+
+-- Addresses kept by the down migration ({"addresses": [...]}) come back
+-- as they were.
+INSERT INTO user_addresses (id, user_id, kind, is_default, lines, city, region, postal_code, country, created_at)
+SELECT gen_random_uuid(),
+       d.user_id,
+       a ->> 'kind',
+       (a ->> 'is_default')::boolean,
+       a -> 'lines',
+       a ->> 'city',
+       coalesce(a ->> 'region', ''),
+       coalesce(a ->> 'postal_code', ''),
+       a ->> 'country',
+       coalesce((a ->> 'created_at')::timestamptz, now())
+FROM user_details d,
+     jsonb_array_elements(CASE WHEN jsonb_typeof(d.address -> 'addresses') = 'array' THEN d.address -> 'addresses' END) a;
+
+-- The free-form addresses ({"line", "city", "postal_code", "country"}) had
+-- the country as typed by the user. ISO 3166-1 codes and the English,
+-- official and common local names are recognised, ignoring case, dots and
+-- extra spaces.
+CREATE TEMPORARY TABLE country_codes
+(
+    name TEXT PRIMARY KEY,
+    code CHAR(2) NOT NULL
+);
+INSERT INTO country_codes (name, code)
+VALUES ('ad', 'AD'), ('and', 'AD'), ('andorra', 'AD'), ('principality of andorra', 'AD'),
+       ('ae', 'AE'), ('are', 'AE'), ('united arab emirates', 'AE'), ('uae', 'AE'),
+       ('af', 'AF'), ('afg', 'AF'), ('afghanistan', 'AF'), ('islamic republic of afghanistan', 'AF'),
+       ('ag', 'AG'), ('atg', 'AG'), ('antigua and barbuda', 'AG'),
+       ('ai', 'AI'), ('aia', 'AI'), ('anguilla', 'AI'),
+       ('al', 'AL'), ('alb', 'AL'), ('albania', 'AL'), ('republic of albania', 'AL'),
+       ('am', 'AM'), ('arm', 'AM'), ('armenia', 'AM'), ('republic of armenia', 'AM'),
+       ('ao', 'AO'), ('ago', 'AO'), ('angola', 'AO'), ('republic of angola', 'AO'),
+       ('aq', 'AQ'), ('ata', 'AQ'), ('antarctica', 'AQ'),
+       ('ar', 'AR'), ('arg', 'AR'), ('argentina', 'AR'), ('argentine republic', 'AR'),
+       ('as', 'AS'), ('asm', 'AS'), ('american samoa', 'AS'),
+       ('at', 'AT'), ('aut', 'AT'), ('austria', 'AT'), ('republic of austria', 'AT'), ('österreich', 'AT'), ('osterreich', 'AT'),
+       ('au', 'AU'), ('aus', 'AU'), ('australia', 'AU'),
+       ('aw', 'AW'), ('abw', 'AW'), ('aruba', 'AW'),
+       ('ax', 'AX'), ('ala', 'AX'), ('åland islands', 'AX'),
+       ('az', 'AZ'), ('aze', 'AZ'), ('azerbaijan', 'AZ'), ('republic of azerbaijan', 'AZ'),
+       ('ba', 'BA'), ('bih', 'BA'), ('bosnia and herzegovina', 'BA'), ('republic of bosnia and herzegovina', 'BA'),
+       ('bb', 'BB'), ('brb', 'BB'), ('barbados', 'BB'),
+       ('bd', 'BD'), ('bgd', 'BD'), ('bangladesh', 'BD'), ('people''s republic of bangladesh', 'BD'),
+       ('be', 'BE'), ('bel', 'BE'), ('belgium', 'BE'), ('kingdom of belgium', 'BE'), ('belgië', 'BE'), ('belgique', 'BE'), ('belgie', 'BE'),
+       ('bf', 'BF'), ('bfa', 'BF'), ('burkina faso', 'BF'),
+       ('bg', 'BG'), ('bgr', 'BG'), ('bulgaria', 'BG'), ('republic of bulgaria', 'BG'),
+       ('bh', 'BH'), ('bhr', 'BH'), ('bahrain', 'BH'), ('kingdom of bahrain', 'BH'),
+       ('bi', 'BI'), ('bdi', 'BI'), ('burundi', 'BI'), ('republic of burundi', 'BI'),
+       ('bj', 'BJ'), ('ben', 'BJ'), ('benin', 'BJ'), ('republic of benin', 'BJ'),
+       ('bl', 'BL'), ('blm', 'BL'), ('saint barthélemy', 'BL'),
+       ('bm', 'BM'), ('bmu', 'BM'), ('bermuda', 'BM'),
+       ('bn', 'BN'), ('brn', 'BN'), ('brunei darussalam', 'BN'), ('brunei', 'BN'),
+       ('bo', 'BO'), ('bol', 'BO'), ('bolivia, plurinational state of', 'BO'), ('plurinational state of bolivia', 'BO'), ('bolivia', 'BO'),
+       ('bq', 'BQ'), ('bes', 'BQ'), ('bonaire, sint eustatius and saba', 'BQ'),
+       ('br', 'BR'), ('bra', 'BR'), ('brazil', 'BR'), ('federative republic of brazil', 'BR'),
+       ('bs', 'BS'), ('bhs', 'BS'), ('bahamas', 'BS'), ('commonwealth of the bahamas', 'BS'),
+       ('bt', 'BT'), ('btn', 'BT'), ('bhutan', 'BT'), ('kingdom of bhutan', 'BT'),
+       ('bv', 'BV'), ('bvt', 'BV'), ('bouvet island', 'BV'),
+       ('bw', 'BW'), ('bwa', 'BW'), ('botswana', 'BW'), ('republic of botswana', 'BW'),
+       ('by', 'BY'), ('blr', 'BY'), ('belarus', 'BY'), ('republic of belarus', 'BY'),
+       ('bz', 'BZ'), ('blz', 'BZ'), ('belize', 'BZ'),
+       ('ca', 'CA'), ('can', 'CA'), ('canada', 'CA'),
+       ('cc', 'CC'), ('cck', 'CC'), ('cocos (keeling) islands', 'CC'),
+       ('cd', 'CD'), ('cod', 'CD'), ('congo, the democratic republic of the', 'CD'), ('dr congo', 'CD'), ('democratic republic of the congo', 'CD'),
+       ('cf', 'CF'), ('caf', 'CF'), ('central african republic', 'CF'),
+       ('cg', 'CG'), ('cog', 'CG'), ('congo', 'CG'), ('republic of the congo', 'CG'),
+       ('ch', 'CH'), ('che', 'CH'), ('switzerland', 'CH'), ('swiss confederation', 'CH'), ('schweiz', 'CH'), ('suisse', 'CH'), ('svizzera', 'CH'),
+       ('ci', 'CI'), ('civ', 'CI'), ('côte d''ivoire', 'CI'), ('republic of côte d''ivoire', 'CI'), ('ivory coast', 'CI'), ('cote d''ivoire', 'CI'),
+       ('ck', 'CK'), ('cok', 'CK'), ('cook islands', 'CK'),
+       ('cl', 'CL'), ('chl', 'CL'), ('chile', 'CL'), ('republic of chile', 'CL'),
+       ('cm', 'CM'), ('cmr', 'CM'), ('cameroon', 'CM'), ('republic of cameroon', 'CM'),
+       ('cn', 'CN'), ('chn', 'CN'), ('china', 'CN'), ('people''s republic of china', 'CN'),
+       ('co', 'CO'), ('col', 'CO'), ('colombia', 'CO'), ('republic of colombia', 'CO'),
+       ('cr', 'CR'), ('cri', 'CR'), ('costa rica', 'CR'), ('republic of costa rica', 'CR'),
+       ('cu', 'CU'), ('cub', 'CU'), ('cuba', 'CU'), ('republic of cuba', 'CU'),
+       ('cv', 'CV'), ('cpv', 'CV'), ('cabo verde', 'CV'), ('republic of cabo verde', 'CV'), ('cape verde', 'CV'),
+       ('cw', 'CW'), ('cuw', 'CW'), ('curaçao', 'CW'),
+       ('cx', 'CX'), ('cxr', 'CX'), ('christmas island', 'CX'),
+       ('cy', 'CY'), ('cyp', 'CY'), ('cyprus', 'CY'), ('republic of cyprus', 'CY'),
+       ('cz', 'CZ'), ('cze', 'CZ'), ('czechia', 'CZ'), ('czech republic', 'CZ'), ('česko', 'CZ'),
+       ('de', 'DE'), ('deu', 'DE'), ('germany', 'DE'), ('federal republic of germany', 'DE'), ('deutschland', 'DE'),
+       ('dj', 'DJ'), ('dji', 'DJ'), ('djibouti', 'DJ'), ('republic of djibouti', 'DJ'),
+       ('dk', 'DK'), ('dnk', 'DK'), ('denmark', 'DK'), ('kingdom of denmark', 'DK'), ('danmark', 'DK'),
+       ('dm', 'DM'), ('dma', 'DM'), ('dominica', 'DM'), ('commonwealth of dominica', 'DM'),
+       ('do', 'DO'), ('dom', 'DO'), ('dominican republic', 'DO'),
+       ('dz', 'DZ'), ('dza', 'DZ'), ('algeria', 'DZ'), ('people''s democratic republic of algeria', 'DZ'),
+       ('ec', 'EC'), ('ecu', 'EC'), ('ecuador', 'EC'), ('republic of ecuador', 'EC'),
+       ('ee', 'EE'), ('est', 'EE'), ('estonia', 'EE'), ('republic of estonia', 'EE'),
+       ('eg', 'EG'), ('egy', 'EG'), ('egypt', 'EG'), ('arab republic of egypt', 'EG'),
+       ('eh', 'EH'), ('esh', 'EH'), ('western sahara', 'EH'),
+       ('er', 'ER'), ('eri', 'ER'), ('eritrea', 'ER'), ('the state of eritrea', 'ER'),
+       ('es', 'ES'), ('esp', 'ES'), ('spain', 'ES'), ('kingdom of spain', 'ES'), ('españa', 'ES'), ('espana', 'ES'),
+       ('et', 'ET'), ('eth', 'ET'), ('ethiopia', 'ET'), ('federal democratic republic of ethiopia', 'ET'),
+       ('fi', 'FI'), ('fin', 'FI'), ('finland', 'FI'), ('republic of finland', 'FI'), ('suomi', 'FI'),
+       ('fj', 'FJ'), ('fji', 'FJ'), ('fiji', 'FJ'), ('republic of fiji', 'FJ'),
+       ('fk', 'FK'), ('flk', 'FK'), ('falkland islands (malvinas)', 'FK'),
+       ('fm', 'FM'), ('fsm', 'FM'), ('micronesia, federated states of', 'FM'), ('federated states of micronesia', 'FM'), ('micronesia', 'FM'),
+       ('fo', 'FO'), ('fro', 'FO'), ('faroe islands', 'FO'),
+       ('fr', 'FR'), ('fra', 'FR'), ('france', 'FR'), ('french republic', 'FR'),
+       ('ga', 'GA'), ('gab', 'GA'), ('gabon', 'GA'), ('gabonese republic', 'GA'),
+       ('gb', 'GB'), ('gbr', 'GB'), ('united kingdom', 'GB'), ('united kingdom of great britain and northern ireland', 'GB'), ('uk', 'GB'), ('great britain', 'GB'), ('britain', 'GB'), ('england', 'GB'), ('scotland', 'GB'), ('wales', 'GB'), ('northern ireland', 'GB'),
+       ('gd', 'GD'), ('grd', 'GD'), ('grenada', 'GD'),
+       ('ge', 'GE'), ('geo', 'GE'), ('georgia', 'GE'),
+       ('gf', 'GF'), ('guf', 'GF'), ('french guiana', 'GF'),
+       ('gg', 'GG'), ('ggy', 'GG'), ('guernsey', 'GG'),
+       ('gh', 'GH'), ('gha', 'GH'), ('ghana', 'GH'), ('republic of ghana', 'GH'),
+       ('gi', 'GI'), ('gib', 'GI'), ('gibraltar', 'GI'),
+       ('gl', 'GL'), ('grl', 'GL'), ('greenland', 'GL'),
+       ('gm', 'GM'), ('gmb', 'GM'), ('gambia', 'GM'), ('republic of the gambia', 'GM'),
+       ('gn', 'GN'), ('gin', 'GN'), ('guinea', 'GN'), ('republic of guinea', 'GN'),
+       ('gp', 'GP'), ('glp', 'GP'), ('guadeloupe', 'GP'),
+       ('gq', 'GQ'), ('gnq', 'GQ'), ('equatorial guinea', 'GQ'), ('republic of equatorial guinea', 'GQ'),
+       ('gr', 'GR'), ('grc', 'GR'), ('greece', 'GR'), ('hellenic republic', 'GR'), ('ellada', 'GR'), ('hellas', 'GR'),
+       ('gs', 'GS'), ('sgs', 'GS'), ('south georgia and the south sandwich islands', 'GS'),
+       ('gt', 'GT'), ('gtm', 'GT'), ('guatemala', 'GT'), ('republic of guatemala', 'GT'),
+       ('gu', 'GU'), ('gum', 'GU'), ('guam', 'GU'),
+       ('gw', 'GW'), ('gnb', 'GW'), ('guinea-bissau', 'GW'), ('republic of guinea-bissau', 'GW'),
+       ('gy', 'GY'), ('guy', 'GY'), ('guyana', 'GY'), ('republic of guyana', 'GY'),
+       ('hk', 'HK'), ('hkg', 'HK'), ('hong kong', 'HK'), ('hong kong special administrative region of china', 'HK'),
+       ('hm', 'HM'), ('hmd', 'HM'), ('heard island and mcdonald islands', 'HM'),
+       ('hn', 'HN'), ('hnd', 'HN'), ('honduras', 'HN'), ('republic of honduras', 'HN'),
+       ('hr', 'HR'), ('hrv', 'HR'), ('croatia', 'HR'), ('republic of croatia', 'HR'), ('hrvatska', 'HR'),
+       ('ht', 'HT'), ('hti', 'HT'), ('haiti', 'HT'), ('republic of haiti', 'HT'),
+       ('hu', 'HU'), ('hun', 'HU'), ('hungary', 'HU'), ('magyarország', 'HU'), ('magyarorszag', 'HU'),
+       ('id', 'ID'), ('idn', 'ID'), ('indonesia', 'ID'), ('republic of indonesia', 'ID'),
+       ('ie', 'IE'), ('irl', 'IE'), ('ireland', 'IE'), ('éire', 'IE'), ('eire', 'IE'),
+       ('il', 'IL'), ('isr', 'IL'), ('israel', 'IL'), ('state of israel', 'IL'),
+       ('im', 'IM'), ('imn', 'IM'), ('isle of man', 'IM'),
+       ('in', 'IN'), ('ind', 'IN'), ('india', 'IN'), ('republic of india', 'IN'),
+       ('io', 'IO'), ('iot', 'IO'), ('british indian ocean territory', 'IO'),
+       ('iq', 'IQ'), ('irq', 'IQ'), ('iraq', 'IQ'), ('republic of iraq', 'IQ'),
+       ('ir', 'IR'), ('irn', 'IR'), ('iran, islamic republic of', 'IR'), ('islamic republic of iran', 'IR'), ('iran', 'IR'),
+       ('is', 'IS'), ('isl', 'IS'), ('iceland', 'IS'), ('republic of iceland', 'IS'),
+       ('it', 'IT'), ('ita', 'IT'), ('italy', 'IT'), ('italian republic', 'IT'), ('italia', 'IT'),
+       ('je', 'JE'), ('jey', 'JE'), ('jersey', 'JE'),
+       ('jm', 'JM'), ('jam', 'JM'), ('jamaica', 'JM'),
+       ('jo', 'JO'), ('jor', 'JO'), ('jordan', 'JO'), ('hashemite kingdom of jordan', 'JO'),
+       ('jp', 'JP'), ('jpn', 'JP'), ('japan', 'JP'),
+       ('ke', 'KE'), ('ken', 'KE'), ('kenya', 'KE'), ('republic of kenya', 'KE'),
+       ('kg', 'KG'), ('kgz', 'KG'), ('kyrgyzstan', 'KG'), ('kyrgyz republic', 'KG'),
+       ('kh', 'KH'), ('khm', 'KH'), ('cambodia', 'KH'), ('kingdom of cambodia', 'KH'),
+       ('ki', 'KI'), ('kir', 'KI'), ('kiribati', 'KI'), ('republic of kiribati', 'KI'),
+       ('km', 'KM'), ('com', 'KM'), ('comoros', 'KM'), ('union of the comoros', 'KM'),
+       ('kn', 'KN'), ('kna', 'KN'), ('saint kitts and nevis', 'KN'),
+       ('kp', 'KP'), ('prk', 'KP'), ('korea, democratic people''s republic of', 'KP'), ('democratic people''s republic of korea', 'KP'), ('north korea', 'KP'),
+       ('kr', 'KR'), ('kor', 'KR'), ('korea, republic of', 'KR'), ('south korea', 'KR'), ('korea', 'KR'),
+       ('kw', 'KW'), ('kwt', 'KW'), ('kuwait', 'KW'), ('state of kuwait', 'KW'),
+       ('ky', 'KY'), ('cym', 'KY'), ('cayman islands', 'KY'),
+       ('kz', 'KZ'), ('kaz', 'KZ'), ('kazakhstan', 'KZ'), ('republic of kazakhstan', 'KZ'),
+       ('la', 'LA'), ('lao', 'LA'), ('lao people''s democratic republic', 'LA'), ('laos', 'LA'),
+       ('lb', 'LB'), ('lbn', 'LB'), ('lebanon', 'LB'), ('lebanese republic', 'LB'),
+       ('lc', 'LC'), ('lca', 'LC'), ('saint lucia', 'LC'),
+       ('li', 'LI'), ('lie', 'LI'), ('liechtenstein', 'LI'), ('principality of liechtenstein', 'LI'),
+       ('lk', 'LK'), ('lka', 'LK'), ('sri lanka', 'LK'), ('democratic socialist republic of sri lanka', 'LK'),
+       ('lr', 'LR'), ('lbr', 'LR'), ('liberia', 'LR'), ('republic of liberia', 'LR'),
+       ('ls', 'LS'), ('lso', 'LS'), ('lesotho', 'LS'), ('kingdom of lesotho', 'LS'),
+       ('lt', 'LT'), ('ltu', 'LT'), ('lithuania', 'LT'), ('republic of lithuania', 'LT'),
+       ('lu', 'LU'), ('lux', 'LU'), ('luxembourg', 'LU'), ('grand duchy of luxembourg', 'LU'),
+       ('lv', 'LV'), ('lva', 'LV'), ('latvia', 'LV'), ('republic of latvia', 'LV'),
+       ('ly', 'LY'), ('lby', 'LY'), ('libya', 'LY'),
+       ('ma', 'MA'), ('mar', 'MA'), ('morocco', 'MA'), ('kingdom of morocco', 'MA'),
+       ('mc', 'MC'), ('mco', 'MC'), ('monaco', 'MC'), ('principality of monaco', 'MC'),
+       ('md', 'MD'), ('mda', 'MD'), ('moldova, republic of', 'MD'), ('republic of moldova', 'MD'), ('moldova', 'MD'),
+       ('me', 'ME'), ('mne', 'ME'), ('montenegro', 'ME'),
+       ('mf', 'MF'), ('maf', 'MF'), ('saint martin (french part)', 'MF'),
+       ('mg', 'MG'), ('mdg', 'MG'), ('madagascar', 'MG'), ('republic of madagascar', 'MG'),
+       ('mh', 'MH'), ('mhl', 'MH'), ('marshall islands', 'MH'), ('republic of the marshall islands', 'MH'),
+       ('mk', 'MK'), ('mkd', 'MK'), ('north macedonia', 'MK'), ('republic of north macedonia', 'MK'), ('macedonia', 'MK'),
+       ('ml', 'ML'), ('mli', 'ML'), ('mali', 'ML'), ('republic of mali', 'ML'),
+       ('mm', 'MM'), ('mmr', 'MM'), ('myanmar', 'MM'), ('republic of myanmar', 'MM'), ('burma', 'MM'),
+       ('mn', 'MN'), ('mng', 'MN'), ('mongolia', 'MN'),
+       ('mo', 'MO'), ('mac', 'MO'), ('macao', 'MO'), ('macao special administrative region of china', 'MO'),
+       ('mp', 'MP'), ('mnp', 'MP'), ('northern mariana islands', 'MP'), ('commonwealth of the northern mariana islands', 'MP'),
+       ('mq', 'MQ'), ('mtq', 'MQ'), ('martinique', 'MQ'),
+       ('mr', 'MR'), ('mrt', 'MR'), ('mauritania', 'MR'), ('islamic republic of mauritania', 'MR'),
+       ('ms', 'MS'), ('msr', 'MS'), ('montserrat', 'MS'),
+       ('mt', 'MT'), ('mlt', 'MT'), ('malta', 'MT'), ('republic of malta', 'MT'),
+       ('mu', 'MU'), ('mus', 'MU'), ('mauritius', 'MU'), ('republic of mauritius', 'MU'),
+       ('mv', 'MV'), ('mdv', 'MV'), ('maldives', 'MV'), ('republic of maldives', 'MV'),
+       ('mw', 'MW'), ('mwi', 'MW'), ('malawi', 'MW'), ('republic of malawi', 'MW'),
+       ('mx', 'MX'), ('mex', 'MX'), ('mexico', 'MX'), ('united mexican states', 'MX'),
+       ('my', 'MY'), ('mys', 'MY'), ('malaysia', 'MY'),
+       ('mz', 'MZ'), ('moz', 'MZ'), ('mozambique', 'MZ'), ('republic of mozambique', 'MZ'),
+       ('na', 'NA'), ('nam', 'NA'), ('namibia', 'NA'), ('republic of namibia', 'NA'),
+       ('nc', 'NC'), ('ncl', 'NC'), ('new caledonia', 'NC'),
+       ('ne', 'NE'), ('ner', 'NE'), ('niger', 'NE'), ('republic of the niger', 'NE'),
+       ('nf', 'NF'), ('nfk', 'NF'), ('norfolk island', 'NF'),
+       ('ng', 'NG'), ('nga', 'NG'), ('nigeria', 'NG'), ('federal republic of nigeria', 'NG'),
+       ('ni', 'NI'), ('nic', 'NI'), ('nicaragua', 'NI'), ('republic of nicaragua', 'NI'),
+       ('nl', 'NL'), ('nld', 'NL'), ('netherlands', 'NL'), ('kingdom of the netherlands', 'NL'), ('holland', 'NL'), ('the netherlands', 'NL'), ('nederland', 'NL'),
+       ('no', 'NO'), ('nor', 'NO'), ('norway', 'NO'), ('kingdom of norway', 'NO'), ('norge', 'NO'),
+       ('np', 'NP'), ('npl', 'NP'), ('nepal', 'NP'), ('federal democratic republic of nepal', 'NP'),
+       ('nr', 'NR'), ('nru', 'NR'), ('nauru', 'NR'), ('republic of nauru', 'NR'),
+       ('nu', 'NU'), ('niu', 'NU'), ('niue', 'NU'),
+       ('nz', 'NZ'), ('nzl', 'NZ'), ('new zealand', 'NZ'),
+       ('om', 'OM'), ('omn', 'OM'), ('oman', 'OM'), ('sultanate of oman', 'OM'),
+       ('pa', 'PA'), ('pan', 'PA'), ('panama', 'PA'), ('republic of panama', 'PA'),
+       ('pe', 'PE'), ('per', 'PE'), ('peru', 'PE'), ('republic of peru', 'PE'),
+       ('pf', 'PF'), ('pyf', 'PF'), ('french polynesia', 'PF'),
+       ('pg', 'PG'), ('png', 'PG'), ('papua new guinea', 'PG'), ('independent state of papua new guinea', 'PG'),
+       ('ph', 'PH'), ('phl', 'PH'), ('philippines', 'PH'), ('republic of the philippines', 'PH'),
+       ('pk', 'PK'), ('pak', 'PK'), ('pakistan', 'PK'), ('islamic republic of pakistan', 'PK'),
+       ('pl', 'PL'), ('pol', 'PL'), ('poland', 'PL'), ('republic of poland', 'PL'), ('polska', 'PL'),
+       ('pm', 'PM'), ('spm', 'PM'), ('saint pierre and miquelon', 'PM'),
+       ('pn', 'PN'), ('pcn', 'PN'), ('pitcairn', 'PN'),
+       ('pr', 'PR'), ('pri', 'PR'), ('puerto rico', 'PR'),
+       ('ps', 'PS'), ('pse', 'PS'), ('palestine, state of', 'PS'), ('the state of palestine', 'PS'), ('palestine', 'PS'),
+       ('pt', 'PT'), ('prt', 'PT'), ('portugal', 'PT'), ('portuguese republic', 'PT'),
+       ('pw', 'PW'), ('plw', 'PW'), ('palau', 'PW'), ('republic of palau', 'PW'),
+       ('py', 'PY'), ('pry', 'PY'), ('paraguay', 'PY'), ('republic of paraguay', 'PY'),
+       ('qa', 'QA'), ('qat', 'QA'), ('qatar', 'QA'), ('state of qatar', 'QA'),
+       ('re', 'RE'), ('reu', 'RE'), ('réunion', 'RE'),
+       ('ro', 'RO'), ('rou', 'RO'), ('romania', 'RO'), ('românia', 'RO'), ('romînia', 'RO'),
+       ('rs', 'RS'), ('srb', 'RS'), ('serbia', 'RS'), ('republic of serbia', 'RS'),
+       ('ru', 'RU'), ('rus', 'RU'), ('russian federation', 'RU'), ('russia', 'RU'),
+       ('rw', 'RW'), ('rwa', 'RW'), ('rwanda', 'RW'), ('rwandese republic', 'RW'),
+       ('sa', 'SA'), ('sau', 'SA'), ('saudi arabia', 'SA'), ('kingdom of saudi arabia', 'SA'),
+       ('sb', 'SB'), ('slb', 'SB'), ('solomon islands', 'SB'),
+       ('sc', 'SC'), ('syc', 'SC'), ('seychelles', 'SC'), ('republic of seychelles', 'SC'),
+       ('sd', 'SD'), ('sdn', 'SD'), ('sudan', 'SD'), ('republic of the sudan', 'SD'),
+       ('se', 'SE'), ('swe', 'SE'), ('sweden', 'SE'), ('kingdom of sweden', 'SE'), ('sverige', 'SE'),
+       ('sg', 'SG'), ('sgp', 'SG'), ('singapore', 'SG'), ('republic of singapore', 'SG'),
+       ('sh', 'SH'), ('shn', 'SH'), ('saint helena, ascension and tristan da cunha', 'SH'),
+       ('si', 'SI'), ('svn', 'SI'), ('slovenia', 'SI'), ('republic of slovenia', 'SI'), ('slovenija', 'SI'),
+       ('sj', 'SJ'), ('sjm', 'SJ'), ('svalbard and jan mayen', 'SJ'),
+       ('sk', 'SK'), ('svk', 'SK'), ('slovakia', 'SK'), ('slovak republic', 'SK'), ('slovensko', 'SK'),
+       ('sl', 'SL'), ('sle', 'SL'), ('sierra leone', 'SL'), ('republic of sierra leone', 'SL'),
+       ('sm', 'SM'), ('smr', 'SM'), ('san marino', 'SM'), ('republic of san marino', 'SM'),
+       ('sn', 'SN'), ('sen', 'SN'), ('senegal', 'SN'), ('republic of senegal', 'SN'),
+       ('so', 'SO'), ('som', 'SO'), ('somalia', 'SO'), ('federal republic of somalia', 'SO'),
+       ('sr', 'SR'), ('sur', 'SR'), ('suriname', 'SR'), ('republic of suriname', 'SR'),
+       ('ss', 'SS'), ('ssd', 'SS'), ('south sudan', 'SS'), ('republic of south sudan', 'SS'),
+       ('st', 'ST'), ('stp', 'ST'), ('sao tome and principe', 'ST'), ('democratic republic of sao tome and principe', 'ST'),
+       ('sv', 'SV'), ('slv', 'SV'), ('el salvador', 'SV'), ('republic of el salvador', 'SV'),
+       ('sx', 'SX'), ('sxm', 'SX'), ('sint maarten (dutch part)', 'SX'),
+       ('sy', 'SY'), ('syr', 'SY'), ('syrian arab republic', 'SY'), ('syria', 'SY'),
+       ('sz', 'SZ'), ('swz', 'SZ'), ('eswatini', 'SZ'), ('kingdom of eswatini', 'SZ'), ('swaziland', 'SZ'),
+       ('tc', 'TC'), ('tca', 'TC'), ('turks and caicos islands', 'TC'),
+       ('td', 'TD'), ('tcd', 'TD'), ('chad', 'TD'), ('republic of chad', 'TD'),
+       ('tf', 'TF'), ('atf', 'TF'), ('french southern territories', 'TF'),
+       ('tg', 'TG'), ('tgo', 'TG'), ('togo', 'TG'), ('togolese republic', 'TG'),
+       ('th', 'TH'), ('tha', 'TH'), ('thailand', 'TH'), ('kingdom of thailand', 'TH'),
+       ('tj', 'TJ'), ('tjk', 'TJ'), ('tajikistan', 'TJ'), ('republic of tajikistan', 'TJ'),
+       ('tk', 'TK'), ('tkl', 'TK'), ('tokelau', 'TK'),
+       ('tl', 'TL'), ('tls', 'TL'), ('timor-leste', 'TL'), ('democratic republic of timor-leste', 'TL'), ('east timor', 'TL'),
+       ('tm', 'TM'), ('tkm', 'TM'), ('turkmenistan', 'TM'),
+       ('tn', 'TN'), ('tun', 'TN'), ('tunisia', 'TN'), ('republic of tunisia', 'TN'),
+       ('to', 'TO'), ('ton', 'TO'), ('tonga', 'TO'), ('kingdom of tonga', 'TO'),
+       ('tr', 'TR'), ('tur', 'TR'), ('türkiye', 'TR'), ('republic of türkiye', 'TR'), ('turkey', 'TR'), ('turkiye', 'TR'),
+       ('tt', 'TT'), ('tto', 'TT'), ('trinidad and tobago', 'TT'), ('republic of trinidad and tobago', 'TT'),
+       ('tv', 'TV'), ('tuv', 'TV'), ('tuvalu', 'TV'),
+       ('tw', 'TW'), ('twn', 'TW'), ('taiwan, province of china', 'TW'), ('taiwan', 'TW'),
+       ('tz', 'TZ'), ('tza', 'TZ'), ('tanzania, united republic of', 'TZ'), ('united republic of tanzania', 'TZ'), ('tanzania', 'TZ'),
+       ('ua', 'UA'), ('ukr', 'UA'), ('ukraine', 'UA'),
+       ('ug', 'UG'), ('uga', 'UG'), ('uganda', 'UG'), ('republic of uganda', 'UG'),
+       ('um', 'UM'), ('umi', 'UM'), ('united states minor outlying islands', 'UM'),
+       ('us', 'US'), ('usa', 'US'), ('united states', 'US'), ('united states of america', 'US'), ('america', 'US'),
+       ('uy', 'UY'), ('ury', 'UY'), ('uruguay', 'UY'), ('eastern republic of uruguay', 'UY'),
+       ('uz', 'UZ'), ('uzb', 'UZ'), ('uzbekistan', 'UZ'), ('republic of uzbekistan', 'UZ'),
+       ('va', 'VA'), ('vat', 'VA'), ('holy see (vatican city state)', 'VA'), ('vatican', 'VA'), ('vatican city', 'VA'),
+       ('vc', 'VC'), ('vct', 'VC'), ('saint vincent and the grenadines', 'VC'),
+       ('ve', 'VE'), ('ven', 'VE'), ('venezuela, bolivarian republic of', 'VE'), ('bolivarian republic of venezuela', 'VE'), ('venezuela', 'VE'),
+       ('vg', 'VG'), ('vgb', 'VG'), ('virgin islands, british', 'VG'), ('british virgin islands', 'VG'),
+       ('vi', 'VI'), ('vir', 'VI'), ('virgin islands, us', 'VI'), ('virgin islands of the united states', 'VI'),
+       ('vn', 'VN'), ('vnm', 'VN'), ('viet nam', 'VN'), ('socialist republic of viet nam', 'VN'), ('vietnam', 'VN'),
+       ('vu', 'VU'), ('vut', 'VU'), ('vanuatu', 'VU'), ('republic of vanuatu', 'VU'),
+       ('wf', 'WF'), ('wlf', 'WF'), ('wallis and futuna', 'WF'),
+       ('ws', 'WS'), ('wsm', 'WS'), ('samoa', 'WS'), ('independent state of samoa', 'WS'),
+       ('ye', 'YE'), ('yem', 'YE'), ('yemen', 'YE'), ('republic of yemen', 'YE'),
+       ('yt', 'YT'), ('myt', 'YT'), ('mayotte', 'YT'),
+       ('za', 'ZA'), ('zaf', 'ZA'), ('south africa', 'ZA'), ('republic of south africa', 'ZA'),
+       ('zm', 'ZM'), ('zmb', 'ZM'), ('zambia', 'ZM'), ('republic of zambia', 'ZM'),
+       ('zw', 'ZW'), ('zwe', 'ZW'), ('zimbabwe', 'ZW'), ('republic of zimbabwe', 'ZW');
+
+CREATE TEMPORARY TABLE legacy_addresses AS
+SELECT d.user_id,
+       d.address,
+       trim(coalesce(d.address ->> 'line', ''))                AS line,
+       trim(coalesce(d.address ->> 'city', ''))                AS city,
+       upper(trim(coalesce(d.address ->> 'postal_code', ''))) AS postal_code,
+       trim(coalesce(d.address ->> 'country', ''))             AS country_name,
+       c.code                                                  AS country
+FROM user_details d
+         LEFT JOIN country_codes c
+                   ON c.name = lower(regexp_replace(replace(trim(d.address ->> 'country'), '.', ''), '\s+', ' ', 'g'))
+WHERE d.address IS NOT NULL
+  AND jsonb_typeof(d.address -> 'addresses') IS DISTINCT FROM 'array';
+
+-- Nothing is dropped: an address that cannot be typed stops the migration
+-- until it is corrected or cleared. Empty ones are skipped.
+DO
+$$
+DECLARE
+    n     INTEGER;
+    users TEXT;
+BEGIN
+    SELECT count(*), string_agg(user_id::text, ', ' ORDER BY user_id) FILTER (WHERE rn <= 20)
+    INTO n, users
+    FROM (SELECT user_id, row_number() OVER (ORDER BY user_id) AS rn
+          FROM legacy_addresses
+          WHERE (line = '' OR city = '' OR country IS NULL)
+            AND NOT (address IN ('null', '""', '{}')
+              OR (jsonb_typeof(address) = 'object' AND line = '' AND city = '' AND postal_code = '' AND country_name = ''))) bad;
+    IF n > 0 THEN
+        RAISE EXCEPTION '% user_details.address values lack a street line, a city or a known country', n
+            USING DETAIL = 'users: ' || users,
+                HINT = 'Correct "line", "city" or "country" (an ISO 3166-1 code) of these addresses, or set them to NULL, and migrate again.';
+    END IF;
+END
+$$;
+
+INSERT INTO user_addresses (id, user_id, kind, is_default, lines, city, postal_code, country)
+SELECT gen_random_uuid(),
+       user_id,
+       'shipping',
+       true,
+       jsonb_build_array(line),
+       city,
+       postal_code,
+       country
+FROM legacy_addresses
+WHERE line <> ''
+  AND city <> ''
+  AND country IS NOT NULL;
+
+DROP TABLE legacy_addresses;
+DROP TABLE country_codes;
+
+ALTER TABLE user_details DROP COLUMN address;
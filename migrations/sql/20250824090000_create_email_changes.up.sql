@@ -0,0 +1,23 @@
+CREATE TABLE email_changes
+(
+    user_id    UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    new_email  VARCHAR(255) NOT NULL,
+    token_hash CHAR(64)     NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ  NOT NULL
+);
+
+-- Transactional outbox shared by the services (pkg/events): rows are
+-- written together with the change they describe and published by a relay.
+CREATE TABLE outbox_events
+(
+    id           UUID PRIMARY KEY,
+    type         TEXT        NOT NULL,
+    source       TEXT        NOT NULL,
+    subject      TEXT        NOT NULL,
+    payload      JSONB       NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ,
+    locked_until TIMESTAMPTZ -- a relay is publishing the row until then
+);
+CREATE INDEX idx_outbox_events_pending ON outbox_events (source, created_at) WHERE published_at IS NULL;
@@ -0,0 +1,5 @@
+-- Products listed before ownership existed keep a NULL seller and can only
+-- be changed by admins. No foreign key to users: purged accounts are
+-- hard-deleted by the auth service.
+ALTER TABLE products ADD COLUMN seller_id UUID;
+CREATE INDEX idx_products_seller_id ON products (seller_id, created_at DESC);
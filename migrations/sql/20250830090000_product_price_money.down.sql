@@ -0,0 +1,8 @@
+-- Only exact for currencies with two decimals.
+ALTER TABLE products ADD COLUMN price NUMERIC(10, 2);
+UPDATE products SET price = price_amount / 100.0;
+ALTER TABLE products
+    ALTER COLUMN price SET NOT NULL,
+    DROP COLUMN price_amount,
+    DROP COLUMN price_currency,
+    DROP COLUMN price_missing;
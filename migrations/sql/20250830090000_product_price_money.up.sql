@@ -0,0 +1,16 @@
+-- Prices become an integer amount of minor units plus an ISO 4217 currency.
+-- Existing prices carried no currency; they were entered in US dollars.
+-- Prices that were zero or negative are kept but flagged as missing: such
+-- products cannot be reserved until the seller sets a price.
+ALTER TABLE products
+    ADD COLUMN price_amount   BIGINT,
+    ADD COLUMN price_currency CHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN price_missing  BOOLEAN NOT NULL DEFAULT false;
+UPDATE products SET price_amount = round(price * 100);
+UPDATE products SET price_missing = true WHERE price_amount <= 0;
+ALTER TABLE products
+    ALTER COLUMN price_amount SET NOT NULL,
+    ALTER COLUMN price_currency DROP DEFAULT,
+    ADD CONSTRAINT products_price_amount_check CHECK (price_amount > 0 OR price_missing),
+    ADD CONSTRAINT products_price_currency_check CHECK (price_currency ~ '^[A-Z]{3}$'),
+    DROP COLUMN price;
@@ -0,0 +1,18 @@
+-- Categories form a tree stored as a materialized path: path lists the ids
+-- from the root down to the category itself, e.g. '/<root>/<child>/', so
+-- a subtree is every row whose path starts with the path of its root.
+CREATE TABLE IF NOT EXISTS categories
+(
+    id         UUID PRIMARY KEY   DEFAULT uuid_generate_v4(),
+    parent_id  UUID REFERENCES categories (id),
+    name       TEXT      NOT NULL,
+    slug       TEXT      NOT NULL UNIQUE,
+    path       TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_categories_parent_id ON categories (parent_id);
+CREATE INDEX idx_categories_path ON categories (path text_pattern_ops);
+
+-- Deleting a category leaves its products uncategorised.
+ALTER TABLE products ADD COLUMN category_id UUID REFERENCES categories (id) ON DELETE SET NULL;
+CREATE INDEX idx_products_category_id ON products (category_id, created_at DESC);
@@ -0,0 +1,20 @@
+-- Options are the dimensions variants differ in:
+-- [{"name": "size", "values": ["S", "M"]}, ...]
+ALTER TABLE products ADD COLUMN options JSONB NOT NULL DEFAULT '[]';
+
+-- A variant picks one value per option, e.g. {"size": "M"}. jsonb stores
+-- object keys in a canonical order, so the unique index on (product_id,
+-- options) rejects the same combination written in another key order.
+CREATE TABLE IF NOT EXISTS product_variants
+(
+    id             UUID PRIMARY KEY   DEFAULT uuid_generate_v4(),
+    product_id     UUID      NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    sku            TEXT      NOT NULL,
+    options        JSONB     NOT NULL DEFAULT '{}',
+    price_amount   BIGINT    NOT NULL CHECK (price_amount > 0),
+    price_currency CHAR(3)   NOT NULL CHECK (price_currency ~ '^[A-Z]{3}$'),
+    stock          INTEGER   NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    created_at     TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX product_variants_sku_key ON product_variants (sku);
+CREATE UNIQUE INDEX product_variants_options_key ON product_variants (product_id, options);
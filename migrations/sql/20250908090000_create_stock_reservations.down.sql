@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS stock_reservation_items;
+DROP TABLE IF EXISTS stock_reservations;
+ALTER TABLE product_variants
+    DROP CONSTRAINT IF EXISTS product_variants_reserved_check,
+    DROP COLUMN IF EXISTS reserved;
+ALTER TABLE products
+    DROP CONSTRAINT IF EXISTS products_stock_check,
+    DROP COLUMN IF EXISTS reserved,
+    DROP COLUMN IF EXISTS stock;
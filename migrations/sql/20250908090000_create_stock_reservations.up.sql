@@ -0,0 +1,33 @@
+-- Stock is the quantity on hand; reserved is the part held by pending
+-- reservations. Available = stock - reserved, and the checks make
+-- overselling impossible even if a code path forgets to lock.
+-- Products with variants keep their stock on the variants.
+ALTER TABLE products
+    ADD COLUMN stock    INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN reserved INTEGER NOT NULL DEFAULT 0,
+    ADD CONSTRAINT products_stock_check CHECK (reserved >= 0 AND reserved <= stock);
+ALTER TABLE product_variants
+    ADD COLUMN reserved INTEGER NOT NULL DEFAULT 0,
+    ADD CONSTRAINT product_variants_reserved_check CHECK (reserved >= 0 AND reserved <= stock);
+
+CREATE TABLE IF NOT EXISTS stock_reservations
+(
+    id          UUID PRIMARY KEY   DEFAULT uuid_generate_v4(),
+    status      TEXT      NOT NULL CHECK (status IN ('pending', 'committed', 'released', 'expired')),
+    reserved_by UUID,     -- user; NULL for service principals
+    reserved_by_service TEXT, -- SAN of the service principal that reserved
+    expires_at  TIMESTAMP NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_stock_reservations_pending ON stock_reservations (expires_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS stock_reservation_items
+(
+    id             UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    reservation_id UUID    NOT NULL REFERENCES stock_reservations (id) ON DELETE CASCADE,
+    product_id     UUID    NOT NULL,
+    variant_id     UUID,
+    quantity       INTEGER NOT NULL CHECK (quantity > 0)
+);
+CREATE INDEX idx_stock_reservation_items_reservation_id ON stock_reservation_items (reservation_id);
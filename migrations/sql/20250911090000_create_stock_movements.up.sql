@@ -0,0 +1,59 @@
+-- Every change of stock or reserved is an immutable ledger entry. The stock
+-- and reserved columns of products and product_variants are a projection of
+-- it, written in the same transaction as each entry. There is no foreign key
+-- so that the history outlives deleted products and variants.
+CREATE TABLE IF NOT EXISTS stock_movements
+(
+    id             BIGSERIAL PRIMARY KEY, -- ledger order
+    product_id     UUID      NOT NULL,
+    variant_id     UUID,
+    sku            TEXT      NOT NULL DEFAULT '',
+    kind           TEXT      NOT NULL CHECK (kind IN ('received', 'reserved', 'released', 'sold', 'adjusted', 'returned')),
+    stock_delta    INTEGER   NOT NULL,
+    reserved_delta INTEGER   NOT NULL,
+    stock_after    INTEGER   NOT NULL,
+    reserved_after INTEGER   NOT NULL,
+    reservation_id UUID,
+    actor_id       UUID,
+    reason         TEXT      NOT NULL DEFAULT '',
+    created_at     TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_stock_movements_product ON stock_movements (product_id, variant_id, id);
+CREATE INDEX idx_stock_movements_sku ON stock_movements (sku, id) WHERE sku <> '';
+
+CREATE OR REPLACE FUNCTION stock_movements_immutable() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stock_movements_immutable
+    BEFORE UPDATE OR DELETE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION stock_movements_immutable();
+
+-- A snapshot is the projection up to and including movement_id, so that
+-- stock can be rebuilt without replaying the whole ledger.
+CREATE TABLE IF NOT EXISTS stock_snapshots
+(
+    id          UUID PRIMARY KEY   DEFAULT uuid_generate_v4(),
+    product_id  UUID      NOT NULL,
+    variant_id  UUID,
+    movement_id BIGINT    NOT NULL,
+    stock       INTEGER   NOT NULL,
+    reserved    INTEGER   NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_stock_snapshots_product ON stock_snapshots (product_id, variant_id, movement_id DESC);
+
+-- Open the ledger with the quantities tracked so far.
+INSERT INTO stock_movements (product_id, kind, stock_delta, reserved_delta, stock_after, reserved_after, reason)
+SELECT p.id, 'adjusted', p.stock, p.reserved, p.stock, p.reserved, 'opening balance'
+FROM products p
+WHERE (p.stock <> 0 OR p.reserved <> 0)
+  AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id);
+
+INSERT INTO stock_movements (product_id, variant_id, sku, kind, stock_delta, reserved_delta, stock_after, reserved_after, reason)
+SELECT v.product_id, v.id, v.sku, 'adjusted', v.stock, v.reserved, v.stock, v.reserved, 'opening balance'
+FROM product_variants v
+WHERE v.stock <> 0 OR v.reserved <> 0;
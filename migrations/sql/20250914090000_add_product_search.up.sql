@@ -0,0 +1,12 @@
+-- Full-text search over name (weight A) and description (weight B). Each
+-- product is indexed with its own text search configuration, set from
+-- SEARCH_CONFIG when it is created; changing the configuration of existing
+-- products with UPDATE regenerates their search_vector.
+ALTER TABLE products
+    ADD COLUMN search_config regconfig NOT NULL DEFAULT 'english';
+ALTER TABLE products
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector(search_config, coalesce(name, '')), 'A') ||
+        setweight(to_tsvector(search_config, coalesce(description, '')), 'B')
+    ) STORED;
+CREATE INDEX idx_products_search_vector ON products USING GIN (search_vector);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_products_name;
+DROP INDEX IF EXISTS idx_products_price;
+DROP INDEX IF EXISTS idx_products_created_at;
+DROP INDEX IF EXISTS idx_products_tags;
+ALTER TABLE products DROP COLUMN IF EXISTS tags;
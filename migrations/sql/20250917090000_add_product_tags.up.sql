@@ -0,0 +1,9 @@
+-- Free-form tags for filtering and facets, as a JSON array of lowercase
+-- strings. jsonb_path_ops supports the @> containment used by the filter.
+ALTER TABLE products ADD COLUMN tags JSONB NOT NULL DEFAULT '[]';
+CREATE INDEX idx_products_tags ON products USING GIN (tags jsonb_path_ops);
+
+-- Listing sort orders.
+CREATE INDEX idx_products_created_at ON products (created_at DESC, id DESC);
+CREATE INDEX idx_products_price ON products (price_currency, price_amount, id);
+CREATE INDEX idx_products_name ON products (lower(name), id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_products_price;
+DROP INDEX IF EXISTS idx_products_name;
+CREATE INDEX idx_products_price ON products (price_currency, price_amount, id);
+CREATE INDEX idx_products_name ON products (lower(name), id);
@@ -0,0 +1,7 @@
+-- Listings page by seeking past the sort key of the previous page, with
+-- (created_at, id) breaking ties. The indexes carry the full key so that a
+-- page is a single index range scan.
+DROP INDEX IF EXISTS idx_products_price;
+DROP INDEX IF EXISTS idx_products_name;
+CREATE INDEX idx_products_price ON products (price_currency, price_amount, created_at DESC, id DESC);
+CREATE INDEX idx_products_name ON products (lower(name), created_at DESC, id DESC);
@@ -0,0 +1,6 @@
+CREATE OR REPLACE FUNCTION impersonation_logs_immutable() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'impersonation_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,14 @@
+-- The impersonation trail stays append-only, except that the erasure of an
+-- account may blank the client IP and user agent recorded for it.
+CREATE OR REPLACE FUNCTION impersonation_logs_immutable() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND COALESCE(NEW.ip, '') = ''
+        AND COALESCE(NEW.user_agent, '') = ''
+        AND to_jsonb(NEW) - 'ip' - 'user_agent' = to_jsonb(OLD) - 'ip' - 'user_agent' THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'impersonation_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
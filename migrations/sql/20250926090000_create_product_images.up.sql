@@ -0,0 +1,14 @@
+-- Pictures of a product, in display order. Keys are blob store keys of the
+-- processed image and of its thumbnail; the same image can be added to a
+-- product only once, so deleting a row may delete its blobs.
+CREATE TABLE IF NOT EXISTS product_images
+(
+    id            UUID PRIMARY KEY   DEFAULT uuid_generate_v4(),
+    product_id    UUID      NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    key           TEXT      NOT NULL,
+    thumbnail_key TEXT      NOT NULL DEFAULT '',
+    position      INTEGER   NOT NULL,
+    created_at    TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX product_images_key ON product_images (product_id, key);
+CREATE INDEX idx_product_images_position ON product_images (product_id, position);
@@ -0,0 +1,171 @@
+// Package address holds a postal address value with per-country
+// normalization, validation and display formatting.
+package address
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaxLines is the number of street lines an address may have.
+const MaxLines = 2
+
+// Address is a postal address. Country is an ISO 3166-1 alpha-2 code;
+// Region is the state, province or county where the country uses one.
+type Address struct {
+	Lines      []string `json:"lines" gorm:"serializer:json;not null"`
+	City       string   `json:"city" gorm:"not null"`
+	Region     string   `json:"region,omitempty" gorm:"not null;default:''"`
+	PostalCode string   `json:"postalCode,omitempty" gorm:"not null;default:''"`
+	Country    string   `json:"country" gorm:"type:char(2);not null"`
+}
+
+// FieldError describes one invalid field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a value.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, f := range e {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "invalid address: " + strings.Join(msgs, "; ")
+}
+
+// Fields returns the errors keyed by field name.
+func (e ValidationError) Fields() map[string]string {
+	m := make(map[string]string, len(e))
+	for _, f := range e {
+		m[f.Field] = f.Message
+	}
+	return m
+}
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// Normalize trims the fields, drops empty lines, upper-cases the country
+// and region codes and brings the postal code into the country's canonical
+// form (e.g. "sw1a1aa" → "SW1A 1AA").
+func (a *Address) Normalize() {
+	lines := a.Lines[:0]
+	for _, l := range a.Lines {
+		if l = strings.TrimSpace(spaces.ReplaceAllString(l, " ")); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	a.Lines = lines
+	a.City = strings.TrimSpace(spaces.ReplaceAllString(a.City, " "))
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	a.Region = strings.TrimSpace(a.Region)
+	a.PostalCode = strings.ToUpper(strings.TrimSpace(spaces.ReplaceAllString(a.PostalCode, " ")))
+	r, ok := rules[a.Country]
+	if !ok {
+		return
+	}
+	if r.regions != nil {
+		a.Region = strings.ToUpper(a.Region)
+	}
+	if r.canonicalPostal != nil {
+		a.PostalCode = r.canonicalPostal(a.PostalCode)
+	}
+}
+
+// Validate checks a normalized address against the rules of its country.
+func (a Address) Validate() error {
+	var errs ValidationError
+	add := func(field, msg string) { errs = append(errs, FieldError{Field: field, Message: msg}) }
+
+	switch {
+	case len(a.Lines) == 0:
+		add("lines", "at least one street line is required")
+	case len(a.Lines) > MaxLines:
+		add("lines", "at most 2 street lines are allowed")
+	}
+	for _, l := range a.Lines {
+		if len(l) > 200 {
+			add("lines", "street lines are limited to 200 characters")
+			break
+		}
+	}
+	if a.City == "" {
+		add("city", "required")
+	} else if len(a.City) > 100 {
+		add("city", "limited to 100 characters")
+	}
+	if !countries[a.Country] {
+		add("country", "must be an ISO 3166-1 alpha-2 code")
+		return errs
+	}
+
+	r := rules[a.Country]
+	switch {
+	case r.regions != nil && !r.regions[a.Region]:
+		add("region", "must be a "+a.Country+" region code, e.g. "+r.regionExample)
+	case r.regionRequired && a.Region == "":
+		add("region", "required in "+a.Country)
+	case len(a.Region) > 100:
+		add("region", "limited to 100 characters")
+	}
+	switch {
+	case r.postal != nil && a.PostalCode == "":
+		add("postalCode", "required in "+a.Country)
+	case r.postal != nil && !r.postal.MatchString(a.PostalCode):
+		add("postalCode", "invalid format for "+a.Country+", e.g. "+r.postalExample)
+	case len(a.PostalCode) > 20:
+		add("postalCode", "limited to 20 characters")
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Format returns the address as display lines in the order and layout
+// customary in its country, ending with the country code.
+func (a Address) Format() []string {
+	out := append([]string(nil), a.Lines...)
+	style := rules[a.Country].style
+	join := func(parts ...string) string {
+		var kept []string
+		for _, p := range parts {
+			if p != "" {
+				kept = append(kept, p)
+			}
+		}
+		return strings.Join(kept, " ")
+	}
+	switch style {
+	case styleCityRegionPostal: // US, CA, AU: "Springfield, IL 62704"
+		line := a.City
+		if rp := join(a.Region, a.PostalCode); rp != "" {
+			line += ", " + rp
+		}
+		out = append(out, line)
+	case stylePostalLast: // GB: city and postcode on their own lines
+		out = append(out, a.City)
+		if a.Region != "" {
+			out = append(out, a.Region)
+		}
+		if a.PostalCode != "" {
+			out = append(out, a.PostalCode)
+		}
+	case stylePostalRegion: // IT, ES, BR: "00184 Roma RM"
+		out = append(out, join(a.PostalCode, a.City, a.Region))
+	case styleCityPostal: // RO, MD, ...
+		out = append(out, join(a.City, a.PostalCode))
+		if a.Region != "" {
+			out = append(out, a.Region)
+		}
+	default: // most of Europe: "10115 Berlin"
+		out = append(out, join(a.PostalCode, a.City))
+		if a.Region != "" {
+			out = append(out, a.Region)
+		}
+	}
+	return append(out, a.Country)
+}
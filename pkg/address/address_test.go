@@ -0,0 +1,80 @@
+package address_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/address"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	a := address.Address{
+		Lines:      []string{"  10  Downing   Street ", "", " "},
+		City:       " London ",
+		PostalCode: "sw1a2aa",
+		Country:    "gb",
+	}
+	a.Normalize()
+	assert.Equal(t, []string{"10 Downing Street"}, a.Lines)
+	assert.Equal(t, "London", a.City)
+	assert.Equal(t, "SW1A 2AA", a.PostalCode)
+	assert.Equal(t, "GB", a.Country)
+	assert.NoError(t, a.Validate())
+
+	md := address.Address{Lines: []string{"bd. Ștefan cel Mare 1"}, City: "Chișinău", PostalCode: "2001", Country: "MD"}
+	md.Normalize()
+	assert.Equal(t, "MD-2001", md.PostalCode)
+
+	ca := address.Address{Lines: []string{"24 Sussex Dr"}, City: "Ottawa", Region: "on", PostalCode: "k1m1m4", Country: "CA"}
+	ca.Normalize()
+	assert.Equal(t, "ON", ca.Region)
+	assert.Equal(t, "K1M 1M4", ca.PostalCode)
+	assert.NoError(t, ca.Validate())
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		addr   address.Address
+		fields []string
+	}{
+		{"valid US", address.Address{Lines: []string{"1 Main St"}, City: "Springfield", Region: "IL", PostalCode: "62704-1234", Country: "US"}, nil},
+		{"valid DE", address.Address{Lines: []string{"Unter den Linden 1"}, City: "Berlin", PostalCode: "10117", Country: "DE"}, nil},
+		{"country without rules", address.Address{Lines: []string{"Main Road"}, City: "Suva", Country: "FJ"}, nil},
+		{"missing fields", address.Address{Country: "DE"}, []string{"lines", "city", "postalCode"}},
+		{"too many lines", address.Address{Lines: []string{"a", "b", "c"}, City: "Paris", PostalCode: "75001", Country: "FR"}, []string{"lines"}},
+		{"unknown country", address.Address{Lines: []string{"x"}, City: "y", Country: "XX"}, []string{"country"}},
+		{"US state", address.Address{Lines: []string{"1 Main St"}, City: "Springfield", Region: "Illinois", PostalCode: "62704", Country: "US"}, []string{"region"}},
+		{"US zip", address.Address{Lines: []string{"1 Main St"}, City: "Springfield", Region: "IL", PostalCode: "6270", Country: "US"}, []string{"postalCode"}},
+		{"RO county required", address.Address{Lines: []string{"Str. Unirii 1"}, City: "Cluj-Napoca", PostalCode: "400001", Country: "RO"}, []string{"region"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.addr.Validate()
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var verr address.ValidationError
+			if assert.True(t, errors.As(err, &verr)) {
+				got := make([]string, len(verr))
+				for i, f := range verr {
+					got[i] = f.Field
+				}
+				assert.Equal(t, tt.fields, got)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	us := address.Address{Lines: []string{"1 Main St", "Apt 2"}, City: "Springfield", Region: "IL", PostalCode: "62704", Country: "US"}
+	assert.Equal(t, []string{"1 Main St", "Apt 2", "Springfield, IL 62704", "US"}, us.Format())
+
+	de := address.Address{Lines: []string{"Unter den Linden 1"}, City: "Berlin", PostalCode: "10117", Country: "DE"}
+	assert.Equal(t, []string{"Unter den Linden 1", "10117 Berlin", "DE"}, de.Format())
+
+	gb := address.Address{Lines: []string{"10 Downing Street"}, City: "London", PostalCode: "SW1A 2AA", Country: "GB"}
+	assert.Equal(t, []string{"10 Downing Street", "London", "SW1A 2AA", "GB"}, gb.Format())
+}
@@ -0,0 +1,174 @@
+package address
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Display layouts of the last address line(s), see Address.Format.
+const (
+	stylePostalCity = iota // default: "10115 Berlin"
+	styleCityRegionPostal
+	stylePostalLast
+	stylePostalRegion
+	styleCityPostal
+)
+
+// rule holds the conventions of one country. Countries without a rule
+// accept any postal code and region.
+type rule struct {
+	postal          *regexp.Regexp // postal code format; nil → optional
+	postalExample   string
+	canonicalPostal func(string) string
+	regionRequired  bool
+	regions         map[string]bool // allowed region codes; nil → free text
+	regionExample   string
+	style           int
+}
+
+func postal(pattern, example string) (*regexp.Regexp, string) {
+	return regexp.MustCompile(`^(?:` + pattern + `)$`), example
+}
+
+func set(codes string) map[string]bool {
+	m := map[string]bool{}
+	for _, c := range strings.Fields(codes) {
+		m[c] = true
+	}
+	return m
+}
+
+// insertSpace returns a canonicalizer that puts a single space before the
+// last n characters ("SW1A1AA" → "SW1A 1AA").
+func insertSpace(n int) func(string) string {
+	return func(s string) string {
+		s = strings.ReplaceAll(s, " ", "")
+		if len(s) <= n {
+			return s
+		}
+		return s[:len(s)-n] + " " + s[len(s)-n:]
+	}
+}
+
+// withPrefix prepends prefix to purely numeric codes ("2001" → "MD-2001").
+func withPrefix(prefix string) func(string) string {
+	return func(s string) string {
+		if s != "" && !strings.HasPrefix(s, prefix) {
+			return prefix + s
+		}
+		return s
+	}
+}
+
+var rules = map[string]rule{}
+
+func init() {
+	add := func(country string, r rule) { rules[country] = r }
+	r := func(pattern, example string) rule {
+		re, ex := postal(pattern, example)
+		return rule{postal: re, postalExample: ex}
+	}
+
+	us := r(`\d{5}(-\d{4})?`, "94105")
+	us.regions = set(`AL AK AZ AR CA CO CT DE DC FL GA HI ID IL IN IA KS KY LA ME MD MA MI MN MS MO MT NE NV NH NJ NM NY
+		NC ND OH OK OR PA RI SC SD TN TX UT VT VA WA WV WI WY AS GU MP PR VI AA AE AP`)
+	us.regionExample, us.style = "CA", styleCityRegionPostal
+	add("US", us)
+
+	ca := r(`[A-Z]\d[A-Z] \d[A-Z]\d`, "K1A 0B1")
+	ca.canonicalPostal = insertSpace(3)
+	ca.regions = set("AB BC MB NB NL NS NT NU ON PE QC SK YT")
+	ca.regionExample, ca.style = "ON", styleCityRegionPostal
+	add("CA", ca)
+
+	au := r(`\d{4}`, "2000")
+	au.regions = set("ACT NSW NT QLD SA TAS VIC WA")
+	au.regionExample, au.style = "NSW", styleCityRegionPostal
+	add("AU", au)
+
+	gb := r(`[A-Z]{1,2}\d[A-Z\d]? \d[A-Z]{2}`, "SW1A 1AA")
+	gb.canonicalPostal, gb.style = insertSpace(3), stylePostalLast
+	add("GB", gb)
+
+	ie := r(`[A-Z]\d[\dW] [A-Z\d]{4}`, "D02 X285")
+	ie.canonicalPostal, ie.regionRequired = insertSpace(4), true
+	ie.regionExample, ie.style = "Dublin", stylePostalLast
+	add("IE", ie)
+
+	nl := r(`\d{4} [A-Z]{2}`, "1012 AB")
+	nl.canonicalPostal = insertSpace(2)
+	add("NL", nl)
+
+	pl := r(`\d{2}-\d{3}`, "00-950")
+	add("PL", pl)
+
+	pt := r(`\d{4}-\d{3}`, "1000-001")
+	add("PT", pt)
+
+	se := r(`\d{3} \d{2}`, "114 55")
+	se.canonicalPostal = insertSpace(2)
+	add("SE", se)
+
+	cz := r(`\d{3} \d{2}`, "110 00")
+	cz.canonicalPostal = insertSpace(2)
+	add("CZ", cz)
+	sk := cz
+	sk.postalExample = "811 01"
+	add("SK", sk)
+
+	it := r(`\d{5}`, "00184")
+	it.style = stylePostalRegion
+	add("IT", it)
+	es := it
+	es.postalExample = "28013"
+	add("ES", es)
+
+	br := r(`\d{5}-\d{3}`, "01310-100")
+	br.regions = set("AC AL AP AM BA CE DF ES GO MA MT MS MG PA PB PR PE PI RJ RN RS RO RR SC SP SE TO")
+	br.regionExample, br.style = "SP", stylePostalRegion
+	add("BR", br)
+
+	for country, example := range map[string]string{
+		"DE": "10115", "FR": "75001", "FI": "00100", "HR": "10000", "EE": "10111",
+		"GR": "10431", "TR": "34000", "UA": "01001",
+	} {
+		add(country, r(`\d{5}`, example))
+	}
+	for country, example := range map[string]string{
+		"AT": "1010", "BE": "1000", "BG": "1000", "CH": "8001", "DK": "1050",
+		"HU": "1051", "LU": "1111", "NO": "0150", "SI": "1000", "ZA": "8001",
+	} {
+		add(country, r(`\d{4}`, example))
+	}
+
+	ro := r(`\d{6}`, "010011")
+	ro.regionRequired, ro.regionExample, ro.style = true, "București", styleCityPostal
+	add("RO", ro)
+
+	md := r(`MD-\d{4}`, "MD-2001")
+	md.canonicalPostal, md.style = withPrefix("MD-"), styleCityPostal
+	add("MD", md)
+
+	jp := r(`\d{3}-\d{4}`, "100-0001")
+	jp.regionRequired, jp.regionExample = true, "Tokyo"
+	add("JP", jp)
+
+	in := r(`\d{6}`, "110001")
+	in.regionRequired, in.regionExample = true, "Delhi"
+	in.style = styleCityPostal
+	add("IN", in)
+
+	mx := r(`\d{5}`, "06600")
+	mx.regionRequired, mx.regionExample = true, "CDMX"
+	add("MX", mx)
+}
+
+// countries lists the officially assigned ISO 3166-1 alpha-2 codes.
+var countries = set(`
+	AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ
+	CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO
+	FR GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU ID IE IL IM IN IO IQ IR IS IT JE
+	JM JO JP KE KG KH KI KM KN KP KR KW KY KZ LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO
+	MP MQ MR MS MT MU MV MW MX MY MZ NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM PN PR PS PT PW
+	PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM
+	TN TO TR TT TV TW TZ UA UG UM US UY UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW`)
@@ -0,0 +1,146 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local stores objects as files under a directory. It is also an
+// http.Handler serving them when the request carries a valid signature;
+// mount it under URLPrefix with the prefix stripped.
+type Local struct {
+	dir       string
+	urlPrefix string
+	secret    []byte
+}
+
+// NewLocal returns a Local store rooted at dir.
+func NewLocal(dir, urlPrefix string, secret []byte) (*Local, error) {
+	if dir == "" || len(secret) == 0 {
+		return nil, errors.New("local blob store needs a directory and a signing secret")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir, urlPrefix: strings.TrimSuffix(urlPrefix, "/"), secret: secret}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the object atomically through a temporary file.
+func (l *Local) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (l *Local) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete removes the object; deleting a missing object is not an error.
+func (l *Local) Delete(_ context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL returns <urlPrefix>/<key>?expires=<unix>&signature=<hmac>.
+func (l *Local) URL(key string, ttl time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	exp := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	q := url.Values{"expires": {exp}, "signature": {l.sign(key, exp)}}
+	return l.urlPrefix + "/" + key + "?" + q.Encode(), nil
+}
+
+func (l *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves the object named by the request path after checking the
+// URL signature and expiry.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	exp := r.URL.Query().Get("expires")
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > unix ||
+		!hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(l.sign(key, exp))) {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+	p, err := l.path(key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil || st.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Keys are content addressed, so the object never changes.
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.FormatInt(max(unix-time.Now().Unix(), 0), 10)+", immutable")
+	http.ServeContent(w, r, "", st.ModTime(), f)
+}
@@ -0,0 +1,259 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Options configures an S3-compatible backend (AWS S3, MinIO, ...).
+type S3Options struct {
+	Endpoint  string // e.g. "https://s3.eu-central-1.amazonaws.com" or "http://minio:9000"
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // address objects as <endpoint>/<bucket>/<key> (required by most MinIO setups)
+}
+
+// S3 talks to an S3-compatible API with AWS Signature Version 4.
+type S3 struct {
+	opts     S3Options
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// unsignedPayload lets uploads stream without hashing the body first; the
+// transport (TLS) protects its integrity.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// NewS3 returns an S3 store.
+func NewS3(o S3Options) (*S3, error) {
+	u, err := url.Parse(o.Endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("invalid S3 endpoint")
+	}
+	if o.Bucket == "" || o.AccessKey == "" || o.SecretKey == "" {
+		return nil, errors.New("S3 bucket and credentials are required")
+	}
+	if o.Region == "" {
+		o.Region = "us-east-1"
+	}
+	return &S3{opts: o, endpoint: u, client: &http.Client{Timeout: time.Minute}, now: time.Now}, nil
+}
+
+// objectURL returns the unsigned URL of key.
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.opts.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.opts.Bucket + "/" + key
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	return &u
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object; S3 treats deleting a missing key as success.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// URL returns a presigned GET URL (query-string authentication).
+func (s *S3) URL(key string, ttl time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > 7*24*time.Hour {
+		return "", errors.New("presigned URL lifetime must be between 1s and 7 days")
+	}
+	now := s.now().UTC()
+	u := s.objectURL(key)
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.opts.AccessKey+"/"+s.scope(now))
+	q.Set("X-Amz-Date", now.Format(amzDateFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl/time.Second)))
+	q.Set("X-Amz-SignedHeaders", "host")
+	u.RawQuery = canonicalQuery(q)
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		escapePath(u.Path),
+		u.RawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	u.RawQuery += "&X-Amz-Signature=" + s.signature(now, canonical)
+	return u.String(), nil
+}
+
+// do signs req with an Authorization header, sends it and maps error
+// statuses.
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	now := s.now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		signed = append([]string{"content-type"}, signed...)
+	}
+	var headers strings.Builder
+	for _, h := range signed {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		headers.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	canonical := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		headers.String(),
+		strings.Join(signed, ";"),
+		unsignedPayload,
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, s.scope(now), strings.Join(signed, ";"), s.signature(now, canonical)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, body)
+	}
+	return resp, nil
+}
+
+const amzDateFormat = "20060102T150405Z"
+
+func (s *S3) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.opts.Region + "/s3/aws4_request"
+}
+
+func (s *S3) signature(t time.Time, canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	toSign := "AWS4-HMAC-SHA256\n" + t.Format(amzDateFormat) + "\n" + s.scope(t) + "\n" + hex.EncodeToString(sum[:])
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes q sorted by key, as SigV4 requires.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func escapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return uriEncode(p, false)
+}
+
+// uriEncode percent-encodes everything except unreserved characters (and
+// '/' unless encodeSlash).
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -9,7 +9,16 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/fx"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/imageproc"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/mail"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/tlsconfig"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/upload"
 	"github.com/joho/godotenv"
 )
 
@@ -17,12 +26,48 @@ import (
 // Extend this struct with additional fields (Kafka, Redis, etc.) as needed.
 
 type Config struct {
-	ProdHTTPAddr string // HTTP listen address, default ":8080"
-	ProdGRPCAddr string // gRPC listen address, default ":50051"
-	AuthHTTPAddr string // HTTP listen address, default ":8090"
-	AuthGRPCAddr string // gRPC listen address, default ":50052"
-	DBURL        string // Postgres DSN (required)
-	SymmetricKey string // 32‑byte key for Paseto (required)
+	ProdHTTPAddr   string            // HTTP listen address, default ":8080"
+	ProdGRPCAddr   string            // gRPC listen address, default ":50051"
+	AuthHTTPAddr   string            // HTTP listen address, default ":8090"
+	AuthGRPCAddr   string            // gRPC listen address, default ":50052"
+	DBURL          string            // Postgres DSN (required)
+	SymmetricKey   string            // 32‑byte key for Paseto (required)
+	TLS            tlsconfig.Options // TLS / mTLS for the HTTP and gRPC listeners, disabled by default
+	AuthService    string            // auth-service gRPC target that product-service verifies API keys with
+	ProductService string            // product-service gRPC target that auth-service exports products from
+
+	CookieSession  bool   // browser session mode: tokens in HttpOnly cookies + CSRF
+	CookieDomain   string // Domain attribute of session cookies (empty → host only)
+	CookieSecure   bool   // Secure attribute, default true
+	CookieSameSite string // "lax" (default), "strict" or "none"
+
+	AccountDeletionGrace time.Duration // delay between a deletion request and the erasure, default 30 days
+	AccountRetention     time.Duration // how long soft-deleted accounts are kept before the purge, default 90 days
+
+	Images imageproc.Options // profile photo and product image processing
+	Blob   blob.Options      // storage of uploaded media
+
+	PhotoUpload   upload.Policy // size limit and allowed types of photo uploads
+	ImageUpload   upload.Policy // size limit and allowed types of product image uploads
+	ClamAVAddr    string        // clamd address; empty disables malware scanning
+	ClamAVTimeout time.Duration
+
+	UploadSessionTTL time.Duration // how long an interrupted streaming upload can be resumed, default 24h
+
+	Mail            mail.Options  // outgoing email; without an SMTP server messages are logged
+	EmailConfirmURL string        // page that confirms an email change with ?token=
+	EmailChangeTTL  time.Duration // validity of email change tokens, default 24h
+
+	EventWebhookURLs   []string // receivers of published events; empty logs them
+	EventWebhookSecret string   // HMAC key signing webhook bodies
+
+	FX fx.Options // exchange rates for display prices; disabled without a file or URL
+
+	ReservationTTL time.Duration // default lifetime of stock reservations
+	LowStock       int           // inventory.stock_low fires when available stock drops to this
+	StockSnapshots time.Duration // how often the stock ledger is snapshotted
+
+	SearchConfig string // PostgreSQL text search configuration of new products
 }
 
 // Load loads .env (when present) and returns a Config struct.
@@ -32,6 +77,57 @@ type Config struct {
 //	GRPC_ADDR      → default ":50051"
 //	DATABASE_URL   → REQUIRED, no default
 //	SYMMETRIC_KEY  → REQUIRED, exactly 32 characters (v2‑local)
+//
+//	TLS_CERT_FILE, TLS_KEY_FILE  → enable TLS when set
+//	TLS_CLIENT_CA_FILE           → verify client certificates (mTLS)
+//	TLS_REQUIRE_CLIENT_CERT      → "true" to reject clients without a certificate
+//	TLS_ALLOWED_SANS             → comma-separated SANs accepted as service principals
+//	TLS_SERVICE_SCOPES           → comma-separated "SAN=scope scope" grants; services get no other scopes
+//	TLS_CA_FILE                  → verify the servers of outgoing calls (system roots when empty)
+//	AUTH_SERVICE_ADDR            → auth-service gRPC target, default "localhost:50052"
+//	PRODUCT_SERVICE_ADDR         → product-service gRPC target, default "localhost:50051"
+//
+//	COOKIE_SESSION_MODE → "true" to issue HttpOnly session cookies
+//	COOKIE_DOMAIN, COOKIE_SECURE (default true), COOKIE_SAMESITE (default "lax")
+//
+//	ACCOUNT_DELETION_GRACE → Go duration, default "720h"
+//	ACCOUNT_RETENTION      → Go duration, default "2160h"
+//
+//	PHOTO_MAX_DIMENSION → longest side of stored photos, default 2048
+//	THUMBNAIL_SIZES     → comma-separated square sizes, default "256"; the first is the profile thumbnail
+//	PHOTO_JPEG_QUALITY  → default 85
+//
+//	BLOB_BACKEND    → "local" (default) or "s3"
+//	BLOB_LOCAL_DIR  → default "./data/blobs"
+//	BLOB_URL_PREFIX → path serving local blobs, default "/media"
+//	BLOB_URL_TTL    → lifetime of signed download URLs, default "1h"
+//	BLOB_SIGNING_KEY → key of the signed URLs; derived from SYMMETRIC_KEY with HKDF when unset
+//	S3_ENDPOINT, S3_REGION (default "us-east-1"), S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY
+//	S3_PATH_STYLE   → "true" for MinIO and other servers without virtual-host buckets
+//
+//	PHOTO_MAX_BYTES     → default 10485760 (10 MiB)
+//	PHOTO_ALLOWED_TYPES → comma-separated content types, default jpeg, png, gif and webp
+//	CLAMAV_ADDR         → clamd "host:port" or unix socket path; empty disables scanning
+//	CLAMAV_TIMEOUT      → Go duration, default "30s"
+//	UPLOAD_SESSION_TTL  → Go duration, default "24h"
+//
+//	SMTP_ADDR           → "host:port"; empty logs outgoing mail instead
+//	SMTP_USERNAME, SMTP_PASSWORD, MAIL_FROM (default "no-reply@marketplace.local")
+//	EMAIL_CONFIRM_URL   → default "http://localhost:5173/confirm-email"
+//	EMAIL_CHANGE_TTL    → Go duration, default "24h"
+//	EVENTS_WEBHOOK_URLS → comma-separated URLs receiving events as JSON POSTs
+//	EVENTS_WEBHOOK_SECRET → key of the X-Event-Signature HMAC; required with EVENTS_WEBHOOK_URLS
+//
+//	FX_RATES_FILE → JSON rate document ({"base", "date", "rates"}); takes precedence over FX_RATES_URL
+//	FX_RATES_URL  → HTTP endpoint returning the same document
+//	FX_TIMEOUT    → Go duration, default "5s"
+//	FX_CACHE_TTL  → Go duration, default "1h"
+//	FX_MAX_STALE  → how long cached rates are served while refreshes fail, default "24h"
+//
+//	RESERVATION_TTL     → Go duration, default "15m" (at most "1h")
+//	LOW_STOCK_THRESHOLD → default 5
+//
+//	SEARCH_CONFIG → PostgreSQL text search configuration, default "english"
 func Load() Config {
 	// Load .env silently; ignore error when file not found.
 	_ = godotenv.Load()
@@ -43,11 +139,92 @@ func Load() Config {
 		ProdGRPCAddr: getEnv("PROD_GRPC_ADDR", ":50051"),
 		DBURL:        mustGetEnv("DATABASE_URL"),
 		SymmetricKey: mustGetEnv("SYMMETRIC_KEY"),
+		TLS: tlsconfig.Options{
+			CertFile:          getEnv("TLS_CERT_FILE", ""),
+			KeyFile:           getEnv("TLS_KEY_FILE", ""),
+			ClientCAFile:      getEnv("TLS_CLIENT_CA_FILE", ""),
+			RequireClientCert: getEnvBool("TLS_REQUIRE_CLIENT_CERT", false),
+			AllowedSANs:       getEnvList("TLS_ALLOWED_SANS"),
+			ServiceScopes:     getEnvScopes("TLS_SERVICE_SCOPES"),
+			CAFile:            getEnv("TLS_CA_FILE", ""),
+		},
+		AuthService:    getEnv("AUTH_SERVICE_ADDR", "localhost:50052"),
+		ProductService: getEnv("PRODUCT_SERVICE_ADDR", "localhost:50051"),
+		CookieSession:  getEnvBool("COOKIE_SESSION_MODE", false),
+		CookieDomain:   getEnv("COOKIE_DOMAIN", ""),
+		CookieSecure:   getEnvBool("COOKIE_SECURE", true),
+		CookieSameSite: getEnv("COOKIE_SAMESITE", "lax"),
+
+		AccountDeletionGrace: getEnvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+		AccountRetention:     getEnvDuration("ACCOUNT_RETENTION", 90*24*time.Hour),
+
+		Images: imageproc.Options{
+			MaxDimension:   getEnvInt("PHOTO_MAX_DIMENSION", imageproc.DefaultOptions.MaxDimension),
+			ThumbnailSizes: getEnvIntList("THUMBNAIL_SIZES", imageproc.DefaultOptions.ThumbnailSizes),
+			JPEGQuality:    getEnvInt("PHOTO_JPEG_QUALITY", imageproc.DefaultOptions.JPEGQuality),
+		},
+		Blob: blob.Options{
+			Backend:    getEnv("BLOB_BACKEND", "local"),
+			LocalDir:   getEnv("BLOB_LOCAL_DIR", "./data/blobs"),
+			URLPrefix:  getEnv("BLOB_URL_PREFIX", "/media"),
+			URLTTL:     getEnvDuration("BLOB_URL_TTL", time.Hour),
+			SigningKey: getEnv("BLOB_SIGNING_KEY", ""),
+			S3: blob.S3Options{
+				Endpoint:  getEnv("S3_ENDPOINT", ""),
+				Region:    getEnv("S3_REGION", "us-east-1"),
+				Bucket:    getEnv("S3_BUCKET", ""),
+				AccessKey: getEnv("S3_ACCESS_KEY", ""),
+				SecretKey: getEnv("S3_SECRET_KEY", ""),
+				PathStyle: getEnvBool("S3_PATH_STYLE", false),
+			},
+		},
+
+		PhotoUpload: upload.Policy{
+			MaxBytes:     int64(getEnvInt("PHOTO_MAX_BYTES", int(upload.DefaultImagePolicy.MaxBytes))),
+			AllowedTypes: getEnvListDefault("PHOTO_ALLOWED_TYPES", upload.DefaultImagePolicy.AllowedTypes),
+		},
+		ImageUpload: upload.Policy{
+			MaxBytes:     int64(getEnvInt("PRODUCT_IMAGE_MAX_BYTES", int(upload.DefaultImagePolicy.MaxBytes))),
+			AllowedTypes: getEnvListDefault("PRODUCT_IMAGE_ALLOWED_TYPES", upload.DefaultImagePolicy.AllowedTypes),
+		},
+		ClamAVAddr:    getEnv("CLAMAV_ADDR", ""),
+		ClamAVTimeout: getEnvDuration("CLAMAV_TIMEOUT", 30*time.Second),
+
+		UploadSessionTTL: getEnvDuration("UPLOAD_SESSION_TTL", 24*time.Hour),
+
+		Mail: mail.Options{
+			Addr:     getEnv("SMTP_ADDR", ""),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("MAIL_FROM", "no-reply@marketplace.local"),
+		},
+		EmailConfirmURL: getEnv("EMAIL_CONFIRM_URL", "http://localhost:5173/confirm-email"),
+		EmailChangeTTL:  getEnvDuration("EMAIL_CHANGE_TTL", 24*time.Hour),
+
+		EventWebhookURLs:   getEnvList("EVENTS_WEBHOOK_URLS"),
+		EventWebhookSecret: getEnv("EVENTS_WEBHOOK_SECRET", ""),
+
+		FX: fx.Options{
+			File:     getEnv("FX_RATES_FILE", ""),
+			URL:      getEnv("FX_RATES_URL", ""),
+			Timeout:  getEnvDuration("FX_TIMEOUT", 5*time.Second),
+			CacheTTL: getEnvDuration("FX_CACHE_TTL", time.Hour),
+			MaxStale: getEnvDuration("FX_MAX_STALE", 24*time.Hour),
+		},
+
+		ReservationTTL: getEnvDuration("RESERVATION_TTL", 15*time.Minute),
+		LowStock:       getEnvInt("LOW_STOCK_THRESHOLD", 5),
+		StockSnapshots: getEnvDuration("STOCK_SNAPSHOT_INTERVAL", time.Hour),
+
+		SearchConfig: getEnv("SEARCH_CONFIG", "english"),
 	}
 
 	if len(cfg.SymmetricKey) != 32 {
 		log.Fatalf("SYMMETRIC_KEY must be exactly 32 characters, got %d", len(cfg.SymmetricKey))
 	}
+	if len(cfg.EventWebhookURLs) > 0 && cfg.EventWebhookSecret == "" {
+		log.Fatalf("EVENTS_WEBHOOK_SECRET is required when EVENTS_WEBHOOK_URLS is set")
+	}
 	return cfg
 }
 
@@ -59,6 +236,83 @@ func getEnv(key, def string) string {
 	return def
 }
 
+// getEnvBool parses a boolean env var, falling back to def when unset or invalid.
+func getEnvBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(getEnv(key, ""))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// getEnvInt parses an integer env var, falling back to def when unset or invalid.
+func getEnvInt(key string, def int) int {
+	n, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// getEnvIntList parses a comma-separated list of integers, falling back to
+// def when unset or when any item is invalid.
+func getEnvIntList(key string, def []int) []int {
+	items := getEnvList(key)
+	if len(items) == 0 {
+		return def
+	}
+	out := make([]int, 0, len(items))
+	for _, item := range items {
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			return def
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// getEnvDuration parses a Go duration env var, falling back to def when unset or invalid.
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// getEnvList splits a comma-separated env var, dropping empty items.
+func getEnvList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// getEnvScopes parses "key=scope scope,key2=scope" into a map.
+func getEnvScopes(key string) map[string][]string {
+	out := map[string][]string{}
+	for _, item := range getEnvList(key) {
+		name, scopes, ok := strings.Cut(item, "=")
+		if !ok {
+			log.Fatalf("%s: %q is not of the form SAN=scopes", key, item)
+		}
+		out[strings.TrimSpace(name)] = strings.Fields(scopes)
+	}
+	return out
+}
+
+// getEnvListDefault is getEnvList falling back to def when the list is empty.
+func getEnvListDefault(key string, def []string) []string {
+	if out := getEnvList(key); len(out) > 0 {
+		return out
+	}
+	return def
+}
+
 // mustGetEnv fetches an env var or terminates the program if missing.
 func mustGetEnv(key string) string {
 	if v := os.Getenv(key); v != "" {
@@ -0,0 +1,45 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_PublishSigned(t *testing.T) {
+	secret := "s3cret"
+	var got events.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, events.Sign([]byte(secret), body), r.Header.Get(events.SignatureHeader))
+		assert.NoError(t, json.Unmarshal(body, &got))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	e, err := events.New("auth-service", "user.email_changed", "42", map[string]string{"email": "new@abc.com"})
+	require.NoError(t, err)
+	require.NoError(t, events.NewWebhook([]string{srv.URL}, secret, time.Second).Publish(context.Background(), e))
+	assert.Equal(t, e.ID, got.ID)
+	assert.Equal(t, "user.email_changed", got.Type)
+	assert.JSONEq(t, `{"email":"new@abc.com"}`, string(got.Data))
+}
+
+func TestWebhook_FailsOnErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	e, err := events.New("auth-service", "user.email_changed", "42", nil)
+	require.NoError(t, err)
+	assert.Error(t, events.NewWebhook([]string{srv.URL}, "s3cret", time.Second).Publish(context.Background(), e))
+}
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// relayBatch is the number of events delivered per Flush call.
+const relayBatch = 100
+
+// relayLease is how long a relay may take to publish a claimed batch;
+// afterwards another relay may claim the events again.
+const relayLease = 2 * time.Minute
+
+// OutboxEvent is an event waiting in the outbox_events table.
+type OutboxEvent struct {
+	ID          uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	Type        string          `gorm:"not null"`
+	Source      string          `gorm:"not null"`
+	Subject     string          `gorm:"not null"`
+	Payload     json.RawMessage `gorm:"type:jsonb;not null"`
+	CreatedAt   time.Time       `gorm:"not null"`
+	PublishedAt *time.Time      // nil until delivered
+	LockedUntil *time.Time      // claimed by a relay until then
+}
+
+func (OutboxEvent) TableName() string { return "outbox_events" }
+
+// Add stores e in the outbox. Call it with the transaction that makes the
+// change e describes.
+func Add(tx *gorm.DB, e Event) error {
+	return tx.Create(&OutboxEvent{
+		ID:        e.ID,
+		Type:      e.Type,
+		Source:    e.Source,
+		Subject:   e.Subject,
+		Payload:   e.Data,
+		CreatedAt: e.Time,
+	}).Error
+}
+
+// Relay delivers the outbox events of one source in creation order.
+type Relay struct {
+	db     *gorm.DB
+	source string
+	pub    Publisher
+}
+
+// NewRelay returns a relay publishing the events of source with pub.
+func NewRelay(db *gorm.DB, source string, pub Publisher) *Relay {
+	return &Relay{db: db, source: source, pub: pub}
+}
+
+// Flush publishes up to one batch of pending events and returns how many
+// were delivered. It stops at the first failure so that order is kept; the
+// failed event is retried on the next call. The batch is claimed with a
+// lease in a short transaction and published outside of it, so no rows
+// stay locked while consumers answer. Only one relay of a source holds a
+// lease at a time, so several replicas can run the relay.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	until := time.Now().Add(relayLease)
+	pending, err := r.claim(ctx, until)
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+	// stop before the lease runs out and another relay claims the batch
+	pubCtx, cancel := context.WithDeadline(ctx, until)
+	defer cancel()
+	var done, failed []uuid.UUID
+	var pubErr error
+	for _, o := range pending {
+		if pubErr == nil {
+			e := Event{ID: o.ID, Type: o.Type, Source: o.Source, Subject: o.Subject, Time: o.CreatedAt, Data: o.Payload}
+			pubErr = r.pub.Publish(pubCtx, e)
+		}
+		if pubErr == nil {
+			done = append(done, o.ID)
+		} else {
+			failed = append(failed, o.ID)
+		}
+	}
+
+	// Record what was delivered even when the caller is cancelled meanwhile,
+	// and give the rest back for the next call.
+	db := r.db.WithContext(context.WithoutCancel(ctx))
+	if len(done) > 0 {
+		if err := db.Model(&OutboxEvent{}).Where("id IN ?", done).
+			Updates(map[string]any{"published_at": time.Now(), "locked_until": nil}).Error; err != nil {
+			return 0, err
+		}
+	}
+	if len(failed) > 0 {
+		if err := db.Model(&OutboxEvent{}).Where("id IN ?", failed).Update("locked_until", nil).Error; err != nil {
+			return len(done), err
+		}
+	}
+	return len(done), pubErr
+}
+
+// claim leases the oldest pending events of the source until the given
+// time. It claims nothing while another relay holds an unexpired lease,
+// since publishing later events first would break their order.
+func (r *Relay) claim(ctx context.Context, until time.Time) ([]OutboxEvent, error) {
+	var pending []OutboxEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// serialises the claims of a source until commit
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "outbox_events:"+r.source).Error; err != nil {
+			return err
+		}
+		var leased int64
+		if err := tx.Model(&OutboxEvent{}).
+			Where("source = ? AND published_at IS NULL AND locked_until > ?", r.source, time.Now()).
+			Count(&leased).Error; err != nil || leased > 0 {
+			return err
+		}
+		if err := tx.Where("source = ? AND published_at IS NULL", r.source).
+			Order("created_at, id").Limit(relayBatch).
+			Find(&pending).Error; err != nil || len(pending) == 0 {
+			return err
+		}
+		ids := make([]uuid.UUID, len(pending))
+		for i, o := range pending {
+			ids[i] = o.ID
+		}
+		return tx.Model(&OutboxEvent{}).Where("id IN ?", ids).Update("locked_until", until).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
@@ -0,0 +1,92 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/events"
+)
+
+// failingAfter publishes n events and fails afterwards.
+type failingAfter struct {
+	n   int
+	got []uuid.UUID
+}
+
+func (p *failingAfter) Publish(_ context.Context, e events.Event) error {
+	if len(p.got) == p.n {
+		return errors.New("consumer down")
+	}
+	p.got = append(p.got, e.ID)
+	return nil
+}
+
+func TestRelay_PublishesOutsideTheClaim(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	require.NoError(t, err)
+
+	first, second := uuid.New(), uuid.New()
+	rows := sqlmock.NewRows([]string{"id", "type", "source", "subject", "payload", "created_at"}).
+		AddRow(first, "user.email_changed", "auth-service", "1", []byte(`{}`), time.Now()).
+		AddRow(second, "user.email_changed", "auth-service", "2", []byte(`{}`), time.Now())
+
+	// the batch is claimed and committed before anything is published
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "outbox_events" WHERE .*locked_until >`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT \* FROM "outbox_events" WHERE source = \$1 AND published_at IS NULL ORDER BY created_at, id LIMIT`).
+		WillReturnRows(rows)
+	mock.ExpectExec(`UPDATE "outbox_events" SET "locked_until"=\$1 WHERE id IN \(\$2,\$3\)`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	// the delivered event is marked, the failed one released for a retry
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "outbox_events" SET "locked_until"=\$1,"published_at"=\$2 WHERE id IN \(\$3\)`).
+		WithArgs(nil, sqlmock.AnyArg(), first).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "outbox_events" SET "locked_until"=\$1 WHERE id IN \(\$2\)`).
+		WithArgs(nil, second).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	pub := &failingAfter{n: 1}
+	n, err := events.NewRelay(db, "auth-service", pub).Flush(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []uuid.UUID{first}, pub.got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelay_WaitsForAnotherRelaysLease(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "outbox_events"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectCommit()
+
+	pub := &failingAfter{n: 10}
+	n, err := events.NewRelay(db, "auth-service", pub).Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Empty(t, pub.got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
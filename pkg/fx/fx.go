@@ -0,0 +1,170 @@
+// Package fx converts money between currencies with exchange rates from a
+// pluggable provider: a static JSON file or an HTTP endpoint returning the
+// same document. Rates are cached and carry the time they were quoted.
+package fx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+)
+
+// rateDecimals is the precision of the cross rates used for conversions.
+const rateDecimals = 8
+
+var (
+	ErrNoRate      = errors.New("no exchange rate for currency")
+	ErrUnavailable = errors.New("exchange rates unavailable")
+)
+
+// Table holds the rates of every currency against Base: one unit of Base
+// buys Rates[c] units of c.
+type Table struct {
+	Base  string
+	AsOf  time.Time // when the provider quoted the rates
+	Rates map[string]*big.Rat
+}
+
+// Provider returns the current rate table.
+type Provider interface {
+	Rates(ctx context.Context) (*Table, error)
+}
+
+// Rate is the rate used for one conversion: one unit of From buys Value
+// units of To.
+type Rate struct {
+	From  string
+	To    string
+	Value string // decimal, at most 8 fractional digits
+	AsOf  time.Time
+}
+
+// Converter converts money with the rates of a provider.
+type Converter struct {
+	rates Provider
+}
+
+// NewConverter returns a converter using p, usually a *Cache.
+func NewConverter(p Provider) *Converter {
+	return &Converter{rates: p}
+}
+
+// Convert returns m in currency to, rounded half away from zero to the minor
+// unit of to, together with the rate used.
+func (c *Converter) Convert(ctx context.Context, m money.Money, to string) (money.Money, Rate, error) {
+	if !money.ValidCurrency(to) {
+		return money.Money{}, Rate{}, fmt.Errorf("%w: %w: %q", ErrNoRate, money.ErrInvalidCurrency, to)
+	}
+	if m.Currency == to {
+		return m, Rate{From: to, To: to, Value: "1"}, nil
+	}
+	t, err := c.rates.Rates(ctx)
+	if err != nil {
+		return money.Money{}, Rate{}, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	from, ok := t.rate(m.Currency)
+	if !ok {
+		return money.Money{}, Rate{}, fmt.Errorf("%w %s", ErrNoRate, m.Currency)
+	}
+	into, ok := t.rate(to)
+	if !ok {
+		return money.Money{}, Rate{}, fmt.Errorf("%w %s", ErrNoRate, to)
+	}
+	text := new(big.Rat).Quo(into, from).FloatString(rateDecimals)
+	text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	rate, _ := new(big.Rat).SetString(text)
+
+	// amount · rate · 10^(exp(to) − exp(from)), in minor units
+	d := money.Exponent(to) - money.Exponent(m.Currency)
+	v := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+	shift := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(d))), nil))
+	if d >= 0 {
+		v.Mul(v, shift)
+	} else {
+		v.Quo(v, shift)
+	}
+	amount, ok := roundHalfAway(v)
+	if !ok {
+		return money.Money{}, Rate{}, fmt.Errorf("%w: %s in %s is out of range", money.ErrInvalidAmount, m, to)
+	}
+	return money.New(amount, to), Rate{From: m.Currency, To: to, Value: text, AsOf: t.AsOf}, nil
+}
+
+// rate returns the rate of c against the base; the base itself is 1.
+func (t *Table) rate(c string) (*big.Rat, bool) {
+	if c == t.Base {
+		return big.NewRat(1, 1), true
+	}
+	r, ok := t.Rates[c]
+	return r, ok && r.Sign() > 0
+}
+
+func roundHalfAway(v *big.Rat) (int64, bool) {
+	num, den := new(big.Int).Abs(v.Num()), v.Denom()
+	// (2·num + den) / (2·den), truncated
+	q := new(big.Int).Quo(new(big.Int).Add(new(big.Int).Lsh(num, 1), den), new(big.Int).Lsh(den, 1))
+	if v.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q.Int64(), q.IsInt64()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// document is the JSON rate document read by Static and HTTP, the format
+// of common public rate APIs:
+//
+//	{"base": "EUR", "date": "2025-08-29", "rates": {"USD": 1.0842, "RON": 5.0713}}
+//
+// "date" may also be an RFC 3339 timestamp.
+type document struct {
+	Base  string                 `json:"base"`
+	Date  string                 `json:"date"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+// parseTable decodes a rate document; rates are read exactly from their
+// decimal text.
+func parseTable(data []byte) (*Table, error) {
+	var doc document
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("fx: decoding rates: %w", err)
+	}
+	doc.Base = strings.ToUpper(doc.Base)
+	if !money.ValidCurrency(doc.Base) {
+		return nil, fmt.Errorf("fx: invalid base currency %q", doc.Base)
+	}
+	t := &Table{Base: doc.Base, Rates: make(map[string]*big.Rat, len(doc.Rates))}
+	if doc.Date != "" {
+		asOf, err := time.Parse(time.RFC3339, doc.Date)
+		if err != nil {
+			if asOf, err = time.Parse(time.DateOnly, doc.Date); err != nil {
+				return nil, fmt.Errorf("fx: invalid date %q", doc.Date)
+			}
+		}
+		t.AsOf = asOf
+	}
+	for code, n := range doc.Rates {
+		code = strings.ToUpper(code)
+		r, ok := new(big.Rat).SetString(string(n))
+		if !ok || r.Sign() <= 0 || !money.ValidCurrency(code) {
+			return nil, fmt.Errorf("fx: invalid rate %s=%s", code, n)
+		}
+		t.Rates[code] = r
+	}
+	return t, nil
+}
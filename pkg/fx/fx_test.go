@@ -0,0 +1,128 @@
+package fx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/fx"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	ctx := context.Background()
+	conv := fx.NewConverter(fx.NewStatic("testdata/rates.json"))
+
+	got, rate, err := conv.Convert(ctx, money.New(1999, "EUR"), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, money.New(2167, "USD"), got) // 21.673158 → 21.67
+	assert.Equal(t, fx.Rate{From: "EUR", To: "USD", Value: "1.0842",
+		AsOf: time.Date(2025, 8, 29, 0, 0, 0, 0, time.UTC)}, rate)
+
+	// cross rate through the base, into a currency without minor units
+	got, rate, err = conv.Convert(ctx, money.New(1000, "USD"), "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, "148.0538646", rate.Value, "rate is rounded to 8 decimals")
+	assert.Equal(t, money.New(1481, "JPY"), got)
+
+	got, _, err = conv.Convert(ctx, money.New(1500, "JPY"), "KWD")
+	require.NoError(t, err)
+	assert.Equal(t, money.New(3106, "KWD"), got) // 3.10627... KWD
+
+	got, rate, err = conv.Convert(ctx, money.New(1999, "EUR"), "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, money.New(1999, "EUR"), got)
+	assert.Equal(t, "1", rate.Value)
+
+	_, _, err = conv.Convert(ctx, money.New(1999, "EUR"), "GBP")
+	assert.ErrorIs(t, err, fx.ErrNoRate)
+	_, _, err = conv.Convert(ctx, money.New(1999, "EUR"), "XYZ")
+	assert.ErrorIs(t, err, fx.ErrNoRate)
+	_, _, err = fx.NewConverter(fx.NewStatic("testdata/missing.json")).Convert(ctx, money.New(1, "EUR"), "USD")
+	assert.ErrorIs(t, err, fx.ErrUnavailable)
+}
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"base":"usd","date":"2025-08-29T12:00:00Z","rates":{"EUR":0.9223}}`))
+	}))
+	defer srv.Close()
+
+	table, err := fx.NewHTTP(srv.URL, time.Second).Rates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "USD", table.Base)
+	assert.Equal(t, "0.9223", table.Rates["EUR"].FloatString(4))
+	assert.Equal(t, time.Date(2025, 8, 29, 12, 0, 0, 0, time.UTC), table.AsOf)
+}
+
+// flaky fails every call after the first.
+type flaky struct{ calls int }
+
+func (f *flaky) Rates(context.Context) (*fx.Table, error) {
+	f.calls++
+	if f.calls > 1 {
+		return nil, errors.New("down")
+	}
+	return &fx.Table{Base: "EUR"}, nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	p := &flaky{}
+	c := fx.NewCache(p, time.Hour, time.Hour)
+	first, err := c.Rates(ctx)
+	require.NoError(t, err)
+	again, err := c.Rates(ctx)
+	require.NoError(t, err)
+	assert.Same(t, first, again)
+	assert.Equal(t, 1, p.calls)
+
+	expired := fx.NewCache(p, 0, 0)
+	_, err = expired.Rates(ctx)
+	assert.Error(t, err, "nothing cached to fall back to")
+}
+
+// slow blocks every fetch until release is closed.
+type slow struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *slow) Rates(context.Context) (*fx.Table, error) {
+	s.calls.Add(1)
+	<-s.release
+	return &fx.Table{Base: "EUR"}, nil
+}
+
+func TestCache_SharesOneRefresh(t *testing.T) {
+	p := &slow{release: make(chan struct{})}
+	c := fx.NewCache(p, time.Hour, time.Hour)
+
+	// a caller that gives up does not wait for the provider
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.Rates(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			table, err := c.Rates(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "EUR", table.Base)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(p.release)
+	wg.Wait()
+	assert.Equal(t, int32(1), p.calls.Load(), "concurrent callers share the fetch")
+}
@@ -0,0 +1,176 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Static reads rates from a JSON file on every call; wrap it in a Cache.
+// Editing the file changes the rates once the cache expires.
+type Static struct {
+	path string
+}
+
+// NewStatic returns a provider reading the rate document at path.
+func NewStatic(path string) *Static {
+	return &Static{path: path}
+}
+
+func (s *Static) Rates(context.Context) (*Table, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseTable(data)
+	if err != nil {
+		return nil, err
+	}
+	if t.AsOf.IsZero() {
+		if fi, err := os.Stat(s.path); err == nil {
+			t.AsOf = fi.ModTime()
+		}
+	}
+	return t, nil
+}
+
+// HTTP fetches the rate document from a URL, e.g. a public rate API or a
+// local stand-in serving a file.
+type HTTP struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTP returns a provider fetching url with the given timeout.
+func NewHTTP(url string, timeout time.Duration) *HTTP {
+	return &HTTP{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (h *HTTP) Rates(ctx context.Context) (*Table, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fx: %s answered %s", h.url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseTable(data)
+	if err != nil {
+		return nil, err
+	}
+	if t.AsOf.IsZero() {
+		t.AsOf = time.Now()
+	}
+	return t, nil
+}
+
+// refreshBackoff is how long a Cache serves its stale table after a failed
+// refresh before asking the provider again.
+const refreshBackoff = 30 * time.Second
+
+// Cache keeps the last table of a provider for ttl. When a refresh fails
+// it keeps serving the previous table, which still carries its AsOf time,
+// for up to maxStale, and retries at most once per refreshBackoff.
+// Concurrent callers share a single refresh.
+type Cache struct {
+	next     Provider
+	ttl      time.Duration
+	maxStale time.Duration
+	refresh  singleflight.Group
+
+	mu          sync.Mutex
+	table       *Table
+	fetchedAt   time.Time
+	lastFailure time.Time
+	now         func() time.Time
+}
+
+// NewCache wraps p.
+func NewCache(p Provider, ttl, maxStale time.Duration) *Cache {
+	return &Cache{next: p, ttl: ttl, maxStale: maxStale, now: time.Now}
+}
+
+func (c *Cache) Rates(ctx context.Context) (*Table, error) {
+	c.mu.Lock()
+	t, fetchedAt, lastFailure := c.table, c.fetchedAt, c.lastFailure
+	c.mu.Unlock()
+	now := c.now()
+	if t != nil && now.Sub(fetchedAt) < c.ttl {
+		return t, nil
+	}
+	// A provider that just failed is not asked again on every call.
+	if t != nil && now.Sub(lastFailure) < refreshBackoff && now.Sub(fetchedAt) < c.ttl+c.maxStale {
+		return t, nil
+	}
+	// The fetch runs without mu and outlives a caller that gives up, since
+	// other callers may be waiting for it.
+	ch := c.refresh.DoChan("rates", func() (any, error) {
+		return c.fetch(context.WithoutCancel(ctx))
+	})
+	select {
+	case r := <-ch:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.(*Table), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch asks the provider for a new table and caches it, or falls back to
+// the cached table while it is within maxStale.
+func (c *Cache) fetch(ctx context.Context) (*Table, error) {
+	now := c.now()
+	t, err := c.next.Rates(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.lastFailure = now
+		if c.table != nil && now.Sub(c.fetchedAt) < c.ttl+c.maxStale {
+			log.Printf("fx: refreshing rates: %v (serving rates fetched at %s)", err, c.fetchedAt.Format(time.RFC3339))
+			return c.table, nil
+		}
+		return nil, err
+	}
+	c.table, c.fetchedAt = t, now
+	return t, nil
+}
+
+// Options selects and configures the rate provider.
+type Options struct {
+	File     string        // static rate document; takes precedence over URL
+	URL      string        // HTTP endpoint returning a rate document
+	Timeout  time.Duration // HTTP timeout
+	CacheTTL time.Duration
+	MaxStale time.Duration // how long cached rates outlive failed refreshes
+}
+
+// NewProvider returns the cached provider configured by o, or nil when
+// neither a file nor a URL is set.
+func NewProvider(o Options) Provider {
+	switch {
+	case o.File != "":
+		return NewCache(NewStatic(o.File), o.CacheTTL, o.MaxStale)
+	case o.URL != "":
+		return NewCache(NewHTTP(o.URL, o.Timeout), o.CacheTTL, o.MaxStale)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// down serves one table and then fails.
+type down struct{ calls int }
+
+func (d *down) Rates(context.Context) (*Table, error) {
+	d.calls++
+	if d.calls > 1 {
+		return nil, errors.New("down")
+	}
+	return &Table{Base: "EUR"}, nil
+}
+
+func TestCache_BacksOffAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	p := &down{}
+	c := NewCache(p, time.Minute, time.Hour)
+	now := time.Date(2025, 8, 29, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	first, err := c.Rates(ctx)
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	for range 3 {
+		got, err := c.Rates(ctx)
+		require.NoError(t, err)
+		assert.Same(t, first, got)
+	}
+	now = now.Add(refreshBackoff - time.Second)
+	_, err = c.Rates(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.calls, "one provider call per backoff window")
+
+	now = now.Add(time.Second)
+	got, err := c.Rates(ctx)
+	require.NoError(t, err)
+	assert.Same(t, first, got)
+	assert.Equal(t, 3, p.calls)
+}
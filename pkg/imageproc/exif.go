@@ -0,0 +1,105 @@
+package imageproc
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// exifOrientation returns the EXIF orientation (1–8) of a JPEG, or 1 when
+// the file has none.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan / end of image
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		seg := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(seg) >= 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// tiffOrientation reads tag 0x0112 from IFD0 of a TIFF header.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	n := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < n; e++ {
+		off := ifd + 2 + e*12
+		if off+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[off:]) == 0x0112 {
+			if v := int(order.Uint16(tiff[off+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient returns img transformed so that it displays upright for the given
+// EXIF orientation.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror horizontal
+				sx, sy = w-1-dx, dy
+			case 3: // rotate 180
+				sx, sy = w-1-dx, h-1-dy
+			case 4: // mirror vertical
+				sx, sy = dx, h-1-dy
+			case 5: // transpose
+				sx, sy = dy, dx
+			case 6: // rotate 90 CW
+				sx, sy = dy, h-1-dx
+			case 7: // transverse
+				sx, sy = w-1-dy, h-1-dx
+			case 8: // rotate 90 CCW
+				sx, sy = w-1-dy, dx
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,166 @@
+// Package imageproc validates uploaded images and re-encodes them: EXIF
+// orientation is applied, all metadata is dropped, the image is bounded to a
+// maximum size and square thumbnails are produced.
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif" // register the GIF decoder; only the first frame is kept
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooLarge     = errors.New("image dimensions too large")
+)
+
+// maxPixels bounds the decoded size to guard against decompression bombs.
+const maxPixels = 50_000_000
+
+// Options controls Process.
+type Options struct {
+	MaxDimension   int   // longest side of the stored image in pixels, 0 keeps the original size
+	ThumbnailSizes []int // edge lengths of the square thumbnails
+	JPEGQuality    int   // 1–100
+}
+
+// DefaultOptions is used for profile photos unless configured otherwise.
+var DefaultOptions = Options{MaxDimension: 2048, ThumbnailSizes: []int{256}, JPEGQuality: 85}
+
+// Image is one encoded output.
+type Image struct {
+	Data        []byte
+	Ext         string // ".jpg" or ".png"
+	ContentType string
+}
+
+// Result holds the processed image and its thumbnails, keyed by size.
+type Result struct {
+	Image      Image
+	Thumbnails map[int]Image
+}
+
+// allowed maps sniffed content types to whether the format can carry
+// transparency (and is therefore re-encoded as PNG).
+var allowed = map[string]bool{
+	"image/jpeg": false,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": false,
+}
+
+// DetectContentType returns the content type of data based on its magic
+// bytes, and whether it is an accepted image format.
+func DetectContentType(data []byte) (string, bool) {
+	ct := http.DetectContentType(data)
+	_, ok := allowed[ct]
+	return ct, ok
+}
+
+// Process decodes data, whatever its claimed file name, and returns the
+// re-encoded image and thumbnails. Re-encoding drops EXIF and every other
+// metadata block.
+func Process(data []byte, o Options) (*Result, error) {
+	ct, ok := DetectContentType(data)
+	if !ok {
+		return nil, ErrUnsupportedFormat
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	img := fit(src, o.MaxDimension)
+	if ct == "image/jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+
+	encode := func(m image.Image) (Image, error) { return encodeJPEG(m, o.JPEGQuality) }
+	if allowed[ct] {
+		encode = encodePNG
+	}
+	res := &Result{Thumbnails: make(map[int]Image, len(o.ThumbnailSizes))}
+	if res.Image, err = encode(img); err != nil {
+		return nil, err
+	}
+	for _, size := range o.ThumbnailSizes {
+		if size <= 0 {
+			continue
+		}
+		if res.Thumbnails[size], err = encode(thumbnail(img, size)); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// fit scales img down so its longest side is at most max.
+func fit(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if max <= 0 || (w <= max && h <= max) {
+		return img
+	}
+	if w >= h {
+		w, h = max, h*max/w
+	} else {
+		w, h = w*max/h, max
+	}
+	return scale(img, b, max1(w), max1(h))
+}
+
+// thumbnail crops the centre square of img and scales it to size×size; it
+// never upscales.
+func thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	return scale(img, image.Rect(x0, y0, x0+side, y0+side), min(size, side), min(size, side))
+}
+
+func scale(img image.Image, src image.Rectangle, w, h int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst
+}
+
+func encodeJPEG(img image.Image, quality int) (Image, error) {
+	if quality <= 0 || quality > 100 {
+		quality = jpeg.DefaultQuality
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return Image{}, err
+	}
+	return Image{Data: buf.Bytes(), Ext: ".jpg", ContentType: "image/jpeg"}, nil
+}
+
+func encodePNG(img image.Image) (Image, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return Image{}, err
+	}
+	return Image{Data: buf.Bytes(), Ext: ".png", ContentType: "image/png"}, nil
+}
+
+func max1(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
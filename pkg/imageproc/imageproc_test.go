@@ -0,0 +1,50 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withOrientation inserts an APP1 EXIF segment carrying the orientation tag
+// right after the SOI marker of a JPEG.
+func withOrientation(jpg []byte, orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")             // little endian, IFD0 at offset 8
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)      // one entry
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112) // orientation
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)      // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)      // count
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0) // value padding + next IFD
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(seg)+2))
+	app1 = append(app1, seg...)
+	return append(append(append([]byte{}, jpg[:2]...), app1...), jpg[2:]...)
+}
+
+func TestProcess_AppliesOrientationAndStripsEXIF(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil))
+	data := withOrientation(buf.Bytes(), 6)
+	assert.Equal(t, 6, exifOrientation(data))
+
+	res, err := Process(data, Options{ThumbnailSizes: []int{8}})
+	assert.NoError(t, err)
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(res.Image.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, 20, cfg.Width) // rotated 90°
+	assert.Equal(t, 40, cfg.Height)
+	assert.Equal(t, 1, exifOrientation(res.Image.Data))
+	assert.NotContains(t, string(res.Image.Data), "Exif")
+	assert.Equal(t, ".jpg", res.Thumbnails[8].Ext)
+}
+
+func TestProcess_RejectsNonImages(t *testing.T) {
+	_, err := Process([]byte("<html><script>alert(1)</script>"), DefaultOptions)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
@@ -0,0 +1,97 @@
+// Package mail sends transactional email: plain text messages over SMTP, or
+// to the log when no server is configured.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// Secrets are values in Body, such as confirmation tokens, that must
+	// not appear in logs.
+	Secrets []string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Options configures New.
+type Options struct {
+	Addr     string // SMTP server "host:port"; empty logs messages instead
+	Username string // PLAIN auth, skipped when empty
+	Password string
+	From     string
+}
+
+// New returns an SMTP sender, or a Log sender when o.Addr is empty.
+func New(o Options) Sender {
+	if o.Addr == "" {
+		return Log{}
+	}
+	return &SMTP{opts: o}
+}
+
+// Log writes messages to the standard logger with their secrets redacted;
+// meant for development.
+type Log struct{}
+
+func (Log) Send(_ context.Context, m Message) error {
+	body := m.Body
+	for _, secret := range m.Secrets {
+		if secret != "" {
+			body = strings.ReplaceAll(body, secret, "[REDACTED]")
+		}
+	}
+	log.Printf("mail to %s: %s\n%s", m.To, m.Subject, body)
+	return nil
+}
+
+// SMTP sends messages through an SMTP server, using STARTTLS when the
+// server offers it.
+type SMTP struct {
+	opts Options
+}
+
+func (s *SMTP) Send(ctx context.Context, m Message) error {
+	if strings.ContainsAny(m.To+m.Subject, "\r\n") {
+		return fmt.Errorf("mail: invalid header value")
+	}
+	host, _, err := net.SplitHostPort(s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if s.opts.Username != "" {
+		auth = smtp.PlainAuth("", s.opts.Username, s.opts.Password, host)
+	}
+	msg := "From: " + s.opts.From + "\r\n" +
+		"To: " + m.To + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject) + "\r\n" +
+		"Date: " + time.Now().Format(time.RFC1123Z) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + strings.ReplaceAll(m.Body, "\n", "\r\n")
+
+	// net/smtp has no context support; run it aside so ctx can end the wait.
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(s.opts.Addr, auth, s.opts.From, []string{m.To}, []byte(msg)) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
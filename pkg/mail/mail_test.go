@@ -0,0 +1,29 @@
+package mail_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/mail"
+)
+
+func TestLog_RedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	require.NoError(t, mail.Log{}.Send(context.Background(), mail.Message{
+		To:      "ana@abc.com",
+		Subject: "Confirm",
+		Body:    "open https://shop.test/confirm?token=s3cr3t-t0k3n",
+		Secrets: []string{"s3cr3t-t0k3n"},
+	}))
+	assert.Contains(t, buf.String(), "ana@abc.com")
+	assert.Contains(t, buf.String(), "token=[REDACTED]")
+	assert.NotContains(t, buf.String(), "s3cr3t-t0k3n")
+}
@@ -0,0 +1,39 @@
+package money
+
+import "strings"
+
+// exponents maps the active ISO 4217 currency codes to the number of
+// digits of their minor unit. Funds and precious metals are left out.
+var exponents = func() map[string]int {
+	m := map[string]int{}
+	add := func(exp int, codes string) {
+		for _, c := range strings.Fields(codes) {
+			m[c] = exp
+		}
+	}
+	add(0, "BIF CLP DJF GNF ISK JPY KMF KRW PYG RWF UGX UYI VND VUV XAF XOF XPF")
+	add(2, "AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB BRL BSD BTN BWP BYN BZD "+
+		"CAD CDF CHF CNY COP CRC CUP CVE CZK DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GTQ GYD "+
+		"HKD HNL HTG HUF IDR ILS INR IRR JMD KES KGS KHR KPW KYD KZT LAK LBP LKR LRD LSL MAD MDL MGA MKD "+
+		"MMK MNT MOP MRU MUR MVR MWK MXN MYR MZN NAD NGN NIO NOK NPR NZD PAB PEN PGK PHP PKR PLN QAR RON "+
+		"RSD RUB SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TOP TRY TTD TWD "+
+		"TZS UAH USD UYU UZS VES WST XCD YER ZAR ZMW ZWL")
+	add(3, "BHD IQD JOD KWD LYD OMR TND")
+	add(4, "CLF UYW")
+	return m
+}()
+
+// ValidCurrency reports whether code is an active ISO 4217 currency code.
+func ValidCurrency(code string) bool {
+	_, ok := exponents[code]
+	return ok
+}
+
+// Exponent returns the number of minor unit digits of the currency (2 for
+// EUR, 0 for JPY, 3 for KWD), or -1 for an unknown code.
+func Exponent(code string) int {
+	if e, ok := exponents[code]; ok {
+		return e
+	}
+	return -1
+}
@@ -0,0 +1,177 @@
+// Package money represents amounts exactly, as an integer number of minor
+// units (cents) of an ISO 4217 currency. Floating point is never used, so
+// sums and conversions to and from decimal text do not drift.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidCurrency = errors.New("unknown ISO 4217 currency code")
+	ErrInvalidAmount   = errors.New("invalid amount")
+)
+
+// Money is an amount in a currency. The zero value has no currency and is
+// not valid.
+type Money struct {
+	Amount   int64  `gorm:"not null"`              // minor units, e.g. 1999 for 19.99 EUR
+	Currency string `gorm:"type:char(3);not null"` // ISO 4217 code
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Validate checks the currency code.
+func (m Money) Validate() error {
+	if !ValidCurrency(m.Currency) {
+		return fmt.Errorf("%w: %q", ErrInvalidCurrency, m.Currency)
+	}
+	return nil
+}
+
+// Parse reads a decimal amount such as "19.99" or "-5" in currency. An
+// amount with more fractional digits than the currency has is rejected,
+// never rounded.
+func Parse(amount, currency string) (Money, error) {
+	exp := Exponent(currency)
+	if exp < 0 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+	s := strings.TrimSpace(amount)
+	neg := strings.HasPrefix(s, "-")
+	if neg || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || len(frac) > exp || !digits(whole) || !digits(frac) {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals or is not a number", ErrInvalidAmount, amount, exp)
+	}
+	n, err := strconv.ParseInt(whole+frac+strings.Repeat("0", exp-len(frac)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, amount)
+	}
+	if neg {
+		n = -n
+	}
+	return Money{Amount: n, Currency: currency}, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decimal returns the amount as decimal text with the currency's number of
+// fractional digits, e.g. "19.99" or "-0.05".
+func (m Money) Decimal() string {
+	exp := max(Exponent(m.Currency), 0)
+	s := strconv.FormatUint(abs(m.Amount), 10)
+	if len(s) <= exp {
+		s = strings.Repeat("0", exp-len(s)+1) + s
+	}
+	if exp > 0 {
+		s = s[:len(s)-exp] + "." + s[len(s)-exp:]
+	}
+	if m.Amount < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// String returns e.g. "19.99 EUR".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// UnitsNanos splits the amount into whole units and nano units, as in
+// google.type.Money. Both have the sign of the amount.
+func (m Money) UnitsNanos() (units int64, nanos int32) {
+	exp := max(Exponent(m.Currency), 0)
+	p := pow10(exp)
+	return m.Amount / p, int32(m.Amount % p * pow10(9-exp))
+}
+
+// FromUnitsNanos builds a Money from the google.type.Money representation.
+// Nanos finer than the currency's minor unit are rejected.
+func FromUnitsNanos(currency string, units int64, nanos int32) (Money, error) {
+	exp := Exponent(currency)
+	if exp < 0 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+	if nanos <= -1e9 || nanos >= 1e9 || (units > 0 && nanos < 0) || (units < 0 && nanos > 0) {
+		return Money{}, fmt.Errorf("%w: nanos out of range", ErrInvalidAmount)
+	}
+	step := pow10(9 - exp)
+	if int64(nanos)%step != 0 {
+		return Money{}, fmt.Errorf("%w: %s has %d decimals", ErrInvalidAmount, currency, exp)
+	}
+	p := pow10(exp)
+	if units > math.MaxInt64/p || units < math.MinInt64/p {
+		return Money{}, fmt.Errorf("%w: out of range", ErrInvalidAmount)
+	}
+	return Money{Amount: units*p + int64(nanos)/step, Currency: currency}, nil
+}
+
+// jsonMoney is the JSON form: the amount is decimal text so that clients
+// do not need floating point either.
+type jsonMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes {"amount": "19.99", "currency": "EUR"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the amount as a decimal string or a JSON number; its
+// text is parsed exactly.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Amount   json.RawMessage `json:"amount"`
+		Currency string          `json:"currency"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	amount := string(raw.Amount)
+	if s, err := strconv.Unquote(amount); err == nil {
+		amount = s
+	}
+	v, err := Parse(amount, strings.ToUpper(raw.Currency))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for range n {
+		p *= 10
+	}
+	return p
+}
+
+func abs(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
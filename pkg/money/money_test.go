@@ -0,0 +1,68 @@
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAndDecimal(t *testing.T) {
+	cases := []struct {
+		in, currency string
+		amount       int64
+		out          string
+	}{
+		{"19.99", "EUR", 1999, "19.99"},
+		{"19.9", "EUR", 1990, "19.90"},
+		{"5", "USD", 500, "5.00"},
+		{"-0.05", "USD", -5, "-0.05"},
+		{" 1500 ", "JPY", 1500, "1500"},
+		{"1.234", "KWD", 1234, "1.234"},
+	}
+	for _, c := range cases {
+		m, err := money.Parse(c.in, c.currency)
+		require.NoError(t, err, c.in)
+		assert.Equal(t, c.amount, m.Amount, c.in)
+		assert.Equal(t, c.out, m.Decimal(), c.in)
+	}
+
+	for _, bad := range []string{"", "1.999", "1e3", "--1", "abc", "1,50", ".5", "99999999999999999999"} {
+		_, err := money.Parse(bad, "EUR")
+		assert.ErrorIs(t, err, money.ErrInvalidAmount, bad)
+	}
+	_, err := money.Parse("1.5", "JPY")
+	assert.ErrorIs(t, err, money.ErrInvalidAmount, "yen has no minor unit")
+	_, err = money.Parse("1", "XYZ")
+	assert.ErrorIs(t, err, money.ErrInvalidCurrency)
+}
+
+func TestUnitsNanos(t *testing.T) {
+	m := money.New(-1999, "EUR")
+	units, nanos := m.UnitsNanos()
+	assert.Equal(t, int64(-19), units)
+	assert.Equal(t, int32(-990_000_000), nanos)
+
+	back, err := money.FromUnitsNanos("EUR", units, nanos)
+	require.NoError(t, err)
+	assert.Equal(t, m, back)
+
+	_, err = money.FromUnitsNanos("EUR", 1, 5_000_000) // 1.005 EUR
+	assert.ErrorIs(t, err, money.ErrInvalidAmount)
+	_, err = money.FromUnitsNanos("EUR", 1, -10_000_000)
+	assert.ErrorIs(t, err, money.ErrInvalidAmount)
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(money.New(1999, "EUR"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"19.99","currency":"EUR"}`, string(data))
+
+	var m money.Money
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":0.1,"currency":"usd"}`), &m))
+	assert.Equal(t, money.New(10, "USD"), m, "numbers are parsed from their text, not as floats")
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"0.001","currency":"USD"}`), &m))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"1","currency":"USD","cents":1}`), &m))
+}
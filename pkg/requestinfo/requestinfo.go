@@ -0,0 +1,28 @@
+// Package requestinfo carries the client side of the current request (ID,
+// IP, user agent) through context.Context, so that services can record it
+// without depending on the HTTP or gRPC layer that fills it in.
+package requestinfo
+
+import "context"
+
+// CtxKey is the context key for storing Info, used like token.CtxKey so it is
+// readable from both gin.Context and context.Context.
+const CtxKey = "request_info"
+
+// Info describes the client side of the current request.
+type Info struct {
+	ID        string
+	IP        string
+	UserAgent string
+}
+
+// NewContext returns a copy of ctx carrying info.
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, CtxKey, info)
+}
+
+// FromContext returns the Info stored in ctx, or a zero value.
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(CtxKey).(Info)
+	return info
+}
@@ -0,0 +1,118 @@
+package tlsconfig
+
+// Package tlsconfig builds the TLS / mutual-TLS settings shared by the HTTP
+// and gRPC listeners and by calls to other services, and extracts the peer
+// identity of verified clients.
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Options describes the TLS material of a server. Leaving CertFile empty
+// disables TLS entirely (plaintext, for local development).
+type Options struct {
+	CertFile          string              // PEM server certificate
+	KeyFile           string              // PEM private key
+	ClientCAFile      string              // PEM bundle used to verify client certificates (enables mTLS)
+	RequireClientCert bool                // reject handshakes without a client certificate
+	AllowedSANs       []string            // DNS / URI SANs accepted as service principals
+	ServiceScopes     map[string][]string // scopes granted to each allowed SAN
+	CAFile            string              // PEM bundle verifying the servers this process calls; system roots when empty
+}
+
+// Enabled reports whether TLS is configured.
+func (o Options) Enabled() bool {
+	return o.CertFile != ""
+}
+
+// ServerConfig returns the *tls.Config for o, or nil when TLS is disabled.
+// Without ClientCAFile clients are not asked for a certificate; with it,
+// certificates are verified when presented (or always, if RequireClientCert).
+func ServerConfig(o Options) (*tls.Config, error) {
+	if !o.Enabled() {
+		return nil, nil
+	}
+	if o.KeyFile == "" {
+		return nil, errors.New("tls: key file is required when a certificate is set")
+	}
+	cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: load key pair: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if o.ClientCAFile != "" {
+		pem, err := os.ReadFile(o.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("tls: no certificates found in client CA file")
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if o.RequireClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if o.RequireClientCert {
+		return nil, errors.New("tls: client CA file is required to verify client certificates")
+	}
+	return cfg, nil
+}
+
+// ClientConfig returns the *tls.Config for calls to other services, or nil
+// when TLS is disabled. The server certificate doubles as the client
+// certificate, so that the callee can authenticate this service by its SAN.
+func ClientConfig(o Options) (*tls.Config, error) {
+	if !o.Enabled() {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: load key pair: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: read CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("tls: no certificates found in CA file")
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// PeerIdentity returns the first SAN of the verified client certificate in
+// state that appears in allowed. Unverified certificates never match.
+func PeerIdentity(state *tls.ConnectionState, allowed []string) (string, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	leaf := state.VerifiedChains[0][0]
+	sans := append([]string{}, leaf.DNSNames...)
+	for _, u := range leaf.URIs {
+		sans = append(sans, u.String())
+	}
+	for _, san := range sans {
+		for _, a := range allowed {
+			if san == a {
+				return san, true
+			}
+		}
+	}
+	return "", false
+}
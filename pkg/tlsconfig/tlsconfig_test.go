@@ -0,0 +1,178 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pki writes a CA and a leaf certificate signed by it into a temp dir.
+type pki struct {
+	caFile, certFile, keyFile string
+}
+
+func newPKI(t *testing.T, dnsNames []string, uris []string) pki {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTpl, caTpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "service"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     dnsNames,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, u := range uris {
+		parsed, err := url.Parse(u)
+		require.NoError(t, err)
+		tpl.URIs = append(tpl.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	p := pki{
+		caFile:   filepath.Join(dir, "ca.pem"),
+		certFile: filepath.Join(dir, "cert.pem"),
+		keyFile:  filepath.Join(dir, "key.pem"),
+	}
+	writePEM(t, p.caFile, "CERTIFICATE", caDER)
+	writePEM(t, p.certFile, "CERTIFICATE", der)
+	writePEM(t, p.keyFile, "EC PRIVATE KEY", keyDER)
+	return p
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600))
+}
+
+func TestServerConfig(t *testing.T) {
+	p := newPKI(t, []string{"localhost"}, nil)
+
+	cfg, err := ServerConfig(Options{})
+	assert.NoError(t, err)
+	assert.Nil(t, cfg, "TLS is disabled without a certificate")
+
+	_, err = ServerConfig(Options{CertFile: p.certFile})
+	assert.Error(t, err, "a key is required")
+
+	_, err = ServerConfig(Options{CertFile: p.certFile, KeyFile: p.keyFile, RequireClientCert: true})
+	assert.Error(t, err, "client certificates cannot be verified without a CA")
+
+	cfg, err = ServerConfig(Options{CertFile: p.certFile, KeyFile: p.keyFile})
+	require.NoError(t, err)
+	assert.Equal(t, tls.NoClientCert, cfg.ClientAuth)
+
+	cfg, err = ServerConfig(Options{CertFile: p.certFile, KeyFile: p.keyFile, ClientCAFile: p.caFile})
+	require.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, cfg.ClientAuth)
+
+	cfg, err = ServerConfig(Options{CertFile: p.certFile, KeyFile: p.keyFile, ClientCAFile: p.caFile, RequireClientCert: true})
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+
+	_, err = ServerConfig(Options{CertFile: p.certFile, KeyFile: p.keyFile, ClientCAFile: p.keyFile})
+	assert.Error(t, err, "a CA file without certificates is rejected")
+}
+
+func TestClientConfig(t *testing.T) {
+	p := newPKI(t, []string{"localhost"}, nil)
+
+	cfg, err := ClientConfig(Options{})
+	assert.NoError(t, err)
+	assert.Nil(t, cfg)
+
+	cfg, err = ClientConfig(Options{CertFile: p.certFile, KeyFile: p.keyFile})
+	require.NoError(t, err)
+	assert.Len(t, cfg.Certificates, 1)
+	assert.Nil(t, cfg.RootCAs, "system roots are used without a CA file")
+
+	cfg, err = ClientConfig(Options{CertFile: p.certFile, KeyFile: p.keyFile, CAFile: p.caFile})
+	require.NoError(t, err)
+	assert.NotNil(t, cfg.RootCAs)
+
+	_, err = ClientConfig(Options{CertFile: p.certFile, KeyFile: p.keyFile, CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+}
+
+// handshake connects a client using ClientConfig to a server using
+// ServerConfig and returns the server's view of the connection.
+func handshake(t *testing.T, server, client Options) tls.ConnectionState {
+	t.Helper()
+	scfg, err := ServerConfig(server)
+	require.NoError(t, err)
+	ccfg, err := ClientConfig(client)
+	require.NoError(t, err)
+	ccfg.ServerName = "localhost"
+
+	sc, cc := net.Pipe()
+	defer sc.Close()
+	defer cc.Close()
+	errs := make(chan error, 1)
+	go func() { errs <- tls.Client(cc, ccfg).Handshake() }()
+	srv := tls.Server(sc, scfg)
+	require.NoError(t, srv.Handshake())
+	require.NoError(t, <-errs)
+	return srv.ConnectionState()
+}
+
+func TestPeerIdentity(t *testing.T) {
+	p := newPKI(t, []string{"localhost", "inventory.internal"}, []string{"spiffe://marketplace/order-service"})
+	server := Options{CertFile: p.certFile, KeyFile: p.keyFile, ClientCAFile: p.caFile}
+	client := Options{CertFile: p.certFile, KeyFile: p.keyFile, CAFile: p.caFile}
+
+	state := handshake(t, server, client)
+	san, ok := PeerIdentity(&state, []string{"spiffe://marketplace/order-service"})
+	assert.True(t, ok)
+	assert.Equal(t, "spiffe://marketplace/order-service", san)
+
+	san, ok = PeerIdentity(&state, []string{"inventory.internal"})
+	assert.True(t, ok)
+	assert.Equal(t, "inventory.internal", san)
+
+	_, ok = PeerIdentity(&state, []string{"spiffe://marketplace/other"})
+	assert.False(t, ok, "SANs that are not allowed do not match")
+
+	_, ok = PeerIdentity(nil, []string{"inventory.internal"})
+	assert.False(t, ok)
+
+	unverified := tls.ConnectionState{PeerCertificates: state.PeerCertificates}
+	_, ok = PeerIdentity(&unverified, []string{"inventory.internal"})
+	assert.False(t, ok, "unverified certificates never match")
+}
@@ -22,24 +22,96 @@ var (
 // You can extend it with roles, permissions, etc.
 
 type Payload struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	IssuedAt  time.Time `json:"iat"`
-	ExpiredAt time.Time `json:"exp"`
+	ID             uuid.UUID `json:"id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Role           string    `json:"role,omitempty"`
+	ImpersonatorID string    `json:"impersonator_id,omitempty"` // admin acting as UserID; empty for regular tokens
+	ClientID       string    `json:"client_id,omitempty"`       // set for tokens issued to OAuth2 clients
+	Scopes         []string  `json:"scopes,omitempty"`          // granted scopes (OAuth2 clients, scoped API keys)
+	APIKeyID       string    `json:"api_key_id,omitempty"`      // set when the request authenticated with a personal API key
+	Service        string    `json:"service,omitempty"`         // mTLS peer SAN when the caller is a service principal
+	IssuedAt       time.Time `json:"iat"`
+	ExpiredAt      time.Time `json:"exp"` // zero only for API keys without an expiry
 }
 
+// Roles stored in users.role. RoleAdmin is allowed to use administrative
+// endpoints.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // CtxKey is the context key for storing payloads in context.Context.
 const CtxKey = "payload"
 
-// IsExpired checks if the token is expired.
+// IsExpired checks if the token is expired. A zero ExpiredAt never expires;
+// only payloads of API keys without an expiry have one, as tokens are always
+// created with a lifetime.
 func (p *Payload) IsExpired() bool {
-	return time.Now().After(p.ExpiredAt)
+	return !p.ExpiredAt.IsZero() && time.Now().After(p.ExpiredAt)
+}
+
+// IsAdmin reports whether the token was issued to an administrator.
+func (p *Payload) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+// IsImpersonated reports whether an admin is acting as UserID.
+func (p *Payload) IsImpersonated() bool {
+	return p.ImpersonatorID != ""
+}
+
+// IsThirdParty reports whether the token was issued to an OAuth2 client.
+func (p *Payload) IsThirdParty() bool {
+	return p.ClientID != ""
+}
+
+// IsAPIKey reports whether the payload was resolved from a personal API key.
+func (p *Payload) IsAPIKey() bool {
+	return p.APIKeyID != ""
+}
+
+// IsService reports whether the caller authenticated as a service principal
+// with a client certificate; UserID is uuid.Nil in that case.
+func (p *Payload) IsService() bool {
+	return p.Service != ""
+}
+
+// IsDelegated reports whether the credential acts on the user's behalf
+// (OAuth2 client or API key) rather than being an interactive session.
+func (p *Payload) IsDelegated() bool {
+	return p.IsThirdParty() || p.IsAPIKey()
+}
+
+// IsScoped reports whether the payload is limited to its Scopes: tokens of
+// OAuth2 clients, API keys and service principals.
+func (p *Payload) IsScoped() bool {
+	return p.IsDelegated() || p.IsService()
+}
+
+// HasScope reports whether the payload grants the given scope. First-party
+// tokens (issued by /login or /refresh) and API keys created without scopes
+// carry no scopes and grant everything. OAuth2 clients and service principals
+// only hold the scopes they were granted.
+func (p *Payload) HasScope(scope string) bool {
+	if !p.IsThirdParty() && !p.IsService() && len(p.Scopes) == 0 {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
 
 // Maker defines operations for token generation and verification.
 
 type Maker interface {
 	CreateToken(userID uuid.UUID, duration time.Duration) (string, *Payload, error)
+	// CreateTokenForPayload signs a caller-populated payload. ID, IssuedAt and
+	// ExpiredAt are always overwritten.
+	CreateTokenForPayload(payload *Payload, duration time.Duration) (string, *Payload, error)
 	VerifyToken(token string) (*Payload, error)
 }
 
@@ -63,12 +135,14 @@ func NewPasetoMaker(key string) (*PasetoMaker, error) {
 
 // CreateToken generates a new token for a specific userID and duration.
 func (m *PasetoMaker) CreateToken(userID uuid.UUID, duration time.Duration) (string, *Payload, error) {
-	payload := &Payload{
-		ID:        uuid.New(),
-		UserID:    userID,
-		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(duration),
-	}
+	return m.CreateTokenForPayload(&Payload{UserID: userID}, duration)
+}
+
+// CreateTokenForPayload generates a token carrying the extra claims of payload.
+func (m *PasetoMaker) CreateTokenForPayload(payload *Payload, duration time.Duration) (string, *Payload, error) {
+	payload.ID = uuid.New()
+	payload.IssuedAt = time.Now()
+	payload.ExpiredAt = payload.IssuedAt.Add(duration)
 	token, err := m.paseto.Encrypt(m.symmetricKey, payload, nil)
 	return token, payload, err
 }
@@ -79,6 +153,9 @@ func (m *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 	if err := m.paseto.Decrypt(token, m.symmetricKey, &payload, nil); err != nil {
 		return nil, ErrInvalidToken
 	}
+	if payload.ExpiredAt.IsZero() {
+		return nil, ErrInvalidToken // tokens always carry an expiry
+	}
 	if payload.IsExpired() {
 		return nil, ErrExpiredToken
 	}
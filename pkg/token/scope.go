@@ -0,0 +1,31 @@
+package token
+
+// OAuth2 scopes understood by the marketplace services.
+const (
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+	ScopeProfileRead   = "profile:read"
+)
+
+// ScopeAuthInternal grants the auth-service RPCs other services use to verify
+// API keys and report impersonated requests. Only service principals can hold
+// it; it is not one of KnownScopes.
+const ScopeAuthInternal = "auth:internal"
+
+// ScopeInventoryManage lets a service principal see, commit and release
+// stock reservations made by others, such as a payment service settling an
+// order service's checkout. Like ScopeAuthInternal it is service-only.
+const ScopeInventoryManage = "inventory:manage"
+
+// KnownScopes lists every scope a client may request.
+var KnownScopes = []string{ScopeProductsRead, ScopeProductsWrite, ScopeProfileRead}
+
+// IsKnownScope reports whether s is one of KnownScopes.
+func IsKnownScope(s string) bool {
+	for _, k := range KnownScopes {
+		if k == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamChunk is the size of the chunks sent with INSTREAM; clamd's default
+// StreamMaxLength is far larger than any chunk.
+const clamChunk = 64 << 10
+
+// ClamAV scans uploads with a clamd daemon using the INSTREAM command.
+type ClamAV struct {
+	addr    string // "host:port" or a unix socket path
+	timeout time.Duration
+}
+
+// NewClamAV returns a scanner talking to clamd at addr. Addresses starting
+// with "/" are unix sockets.
+func NewClamAV(addr string, timeout time.Duration) *ClamAV {
+	return &ClamAV{addr: addr, timeout: timeout}
+}
+
+// Scan streams r to clamd. A "FOUND" reply yields an error wrapping
+// ErrInfected with the signature name.
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) error {
+	network := "tcp"
+	if strings.HasPrefix(c.addr, "/") {
+		network = "unix"
+	}
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, network, c.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(c.timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	// "z" selects NUL-terminated commands and replies.
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, clamChunk)
+	size := make([]byte, 4)
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return err
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return err
+	}
+	return parseClamReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamReply interprets "stream: OK", "stream: <name> FOUND" and
+// "<message> ERROR".
+func parseClamReply(reply string) error {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return fmt.Errorf("%w: %s", ErrInfected, strings.TrimSuffix(reply, " FOUND"))
+	default:
+		return fmt.Errorf("clamd: %s", reply)
+	}
+}
@@ -0,0 +1,333 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/logger"
+)
+
+var (
+	ErrNotFound         = errors.New("upload not found or expired")
+	ErrIDTaken          = errors.New("upload ID is already in use")
+	ErrInvalid          = errors.New("invalid upload")
+	ErrMismatch         = errors.New("upload metadata differs from the existing upload")
+	ErrOffset           = errors.New("chunk does not continue the upload")
+	ErrIncomplete       = errors.New("upload is incomplete")
+	ErrChecksumMismatch = errors.New("upload checksum mismatch")
+)
+
+// partSize is the amount of received data buffered before it is stored as
+// one part.
+const partSize = 1 << 20
+
+// Session is a resumable upload in progress. The ID is chosen by the
+// client so it can resume after a dropped connection; the received bytes
+// are kept as Parts in the blob store.
+type Session struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Kind      string    `gorm:"not null"`               // what the upload becomes, e.g. a profile photo
+	Target    string    `gorm:"not null"`               // the resource it is for, e.g. a product ID; empty when implied by the user
+	Size      int64     `gorm:"not null"`               // announced total size
+	SHA256    string    `gorm:"column:sha256;not null"` // announced hex checksum of the whole file
+	Received  int64     `gorm:"not null"`               // bytes stored so far
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ExpiresAt time.Time `gorm:"not null;index"` // unfinished uploads are removed afterwards
+}
+
+func (Session) TableName() string { return "upload_sessions" }
+
+// Part is a contiguous range of an upload stored as one blob.
+type Part struct {
+	UploadID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	StartOffset int64     `gorm:"primaryKey;autoIncrement:false"`
+	Size        int64     `gorm:"not null"`
+	Key         string    `gorm:"not null"` // blob key
+}
+
+func (Part) TableName() string { return "upload_parts" }
+
+// Sessions stores the state of resumable uploads.
+type Sessions interface {
+	// CreateSession fails with ErrIDTaken when the ID is in use.
+	CreateSession(ctx context.Context, s *Session) error
+	// GetSession fails with ErrNotFound.
+	GetSession(ctx context.Context, id uuid.UUID) (*Session, error)
+	// AddPart records a stored part and advances the session's received
+	// counter. It fails with ErrOffset when the part does not start where
+	// the session currently ends.
+	AddPart(ctx context.Context, p *Part) error
+	ListParts(ctx context.Context, uploadID uuid.UUID) ([]Part, error)
+	DeleteSession(ctx context.Context, id uuid.UUID) error
+	ListExpiredSessions(ctx context.Context, kind string, now time.Time, limit int) ([]Session, error)
+}
+
+// purgeBatch is the number of expired uploads removed per PurgeExpired call.
+const purgeBatch = 100
+
+// Resumable receives uploads of one kind in chunks over several streams.
+// Received data is kept in the blob store, so an upload can continue on
+// any replica.
+type Resumable struct {
+	sessions Sessions
+	store    blob.Store
+	kind     string
+	maxBytes int64
+	ttl      time.Duration // how long an unfinished upload can be resumed
+}
+
+// NewResumable returns a Resumable for uploads of the given kind.
+func NewResumable(sessions Sessions, store blob.Store, kind string, maxBytes int64, ttl time.Duration) *Resumable {
+	return &Resumable{sessions: sessions, store: store, kind: kind, maxBytes: maxBytes, ttl: ttl}
+}
+
+// Open starts the upload uploadID or resumes it when the user already
+// started it; target, size and checksum (hex SHA-256) must then match the
+// first call. An ID used by another user or for another kind of upload
+// fails with ErrIDTaken.
+func (u *Resumable) Open(ctx context.Context, userID, uploadID uuid.UUID, target string, size int64, checksum string) (*Upload, error) {
+	checksum = strings.ToLower(checksum)
+	if uploadID == uuid.Nil || size <= 0 || !validSHA256(checksum) {
+		return nil, fmt.Errorf("%w: upload ID, size and SHA-256 are required", ErrInvalid)
+	}
+	if size > u.maxBytes {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, u.maxBytes)
+	}
+	s, err := u.sessions.GetSession(ctx, uploadID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return nil, err
+	case s.UserID != userID || s.Kind != u.kind:
+		return nil, ErrIDTaken
+	case s.ExpiresAt.Before(time.Now()):
+		// the user starts over with an ID whose resume window has passed
+		if err := u.remove(ctx, s); err != nil {
+			return nil, err
+		}
+	case s.Target != target || s.Size != size || s.SHA256 != checksum:
+		return nil, ErrMismatch
+	default:
+		return &Upload{uploads: u, session: s}, nil
+	}
+	s = &Session{
+		ID:        uploadID,
+		UserID:    userID,
+		Kind:      u.kind,
+		Target:    target,
+		Size:      size,
+		SHA256:    checksum,
+		ExpiresAt: time.Now().Add(u.ttl),
+	}
+	if err := u.sessions.CreateSession(ctx, s); err != nil {
+		return nil, err
+	}
+	return &Upload{uploads: u, session: s}, nil
+}
+
+// Status returns the user's unexpired upload so a client can resume it
+// from Received; uploads of other users are reported as not found.
+func (u *Resumable) Status(ctx context.Context, userID, uploadID uuid.UUID) (*Session, error) {
+	s, err := u.sessions.GetSession(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if s.UserID != userID || s.Kind != u.kind || s.ExpiresAt.Before(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return s, nil
+}
+
+// PurgeExpired removes unfinished uploads whose resume window has passed.
+// It returns the number of removed uploads; run it periodically.
+func (u *Resumable) PurgeExpired(ctx context.Context) (int, error) {
+	expired, err := u.sessions.ListExpiredSessions(ctx, u.kind, time.Now(), purgeBatch)
+	if err != nil {
+		return 0, err
+	}
+	for i := range expired {
+		if err := u.remove(ctx, &expired[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(expired), nil
+}
+
+// remove deletes the stored parts and the session.
+func (u *Resumable) remove(ctx context.Context, s *Session) error {
+	parts, err := u.sessions.ListParts(ctx, s.ID)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if err := u.store.Delete(ctx, p.Key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			logger.Error("remove upload part %s: %v", p.Key, err)
+		}
+	}
+	return u.sessions.DeleteSession(ctx, s.ID)
+}
+
+// Upload receives the chunks of one upload stream.
+type Upload struct {
+	uploads *Resumable
+	session *Session
+	buf     []byte // received after session.Received, not stored yet
+}
+
+// Session returns the upload's metadata.
+func (p *Upload) Session() *Session { return p.session }
+
+// Offset returns the number of bytes received so far, including buffered
+// ones; the next chunk must start there.
+func (p *Upload) Offset() int64 { return p.session.Received + int64(len(p.buf)) }
+
+// Write appends a chunk that starts at offset.
+func (p *Upload) Write(ctx context.Context, offset int64, data []byte) error {
+	if offset != p.Offset() {
+		return fmt.Errorf("%w: expected offset %d", ErrOffset, p.Offset())
+	}
+	if p.Offset()+int64(len(data)) > p.session.Size {
+		return fmt.Errorf("%w: data beyond the announced size", ErrInvalid)
+	}
+	p.buf = append(p.buf, data...)
+	if len(p.buf) >= partSize {
+		return p.Flush(ctx)
+	}
+	return nil
+}
+
+// Flush stores the buffered data as a part. Call it when a stream ends,
+// also after an error, so the client can resume from Offset().
+func (p *Upload) Flush(ctx context.Context) error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	// The stream context is cancelled when the client goes away; what was
+	// received must still be saved.
+	ctx = context.WithoutCancel(ctx)
+	part := &Part{
+		UploadID:    p.session.ID,
+		StartOffset: p.session.Received,
+		Size:        int64(len(p.buf)),
+		// unique per attempt: two streams may race for the same offset
+		Key: fmt.Sprintf("uploads/%s/%012d-%s", p.session.ID, p.session.Received, uuid.NewString()),
+	}
+	store := p.uploads.store
+	if err := store.Put(ctx, part.Key, bytes.NewReader(p.buf), part.Size, "application/octet-stream"); err != nil {
+		return err
+	}
+	if err := p.uploads.sessions.AddPart(ctx, part); err != nil {
+		_ = store.Delete(ctx, part.Key)
+		return err
+	}
+	p.session.Received += part.Size
+	p.buf = p.buf[:0]
+	return nil
+}
+
+// Complete passes the assembled upload to process; reading it fails with
+// ErrChecksumMismatch at the end when the data does not match the
+// announced checksum. The upload is discarded when process succeeds or
+// its error is permanent; otherwise (storage or scanner unavailable)
+// Complete can be retried by resuming.
+func (p *Upload) Complete(ctx context.Context, process func(io.Reader) error, permanent func(error) bool) error {
+	if err := p.Flush(ctx); err != nil {
+		return err
+	}
+	if p.session.Received != p.session.Size {
+		return ErrIncomplete
+	}
+	u := p.uploads
+	parts, err := u.sessions.ListParts(ctx, p.session.ID)
+	if err != nil {
+		return err
+	}
+	pr := &partReader{ctx: ctx, store: u.store, parts: parts}
+	defer pr.Close()
+	err = process(&checksumReader{r: pr, h: sha256.New(), want: p.session.SHA256})
+	if err == nil || errors.Is(err, ErrChecksumMismatch) || permanent(err) {
+		if rerr := u.remove(context.WithoutCancel(ctx), p.session); rerr != nil {
+			logger.Error("remove upload %s: %v", p.session.ID, rerr)
+		}
+	}
+	return err
+}
+
+func validSHA256(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == sha256.Size
+}
+
+// partReader reads the parts of an upload in order.
+type partReader struct {
+	ctx   context.Context
+	store blob.Store
+	parts []Part
+	next  int64 // expected start of the next part
+	cur   io.ReadCloser
+}
+
+func (r *partReader) Read(b []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			part := r.parts[0]
+			if part.StartOffset != r.next {
+				return 0, fmt.Errorf("upload part at %d missing", r.next)
+			}
+			rc, err := r.store.Open(r.ctx, part.Key)
+			if err != nil {
+				return 0, err
+			}
+			r.cur, r.parts, r.next = rc, r.parts[1:], part.StartOffset+part.Size
+		}
+		n, err := r.cur.Read(b)
+		if errors.Is(err, io.EOF) {
+			r.cur.Close()
+			r.cur = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *partReader) Close() error {
+	if r.cur != nil {
+		return r.cur.Close()
+	}
+	return nil
+}
+
+// checksumReader fails with ErrChecksumMismatch instead of io.EOF when the
+// data read does not hash to want.
+type checksumReader struct {
+	r    io.Reader
+	h    hash.Hash
+	want string
+}
+
+func (c *checksumReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.h.Write(b[:n])
+	if errors.Is(err, io.EOF) && hex.EncodeToString(c.h.Sum(nil)) != c.want {
+		return n, ErrChecksumMismatch
+	}
+	return n, err
+}
@@ -0,0 +1,81 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// GormSessions keeps upload sessions in the upload_sessions and
+// upload_parts tables.
+type GormSessions struct {
+	db *gorm.DB
+}
+
+// NewGormSessions returns a Sessions backed by db.
+func NewGormSessions(db *gorm.DB) *GormSessions {
+	return &GormSessions{db: db}
+}
+
+func (r *GormSessions) CreateSession(ctx context.Context, s *Session) error {
+	err := r.db.WithContext(ctx).Create(s).Error
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrIDTaken
+	}
+	return err
+}
+
+func (r *GormSessions) GetSession(ctx context.Context, id uuid.UUID) (*Session, error) {
+	var s Session
+	if err := r.db.WithContext(ctx).First(&s, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// AddPart advances received only if it still equals the part's start
+// offset, so two streams resuming the same upload cannot interleave.
+func (r *GormSessions) AddPart(ctx context.Context, p *Part) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Session{}).
+			Where("id = ? AND received = ?", p.UploadID, p.StartOffset).
+			Update("received", p.StartOffset+p.Size)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrOffset
+		}
+		return tx.Create(p).Error
+	})
+}
+
+func (r *GormSessions) ListParts(ctx context.Context, uploadID uuid.UUID) ([]Part, error) {
+	var list []Part
+	if err := r.db.WithContext(ctx).Where("upload_id = ?", uploadID).Order("start_offset").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// DeleteSession deletes the session; its parts cascade.
+func (r *GormSessions) DeleteSession(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&Session{}, "id = ?", id).Error
+}
+
+func (r *GormSessions) ListExpiredSessions(ctx context.Context, kind string, now time.Time, limit int) ([]Session, error) {
+	var list []Session
+	if err := r.db.WithContext(ctx).Where("kind = ? AND expires_at < ?", kind, now).
+		Order("expires_at").Limit(limit).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,131 @@
+// Package upload guards user uploads before they are processed: the size
+// is limited while the data is read, the type is detected from magic bytes
+// and checked against an allowlist, and an optional Scanner can reject
+// malware, in which case the file is moved to a quarantine store.
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/logger"
+)
+
+var (
+	ErrTooLarge       = errors.New("upload exceeds the size limit")
+	ErrTypeNotAllowed = errors.New("upload type is not allowed")
+	ErrInfected       = errors.New("upload rejected by malware scan")
+	ErrScanFailed     = errors.New("malware scan failed")
+)
+
+// sniffLen is the number of leading bytes used for type detection.
+const sniffLen = 512
+
+// Policy limits what an upload may contain.
+type Policy struct {
+	MaxBytes     int64    // larger uploads are rejected as soon as the limit is crossed
+	AllowedTypes []string // content types detected from magic bytes
+}
+
+// DefaultImagePolicy accepts images up to 10 MiB.
+var DefaultImagePolicy = Policy{
+	MaxBytes:     10 << 20,
+	AllowedTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp"},
+}
+
+// Scanner inspects an upload. It returns an error wrapping ErrInfected
+// (with the detected signature in its message) when the content must be
+// rejected. Guard logs that message and returns the bare ErrInfected.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// Guard applies a Policy and an optional Scanner to uploads.
+type Guard struct {
+	policy     Policy
+	scanner    Scanner    // nil → no scanning
+	quarantine blob.Store // nil → rejected files are dropped
+}
+
+// NewGuard returns a Guard. Files rejected by scanner are kept in
+// quarantine under "quarantine/<date>/" for later inspection.
+func NewGuard(p Policy, scanner Scanner, quarantine blob.Store) *Guard {
+	return &Guard{policy: p, scanner: scanner, quarantine: quarantine}
+}
+
+// Read reads an upload from r and returns its content and detected type.
+// Reading stops as soon as the size limit is crossed and the type is
+// checked on the first bytes, before the rest is read.
+func (g *Guard) Read(ctx context.Context, r io.Reader) ([]byte, string, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", err
+	}
+	ct, err := g.Sniff(head)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := io.ReadAll(io.LimitReader(br, g.policy.MaxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > g.policy.MaxBytes {
+		return nil, "", fmt.Errorf("%w of %d bytes", ErrTooLarge, g.policy.MaxBytes)
+	}
+	if err := g.scan(ctx, data); err != nil {
+		return nil, "", err
+	}
+	return data, ct, nil
+}
+
+// Sniff detects the content type of head (the first bytes of an upload)
+// and checks it against the allowlist.
+func (g *Guard) Sniff(head []byte) (string, error) {
+	ct := http.DetectContentType(head)
+	for _, allowed := range g.policy.AllowedTypes {
+		if ct == allowed {
+			return ct, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrTypeNotAllowed, ct)
+}
+
+func (g *Guard) scan(ctx context.Context, data []byte) error {
+	if g.scanner == nil {
+		return nil
+	}
+	// Signatures, storage keys and scanner failures are logged; callers
+	// get the bare sentinel errors, which are safe to show to the uploader.
+	err := g.scanner.Scan(ctx, bytes.NewReader(data))
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrInfected):
+		if key, qerr := g.quarantineFile(ctx, data); qerr != nil {
+			logger.Error("upload rejected (%v), quarantine failed: %v", err, qerr)
+		} else {
+			logger.Info("upload rejected (%v), quarantined as %s", err, key)
+		}
+		return ErrInfected
+	default:
+		logger.Error("malware scan: %v", err)
+		return ErrScanFailed
+	}
+}
+
+func (g *Guard) quarantineFile(ctx context.Context, data []byte) (string, error) {
+	if g.quarantine == nil {
+		return "", errors.New("no quarantine store")
+	}
+	key := blob.ContentKey("quarantine/"+time.Now().UTC().Format("2006-01-02"), data, "")
+	err := g.quarantine.Put(context.WithoutCancel(ctx), key, bytes.NewReader(data), int64(len(data)), "application/octet-stream")
+	return key, err
+}
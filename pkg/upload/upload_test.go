@@ -0,0 +1,124 @@
+package upload_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ADRPUR/event-driven-marketplace/pkg/blob"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/upload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// stubClamd answers INSTREAM requests like clamd, flagging the EICAR string.
+func stubClamd(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+					io.WriteString(conn, "UNKNOWN COMMAND\x00")
+					return
+				}
+				var body bytes.Buffer
+				for {
+					var n uint32
+					if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+						return
+					}
+					if n == 0 {
+						break
+					}
+					if _, err := io.CopyN(&body, r, int64(n)); err != nil {
+						return
+					}
+				}
+				if strings.Contains(body.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+					io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+					return
+				}
+				io.WriteString(conn, "stream: OK\x00")
+			}(conn)
+		}
+	}()
+	return lis.Addr().String()
+}
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))))
+	return buf.Bytes()
+}
+
+func TestGuard_SizeAndType(t *testing.T) {
+	ctx := context.Background()
+	img := pngBytes(t, 64, 64)
+	g := upload.NewGuard(upload.Policy{MaxBytes: int64(len(img)), AllowedTypes: []string{"image/png"}}, nil, nil)
+
+	data, ct, err := g.Read(ctx, bytes.NewReader(img))
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", ct)
+	assert.Equal(t, img, data)
+
+	_, _, err = g.Read(ctx, io.MultiReader(bytes.NewReader(img), strings.NewReader("x")))
+	assert.ErrorIs(t, err, upload.ErrTooLarge)
+
+	_, _, err = g.Read(ctx, strings.NewReader("<html><script>alert(1)</script></html>"))
+	assert.ErrorIs(t, err, upload.ErrTypeNotAllowed)
+
+	// a GIF is a valid image but not on this allowlist
+	_, _, err = g.Read(ctx, strings.NewReader("GIF89a\x01\x00\x01\x00"))
+	assert.ErrorIs(t, err, upload.ErrTypeNotAllowed)
+}
+
+func TestGuard_ClamAVQuarantine(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewLocal(t.TempDir(), "/media", []byte("secret"))
+	require.NoError(t, err)
+	scanner := upload.NewClamAV(stubClamd(t), 5*time.Second)
+	g := upload.NewGuard(upload.Policy{MaxBytes: 1 << 20, AllowedTypes: []string{"image/png", "text/plain; charset=utf-8"}}, scanner, store)
+
+	_, _, err = g.Read(ctx, bytes.NewReader(pngBytes(t, 300, 300)))
+	assert.NoError(t, err)
+
+	_, _, err = g.Read(ctx, strings.NewReader(eicar))
+	require.ErrorIs(t, err, upload.ErrInfected)
+	assert.NotContains(t, err.Error(), "quarantine", "storage keys are not shown to the uploader")
+
+	key := blob.ContentKey("quarantine/"+time.Now().UTC().Format("2006-01-02"), []byte(eicar), "")
+	rc, err := store.Open(ctx, key)
+	require.NoError(t, err)
+	kept, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, eicar, string(kept))
+}
+
+func TestGuard_ScannerUnavailable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+
+	g := upload.NewGuard(upload.DefaultImagePolicy, upload.NewClamAV(addr, time.Second), nil)
+	_, _, err = g.Read(context.Background(), bytes.NewReader(pngBytes(t, 8, 8)))
+	assert.ErrorIs(t, err, upload.ErrScanFailed)
+}
@@ -28,7 +28,7 @@ func bufDialer(lis *bufconn.Listener) func(context.Context, string) (net.Conn, e
 func startIntegrationGRPCServer(t *testing.T) (*grpc.ClientConn, func(), service.AuthService) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
-	require.NoError(t, db.AutoMigrate(&model.User{}, &model.UserDetails{}, &model.Session{}))
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.UserDetails{}, &model.Address{}, &model.Session{}))
 	userRepo := repository.NewGormRepository(db)
 	sessionRepo := repository.NewGormRepository(db)
 	maker, _ := token.NewPasetoMaker("12345678901234567890123456789012")
@@ -18,6 +18,7 @@ import (
 
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/model"
 	"github.com/ADRPUR/event-driven-marketplace/internal/product/repository"
+	"github.com/ADRPUR/event-driven-marketplace/pkg/money"
 )
 
 // startPostgresContainer boots a Postgres 15 container and returns the handle + DSN.
@@ -53,7 +54,7 @@ func TestProductCRUD(t *testing.T) {
 	require.NoError(t, err)
 
 	// auto‑migrate schema
-	require.NoError(t, db.AutoMigrate(&model.Product{}))
+	require.NoError(t, db.AutoMigrate(&model.Product{}, &model.Variant{}))
 
 	repo := repository.NewGormRepository(db)
 
@@ -62,7 +63,7 @@ func TestProductCRUD(t *testing.T) {
 		ID:          uuid.New(),
 		Name:        "Test product",
 		Description: "From integration test",
-		Price:       199.99,
+		Price:       money.New(19999, "EUR"),
 	}
 	require.NoError(t, repo.Create(ctx, prod))
 
@@ -72,7 +73,7 @@ func TestProductCRUD(t *testing.T) {
 	require.Equal(t, prod.Name, got.Name)
 
 	// ---- UPDATE ----
-	prod.Price = 149.99
+	prod.Price = money.New(14999, "EUR")
 	require.NoError(t, repo.Update(ctx, prod))
 
 	// ---- DELETE ----